@@ -0,0 +1,58 @@
+// Package catalog holds product identification helpers: barcode validation
+// and product name search.
+package catalog
+
+import (
+	"errors"
+	"strings"
+)
+
+// NormalizeBarcode trims spaces scanners and people add around a code.
+func NormalizeBarcode(code string) string {
+	return strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+}
+
+// ValidateBarcode checks that code is an EAN-8, UPC-A, EAN-13 or GTIN-14 with
+// a correct check digit.
+func ValidateBarcode(code string) error {
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return errors.New("barcode must be EAN-8, UPC-A (12), EAN-13 or GTIN-14 digits")
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return errors.New("barcode must contain digits only")
+		}
+	}
+	if checkDigit(code[:len(code)-1]) != code[len(code)-1] {
+		return errors.New("invalid barcode check digit")
+	}
+	return nil
+}
+
+// checkDigit computes the GS1 check digit: weights 3,1,3,... from the right.
+func checkDigit(body string) byte {
+	sum := 0
+	for i := 0; i < len(body); i++ {
+		d := int(body[len(body)-1-i] - '0')
+		if i%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// BarcodeVariants returns the forms a scanned code may be stored under: a
+// UPC-A is also an EAN-13 with a leading zero and vice versa.
+func BarcodeVariants(code string) []string {
+	variants := []string{code}
+	switch {
+	case len(code) == 12:
+		variants = append(variants, "0"+code)
+	case len(code) == 13 && code[0] == '0':
+		variants = append(variants, code[1:])
+	}
+	return variants
+}
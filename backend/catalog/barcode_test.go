@@ -0,0 +1,60 @@
+package catalog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateBarcode(t *testing.T) {
+	tests := []struct {
+		code string
+		ok   bool
+	}{
+		{"8991234567891", true},  // EAN-13
+		{"8991234567892", false}, // check digit salah
+		{"96385074", true},       // EAN-8
+		{"96385075", false},
+		{"036000291452", true}, // UPC-A
+		{"036000291453", false},
+		{"10012345678902", true}, // GTIN-14
+		{"899123456789", false},  // 12 digit, bukan UPC-A yang sah
+		{"123", false},
+		{"89912345678a1", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		err := ValidateBarcode(tt.code)
+		if (err == nil) != tt.ok {
+			t.Errorf("ValidateBarcode(%q) = %v, want ok %v", tt.code, err, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeBarcode(t *testing.T) {
+	for in, want := range map[string]string{
+		" 8991234567891\n": "8991234567891",
+		"899 1234 567891":  "8991234567891",
+		"":                 "",
+	} {
+		if got := NormalizeBarcode(in); got != want {
+			t.Errorf("NormalizeBarcode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBarcodeVariants(t *testing.T) {
+	tests := []struct {
+		code string
+		want []string
+	}{
+		{"036000291452", []string{"036000291452", "0036000291452"}},
+		{"0036000291452", []string{"0036000291452", "036000291452"}},
+		{"8991234567891", []string{"8991234567891"}},
+		{"96385074", []string{"96385074"}},
+	}
+	for _, tt := range tests {
+		if got := BarcodeVariants(tt.code); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("BarcodeVariants(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
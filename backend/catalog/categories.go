@@ -0,0 +1,84 @@
+package catalog
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/models"
+)
+
+// maxCategoryDepth guards the parent walks against a cycle that slipped in
+// through sync.
+const maxCategoryDepth = 32
+
+// Categories holds the live categories by id.
+type Categories map[string]models.Category
+
+// LoadCategories reads all live categories.
+func LoadCategories(db *gorm.DB) (Categories, error) {
+	var rows []models.Category
+	if err := db.Where("is_deleted = ?", false).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	cats := make(Categories, len(rows))
+	for _, c := range rows {
+		cats[c.ID] = c
+	}
+	return cats, nil
+}
+
+// Ancestors returns id followed by its parents up to the top-level category.
+// Unknown ids yield nil.
+func (cats Categories) Ancestors(id string) []string {
+	var chain []string
+	for id != "" && len(chain) < maxCategoryDepth {
+		c, ok := cats[id]
+		if !ok {
+			break
+		}
+		chain = append(chain, id)
+		id = c.ParentID
+	}
+	return chain
+}
+
+// Path is the category name prefixed by its parents, e.g. "Minuman > Kopi".
+func (cats Categories) Path(id string) string {
+	chain := cats.Ancestors(id)
+	names := make([]string, len(chain))
+	for i, cid := range chain {
+		names[len(chain)-1-i] = cats[cid].Name
+	}
+	return strings.Join(names, " > ")
+}
+
+// Subtree returns id and the ids of every category below it.
+func (cats Categories) Subtree(id string) []string {
+	children := map[string][]string{}
+	for _, c := range cats {
+		children[c.ParentID] = append(children[c.ParentID], c.ID)
+	}
+	ids := []string{id}
+	seen := map[string]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// IsUnder reports whether id is parentID or one of its descendants; used to
+// reject moves that would make a category its own ancestor.
+func (cats Categories) IsUnder(id, parentID string) bool {
+	for _, a := range cats.Ancestors(parentID) {
+		if a == id {
+			return true
+		}
+	}
+	return false
+}
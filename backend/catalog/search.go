@@ -0,0 +1,93 @@
+package catalog
+
+import (
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// ftsEnabled is set by Setup when the SQLite build has FTS5.
+var ftsEnabled bool
+
+// Setup creates the products_fts index and the triggers that keep it in step
+// with products. FTS5 is only compiled into go-sqlite3 with the sqlite_fts5
+// build tag; without it search falls back to LIKE and the triggers are
+// dropped so writes to products keep working.
+func Setup(db *gorm.DB) {
+	stmts := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS products_fts USING fts5(name, sku, content='products', content_rowid='rowid', tokenize='trigram')`,
+		`CREATE TRIGGER IF NOT EXISTS products_fts_ai AFTER INSERT ON products BEGIN
+			INSERT INTO products_fts(rowid, name, sku) VALUES (new.rowid, new.name, new.sku);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS products_fts_ad AFTER DELETE ON products BEGIN
+			INSERT INTO products_fts(products_fts, rowid, name, sku) VALUES ('delete', old.rowid, old.name, old.sku);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS products_fts_au AFTER UPDATE OF name, sku ON products BEGIN
+			INSERT INTO products_fts(products_fts, rowid, name, sku) VALUES ('delete', old.rowid, old.name, old.sku);
+			INSERT INTO products_fts(rowid, name, sku) VALUES (new.rowid, new.name, new.sku);
+		END`,
+		// rowid produk bisa berubah setelah VACUUM, jadi indeks dibangun ulang saat start
+		`INSERT INTO products_fts(products_fts) VALUES ('rebuild')`,
+	}
+	for _, stmt := range stmts {
+		if err := db.Exec(stmt).Error; err != nil {
+			log.Printf("warn: product full-text search unavailable, using LIKE: %v", err)
+			for _, t := range []string{"products_fts_ai", "products_fts_ad", "products_fts_au"} {
+				db.Exec("DROP TRIGGER IF EXISTS " + t)
+			}
+			ftsEnabled = false
+			return
+		}
+	}
+	ftsEnabled = true
+}
+
+// SearchProducts narrows q (a query on products) to those matching term by
+// name or SKU. With FTS5 every word of three or more letters must appear;
+// when nothing matches, products sharing the most trigrams with the term are
+// returned instead, so small typos still find the product. ranked reports
+// whether q now carries a relevance column, fts.fts_rank (lower is better).
+func SearchProducts(db, q *gorm.DB, term string) (result *gorm.DB, ranked bool) {
+	words := strings.Fields(strings.ToLower(strings.ReplaceAll(term, `"`, " ")))
+	var long, short []string
+	for _, w := range words {
+		if utf8.RuneCountInString(w) >= 3 {
+			long = append(long, w)
+		} else {
+			short = append(short, w)
+		}
+	}
+	if !ftsEnabled || len(long) == 0 {
+		for _, w := range words {
+			like := "%" + w + "%"
+			q = q.Where("(LOWER(products.name) LIKE ? OR LOWER(products.sku) LIKE ?)", like, like)
+		}
+		return q, false
+	}
+
+	exact := make([]string, len(long))
+	for i, w := range long {
+		exact[i] = `"` + w + `"`
+	}
+	expr := strings.Join(exact, " AND ")
+	var hits int64
+	if err := db.Raw("SELECT COUNT(*) FROM products_fts WHERE products_fts MATCH ?", expr).Scan(&hits).Error; err == nil && hits == 0 {
+		var grams []string
+		for _, w := range long {
+			r := []rune(w)
+			for i := 0; i+3 <= len(r); i++ {
+				grams = append(grams, `"`+string(r[i:i+3])+`"`)
+			}
+		}
+		expr = strings.Join(grams, " OR ")
+	}
+
+	q = q.Joins("JOIN (SELECT rowid AS fts_rowid, bm25(products_fts) AS fts_rank FROM products_fts WHERE products_fts MATCH ?) fts ON fts.fts_rowid = products.rowid", expr)
+	for _, w := range short {
+		like := "%" + w + "%"
+		q = q.Where("(LOWER(products.name) LIKE ? OR LOWER(products.sku) LIKE ?)", like, like)
+	}
+	return q, true
+}
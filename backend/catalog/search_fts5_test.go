@@ -0,0 +1,21 @@
+//go:build sqlite_fts5
+
+package catalog
+
+import "testing"
+
+// Shipped builds use the sqlite_fts5 tag; search must then run on FTS5.
+func TestSearchUsesFTS5(t *testing.T) {
+	db := searchDB(t)
+	if !ftsEnabled {
+		t.Fatal("FTS5 not enabled although built with sqlite_fts5")
+	}
+	_, ranked := SearchProducts(db, db, "beras")
+	if !ranked {
+		t.Error("search is not ranked by FTS5")
+	}
+	// salah ketik tetap menemukan produk lewat trigram
+	if got := search(t, db, "brass pandn"); !got["p1"] {
+		t.Errorf("typo search found %v, want p1", got)
+	}
+}
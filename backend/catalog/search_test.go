@@ -0,0 +1,89 @@
+package catalog
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"shosha_mart_backend/models"
+)
+
+// searchDB opens an in-memory database with a few products and the search
+// index set up. One connection keeps every query on the same database.
+func searchDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.Product{}); err != nil {
+		t.Fatal(err)
+	}
+	Setup(db)
+	for _, p := range []models.Product{
+		{ID: "p1", SKU: "BRS-05", Name: "Beras Pandan Wangi 5kg"},
+		{ID: "p2", SKU: "GUL-01", Name: "Gula Pasir 1kg"},
+		{ID: "p3", SKU: "KOP-AB", Name: "Kopi Bubuk ABC"},
+	} {
+		if err := db.Create(&p).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func search(t *testing.T, db *gorm.DB, term string) map[string]bool {
+	t.Helper()
+	q, _ := SearchProducts(db, db.Model(&models.Product{}), term)
+	var found []models.Product
+	if err := q.Find(&found).Error; err != nil {
+		t.Fatal(err)
+	}
+	ids := map[string]bool{}
+	for _, p := range found {
+		ids[p.ID] = true
+	}
+	return ids
+}
+
+// TestSearchProducts holds for both the LIKE fallback and FTS5.
+func TestSearchProducts(t *testing.T) {
+	db := searchDB(t)
+	tests := []struct {
+		term string
+		want []string
+	}{
+		{"beras", []string{"p1"}},
+		{"PANDAN beras", []string{"p1"}},
+		{"gul-01", []string{"p2"}},
+		{"kg", []string{"p1", "p2"}},
+		{"kopi ab", []string{"p3"}},
+	}
+	for _, tt := range tests {
+		got := search(t, db, tt.term)
+		if len(got) != len(tt.want) {
+			t.Errorf("%q found %v, want %v", tt.term, got, tt.want)
+			continue
+		}
+		for _, id := range tt.want {
+			if !got[id] {
+				t.Errorf("%q found %v, want %v", tt.term, got, tt.want)
+			}
+		}
+	}
+	// indeks ikut berubah bersama produk
+	if err := db.Model(&models.Product{}).Where("id = ?", "p2").Update("name", "Gula Aren 1kg").Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := search(t, db, "aren"); !got["p2"] {
+		t.Errorf("renamed product not found: %v", got)
+	}
+}
@@ -12,27 +12,87 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 
+	"shosha_mart_backend/migrations"
 	"shosha_mart_backend/models"
+	"shosha_mart_backend/pricing"
 )
 
 type UploadPayload struct {
-	BranchID         string                   `json:"branch_id"`
-	Products         []models.Product         `json:"products"`
-	Branches         []models.Branch          `json:"branches"`
-	Sales            []models.Sale            `json:"sales"`
-	SaleItems        []models.SaleItem        `json:"sale_items"`
-	StockOpnames     []models.StockOpname     `json:"stock_opnames"`
-	StockOpnameItems []models.StockOpnameItem `json:"stock_opname_items"`
+	BranchID              string                        `json:"branch_id"`
+	Products              []models.Product              `json:"products"`
+	Branches              []models.Branch               `json:"branches"`
+	Sales                 []models.Sale                 `json:"sales"`
+	SaleItems             []models.SaleItem             `json:"sale_items"`
+	SalePayments          []models.SalePayment          `json:"sale_payments"`
+	Promotions            []models.Promotion            `json:"promotions"`
+	TaxRates              []models.TaxRate              `json:"tax_rates"`
+	Customers             []models.Customer             `json:"customers"`
+	CashSessions          []models.CashSession          `json:"cash_sessions"`
+	CashMovements         []models.CashMovement         `json:"cash_movements"`
+	ProductBarcodes       []models.ProductBarcode       `json:"product_barcodes"`
+	Categories            []models.Category             `json:"categories"`
+	Brands                []models.Brand                `json:"brands"`
+	ProductUnits          []models.ProductUnit          `json:"product_units"`
+	CostLayers            []models.CostLayer            `json:"cost_layers"`
+	ProductPriceHistories []models.ProductPriceHistory  `json:"product_price_histories"`
+	ScheduledPriceChanges []models.ScheduledPriceChange `json:"scheduled_price_changes"`
+	Suppliers             []models.Supplier             `json:"suppliers"`
+	PurchaseOrders        []models.PurchaseOrder        `json:"purchase_orders"`
+	PurchaseOrderItems    []models.PurchaseOrderItem    `json:"purchase_order_items"`
+	GoodsReceipts         []models.GoodsReceipt         `json:"goods_receipts"`
+	GoodsReceiptItems     []models.GoodsReceiptItem     `json:"goods_receipt_items"`
+	StockTransfers        []models.StockTransfer        `json:"stock_transfers"`
+	StockTransferItems    []models.StockTransferItem    `json:"stock_transfer_items"`
+	StockMovements        []models.StockMovement        `json:"stock_movements"`
+	ReorderLevels         []models.ReorderLevel         `json:"reorder_levels"`
+	StockBatches          []models.StockBatch           `json:"stock_batches"`
+	StockBatchAllocations []models.StockBatchAllocation `json:"stock_batch_allocations"`
+	StockAdjustments      []models.StockAdjustment      `json:"stock_adjustments"`
+	StockAdjustmentItems  []models.StockAdjustmentItem  `json:"stock_adjustment_items"`
+	StockOpnameCounts     []models.StockOpnameCount     `json:"stock_opname_counts"`
+	BranchStocks          []models.BranchStock          `json:"branch_stocks"`
+	BranchPrices          []models.BranchPrice          `json:"branch_prices"`
+	StockOpnames          []models.StockOpname          `json:"stock_opnames"`
+	StockOpnameItems      []models.StockOpnameItem      `json:"stock_opname_items"`
 }
 
 type ChangesResponse struct {
-	Products         []models.Product         `json:"products"`
-	Branches         []models.Branch          `json:"branches"`
-	Sales            []models.Sale            `json:"sales"`
-	SaleItems        []models.SaleItem        `json:"sale_items"`
-	StockOpnames     []models.StockOpname     `json:"stock_opnames"`
-	StockOpnameItems []models.StockOpnameItem `json:"stock_opname_items"`
-	LastSyncAt       *time.Time               `json:"last_sync_at"`
+	Products              []models.Product              `json:"products"`
+	Branches              []models.Branch               `json:"branches"`
+	Sales                 []models.Sale                 `json:"sales"`
+	SaleItems             []models.SaleItem             `json:"sale_items"`
+	SalePayments          []models.SalePayment          `json:"sale_payments"`
+	Promotions            []models.Promotion            `json:"promotions"`
+	TaxRates              []models.TaxRate              `json:"tax_rates"`
+	Customers             []models.Customer             `json:"customers"`
+	CashSessions          []models.CashSession          `json:"cash_sessions"`
+	CashMovements         []models.CashMovement         `json:"cash_movements"`
+	ProductBarcodes       []models.ProductBarcode       `json:"product_barcodes"`
+	Categories            []models.Category             `json:"categories"`
+	Brands                []models.Brand                `json:"brands"`
+	ProductUnits          []models.ProductUnit          `json:"product_units"`
+	CostLayers            []models.CostLayer            `json:"cost_layers"`
+	ProductPriceHistories []models.ProductPriceHistory  `json:"product_price_histories"`
+	ScheduledPriceChanges []models.ScheduledPriceChange `json:"scheduled_price_changes"`
+	Suppliers             []models.Supplier             `json:"suppliers"`
+	PurchaseOrders        []models.PurchaseOrder        `json:"purchase_orders"`
+	PurchaseOrderItems    []models.PurchaseOrderItem    `json:"purchase_order_items"`
+	GoodsReceipts         []models.GoodsReceipt         `json:"goods_receipts"`
+	GoodsReceiptItems     []models.GoodsReceiptItem     `json:"goods_receipt_items"`
+	StockTransfers        []models.StockTransfer        `json:"stock_transfers"`
+	StockTransferItems    []models.StockTransferItem    `json:"stock_transfer_items"`
+	StockMovements        []models.StockMovement        `json:"stock_movements"`
+	ReorderLevels         []models.ReorderLevel         `json:"reorder_levels"`
+	StockBatches          []models.StockBatch           `json:"stock_batches"`
+	StockBatchAllocations []models.StockBatchAllocation `json:"stock_batch_allocations"`
+	StockAdjustments      []models.StockAdjustment      `json:"stock_adjustments"`
+	StockAdjustmentItems  []models.StockAdjustmentItem  `json:"stock_adjustment_items"`
+	StockOpnameCounts     []models.StockOpnameCount     `json:"stock_opname_counts"`
+	BranchStocks          []models.BranchStock          `json:"branch_stocks"`
+	BranchPrices          []models.BranchPrice          `json:"branch_prices"`
+	StockOpnames          []models.StockOpname          `json:"stock_opnames"`
+	StockOpnameItems      []models.StockOpnameItem      `json:"stock_opname_items"`
+	LastSyncAt            *time.Time                    `json:"last_sync_at"`
 }
 
 func main() {
@@ -55,10 +115,16 @@ func main() {
 	if err != nil {
 		log.Fatalf("connect postgres: %v", err)
 	}
-	if err := db.AutoMigrate(&models.Product{}, &models.Branch{}, &models.Sale{}, &models.SaleItem{}, &models.StockOpname{}, &models.StockOpnameItem{}); err != nil {
+	if err := migrations.Run(db); err != nil {
+		log.Fatalf("data migrations: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Product{}, &models.Branch{}, &models.Sale{}, &models.SaleItem{}, &models.SalePayment{}, &models.Promotion{}, &models.TaxRate{}, &models.Customer{}, &models.CashSession{}, &models.CashMovement{}, &models.ProductBarcode{}, &models.Category{}, &models.Brand{}, &models.ProductUnit{}, &models.CostLayer{}, &models.ProductPriceHistory{}, &models.ScheduledPriceChange{}, &models.Supplier{}, &models.PurchaseOrder{}, &models.PurchaseOrderItem{}, &models.GoodsReceipt{}, &models.GoodsReceiptItem{}, &models.StockTransfer{}, &models.StockTransferItem{}, &models.StockMovement{}, &models.ReorderLevel{}, &models.StockBatch{}, &models.StockBatchAllocation{}, &models.StockAdjustment{}, &models.StockAdjustmentItem{}, &models.StockOpnameCount{}, &models.BranchStock{}, &models.BranchPrice{}, &models.StockOpname{}, &models.StockOpnameItem{}); err != nil {
 		log.Fatalf("migrate: %v", err)
 	}
 
+	// perubahan harga terjadwal untuk semua cabang diterapkan di sini saja
+	pricing.StartScheduler(db, time.Minute, "")
+
 	r := gin.Default()
 	r.POST("/api/sync/upload", func(c *gin.Context) {
 		var payload UploadPayload
@@ -77,7 +143,7 @@ func main() {
 				}
 				if err := db.Clauses(clause.OnConflict{
 					Columns:   []clause.Column{{Name: "id"}},
-					DoUpdates: clause.AssignmentColumns([]string{"code", "name", "address", "phone", "synced", "is_deleted", "updated_at", "created_at"}),
+					DoUpdates: clause.AssignmentColumns([]string{"code", "name", "address", "phone", "price_tier", "synced", "is_deleted", "updated_at", "created_at"}),
 				}).Create(&b).Error; err != nil {
 					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 					return
@@ -95,8 +161,8 @@ func main() {
 				}
 				if err := db.Clauses(clause.OnConflict{
 					Columns:   []clause.Column{{Name: "id"}},
-					DoUpdates: clause.AssignmentColumns([]string{"name", "unit", "stock", "price", "price_investor", "price_shosha", "branch_id", "synced", "is_deleted", "updated_at", "created_at"}),
-				}).Create(&p).Error; err != nil {
+					DoUpdates: clause.AssignmentColumns([]string{"sku", "name", "unit", "category_id", "brand_id", "attributes", "price", "price_investor", "price_shosha", "cost", "tax_rate_id", "branch_id", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Omit("stock").Create(&p).Error; err != nil {
 					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 					return
 				}
@@ -105,11 +171,15 @@ func main() {
 		if len(payload.Sales) > 0 {
 			for _, s := range payload.Sales {
 				if s.IsDeleted {
-					// delete items first then sale
+					// delete items and payments first then sale
 					if err := db.Where("sale_id = ?", s.ID).Delete(&models.SaleItem{}).Error; err != nil {
 						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 						return
 					}
+					if err := db.Where("sale_id = ?", s.ID).Delete(&models.SalePayment{}).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
 					if err := db.Delete(&models.Sale{}, "id = ?", s.ID).Error; err != nil {
 						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 						return
@@ -118,7 +188,7 @@ func main() {
 				}
 				if err := db.Clauses(clause.OnConflict{
 					Columns:   []clause.Column{{Name: "id"}},
-					DoUpdates: clause.AssignmentColumns([]string{"receipt_no", "branch_id", "branch_name", "payment_method", "notes", "total", "synced", "is_deleted", "updated_at", "created_at"}),
+					DoUpdates: clause.AssignmentColumns([]string{"receipt_no", "branch_id", "branch_name", "customer_id", "customer_name", "cash_session_id", "payment_method", "price_tier", "notes", "subtotal", "discount_type", "discount_value", "discount_amount", "promotion_id", "tax_amount", "total", "change_due", "print_count", "synced", "is_deleted", "updated_at", "created_at"}),
 				}).Create(&s).Error; err != nil {
 					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 					return
@@ -136,13 +206,535 @@ func main() {
 				}
 				if err := db.Clauses(clause.OnConflict{
 					Columns:   []clause.Column{{Name: "id"}},
-					DoUpdates: clause.AssignmentColumns([]string{"sale_id", "product_id", "qty", "price", "synced", "is_deleted", "updated_at", "created_at"}),
+					DoUpdates: clause.AssignmentColumns([]string{"sale_id", "product_id", "qty", "unit", "unit_factor", "base_qty", "price", "price_overridden", "discount_type", "discount_value", "discount_amount", "promotion_id", "subtotal", "unit_cost", "tax_rate_id", "tax_code", "tax_rate", "tax_inclusive", "tax_base", "tax_amount", "synced", "is_deleted", "updated_at", "created_at"}),
 				}).Create(&si).Error; err != nil {
 					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 					return
 				}
 			}
 		}
+		if len(payload.SalePayments) > 0 {
+			for _, sp := range payload.SalePayments {
+				if sp.IsDeleted {
+					if err := db.Delete(&models.SalePayment{}, "id = ?", sp.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"sale_id", "method", "amount", "tendered", "reference", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&sp).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.Promotions) > 0 {
+			for _, pr := range payload.Promotions {
+				if pr.IsDeleted {
+					if err := db.Delete(&models.Promotion{}, "id = ?", pr.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"name", "type", "product_id", "branch_id", "buy_qty", "free_qty", "bundle_qty", "bundle_price", "discount_type", "discount_value", "min_spend", "starts_at", "ends_at", "daily_start", "daily_end", "active", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&pr).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.TaxRates) > 0 {
+			for _, row := range payload.TaxRates {
+				if row.IsDeleted {
+					if err := db.Delete(&models.TaxRate{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"code", "name", "rate", "inclusive", "is_default", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.Customers) > 0 {
+			for _, row := range payload.Customers {
+				if row.IsDeleted {
+					if err := db.Delete(&models.Customer{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"name", "phone", "address", "npwp", "price_tier", "credit_limit", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.CashSessions) > 0 {
+			for _, row := range payload.CashSessions {
+				if row.IsDeleted {
+					if err := db.Delete(&models.CashSession{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"branch_id", "till_id", "cashier", "status", "opening_float", "opened_at", "closed_at", "expected_cash", "counted_cash", "difference", "close_note", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.CashMovements) > 0 {
+			for _, row := range payload.CashMovements {
+				if row.IsDeleted {
+					if err := db.Delete(&models.CashMovement{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"cash_session_id", "type", "amount", "reason", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.ProductBarcodes) > 0 {
+			for _, row := range payload.ProductBarcodes {
+				if row.IsDeleted {
+					if err := db.Delete(&models.ProductBarcode{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"product_id", "code", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.Categories) > 0 {
+			for _, row := range payload.Categories {
+				if row.IsDeleted {
+					if err := db.Delete(&models.Category{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"parent_id", "name", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.Brands) > 0 {
+			for _, row := range payload.Brands {
+				if row.IsDeleted {
+					if err := db.Delete(&models.Brand{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"name", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.ProductUnits) > 0 {
+			for _, row := range payload.ProductUnits {
+				if row.IsDeleted {
+					if err := db.Delete(&models.ProductUnit{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"product_id", "name", "factor", "price", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.CostLayers) > 0 {
+			for _, row := range payload.CostLayers {
+				if row.IsDeleted {
+					if err := db.Delete(&models.CostLayer{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"product_id", "branch_id", "source", "qty", "remaining", "unit_cost", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.ProductPriceHistories) > 0 {
+			for _, row := range payload.ProductPriceHistories {
+				if row.IsDeleted {
+					if err := db.Delete(&models.ProductPriceHistory{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"product_id", "branch_id", "tier", "old_price", "new_price", "changed_by", "source", "schedule_id", "changed_at", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.ScheduledPriceChanges) > 0 {
+			for _, row := range payload.ScheduledPriceChanges {
+				if row.IsDeleted {
+					if err := db.Delete(&models.ScheduledPriceChange{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"product_id", "branch_id", "tier", "new_price", "effective_at", "created_by", "applied_at", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.Suppliers) > 0 {
+			for _, row := range payload.Suppliers {
+				if row.IsDeleted {
+					if err := db.Delete(&models.Supplier{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"code", "name", "contact_name", "phone", "email", "address", "npwp", "payment_terms", "note", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.PurchaseOrders) > 0 {
+			for _, row := range payload.PurchaseOrders {
+				if row.IsDeleted {
+					if err := db.Delete(&models.PurchaseOrder{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"number", "supplier_id", "branch_id", "status", "order_date", "expected_at", "note", "created_by", "total", "sent_at", "closed_at", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.PurchaseOrderItems) > 0 {
+			for _, row := range payload.PurchaseOrderItems {
+				if row.IsDeleted {
+					if err := db.Delete(&models.PurchaseOrderItem{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"purchase_order_id", "product_id", "unit", "unit_factor", "qty", "unit_cost", "subtotal", "received_qty", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.GoodsReceipts) > 0 {
+			for _, row := range payload.GoodsReceipts {
+				if row.IsDeleted {
+					if err := db.Delete(&models.GoodsReceipt{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"number", "purchase_order_id", "supplier_id", "branch_id", "invoice_no", "received_at", "received_by", "note", "total", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.GoodsReceiptItems) > 0 {
+			for _, row := range payload.GoodsReceiptItems {
+				if row.IsDeleted {
+					if err := db.Delete(&models.GoodsReceiptItem{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"goods_receipt_id", "purchase_order_item_id", "product_id", "unit", "unit_factor", "ordered_qty", "qty", "base_qty", "unit_cost", "subtotal", "batch_no", "expiry_date", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.StockTransfers) > 0 {
+			for _, row := range payload.StockTransfers {
+				if row.IsDeleted {
+					if err := db.Delete(&models.StockTransfer{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"number", "from_branch_id", "to_branch_id", "status", "note", "requested_by", "requested_at", "dispatched_by", "dispatched_at", "received_by", "received_at", "has_discrepancy", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.StockTransferItems) > 0 {
+			for _, row := range payload.StockTransferItems {
+				if row.IsDeleted {
+					if err := db.Delete(&models.StockTransferItem{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"stock_transfer_id", "product_id", "unit", "unit_factor", "requested_qty", "dispatched_qty", "received_qty", "discrepancy", "discrepancy_note", "unit_cost", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.StockMovements) > 0 {
+			for _, row := range payload.StockMovements {
+				if row.IsDeleted {
+					if err := db.Delete(&models.StockMovement{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"product_id", "branch_id", "source", "doc_id", "doc_no", "delta", "balance", "unit_cost", "created_by", "note", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.ReorderLevels) > 0 {
+			for _, row := range payload.ReorderLevels {
+				if row.IsDeleted {
+					if err := db.Delete(&models.ReorderLevel{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"product_id", "branch_id", "min_stock", "reorder_qty", "supplier_id", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.StockBatches) > 0 {
+			for _, row := range payload.StockBatches {
+				if row.IsDeleted {
+					if err := db.Delete(&models.StockBatch{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"product_id", "branch_id", "batch_no", "expiry_date", "qty", "remaining", "unit_cost", "source", "doc_id", "doc_no", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.StockBatchAllocations) > 0 {
+			for _, row := range payload.StockBatchAllocations {
+				if row.IsDeleted {
+					if err := db.Delete(&models.StockBatchAllocation{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"batch_id", "product_id", "doc_id", "qty", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.StockAdjustments) > 0 {
+			for _, row := range payload.StockAdjustments {
+				if row.IsDeleted {
+					if err := db.Delete(&models.StockAdjustment{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"number", "branch_id", "reason", "status", "note", "created_by", "approved_by", "approved_at", "posted_at", "loss_value", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.StockAdjustmentItems) > 0 {
+			for _, row := range payload.StockAdjustmentItems {
+				if row.IsDeleted {
+					if err := db.Delete(&models.StockAdjustmentItem{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"stock_adjustment_id", "product_id", "unit", "unit_factor", "qty", "base_qty", "unit_cost", "value", "note", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.StockOpnameCounts) > 0 {
+			for _, row := range payload.StockOpnameCounts {
+				if row.IsDeleted {
+					if err := db.Delete(&models.StockOpnameCount{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"stock_opname_id", "product_id", "unit", "unit_factor", "qty", "base_qty", "barcode", "counted_by", "recount", "superseded", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.BranchStocks) > 0 {
+			for _, row := range payload.BranchStocks {
+				if row.IsDeleted {
+					if err := db.Delete(&models.BranchStock{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"product_id", "branch_id", "qty", "cost", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+		if len(payload.BranchPrices) > 0 {
+			for _, row := range payload.BranchPrices {
+				if row.IsDeleted {
+					if err := db.Delete(&models.BranchPrice{}, "id = ?", row.ID).Error; err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					continue
+				}
+				if err := db.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "id"}},
+					DoUpdates: clause.AssignmentColumns([]string{"product_id", "branch_id", "tier", "price", "synced", "is_deleted", "updated_at", "created_at"}),
+				}).Create(&row).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
 		if len(payload.StockOpnames) > 0 {
 			for _, so := range payload.StockOpnames {
 				if so.IsDeleted {
@@ -154,7 +746,7 @@ func main() {
 				}
 				if err := db.Clauses(clause.OnConflict{
 					Columns:   []clause.Column{{Name: "id"}},
-					DoUpdates: clause.AssignmentColumns([]string{"branch_id", "performed_by", "note", "synced", "is_deleted", "updated_at", "created_at"}),
+					DoUpdates: clause.AssignmentColumns([]string{"number", "branch_id", "status", "category_id", "performed_by", "note", "finalized_by", "finalized_at", "synced", "is_deleted", "updated_at", "created_at"}),
 				}).Create(&so).Error; err != nil {
 					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 					return
@@ -172,7 +764,7 @@ func main() {
 				}
 				if err := db.Clauses(clause.OnConflict{
 					Columns:   []clause.Column{{Name: "id"}},
-					DoUpdates: clause.AssignmentColumns([]string{"stock_opname_id", "product_id", "system_qty", "physical_qty", "synced", "is_deleted", "updated_at", "created_at"}),
+					DoUpdates: clause.AssignmentColumns([]string{"stock_opname_id", "product_id", "system_qty", "physical_qty", "counted", "counted_at", "expected_qty", "variance", "synced", "is_deleted", "updated_at", "created_at"}),
 				}).Create(&soi).Error; err != nil {
 					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 					return
@@ -196,12 +788,41 @@ func main() {
 			since = time.Time{} // epoch -> all data
 		}
 		var (
-			products []models.Product
-			branches []models.Branch
-			sales    []models.Sale
-			items    []models.SaleItem
-			opnames  []models.StockOpname
-			opItems  []models.StockOpnameItem
+			products       []models.Product
+			branches       []models.Branch
+			sales          []models.Sale
+			items          []models.SaleItem
+			payments       []models.SalePayment
+			promos         []models.Promotion
+			taxRates       []models.TaxRate
+			customers      []models.Customer
+			cashSessions   []models.CashSession
+			cashMovements  []models.CashMovement
+			barcodes       []models.ProductBarcode
+			categories     []models.Category
+			brands         []models.Brand
+			productUnits   []models.ProductUnit
+			costLayers     []models.CostLayer
+			priceHistory   []models.ProductPriceHistory
+			priceSchedules []models.ScheduledPriceChange
+			suppliers      []models.Supplier
+			purchaseOrders []models.PurchaseOrder
+			poItems        []models.PurchaseOrderItem
+			goodsReceipts  []models.GoodsReceipt
+			grItems        []models.GoodsReceiptItem
+			transfers      []models.StockTransfer
+			transferItems  []models.StockTransferItem
+			movements      []models.StockMovement
+			reorderLevels  []models.ReorderLevel
+			batches        []models.StockBatch
+			allocations    []models.StockBatchAllocation
+			adjustments    []models.StockAdjustment
+			adjItems       []models.StockAdjustmentItem
+			opCounts       []models.StockOpnameCount
+			brStock        []models.BranchStock
+			brPrices       []models.BranchPrice
+			opnames        []models.StockOpname
+			opItems        []models.StockOpnameItem
 		)
 		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&products)
 		db.Find(&branches)
@@ -228,6 +849,35 @@ func main() {
 		log.Printf("[SYNC] Products found: %d", len(products))
 		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&items)
 		log.Printf("[SYNC] SaleItems found: %d", len(items))
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&payments)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&promos)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&taxRates)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&customers)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&cashSessions)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&cashMovements)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&barcodes)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&categories)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&brands)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&productUnits)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&costLayers)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&priceHistory)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&priceSchedules)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&suppliers)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&purchaseOrders)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&poItems)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&goodsReceipts)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&grItems)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&transfers)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&transferItems)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&movements)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&reorderLevels)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&batches)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&allocations)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&adjustments)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&adjItems)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&opCounts)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&brStock)
+		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&brPrices)
 		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&opnames)
 		db.Where("updated_at >= ? OR created_at >= ?", since, since).Find(&opItems)
 
@@ -235,13 +885,42 @@ func main() {
 		log.Printf("[SYNC] Sending response: Products=%d, Branches=%d, Sales=%d, SaleItems=%d, Opnames=%d, OpItems=%d",
 			len(products), len(branches), len(sales), len(items), len(opnames), len(opItems))
 		c.JSON(http.StatusOK, ChangesResponse{
-			Products:         products,
-			Branches:         branches,
-			Sales:            sales,
-			SaleItems:        items,
-			StockOpnames:     opnames,
-			StockOpnameItems: opItems,
-			LastSyncAt:       &now,
+			Products:              products,
+			Branches:              branches,
+			Sales:                 sales,
+			SaleItems:             items,
+			SalePayments:          payments,
+			Promotions:            promos,
+			TaxRates:              taxRates,
+			Customers:             customers,
+			CashSessions:          cashSessions,
+			CashMovements:         cashMovements,
+			ProductBarcodes:       barcodes,
+			Categories:            categories,
+			Brands:                brands,
+			ProductUnits:          productUnits,
+			CostLayers:            costLayers,
+			ProductPriceHistories: priceHistory,
+			ScheduledPriceChanges: priceSchedules,
+			Suppliers:             suppliers,
+			PurchaseOrders:        purchaseOrders,
+			PurchaseOrderItems:    poItems,
+			GoodsReceipts:         goodsReceipts,
+			GoodsReceiptItems:     grItems,
+			StockTransfers:        transfers,
+			StockTransferItems:    transferItems,
+			StockMovements:        movements,
+			ReorderLevels:         reorderLevels,
+			StockBatches:          batches,
+			StockBatchAllocations: allocations,
+			StockAdjustments:      adjustments,
+			StockAdjustmentItems:  adjItems,
+			StockOpnameCounts:     opCounts,
+			BranchStocks:          brStock,
+			BranchPrices:          brPrices,
+			StockOpnames:          opnames,
+			StockOpnameItems:      opItems,
+			LastSyncAt:            &now,
 		})
 	})
 
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 )
 
 // AppConfig holds runtime configuration sourced from environment variables.
@@ -11,17 +12,36 @@ type AppConfig struct {
 	ExportDir string
 	Upstream  string
 	BranchID  string
+	// ManagerPIN authorises price overrides at checkout; empty disables overrides.
+	ManagerPIN string
+	// CostMethod is "average" (default) or "fifo" for the cost of goods sold.
+	CostMethod string
+	// AdjustmentApprovalLimit is the value of a stock adjustment, in rupiah,
+	// above which a manager must approve it. Stock taken out and stock added
+	// both count, so one cannot offset the other.
+	AdjustmentApprovalLimit float64
 }
 
 // Load reads environment variables with sane defaults for desktop sidecar usage.
 func Load() AppConfig {
 	return AppConfig{
-		DBPath:    valueOrDefault("POS_DB_PATH", "offline.db"),
-		BindAddr:  valueOrDefault("POS_BIND_ADDR", "0.0.0.0:8080"),
-		ExportDir: valueOrDefault("POS_EXPORT_DIR", "exports"),
-		Upstream:  valueOrDefault("POS_UPSTREAM_URL", ""),
-		BranchID:  valueOrDefault("POS_BRANCH_ID", "local"),
+		DBPath:     valueOrDefault("POS_DB_PATH", "offline.db"),
+		BindAddr:   valueOrDefault("POS_BIND_ADDR", "0.0.0.0:8080"),
+		ExportDir:  valueOrDefault("POS_EXPORT_DIR", "exports"),
+		Upstream:   valueOrDefault("POS_UPSTREAM_URL", ""),
+		BranchID:   valueOrDefault("POS_BRANCH_ID", "local"),
+		ManagerPIN: valueOrDefault("POS_MANAGER_PIN", ""),
+		CostMethod: valueOrDefault("POS_COST_METHOD", "average"),
+
+		AdjustmentApprovalLimit: floatOrDefault("POS_ADJUSTMENT_APPROVAL_LIMIT", 500000),
+	}
+}
+
+func floatOrDefault(key string, fallback float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && v >= 0 {
+		return v
 	}
+	return fallback
 }
 
 func valueOrDefault(key, fallback string) string {
@@ -2,28 +2,54 @@ package controllers
 
 import (
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
 	"shosha_mart_backend/models"
+	"shosha_mart_backend/reports"
 )
 
 type AnalyticsResponse struct {
-	Start        string           `json:"start"`
-	End          string           `json:"end"`
-	TotalRevenue float64          `json:"totalRevenue"`
-	TotalOrders  int64            `json:"totalOrders"`
-	TotalItems   int64            `json:"totalItems"`
-	PerDay       []AnalyticsDaily `json:"perDay"`
+	Start         string                  `json:"start"`
+	End           string                  `json:"end"`
+	TotalRevenue  models.Money            `json:"totalRevenue"`
+	GrossRevenue  models.Money            `json:"grossRevenue"` // sebelum diskon
+	TotalDiscount models.Money            `json:"totalDiscount"`
+	TotalOrders   int64                   `json:"totalOrders"`
+	TotalItems    models.Qty              `json:"totalItems"` // satuan dasar
+	TotalCost     models.Money            `json:"totalCost"`  // HPP barang terjual
+	GrossMargin   models.Money            `json:"grossMargin"`
+	PerDay        []AnalyticsDaily        `json:"perDay"`
+	PerTender     []AnalyticsTender       `json:"perTender"`
+	PerTier       []AnalyticsTier         `json:"perTier"`
+	PerCategory   []reports.CategoryTotal `json:"perCategory"`
+	// laba kotor per produk, cabang dan hari (diskon nota tidak dibagi ke baris)
+	MarginPerProduct []reports.MarginRow `json:"marginPerProduct"`
+	MarginPerBranch  []reports.MarginRow `json:"marginPerBranch"`
+	MarginPerDay     []reports.MarginRow `json:"marginPerDay"`
+}
+
+type AnalyticsTender struct {
+	Method string       `json:"method"`
+	Count  int64        `json:"count"`
+	Amount models.Money `json:"amount"`
+}
+
+// AnalyticsTier is revenue per price tier; "default" is the general price list.
+type AnalyticsTier struct {
+	Tier    string       `json:"tier"`
+	Orders  int64        `json:"orders"`
+	Revenue models.Money `json:"revenue"`
 }
 
 type AnalyticsDaily struct {
-	Day     string  `json:"day"`
-	Orders  int64   `json:"orders"`
-	Items   int64   `json:"items"`
-	Revenue float64 `json:"revenue"`
+	Day     string       `json:"day"`
+	Orders  int64        `json:"orders"`
+	Items   models.Qty   `json:"items"`
+	Revenue models.Money `json:"revenue"`
 }
 
 // SalesAnalytics returns quick numbers for dashboard.
@@ -45,7 +71,7 @@ func SalesAnalytics(db *gorm.DB) gin.HandlerFunc {
 		// inclusive end
 		end = end.Add(24*time.Hour - time.Nanosecond)
 
-		var totalRevenue float64
+		var totalRevenue models.Money
 		var totalOrders int64
 		if err := db.Model(&models.Sale{}).
 			Where("created_at BETWEEN ? AND ?", start, end).
@@ -63,16 +89,42 @@ func SalesAnalytics(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		var totalItems int64
+		var totalItems models.Qty
 		if err := db.Model(&models.SaleItem{}).
 			Joins("JOIN sales ON sales.id = sale_items.sale_id").
 			Where("sales.created_at BETWEEN ? AND ?", start, end).
-			Select("COALESCE(SUM(qty), 0)").
+			Select("COALESCE(SUM(base_qty), 0)").
 			Scan(&totalItems).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
+		// Gross sales and discounts; totalRevenue above is already net of discounts.
+		var grossRevenue, itemDiscount, orderDiscount models.Money
+		if err := db.Table("sale_items").
+			Joins("JOIN sales ON sales.id = sale_items.sale_id").
+			Where("sales.created_at BETWEEN ? AND ? AND sales.is_deleted = ? AND sale_items.is_deleted = ?", start, end, false, false).
+			Select("COALESCE(SUM(CAST(ROUND(sale_items.qty * sale_items.price / 1000.0) AS BIGINT)), 0)").
+			Scan(&grossRevenue).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Table("sale_items").
+			Joins("JOIN sales ON sales.id = sale_items.sale_id").
+			Where("sales.created_at BETWEEN ? AND ? AND sales.is_deleted = ? AND sale_items.is_deleted = ?", start, end, false, false).
+			Select("COALESCE(SUM(sale_items.discount_amount), 0)").
+			Scan(&itemDiscount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Model(&models.Sale{}).
+			Where("created_at BETWEEN ? AND ? AND is_deleted = ?", start, end, false).
+			Select("COALESCE(SUM(discount_amount), 0)").
+			Scan(&orderDiscount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
 		var perDay []AnalyticsDaily
 		if err := db.Table("sales").
 			Select("strftime('%Y-%m-%d', created_at) as day, COUNT(*) as orders, COALESCE(SUM(total), 0) as revenue").
@@ -86,21 +138,112 @@ func SalesAnalytics(db *gorm.DB) gin.HandlerFunc {
 
 		// fill item count per day
 		for i := range perDay {
-			var items int64
+			var items models.Qty
 			db.Table("sale_items").
 				Joins("JOIN sales ON sales.id = sale_items.sale_id").
 				Where("strftime('%Y-%m-%d', sales.created_at) = ?", perDay[i].Day).
-				Select("COALESCE(SUM(qty), 0)").Scan(&items)
+				Select("COALESCE(SUM(base_qty), 0)").Scan(&items)
 			perDay[i].Items = items
 		}
 
+		perTender, err := tenderTotals(db, start, end)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		var perTier []AnalyticsTier
+		if err := db.Table("sales").
+			Select("CASE WHEN price_tier = '' OR price_tier IS NULL THEN 'default' ELSE price_tier END as tier, COUNT(*) as orders, COALESCE(SUM(total), 0) as revenue").
+			Where("created_at BETWEEN ? AND ? AND is_deleted = ?", start, end, false).
+			Group("tier").
+			Order("tier").
+			Scan(&perTier).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		perCategory, err := reports.CategorySales(db, "", start, end)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		margins := map[string][]reports.MarginRow{}
+		for _, by := range []string{reports.MarginByProduct, reports.MarginByBranch, reports.MarginByDay} {
+			if margins[by], err = reports.Margins(db, by, "", start, end); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		// laba kotor total ikut dikurangi diskon nota
+		var totalCost, grossMargin models.Money
+		for _, r := range margins[reports.MarginByProduct] {
+			totalCost += r.Cost
+			grossMargin += r.Margin
+		}
+		grossMargin -= orderDiscount
+
 		c.JSON(http.StatusOK, AnalyticsResponse{
-			Start:        start.Format("2006-01-02"),
-			End:          endStr,
-			TotalRevenue: totalRevenue,
-			TotalOrders:  totalOrders,
-			TotalItems:   totalItems,
-			PerDay:       perDay,
+			Start:         start.Format("2006-01-02"),
+			End:           endStr,
+			TotalRevenue:  totalRevenue,
+			GrossRevenue:  grossRevenue,
+			TotalDiscount: itemDiscount + orderDiscount,
+			TotalOrders:   totalOrders,
+			TotalItems:    totalItems,
+			TotalCost:     totalCost,
+			GrossMargin:   grossMargin,
+			PerDay:        perDay,
+			PerTender:     perTender,
+			PerTier:       perTier,
+			PerCategory:   perCategory,
+
+			MarginPerProduct: margins[reports.MarginByProduct],
+			MarginPerBranch:  margins[reports.MarginByBranch],
+			MarginPerDay:     margins[reports.MarginByDay],
 		})
 	}
 }
+
+// tenderTotals sums payments per method. Sales recorded before split tenders
+// existed have no sale_payments rows and are counted by their payment_method.
+func tenderTotals(db *gorm.DB, start, end time.Time) ([]AnalyticsTender, error) {
+	var tenders []AnalyticsTender
+	if err := db.Table("sale_payments").
+		Select("sale_payments.method as method, COUNT(DISTINCT sale_payments.sale_id) as count, COALESCE(SUM(sale_payments.amount), 0) as amount").
+		Joins("JOIN sales ON sales.id = sale_payments.sale_id").
+		Where("sales.created_at BETWEEN ? AND ?", start, end).
+		Where("sales.is_deleted = ? AND sale_payments.is_deleted = ?", false, false).
+		Group("sale_payments.method").
+		Scan(&tenders).Error; err != nil {
+		return nil, err
+	}
+
+	var legacy []AnalyticsTender
+	if err := db.Table("sales").
+		Select("payment_method as method, COUNT(*) as count, COALESCE(SUM(total), 0) as amount").
+		Where("created_at BETWEEN ? AND ? AND is_deleted = ?", start, end, false).
+		Where("NOT EXISTS (SELECT 1 FROM sale_payments WHERE sale_payments.sale_id = sales.id)").
+		Group("payment_method").
+		Scan(&legacy).Error; err != nil {
+		return nil, err
+	}
+
+	for _, l := range legacy {
+		merged := false
+		for i := range tenders {
+			if tenders[i].Method == l.Method {
+				tenders[i].Count += l.Count
+				tenders[i].Amount += l.Amount
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			tenders = append(tenders, l)
+		}
+	}
+	sort.Slice(tenders, func(i, j int) bool { return tenders[i].Method < tenders[j].Method })
+	return tenders, nil
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/costing"
+	"shosha_mart_backend/inventory"
+	"shosha_mart_backend/models"
+	"shosha_mart_backend/reports"
+)
+
+// defaultExpiryDays is how far ahead the expiry report looks.
+const defaultExpiryDays = 30
+
+// ListBatches returns a branch's batches (?branch_id, default the local
+// branch), soonest expiry first. Only batches with stock left are listed
+// unless ?all=true; ?product_id narrows to one product.
+func ListBatches(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := db.Where("branch_id = ? AND is_deleted = ?", chooseBranch(c.Query("branch_id"), cfg.BranchID), false)
+		if v := c.Query("product_id"); v != "" {
+			q = q.Where("product_id = ?", v)
+		}
+		if all, _ := strconv.ParseBool(c.Query("all")); !all {
+			q = q.Where("remaining > 0")
+		}
+		var batches []models.StockBatch
+		if err := q.Order("CASE WHEN expiry_date IS NULL THEN 1 ELSE 0 END, expiry_date, created_at").Find(&batches).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, batches)
+	}
+}
+
+// ExpiryReport lists the local branch's expired batches and those expiring
+// within ?days (default 30) with their stock and value; ?format=xlsx
+// downloads it as Excel.
+func ExpiryReport(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		days, ok := dayCountQuery(c, "days", defaultExpiryDays, 0)
+		if !ok {
+			return
+		}
+		rows, err := inventory.ExpiringBatches(db, cfg.BranchID, time.Now(), days)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if c.Query("format") == "xlsx" {
+			path, err := reports.GenerateExpiryReport(cfg, rows, days)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.FileAttachment(path, filepath.Base(path))
+			return
+		}
+		expired, near := []inventory.ExpiryRow{}, []inventory.ExpiryRow{}
+		var expiredValue, nearValue models.Money
+		for _, r := range rows {
+			if r.Status == inventory.Expired {
+				expired = append(expired, r)
+				expiredValue += r.Value
+			} else {
+				near = append(near, r)
+				nearValue += r.Value
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"days":              days,
+			"expired":           expired,
+			"expired_value":     expiredValue,
+			"near_expiry":       near,
+			"near_expiry_value": nearValue,
+		})
+	}
+}
+
+type writeOffPayload struct {
+	Note        string `json:"note"`
+	PerformedBy string `json:"performed_by"`
+}
+
+// writeOffBatch takes qty of an expired batch out of stock (the rest of it
+// when qty is zero) and records it in the batch's stock movements.
+func writeOffBatch(tx *gorm.DB, cfg config.AppConfig, batch models.StockBatch, qty models.Qty, ref costing.Ref) (models.StockBatch, error) {
+	if batch.ExpiryDate == nil || !batch.ExpiryDate.Before(startOfToday()) {
+		return batch, &checkoutError{status: http.StatusConflict, msg: "batch " + batch.BatchNo + " has not expired"}
+	}
+	if qty == 0 {
+		qty = batch.Remaining
+	}
+	if qty <= 0 || qty > batch.Remaining {
+		return batch, badCheckout("qty must be greater than 0 and at most %s", batch.Remaining)
+	}
+	ref.Source, ref.DocID, ref.DocNo = models.MovementWriteOff, batch.ID, batch.BatchNo
+	if _, err := costing.WriteOff(tx, cfg.CostMethod, batch, qty, ref); err != nil {
+		return batch, err
+	}
+	batch.Remaining -= qty
+	return batch, nil
+}
+
+func startOfToday() time.Time {
+	y, m, d := time.Now().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// WriteOffBatch writes off an expired batch of the local branch, all that is
+// left of it or the given qty.
+func WriteOffBatch(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			writeOffPayload
+			Qty models.Qty `json:"qty"` // 0 = seluruh sisa batch
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		var batch models.StockBatch
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.First(&batch, "id = ? AND branch_id = ? AND is_deleted = ?", c.Param("id"), cfg.BranchID, false).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return &checkoutError{status: http.StatusNotFound, msg: "batch not found"}
+				}
+				return err
+			}
+			var err error
+			batch, err = writeOffBatch(tx, cfg, batch, payload.Qty, costing.Ref{User: posUser(c, payload.PerformedBy), Note: payload.Note})
+			return err
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, batch)
+	}
+}
+
+// WriteOffExpired writes off what is left of every expired batch of the
+// local branch.
+func WriteOffExpired(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload writeOffPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		var batches []models.StockBatch
+		var value models.Money
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("branch_id = ? AND remaining > 0 AND is_deleted = ? AND expiry_date < ?", cfg.BranchID, false, startOfToday()).
+				Order("expiry_date, created_at").Find(&batches).Error; err != nil {
+				return err
+			}
+			for i := range batches {
+				value += batches[i].UnitCost.TimesQty(batches[i].Remaining)
+				b, err := writeOffBatch(tx, cfg, batches[i], 0, costing.Ref{User: posUser(c, payload.PerformedBy), Note: payload.Note})
+				if err != nil {
+					return err
+				}
+				batches[i] = b
+			}
+			return nil
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"count": len(batches), "value": value, "batches": batches})
+	}
+}
@@ -25,22 +25,28 @@ func ListBranches(db *gorm.DB) gin.HandlerFunc {
 func CreateBranch(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var payload struct {
-			Code    string `json:"code"`
-			Name    string `json:"name"`
-			Address string `json:"address"`
-			Phone   string `json:"phone"`
+			Code      string `json:"code"`
+			Name      string `json:"name"`
+			Address   string `json:"address"`
+			Phone     string `json:"phone"`
+			PriceTier string `json:"price_tier"`
 		}
 		if err := c.ShouldBindJSON(&payload); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 			return
 		}
+		if !models.ValidPriceTier(payload.PriceTier) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "price_tier must be 'investor', 'shosha' or empty"})
+			return
+		}
 		branch := models.Branch{
-			ID:      uuid.NewString(),
-			Code:    payload.Code,
-			Name:    payload.Name,
-			Address: payload.Address,
-			Phone:   payload.Phone,
-			Synced:  false,
+			ID:        uuid.NewString(),
+			Code:      payload.Code,
+			Name:      payload.Name,
+			Address:   payload.Address,
+			Phone:     payload.Phone,
+			PriceTier: payload.PriceTier,
+			Synced:    false,
 		}
 		if err := db.Create(&branch).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -55,15 +61,20 @@ func UpdateBranch(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 		var payload struct {
-			Code    string `json:"code"`
-			Name    string `json:"name"`
-			Address string `json:"address"`
-			Phone   string `json:"phone"`
+			Code      string `json:"code"`
+			Name      string `json:"name"`
+			Address   string `json:"address"`
+			Phone     string `json:"phone"`
+			PriceTier string `json:"price_tier"`
 		}
 		if err := c.ShouldBindJSON(&payload); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 			return
 		}
+		if !models.ValidPriceTier(payload.PriceTier) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "price_tier must be 'investor', 'shosha' or empty"})
+			return
+		}
 
 		// Check if branch exists
 		var branch models.Branch
@@ -77,6 +88,7 @@ func UpdateBranch(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 		branch.Name = payload.Name
 		branch.Address = payload.Address
 		branch.Phone = payload.Phone
+		branch.PriceTier = payload.PriceTier
 		branch.Synced = false // Mark as unsynced when updated
 		if err := db.Save(&branch).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
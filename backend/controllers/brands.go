@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/models"
+)
+
+// checkBrandName rejects an empty name or one already used by another brand.
+func checkBrandName(tx *gorm.DB, id, name string) error {
+	if name == "" {
+		return badCheckout("name is required")
+	}
+	var count int64
+	if err := tx.Model(&models.Brand{}).
+		Where("LOWER(name) = ? AND id <> ? AND is_deleted = ?", strings.ToLower(name), id, false).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return &checkoutError{status: http.StatusConflict, msg: "brand " + name + " already exists"}
+	}
+	return nil
+}
+
+func ListBrands(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var brands []models.Brand
+		if err := db.Where("is_deleted = ?", false).Order("name").Find(&brands).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, brands)
+	}
+}
+
+func CreateBrand(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			Name string `json:"name"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		brand := models.Brand{ID: uuid.NewString(), Name: strings.TrimSpace(payload.Name), Synced: false}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := checkBrandName(tx, "", brand.Name); err != nil {
+				return err
+			}
+			return tx.Create(&brand).Error
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, brand)
+	}
+}
+
+func UpdateBrand(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			Name string `json:"name"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		var brand models.Brand
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.First(&brand, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+				return err
+			}
+			name := strings.TrimSpace(payload.Name)
+			if err := checkBrandName(tx, brand.ID, name); err != nil {
+				return err
+			}
+			brand.Name = name
+			brand.Synced = false
+			return tx.Save(&brand).Error
+		})
+		if err != nil {
+			brandError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, brand)
+	}
+}
+
+// DeleteBrand tombstones a brand no live product uses.
+func DeleteBrand(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var brand models.Brand
+			if err := tx.First(&brand, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+				return err
+			}
+			var products int64
+			if err := tx.Model(&models.Product{}).Where("brand_id = ? AND is_deleted = ?", brand.ID, false).Count(&products).Error; err != nil {
+				return err
+			}
+			if products > 0 {
+				return &checkoutError{status: http.StatusConflict, msg: "brand is still used by products"}
+			}
+			return tx.Model(&brand).Updates(map[string]interface{}{
+				"is_deleted": true,
+				"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+				"synced":     false,
+			}).Error
+		})
+		if err != nil {
+			brandError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "brand deleted"})
+	}
+}
+
+func brandError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "brand not found"})
+		return
+	}
+	respondCheckoutError(c, err)
+}
@@ -0,0 +1,292 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/models"
+	"shosha_mart_backend/reports"
+)
+
+// openCashSessionFor returns the id of the open session a sale belongs to:
+// the one on tillID, or the only open session of the local branch when the
+// sale names no till. The drawer is always the sidecar's, whatever branch
+// the sale is priced for. Sales with no matching session are left unlinked.
+func openCashSessionFor(tx *gorm.DB, cfg config.AppConfig, tillID string) (string, error) {
+	var sessions []models.CashSession
+	q := tx.Where("branch_id = ? AND status = ? AND is_deleted = ?", cfg.BranchID, models.CashSessionOpen, false)
+	if tillID != "" {
+		q = q.Where("till_id = ?", tillID)
+	}
+	if err := q.Limit(2).Find(&sessions).Error; err != nil {
+		return "", err
+	}
+	if len(sessions) != 1 {
+		return "", nil
+	}
+	return sessions[0].ID, nil
+}
+
+func loadCashSession(db *gorm.DB, id string) (models.CashSession, error) {
+	var s models.CashSession
+	err := db.Preload("Movements", "is_deleted = ?", false).
+		First(&s, "id = ? AND is_deleted = ?", id, false).Error
+	return s, err
+}
+
+func cashSessionNotFound(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "cash session not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// OpenCashSession starts a shift on a till with an opening float. A till
+// holds at most one open session.
+func OpenCashSession(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			BranchID     string       `json:"branch_id"`
+			TillID       string       `json:"till_id"`
+			Cashier      string       `json:"cashier"`
+			OpeningFloat models.Money `json:"opening_float"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if strings.TrimSpace(payload.TillID) == "" || strings.TrimSpace(payload.Cashier) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "till_id and cashier are required"})
+			return
+		}
+		if payload.OpeningFloat < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "opening_float must be >= 0"})
+			return
+		}
+
+		session := models.CashSession{
+			ID:           uuid.NewString(),
+			BranchID:     chooseBranch(cfg.BranchID, payload.BranchID), // laci ada di cabang sidecar ini
+			TillID:       strings.TrimSpace(payload.TillID),
+			Cashier:      strings.TrimSpace(payload.Cashier),
+			Status:       models.CashSessionOpen,
+			OpeningFloat: payload.OpeningFloat,
+			OpenedAt:     time.Now(),
+			Synced:       false,
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var open int64
+			if err := tx.Model(&models.CashSession{}).
+				Where("branch_id = ? AND till_id = ? AND status = ? AND is_deleted = ?", session.BranchID, session.TillID, models.CashSessionOpen, false).
+				Count(&open).Error; err != nil {
+				return err
+			}
+			if open > 0 {
+				return &checkoutError{status: http.StatusConflict, msg: "till " + session.TillID + " already has an open cash session"}
+			}
+			return tx.Create(&session).Error
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, session)
+	}
+}
+
+// ListCashSessions lists sessions, newest first. Query: status, branch_id, till_id.
+func ListCashSessions(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := db.Where("is_deleted = ?", false)
+		if s := c.Query("status"); s != "" {
+			q = q.Where("status = ?", s)
+		}
+		if b := c.Query("branch_id"); b != "" {
+			q = q.Where("branch_id = ?", b)
+		}
+		if t := c.Query("till_id"); t != "" {
+			q = q.Where("till_id = ?", t)
+		}
+		var sessions []models.CashSession
+		if err := q.Order("opened_at desc").Find(&sessions).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, sessions)
+	}
+}
+
+// CurrentCashSession returns the open session of ?till_id at the local branch.
+func CurrentCashSession(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tillID := c.Query("till_id")
+		if tillID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "till_id is required"})
+			return
+		}
+		var session models.CashSession
+		if err := db.Preload("Movements", "is_deleted = ?", false).
+			First(&session, "branch_id = ? AND till_id = ? AND status = ? AND is_deleted = ?", cfg.BranchID, tillID, models.CashSessionOpen, false).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "no open cash session on till " + tillID})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, session)
+	}
+}
+
+func GetCashSession(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, err := loadCashSession(db, c.Param("id"))
+		if err != nil {
+			cashSessionNotFound(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, session)
+	}
+}
+
+// AddCashMovement records a pay-in or pay-out on an open session.
+func AddCashMovement(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			Type   string       `json:"type"`
+			Amount models.Money `json:"amount"`
+			Reason string       `json:"reason"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if payload.Type != models.CashPayIn && payload.Type != models.CashPayOut {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "type must be 'pay_in' or 'pay_out'"})
+			return
+		}
+		if payload.Amount <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be > 0"})
+			return
+		}
+
+		session, err := loadCashSession(db, c.Param("id"))
+		if err != nil {
+			cashSessionNotFound(c, err)
+			return
+		}
+		if session.Status != models.CashSessionOpen {
+			c.JSON(http.StatusConflict, gin.H{"error": "cash session is closed"})
+			return
+		}
+		movement := models.CashMovement{
+			ID:            uuid.NewString(),
+			CashSessionID: session.ID,
+			Type:          payload.Type,
+			Amount:        payload.Amount,
+			Reason:        payload.Reason,
+			Synced:        false,
+		}
+		if err := db.Create(&movement).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, movement)
+	}
+}
+
+// CloseCashSession records the counted cash, stores the expected cash and the
+// difference, and returns the Z report.
+func CloseCashSession(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			CountedCash *models.Money `json:"counted_cash"`
+			Note        string        `json:"note"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if payload.CountedCash == nil || *payload.CountedCash < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "counted_cash is required"})
+			return
+		}
+
+		var rep reports.CashSessionReport
+		err := db.Transaction(func(tx *gorm.DB) error {
+			session, err := loadCashSession(tx, c.Param("id"))
+			if err != nil {
+				return err
+			}
+			if session.Status != models.CashSessionOpen {
+				return &checkoutError{status: http.StatusConflict, msg: "cash session is already closed"}
+			}
+			x, err := reports.BuildCashSessionReport(tx, session)
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			session.Status = models.CashSessionClosed
+			session.ClosedAt = &now
+			session.ExpectedCash = x.ExpectedCash
+			session.CountedCash = *payload.CountedCash
+			session.Difference = session.CountedCash - session.ExpectedCash
+			session.CloseNote = payload.Note
+			session.Synced = false
+			if err := tx.Omit("Movements").Save(&session).Error; err != nil {
+				return err
+			}
+			rep, err = reports.BuildCashSessionReport(tx, session)
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				cashSessionNotFound(c, err)
+				return
+			}
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, rep)
+	}
+}
+
+// CashSessionReport returns the X report of an open session or the Z report
+// of a closed one, as JSON or, with ?format=xlsx, as an Excel file.
+func CashSessionReport(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "xlsx" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or xlsx"})
+			return
+		}
+		session, err := loadCashSession(db, c.Param("id"))
+		if err != nil {
+			cashSessionNotFound(c, err)
+			return
+		}
+		rep, err := reports.BuildCashSessionReport(db, session)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if format == "json" {
+			c.JSON(http.StatusOK, rep)
+			return
+		}
+		path, err := reports.GenerateCashSessionReport(cfg, rep)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.FileAttachment(path, path)
+	}
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/costing"
+	"shosha_mart_backend/models"
+)
+
+func TestSaleLinksLocalCashSession(t *testing.T) {
+	db := testDB(t)
+	cfg := config.AppConfig{BranchID: "b1", CostMethod: "average"}
+	if err := db.Create(&models.Product{ID: "p1", Name: "Beras", Unit: "pcs", Price: 1000000, Stock: models.Units(100)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := costing.SyncProductStock(db, cfg.BranchID); err != nil {
+		t.Fatal(err)
+	}
+	// sesi di till dengan id yang sama, diunduh dari cabang lain
+	for _, s := range []models.CashSession{
+		{ID: "local", BranchID: "b1", TillID: "T1", Cashier: "ani", Status: models.CashSessionOpen, OpenedAt: time.Now()},
+		{ID: "remote", BranchID: "b2", TillID: "T1", Cashier: "budi", Status: models.CashSessionOpen, OpenedAt: time.Now(), Synced: true},
+	} {
+		if err := db.Create(&s).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		branchID string
+		tillID   string
+		want     string
+	}{
+		{"legacy sale priced for another branch", "b2", "", "local"},
+		{"till shared with another branch", "b2", "T1", "local"},
+		{"local sale on the till", "b1", "T1", "local"},
+		{"unknown till", "b1", "T9", ""},
+	}
+	for _, tt := range tests {
+		w := serve(t, http.MethodPost, "/api/sales", "/api/sales", CreateSale(db, cfg), map[string]interface{}{
+			"branch_id":      tt.branchID,
+			"till_id":        tt.tillID,
+			"payment_method": "cash",
+			"items":          []map[string]interface{}{{"product_id": "p1", "qty": 1}},
+		})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("%s: %d %s", tt.name, w.Code, w.Body)
+		}
+		var sale models.Sale
+		if err := json.Unmarshal(w.Body.Bytes(), &sale); err != nil {
+			t.Fatal(err)
+		}
+		if sale.CashSessionID != tt.want {
+			t.Errorf("%s: cash session %q, want %q", tt.name, sale.CashSessionID, tt.want)
+		}
+	}
+
+	w := serve(t, http.MethodGet, "/api/cash-sessions/current", "/api/cash-sessions/current?till_id=T1", CurrentCashSession(db, cfg), nil)
+	var current models.CashSession
+	if err := json.Unmarshal(w.Body.Bytes(), &current); err != nil || current.ID != "local" {
+		t.Errorf("current session of T1 = %q (%d), want local", current.ID, w.Code)
+	}
+}
@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/catalog"
+	"shosha_mart_backend/models"
+)
+
+type categoryPayload struct {
+	ParentID string `json:"parent_id"`
+	Name     string `json:"name"`
+}
+
+// checkCategory validates a category's name and parent. id is empty for a
+// new category.
+func checkCategory(tx *gorm.DB, id string, p categoryPayload) error {
+	if p.Name == "" {
+		return badCheckout("name is required")
+	}
+	cats, err := catalog.LoadCategories(tx)
+	if err != nil {
+		return err
+	}
+	if p.ParentID != "" {
+		if _, ok := cats[p.ParentID]; !ok {
+			return badCheckout("parent category not found")
+		}
+		if id != "" && cats.IsUnder(id, p.ParentID) {
+			return badCheckout("a category cannot be moved under itself")
+		}
+	}
+	for _, c := range cats {
+		if c.ID != id && c.ParentID == p.ParentID && strings.EqualFold(c.Name, p.Name) {
+			return &checkoutError{status: http.StatusConflict, msg: "category " + p.Name + " already exists here"}
+		}
+	}
+	return nil
+}
+
+// ListCategories returns live categories with their full path, sorted by path.
+func ListCategories(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cats, err := catalog.LoadCategories(db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		list := make([]models.Category, 0, len(cats))
+		for id, cat := range cats {
+			cat.Path = cats.Path(id)
+			list = append(list, cat)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+func CreateCategory(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload categoryPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		payload.Name = strings.TrimSpace(payload.Name)
+		category := models.Category{
+			ID:       uuid.NewString(),
+			ParentID: payload.ParentID,
+			Name:     payload.Name,
+			Synced:   false,
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := checkCategory(tx, "", payload); err != nil {
+				return err
+			}
+			return tx.Create(&category).Error
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, category)
+	}
+}
+
+// UpdateCategory renames a category or moves it under another parent.
+func UpdateCategory(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload categoryPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		payload.Name = strings.TrimSpace(payload.Name)
+		var category models.Category
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.First(&category, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+				return err
+			}
+			if err := checkCategory(tx, category.ID, payload); err != nil {
+				return err
+			}
+			category.ParentID = payload.ParentID
+			category.Name = payload.Name
+			category.Synced = false
+			return tx.Save(&category).Error
+		})
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, category)
+	}
+}
+
+// DeleteCategory tombstones a category that has no sub-categories and no
+// products left in it.
+func DeleteCategory(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var category models.Category
+			if err := tx.First(&category, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+				return err
+			}
+			var children, products int64
+			if err := tx.Model(&models.Category{}).Where("parent_id = ? AND is_deleted = ?", category.ID, false).Count(&children).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Product{}).Where("category_id = ? AND is_deleted = ?", category.ID, false).Count(&products).Error; err != nil {
+				return err
+			}
+			if children > 0 || products > 0 {
+				return &checkoutError{status: http.StatusConflict, msg: "category still has sub-categories or products"}
+			}
+			return tx.Model(&category).Updates(map[string]interface{}{
+				"is_deleted": true,
+				"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+				"synced":     false,
+			}).Error
+		})
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "category deleted"})
+	}
+}
+
+func categoryError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		return
+	}
+	respondCheckoutError(c, err)
+}
+
+// checkProductGroups rejects a product category or brand that does not exist.
+func checkProductGroups(tx *gorm.DB, categoryID, brandID string) error {
+	if categoryID != "" {
+		var n int64
+		if err := tx.Model(&models.Category{}).Where("id = ? AND is_deleted = ?", categoryID, false).Count(&n).Error; err != nil {
+			return err
+		}
+		if n == 0 {
+			return badCheckout("category not found")
+		}
+	}
+	if brandID != "" {
+		var n int64
+		if err := tx.Model(&models.Brand{}).Where("id = ? AND is_deleted = ?", brandID, false).Count(&n).Error; err != nil {
+			return err
+		}
+		if n == 0 {
+			return badCheckout("brand not found")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/costing"
+	"shosha_mart_backend/models"
+	"shosha_mart_backend/pricing"
+)
+
+// saleItemInput is one cart line as sent by the cashier.
+type saleItemInput struct {
+	ProductID     string       `json:"product_id"`
+	Qty           models.Qty   `json:"qty"`
+	Unit          string       `json:"unit"`  // "" = the product's base unit
+	Price         models.Money `json:"price"` // per unit; 0 = tier price
+	DiscountType  string       `json:"discount_type"`
+	DiscountValue float64      `json:"discount_value"`
+}
+
+// saleInput is a checkout request, either sent directly to CreateSale or
+// assembled from a draft when it is posted.
+type saleInput struct {
+	BranchID      string          `json:"branch_id"`
+	CustomerID    string          `json:"customer_id"` // optional; required for credit-limit checks on hutang
+	TillID        string          `json:"till_id"`     // links the sale to the till's open cash session
+	ReceiptNo     string          `json:"receipt_no"`
+	PaymentMethod string          `json:"payment_method"` // legacy single tender, used when payments is empty
+	Payments      []paymentInput  `json:"payments"`
+	Notes         string          `json:"notes"`
+	CreatedAt     string          `json:"created_at"`
+	DiscountType  string          `json:"discount_type"` // order-level manual discount
+	DiscountValue float64         `json:"discount_value"`
+	ManagerPIN    string          `json:"manager_pin"` // authorises prices that differ from the tier price
+	Items         []saleItemInput `json:"items"`
+	User          string          `json:"-"` // kasir dari header X-POS-User, untuk kartu stok
+}
+
+// checkoutError is a rejected checkout; status is the HTTP status to report.
+type checkoutError struct {
+	status int
+	msg    string
+}
+
+func (e *checkoutError) Error() string { return e.msg }
+
+func badCheckout(format string, args ...interface{}) error {
+	return &checkoutError{status: http.StatusBadRequest, msg: fmt.Sprintf(format, args...)}
+}
+
+// respondCheckoutError writes err with the status carried by a checkoutError,
+// or 500 for anything else.
+func respondCheckoutError(c *gin.Context, err error) {
+	var ce *checkoutError
+	if errors.As(err, &ce) {
+		c.JSON(ce.status, gin.H{"error": ce.msg})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// priceSale validates a checkout and prices it (tier prices, promotions,
+// discounts and taxes) without writing anything. The returned sale carries
+// its unsaved items.
+func priceSale(db *gorm.DB, cfg config.AppConfig, in saleInput) (models.Sale, error) {
+	if len(in.Items) == 0 {
+		return models.Sale{}, badCheckout("at least one item is required")
+	}
+	if !pricing.ValidDiscountType(in.DiscountType) {
+		return models.Sale{}, badCheckout("discount_type must be 'percent' or 'amount'")
+	}
+	approved := managerApproved(cfg, in.ManagerPIN)
+	if in.ManagerPIN != "" && !approved {
+		return models.Sale{}, &checkoutError{status: http.StatusForbidden, msg: "invalid manager pin"}
+	}
+
+	taxRates, err := loadTaxRates(db)
+	if err != nil {
+		return models.Sale{}, err
+	}
+
+	branchID := chooseBranch(in.BranchID, cfg.BranchID)
+	tier, err := lookupPriceTier(db, branchID)
+	if err != nil {
+		return models.Sale{}, err
+	}
+
+	// Tier pelanggan mengalahkan tier cabang.
+	var customer models.Customer
+	if in.CustomerID != "" {
+		if err := db.First(&customer, "id = ? AND is_deleted = ?", in.CustomerID, false).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.Sale{}, badCheckout("customer not found: %s", in.CustomerID)
+			}
+			return models.Sale{}, err
+		}
+		if customer.PriceTier != "" {
+			tier = customer.PriceTier
+		}
+	}
+
+	lines := make([]pricing.Line, 0, len(in.Items))
+	lineTaxes := make([]models.TaxRate, 0, len(in.Items))
+	lineUnits := make([]saleUnit, 0, len(in.Items))
+	overridden := make([]bool, 0, len(in.Items))
+	for _, item := range in.Items {
+		if item.ProductID == "" || item.Qty <= 0 {
+			return models.Sale{}, badCheckout("productId required and qty must be > 0")
+		}
+		if !pricing.ValidDiscountType(item.DiscountType) {
+			return models.Sale{}, badCheckout("discount_type must be 'percent' or 'amount'")
+		}
+		var product models.Product
+		if err := db.First(&product, "id = ?", item.ProductID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.Sale{}, badCheckout("product not found: %s", item.ProductID)
+			}
+			return models.Sale{}, err
+		}
+		if err := pricing.ForBranch(db, branchID, &product); err != nil {
+			return models.Sale{}, err
+		}
+		unit, err := findUnit(db, product, item.Unit)
+		if err != nil {
+			return models.Sale{}, err
+		}
+		price, override, err := resolveUnitPrice(product, unit, tier, item.Price, approved)
+		if err != nil {
+			return models.Sale{}, badCheckout("%s", err.Error())
+		}
+		lineUnits = append(lineUnits, unit)
+		overridden = append(overridden, override)
+		lines = append(lines, pricing.Line{
+			ProductID:     item.ProductID,
+			Qty:           item.Qty,
+			Price:         price,
+			DiscountType:  item.DiscountType,
+			DiscountValue: item.DiscountValue,
+		})
+		rate, _ := taxRates.forProduct(product)
+		lineTaxes = append(lineTaxes, rate)
+	}
+
+	branchName := branchID
+	if branchID != "" {
+		var branch models.Branch
+		err := db.First(&branch, "id = ?", branchID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Sale{}, err
+		}
+		if err == nil {
+			branchName = branch.Name
+		}
+	}
+
+	sale := models.Sale{
+		ID:            uuid.NewString(),
+		ReceiptNo:     in.ReceiptNo,
+		BranchID:      branchID,
+		BranchName:    branchName,
+		CustomerID:    customer.ID,
+		CustomerName:  customer.Name,
+		PriceTier:     tier,
+		Notes:         in.Notes,
+		DiscountType:  in.DiscountType,
+		DiscountValue: in.DiscountValue,
+		Synced:        false,
+	}
+
+	// If caller provided created_at, try to parse it and set CreatedAt accordingly.
+	if in.CreatedAt != "" {
+		// try RFC3339 first, fallback to date-only YYYY-MM-DD
+		if t, err := time.Parse(time.RFC3339, in.CreatedAt); err == nil {
+			sale.CreatedAt = t
+		} else if t2, err2 := time.Parse("2006-01-02", in.CreatedAt); err2 == nil {
+			sale.CreatedAt = t2
+		}
+	}
+
+	// Evaluate promotions valid for this branch at the time of sale.
+	pricedAt := time.Now()
+	if !sale.CreatedAt.IsZero() {
+		pricedAt = sale.CreatedAt
+	}
+	var promos []models.Promotion
+	if err := db.Where("active = ? AND is_deleted = ?", true, false).Find(&promos).Error; err != nil {
+		return models.Sale{}, err
+	}
+	promos = pricing.ActivePromotions(promos, branchID, pricedAt)
+
+	for i := range lines {
+		pricing.ApplyLine(&lines[i], promos)
+		sale.Subtotal += lines[i].Subtotal
+	}
+	sale.DiscountAmount, sale.PromotionID = pricing.OrderDiscount(sale.Subtotal, sale.DiscountType, sale.DiscountValue, promos)
+
+	taxable := make([]pricing.TaxableLine, len(lines))
+	for i, line := range lines {
+		taxable[i] = pricing.TaxableLine{Amount: line.Subtotal, Rate: lineTaxes[i].Rate, Inclusive: lineTaxes[i].Inclusive}
+	}
+	lineTaxResults, totalTax, addedTax := pricing.ApplyTax(taxable, sale.DiscountAmount)
+	sale.TaxAmount = totalTax
+	sale.Total = sale.Subtotal - sale.DiscountAmount + addedTax
+
+	sale.Items = make([]models.SaleItem, len(lines))
+	for i, line := range lines {
+		item := models.SaleItem{
+			ID:              uuid.NewString(),
+			SaleID:          sale.ID,
+			ProductID:       line.ProductID,
+			Qty:             line.Qty,
+			Unit:            lineUnits[i].Name,
+			UnitFactor:      lineUnits[i].Factor,
+			BaseQty:         line.Qty.Mul(lineUnits[i].Factor),
+			Price:           line.Price,
+			PriceOverridden: overridden[i],
+			DiscountType:    line.DiscountType,
+			DiscountValue:   line.DiscountValue,
+			DiscountAmount:  line.DiscountAmount,
+			PromotionID:     line.PromotionID,
+			Subtotal:        line.Subtotal,
+			TaxBase:         lineTaxResults[i].Base,
+			TaxAmount:       lineTaxResults[i].Tax,
+			Synced:          false,
+		}
+		stampTax(&item, lineTaxes[i])
+		sale.Items[i] = item
+	}
+	sale.TaxSummary = pricing.SummarizeTax(sale.Items)
+	return sale, nil
+}
+
+// postSale prices a checkout, settles it with the given tenders and stores the
+// sale, its items and payments while decrementing stock, all in one
+// transaction. db may itself be a transaction.
+func postSale(db *gorm.DB, cfg config.AppConfig, in saleInput) (models.Sale, error) {
+	sale, err := priceSale(db, cfg, in)
+	if err != nil {
+		return models.Sale{}, err
+	}
+
+	tenders := in.Payments
+	if len(tenders) == 0 {
+		// Legacy clients send a single payment_method and no amounts.
+		method := in.PaymentMethod
+		if !validPaymentMethod(method) {
+			method = models.PaymentCash // default
+		}
+		tenders = []paymentInput{{Method: method, Amount: sale.Total}}
+	}
+
+	payments, change, err := allocatePayments(sale.ID, sale.Total, tenders)
+	if err != nil {
+		return models.Sale{}, badCheckout("%s", err.Error())
+	}
+	sale.PaymentMethod = paymentMethodLabel(payments)
+	sale.ChangeDue = change
+
+	items := sale.Items
+	sale.Items = nil
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := checkCreditLimit(tx, sale.CustomerID, "", hutangAmount(payments)); err != nil {
+			return err
+		}
+		sessionID, err := openCashSessionFor(tx, cfg, in.TillID)
+		if err != nil {
+			return err
+		}
+		sale.CashSessionID = sessionID
+
+		// Pastikan branch ada agar FK tidak gagal.
+		if err := ensureBranch(tx, sale.BranchID); err != nil {
+			return err
+		}
+
+		// simpan sale lebih dulu agar FK sale_items -> sales tidak gagal
+		if sale.ReceiptNo == "" {
+			sale.ReceiptNo = generateReceiptNo()
+		}
+		if err := tx.Create(&sale).Error; err != nil {
+			return err
+		}
+
+		// Barang keluar dari cabang sidecar ini; sale.BranchID adalah cabang
+		// yang dipilih di kasir (harga/tier), bukan tempat stoknya.
+		for i := range items {
+			cost, err := costing.Issue(tx, cfg.CostMethod, cfg.BranchID, items[i].ProductID, items[i].BaseQty, saleRef(sale, models.MovementSale, in.User))
+			if err != nil {
+				return err
+			}
+			items[i].UnitCost = cost
+			if err := tx.Create(&items[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		for i := range payments {
+			if err := tx.Create(&payments[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Sale{}, err
+	}
+
+	sale.Items = items
+	sale.Payments = payments
+	return sale, nil
+}
+
+// ensureBranch creates a stub branch when branchID is unknown locally.
+func ensureBranch(tx *gorm.DB, branchID string) error {
+	if branchID == "" {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&models.Branch{}).Where("id = ?", branchID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	// buat stub branch jika belum ada agar tidak gagal FK
+	return tx.Create(&models.Branch{
+		ID:        branchID,
+		Name:      branchID,
+		Synced:    false,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}).Error
+}
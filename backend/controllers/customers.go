@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/models"
+)
+
+type customerPayload struct {
+	Name        string       `json:"name"`
+	Phone       string       `json:"phone"`
+	Address     string       `json:"address"`
+	NPWP        string       `json:"npwp"`
+	PriceTier   string       `json:"price_tier"`
+	CreditLimit models.Money `json:"credit_limit"`
+}
+
+func (p customerPayload) validate() string {
+	if strings.TrimSpace(p.Name) == "" {
+		return "name is required"
+	}
+	if !models.ValidPriceTier(p.PriceTier) {
+		return "price_tier must be 'investor', 'shosha' or empty"
+	}
+	if p.CreditLimit < 0 {
+		return "credit_limit must be >= 0"
+	}
+	return ""
+}
+
+// customerResponse is a customer with its unpaid hutang.
+type customerResponse struct {
+	models.Customer
+	Outstanding models.Money `json:"outstanding"`
+}
+
+// hutangAmount is the part of a sale settled on credit.
+func hutangAmount(payments []models.SalePayment) models.Money {
+	var sum models.Money
+	for _, p := range payments {
+		if p.Method == models.PaymentHutang {
+			sum += p.Amount
+		}
+	}
+	return sum
+}
+
+// customerOutstanding sums the hutang tenders of the customer's live sales,
+// leaving out excludeSaleID (the sale being edited).
+func customerOutstanding(db *gorm.DB, customerID, excludeSaleID string) (models.Money, error) {
+	var total models.Money
+	q := db.Table("sale_payments").
+		Select("COALESCE(SUM(sale_payments.amount), 0)").
+		Joins("JOIN sales ON sales.id = sale_payments.sale_id").
+		Where("sales.customer_id = ? AND sales.is_deleted = ? AND sale_payments.is_deleted = ? AND sale_payments.method = ?",
+			customerID, false, false, models.PaymentHutang)
+	if excludeSaleID != "" {
+		q = q.Where("sales.id <> ?", excludeSaleID)
+	}
+	if err := q.Scan(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// checkCreditLimit rejects a hutang amount that would take the customer over
+// their credit limit. Sales without a customer and customers without a limit
+// are not checked.
+func checkCreditLimit(db *gorm.DB, customerID, excludeSaleID string, amount models.Money) error {
+	if customerID == "" || amount <= 0 {
+		return nil
+	}
+	var customer models.Customer
+	if err := db.First(&customer, "id = ?", customerID).Error; err != nil {
+		return err
+	}
+	if customer.CreditLimit <= 0 {
+		return nil
+	}
+	outstanding, err := customerOutstanding(db, customerID, excludeSaleID)
+	if err != nil {
+		return err
+	}
+	if outstanding+amount > customer.CreditLimit {
+		return badCheckout("credit limit exceeded for %s: outstanding %s + %s > limit %s",
+			customer.Name, outstanding, amount, customer.CreditLimit)
+	}
+	return nil
+}
+
+// ListCustomers returns live customers, optionally filtered by ?q on name or phone.
+func ListCustomers(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := db.Where("is_deleted = ?", false)
+		if s := strings.TrimSpace(c.Query("q")); s != "" {
+			like := "%" + strings.ToLower(s) + "%"
+			q = q.Where("LOWER(name) LIKE ? OR phone LIKE ?", like, like)
+		}
+		var customers []models.Customer
+		if err := q.Order("name").Find(&customers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, customers)
+	}
+}
+
+// GetCustomer returns a customer with the hutang still outstanding.
+func GetCustomer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var customer models.Customer
+		if err := db.First(&customer, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+			customerNotFound(c, err)
+			return
+		}
+		outstanding, err := customerOutstanding(db, customer.ID, "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, customerResponse{Customer: customer, Outstanding: outstanding})
+	}
+}
+
+func CreateCustomer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload customerPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if msg := payload.validate(); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		customer := models.Customer{
+			ID:          uuid.NewString(),
+			Name:        strings.TrimSpace(payload.Name),
+			Phone:       payload.Phone,
+			Address:     payload.Address,
+			NPWP:        payload.NPWP,
+			PriceTier:   payload.PriceTier,
+			CreditLimit: payload.CreditLimit,
+			Synced:      false,
+		}
+		if err := db.Create(&customer).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, customer)
+	}
+}
+
+// UpdateCustomer replaces the customer's details. Existing sales keep the
+// name they were recorded with.
+func UpdateCustomer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload customerPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if msg := payload.validate(); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		var customer models.Customer
+		if err := db.First(&customer, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+			customerNotFound(c, err)
+			return
+		}
+		customer.Name = strings.TrimSpace(payload.Name)
+		customer.Phone = payload.Phone
+		customer.Address = payload.Address
+		customer.NPWP = payload.NPWP
+		customer.PriceTier = payload.PriceTier
+		customer.CreditLimit = payload.CreditLimit
+		customer.Synced = false
+		if err := db.Save(&customer).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, customer)
+	}
+}
+
+// DeleteCustomer tombstones a customer; their sales keep customer_id and name.
+func DeleteCustomer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var customer models.Customer
+		if err := db.First(&customer, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+			customerNotFound(c, err)
+			return
+		}
+		updates := map[string]interface{}{
+			"is_deleted": true,
+			"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+			"synced":     false,
+		}
+		if err := db.Model(&customer).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "customer deleted"})
+	}
+}
+
+// CustomerSales returns the purchase history of a customer, newest first,
+// optionally limited to ?start/?end (YYYY-MM-DD).
+func CustomerSales(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var customer models.Customer
+		if err := db.First(&customer, "id = ?", c.Param("id")).Error; err != nil {
+			customerNotFound(c, err)
+			return
+		}
+		start, end, ok := dateRangeQuery(c)
+		if !ok {
+			return
+		}
+
+		var sales []models.Sale
+		if err := db.Where("customer_id = ? AND is_deleted = ? AND created_at BETWEEN ? AND ?", customer.ID, false, start, end).
+			Preload("Items", "is_deleted = ?", false).
+			Preload("Payments", "is_deleted = ?", false).
+			Order("created_at DESC").
+			Find(&sales).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		var spent, hutang models.Money
+		var lastPurchase *time.Time
+		for i, s := range sales {
+			spent += s.Total
+			hutang += hutangAmount(s.Payments)
+			if lastPurchase == nil {
+				lastPurchase = &sales[i].CreatedAt
+			}
+		}
+		outstanding, err := customerOutstanding(db, customer.ID, "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"customer":      customer,
+			"orders":        len(sales),
+			"total_spent":   spent,
+			"hutang":        hutang, // hutang within the range
+			"outstanding":   outstanding,
+			"last_purchase": lastPurchase,
+			"sales":         sales,
+		})
+	}
+}
+
+func customerNotFound(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -14,6 +14,35 @@ func UnsyncedCounts(db *gorm.DB) gin.HandlerFunc {
 		var branches int64
 		var sales int64
 		var saleItems int64
+		var salePayments int64
+		var promotions int64
+		var taxRates int64
+		var customers int64
+		var cashSessions int64
+		var cashMovements int64
+		var barcodes int64
+		var categories int64
+		var brands int64
+		var productUnits int64
+		var costLayers int64
+		var priceHistory int64
+		var priceSchedules int64
+		var suppliers int64
+		var purchaseOrders int64
+		var poItems int64
+		var goodsReceipts int64
+		var grItems int64
+		var transfers int64
+		var transferItems int64
+		var movements int64
+		var reorderLevels int64
+		var batches int64
+		var allocations int64
+		var adjustments int64
+		var adjItems int64
+		var opCounts int64
+		var brStock int64
+		var brPrices int64
 		var opnames int64
 		var opItems int64
 
@@ -22,16 +51,74 @@ func UnsyncedCounts(db *gorm.DB) gin.HandlerFunc {
 		_ = db.Table("branches").Where("synced = ?", false).Count(&branches).Error
 		_ = db.Table("sales").Where("synced = ?", false).Count(&sales).Error
 		_ = db.Table("sale_items").Where("synced = ?", false).Count(&saleItems).Error
+		_ = db.Table("sale_payments").Where("synced = ?", false).Count(&salePayments).Error
+		_ = db.Table("promotions").Where("synced = ?", false).Count(&promotions).Error
+		_ = db.Table("tax_rates").Where("synced = ?", false).Count(&taxRates).Error
+		_ = db.Table("customers").Where("synced = ?", false).Count(&customers).Error
+		_ = db.Table("cash_sessions").Where("synced = ?", false).Count(&cashSessions).Error
+		_ = db.Table("cash_movements").Where("synced = ?", false).Count(&cashMovements).Error
+		_ = db.Table("product_barcodes").Where("synced = ?", false).Count(&barcodes).Error
+		_ = db.Table("categories").Where("synced = ?", false).Count(&categories).Error
+		_ = db.Table("brands").Where("synced = ?", false).Count(&brands).Error
+		_ = db.Table("product_units").Where("synced = ?", false).Count(&productUnits).Error
+		_ = db.Table("cost_layers").Where("synced = ?", false).Count(&costLayers).Error
+		_ = db.Table("product_price_histories").Where("synced = ?", false).Count(&priceHistory).Error
+		_ = db.Table("scheduled_price_changes").Where("synced = ?", false).Count(&priceSchedules).Error
+		_ = db.Table("suppliers").Where("synced = ?", false).Count(&suppliers).Error
+		_ = db.Table("purchase_orders").Where("synced = ?", false).Count(&purchaseOrders).Error
+		_ = db.Table("purchase_order_items").Where("synced = ?", false).Count(&poItems).Error
+		_ = db.Table("goods_receipts").Where("synced = ?", false).Count(&goodsReceipts).Error
+		_ = db.Table("goods_receipt_items").Where("synced = ?", false).Count(&grItems).Error
+		_ = db.Table("stock_transfers").Where("synced = ?", false).Count(&transfers).Error
+		_ = db.Table("stock_transfer_items").Where("synced = ?", false).Count(&transferItems).Error
+		_ = db.Table("stock_movements").Where("synced = ?", false).Count(&movements).Error
+		_ = db.Table("reorder_levels").Where("synced = ?", false).Count(&reorderLevels).Error
+		_ = db.Table("stock_batches").Where("synced = ?", false).Count(&batches).Error
+		_ = db.Table("stock_batch_allocations").Where("synced = ?", false).Count(&allocations).Error
+		_ = db.Table("stock_adjustments").Where("synced = ?", false).Count(&adjustments).Error
+		_ = db.Table("stock_adjustment_items").Where("synced = ?", false).Count(&adjItems).Error
+		_ = db.Table("stock_opname_counts").Where("synced = ?", false).Count(&opCounts).Error
+		_ = db.Table("branch_stocks").Where("synced = ?", false).Count(&brStock).Error
+		_ = db.Table("branch_prices").Where("synced = ?", false).Count(&brPrices).Error
 		_ = db.Table("stock_opnames").Where("synced = ?", false).Count(&opnames).Error
 		_ = db.Table("stock_opname_items").Where("synced = ?", false).Count(&opItems).Error
 
 		c.JSON(http.StatusOK, gin.H{
-			"products":           products,
-			"branches":           branches,
-			"sales":              sales,
-			"sale_items":         saleItems,
-			"stock_opnames":      opnames,
-			"stock_opname_items": opItems,
+			"products":                products,
+			"branches":                branches,
+			"sales":                   sales,
+			"sale_items":              saleItems,
+			"sale_payments":           salePayments,
+			"promotions":              promotions,
+			"tax_rates":               taxRates,
+			"customers":               customers,
+			"cash_sessions":           cashSessions,
+			"cash_movements":          cashMovements,
+			"product_barcodes":        barcodes,
+			"categories":              categories,
+			"brands":                  brands,
+			"product_units":           productUnits,
+			"cost_layers":             costLayers,
+			"product_price_histories": priceHistory,
+			"scheduled_price_changes": priceSchedules,
+			"suppliers":               suppliers,
+			"purchase_orders":         purchaseOrders,
+			"purchase_order_items":    poItems,
+			"goods_receipts":          goodsReceipts,
+			"goods_receipt_items":     grItems,
+			"stock_transfers":         transfers,
+			"stock_transfer_items":    transferItems,
+			"stock_movements":         movements,
+			"reorder_levels":          reorderLevels,
+			"stock_batches":           batches,
+			"stock_batch_allocations": allocations,
+			"stock_adjustments":       adjustments,
+			"stock_adjustment_items":  adjItems,
+			"stock_opname_counts":     opCounts,
+			"branch_stocks":           brStock,
+			"branch_prices":           brPrices,
+			"stock_opnames":           opnames,
+			"stock_opname_items":      opItems,
 		})
 	}
 }
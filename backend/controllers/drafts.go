@@ -0,0 +1,539 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/models"
+	"shosha_mart_backend/pricing"
+)
+
+// draftResponse is a draft with the totals it would post at right now.
+type draftResponse struct {
+	models.DraftSale
+	Preview      *models.Sale `json:"preview,omitempty"`
+	PreviewError string       `json:"preview_error,omitempty"`
+}
+
+// draftSaleInput turns a draft into a checkout request.
+func draftSaleInput(d models.DraftSale) saleInput {
+	in := saleInput{
+		BranchID:      d.BranchID,
+		CustomerID:    d.CustomerID,
+		TillID:        d.TillID,
+		Notes:         d.Notes,
+		DiscountType:  d.DiscountType,
+		DiscountValue: d.DiscountValue,
+		Items:         make([]saleItemInput, len(d.Items)),
+	}
+	for i, item := range d.Items {
+		in.Items[i] = saleItemInput{
+			ProductID:     item.ProductID,
+			Qty:           item.Qty,
+			Unit:          item.Unit,
+			Price:         item.Price,
+			DiscountType:  item.DiscountType,
+			DiscountValue: item.DiscountValue,
+		}
+	}
+	return in
+}
+
+// loadDraft loads a draft that has not been posted, with its items.
+func loadDraft(db *gorm.DB, id string) (models.DraftSale, error) {
+	var d models.DraftSale
+	if err := db.Preload("Items", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("created_at ASC")
+	}).First(&d, "id = ? AND status <> ?", id, models.DraftPosted).Error; err != nil {
+		return d, err
+	}
+	return d, nil
+}
+
+// respondDraft writes a draft together with its priced preview. Pricing
+// problems (e.g. a price that needs a manager override) are reported in
+// preview_error rather than failing the request.
+func respondDraft(c *gin.Context, db *gorm.DB, cfg config.AppConfig, status int, d models.DraftSale) {
+	resp := draftResponse{DraftSale: d}
+	if len(d.Items) > 0 {
+		sale, err := priceSale(db, cfg, draftSaleInput(d))
+		if err != nil {
+			resp.PreviewError = err.Error()
+		} else {
+			resp.Preview = &sale
+		}
+	}
+	c.JSON(status, resp)
+}
+
+// draftNotFound answers 404 for a missing or already posted draft.
+func draftNotFound(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "draft not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// touchDraft bumps updated_at after a line change. It updates by id so the
+// loaded items are not written back.
+func touchDraft(db *gorm.DB, id string) {
+	db.Model(&models.DraftSale{}).Where("id = ?", id).Update("updated_at", time.Now())
+}
+
+// checkDraftProducts rejects a draft holding a product that has since been
+// removed, naming the product so the cashier can take it off the draft.
+func checkDraftProducts(tx *gorm.DB, d models.DraftSale) error {
+	for _, item := range d.Items {
+		var product models.Product
+		if err := tx.Select("id", "name", "is_deleted").Limit(1).Find(&product, "id = ?", item.ProductID).Error; err != nil {
+			return err
+		}
+		if product.ID == "" {
+			return badCheckout("product %s of this draft no longer exists", item.ProductID)
+		}
+		if product.IsDeleted {
+			return badCheckout("%s has been removed from the catalogue; take it off the draft", product.Name)
+		}
+	}
+	return nil
+}
+
+func validDraftItem(item saleItemInput) error {
+	if item.ProductID == "" || item.Qty <= 0 {
+		return errors.New("product_id required and qty must be > 0")
+	}
+	if !pricing.ValidDiscountType(item.DiscountType) {
+		return errors.New("discount_type must be 'percent' or 'amount'")
+	}
+	return nil
+}
+
+// ListDrafts returns drafts that have not been posted, optionally filtered
+// by ?status=open|parked, ?branch_id= and ?till_id=.
+func ListDrafts(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := db.Where("status <> ?", models.DraftPosted)
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if branchID := c.Query("branch_id"); branchID != "" {
+			query = query.Where("branch_id = ?", branchID)
+		}
+		if tillID := c.Query("till_id"); tillID != "" {
+			query = query.Where("till_id = ?", tillID)
+		}
+		var drafts []models.DraftSale
+		if err := query.Preload("Items").Order("updated_at desc").Find(&drafts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, drafts)
+	}
+}
+
+// GetDraft returns a draft with a preview of its totals.
+func GetDraft(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d, err := loadDraft(db, c.Param("id"))
+		if err != nil {
+			draftNotFound(c, err)
+			return
+		}
+		respondDraft(c, db, cfg, http.StatusOK, d)
+	}
+}
+
+// CreateDraft starts a new cart on a till, optionally with items.
+func CreateDraft(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			Label         string          `json:"label"`
+			BranchID      string          `json:"branch_id"`
+			TillID        string          `json:"till_id"`
+			CustomerID    string          `json:"customer_id"`
+			Notes         string          `json:"notes"`
+			DiscountType  string          `json:"discount_type"`
+			DiscountValue float64         `json:"discount_value"`
+			Items         []saleItemInput `json:"items"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if !pricing.ValidDiscountType(payload.DiscountType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "discount_type must be 'percent' or 'amount'"})
+			return
+		}
+
+		d := models.DraftSale{
+			ID:            uuid.NewString(),
+			Label:         payload.Label,
+			Status:        models.DraftOpen,
+			BranchID:      chooseBranch(payload.BranchID, cfg.BranchID),
+			TillID:        payload.TillID,
+			CustomerID:    payload.CustomerID,
+			Notes:         payload.Notes,
+			DiscountType:  payload.DiscountType,
+			DiscountValue: payload.DiscountValue,
+		}
+		for _, item := range payload.Items {
+			if err := validDraftItem(item); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			d.Items = append(d.Items, models.DraftSaleItem{
+				ID:            uuid.NewString(),
+				DraftSaleID:   d.ID,
+				ProductID:     item.ProductID,
+				Qty:           item.Qty,
+				Unit:          item.Unit,
+				Price:         item.Price,
+				DiscountType:  item.DiscountType,
+				DiscountValue: item.DiscountValue,
+			})
+		}
+		if err := db.Create(&d).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		respondDraft(c, db, cfg, http.StatusCreated, d)
+	}
+}
+
+// UpdateDraft changes the header of a draft (label, branch, customer, notes, order discount).
+func UpdateDraft(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			Label         *string  `json:"label"`
+			BranchID      *string  `json:"branch_id"`
+			CustomerID    *string  `json:"customer_id"`
+			Notes         *string  `json:"notes"`
+			DiscountType  *string  `json:"discount_type"`
+			DiscountValue *float64 `json:"discount_value"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if payload.DiscountType != nil && !pricing.ValidDiscountType(*payload.DiscountType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "discount_type must be 'percent' or 'amount'"})
+			return
+		}
+
+		d, err := loadDraft(db, c.Param("id"))
+		if err != nil {
+			draftNotFound(c, err)
+			return
+		}
+		if payload.Label != nil {
+			d.Label = *payload.Label
+		}
+		if payload.BranchID != nil {
+			d.BranchID = chooseBranch(*payload.BranchID, cfg.BranchID)
+		}
+		if payload.CustomerID != nil {
+			d.CustomerID = *payload.CustomerID
+		}
+		if payload.Notes != nil {
+			d.Notes = *payload.Notes
+		}
+		if payload.DiscountType != nil {
+			d.DiscountType = *payload.DiscountType
+		}
+		if payload.DiscountValue != nil {
+			d.DiscountValue = *payload.DiscountValue
+		}
+		if err := db.Omit("Items").Save(&d).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		respondDraft(c, db, cfg, http.StatusOK, d)
+	}
+}
+
+// AddDraftItem adds a line to a draft; adding a product already in the cart
+// at the same price and discount increases its qty instead.
+func AddDraftItem(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload saleItemInput
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if err := validDraftItem(payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		d, err := loadDraft(db, c.Param("id"))
+		if err != nil {
+			draftNotFound(c, err)
+			return
+		}
+		var product models.Product
+		if err := db.First(&product, "id = ? AND is_deleted = ?", payload.ProductID, false).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "product not found"})
+			return
+		}
+		unit, err := findUnit(db, product, payload.Unit)
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for _, item := range d.Items {
+				if item.ProductID == payload.ProductID && item.Unit == unit.Name && item.Price == payload.Price &&
+					item.DiscountType == payload.DiscountType && item.DiscountValue == payload.DiscountValue {
+					return tx.Model(&item).Update("qty", item.Qty+payload.Qty).Error
+				}
+			}
+			return tx.Create(&models.DraftSaleItem{
+				ID:            uuid.NewString(),
+				DraftSaleID:   d.ID,
+				ProductID:     payload.ProductID,
+				Qty:           payload.Qty,
+				Unit:          unit.Name,
+				Price:         payload.Price,
+				DiscountType:  payload.DiscountType,
+				DiscountValue: payload.DiscountValue,
+			}).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		touchDraft(db, d.ID)
+
+		d, err = loadDraft(db, d.ID)
+		if err != nil {
+			draftNotFound(c, err)
+			return
+		}
+		respondDraft(c, db, cfg, http.StatusOK, d)
+	}
+}
+
+// UpdateDraftItem replaces the qty, unit, price and discount of a draft line.
+func UpdateDraftItem(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload saleItemInput
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		d, err := loadDraft(db, c.Param("id"))
+		if err != nil {
+			draftNotFound(c, err)
+			return
+		}
+		var item models.DraftSaleItem
+		if err := db.First(&item, "id = ? AND draft_sale_id = ?", c.Param("itemId"), d.ID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "item not found"})
+			return
+		}
+		payload.ProductID = item.ProductID
+		if err := validDraftItem(payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var product models.Product
+		if err := db.First(&product, "id = ?", item.ProductID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		unit, err := findUnit(db, product, payload.Unit)
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+
+		if err := db.Model(&item).Updates(map[string]interface{}{
+			"qty":            payload.Qty,
+			"unit":           unit.Name,
+			"price":          payload.Price,
+			"discount_type":  payload.DiscountType,
+			"discount_value": payload.DiscountValue,
+		}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		touchDraft(db, d.ID)
+
+		d, err = loadDraft(db, d.ID)
+		if err != nil {
+			draftNotFound(c, err)
+			return
+		}
+		respondDraft(c, db, cfg, http.StatusOK, d)
+	}
+}
+
+// DeleteDraftItem removes a line from a draft.
+func DeleteDraftItem(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d, err := loadDraft(db, c.Param("id"))
+		if err != nil {
+			draftNotFound(c, err)
+			return
+		}
+		res := db.Where("id = ? AND draft_sale_id = ?", c.Param("itemId"), d.ID).Delete(&models.DraftSaleItem{})
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": res.Error.Error()})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "item not found"})
+			return
+		}
+		touchDraft(db, d.ID)
+
+		d, err = loadDraft(db, d.ID)
+		if err != nil {
+			draftNotFound(c, err)
+			return
+		}
+		respondDraft(c, db, cfg, http.StatusOK, d)
+	}
+}
+
+// ParkDraft puts a cart on hold under a label so any till can resume it.
+func ParkDraft(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			Label string `json:"label"`
+		}
+		_ = c.ShouldBindJSON(&payload)
+
+		d, err := loadDraft(db, c.Param("id"))
+		if err != nil {
+			draftNotFound(c, err)
+			return
+		}
+		if payload.Label != "" {
+			d.Label = payload.Label
+		}
+		if d.Label == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "label is required to park a draft"})
+			return
+		}
+		d.Status = models.DraftParked
+		if err := db.Omit("Items").Save(&d).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		respondDraft(c, db, cfg, http.StatusOK, d)
+	}
+}
+
+// ResumeDraft reopens a parked draft on the calling till. A draft that is
+// open on another till must be parked there first.
+func ResumeDraft(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			TillID string `json:"till_id"`
+		}
+		_ = c.ShouldBindJSON(&payload)
+
+		d, err := loadDraft(db, c.Param("id"))
+		if err != nil {
+			draftNotFound(c, err)
+			return
+		}
+		if d.Status == models.DraftOpen && d.TillID != "" && d.TillID != payload.TillID {
+			c.JSON(http.StatusConflict, gin.H{"error": "draft is open on till " + d.TillID})
+			return
+		}
+		d.Status = models.DraftOpen
+		d.TillID = payload.TillID
+		if err := db.Omit("Items").Save(&d).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		respondDraft(c, db, cfg, http.StatusOK, d)
+	}
+}
+
+// PostDraft turns a draft into a Sale through the same checkout as
+// CreateSale; stock and sync are only touched at this point.
+func PostDraft(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			ReceiptNo     string         `json:"receipt_no"`
+			PaymentMethod string         `json:"payment_method"`
+			Payments      []paymentInput `json:"payments"`
+			CreatedAt     string         `json:"created_at"`
+			ManagerPIN    string         `json:"manager_pin"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		var sale models.Sale
+		err := db.Transaction(func(tx *gorm.DB) error {
+			d, err := loadDraft(tx, c.Param("id"))
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &checkoutError{status: http.StatusNotFound, msg: "draft not found"}
+			}
+			if err != nil {
+				return err
+			}
+			if err := checkDraftProducts(tx, d); err != nil {
+				return err
+			}
+			in := draftSaleInput(d)
+			in.ReceiptNo = payload.ReceiptNo
+			in.PaymentMethod = payload.PaymentMethod
+			in.Payments = payload.Payments
+			in.CreatedAt = payload.CreatedAt
+			in.ManagerPIN = payload.ManagerPIN
+			in.User = posUser(c, "")
+
+			sale, err = postSale(tx, cfg, in)
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			return tx.Model(&models.DraftSale{}).Where("id = ?", d.ID).Updates(map[string]interface{}{
+				"status":    models.DraftPosted,
+				"sale_id":   sale.ID,
+				"posted_at": &now,
+			}).Error
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, sale)
+	}
+}
+
+// DeleteDraft discards a draft and its lines. Drafts were never synced, so
+// they are removed outright instead of tombstoned.
+func DeleteDraft(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d, err := loadDraft(db, c.Param("id"))
+		if err != nil {
+			draftNotFound(c, err)
+			return
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("draft_sale_id = ?", d.ID).Delete(&models.DraftSaleItem{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&models.DraftSale{}, "id = ?", d.ID).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "draft deleted"})
+	}
+}
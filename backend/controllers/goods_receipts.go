@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/costing"
+	"shosha_mart_backend/models"
+)
+
+type goodsReceiptItemInput struct {
+	PurchaseOrderItemID string       `json:"purchase_order_item_id"`
+	ProductID           string       `json:"product_id"` // untuk baris di luar PO
+	Unit                string       `json:"unit"`
+	Qty                 models.Qty   `json:"qty"`
+	UnitCost            models.Money `json:"unit_cost"` // 0 = harga di PO, atau HPP produk
+	BatchNo             string       `json:"batch_no"`
+	ExpiryDate          string       `json:"expiry_date"` // YYYY-MM-DD
+}
+
+type goodsReceiptPayload struct {
+	PurchaseOrderID string                  `json:"purchase_order_id"`
+	SupplierID      string                  `json:"supplier_id"`
+	InvoiceNo       string                  `json:"invoice_no"`
+	ReceivedAt      *time.Time              `json:"received_at"`
+	ReceivedBy      string                  `json:"received_by"`
+	Note            string                  `json:"note"`
+	Items           []goodsReceiptItemInput `json:"items"`
+}
+
+// receivePurchaseOrderLine checks a received line against the open purchase
+// order line it belongs to and books it on that line.
+func receivePurchaseOrderLine(tx *gorm.DB, po models.PurchaseOrder, in goodsReceiptItemInput, item *models.GoodsReceiptItem) error {
+	var line *models.PurchaseOrderItem
+	for i := range po.Items {
+		if po.Items[i].ID == in.PurchaseOrderItemID {
+			line = &po.Items[i]
+		}
+	}
+	if line == nil {
+		return badCheckout("purchase order item %s is not on %s", in.PurchaseOrderItemID, po.Number)
+	}
+	if left := line.Qty - line.ReceivedQty; in.Qty > left {
+		return badCheckout("only %s of this line is still to be received", strings.TrimSpace(left.String()+" "+line.Unit))
+	}
+	item.PurchaseOrderItemID = line.ID
+	item.ProductID = line.ProductID
+	item.Unit = line.Unit
+	item.UnitFactor = line.UnitFactor
+	item.OrderedQty = line.Qty
+	if item.UnitCost == 0 {
+		item.UnitCost = line.UnitCost
+	}
+	line.ReceivedQty += in.Qty
+	return tx.Model(&models.PurchaseOrderItem{}).Where("id = ?", line.ID).Updates(map[string]interface{}{
+		"received_qty": line.ReceivedQty,
+		"synced":       false,
+	}).Error
+}
+
+// refreshPurchaseOrderStatus moves a sent order to partially received, or
+// closes it once every line has arrived.
+func refreshPurchaseOrderStatus(tx *gorm.DB, po models.PurchaseOrder, at time.Time) error {
+	updates := map[string]interface{}{"status": models.PurchaseOrderClosed, "closed_at": at, "synced": false}
+	for _, it := range po.Items {
+		if it.ReceivedQty < it.Qty {
+			updates = map[string]interface{}{"status": models.PurchaseOrderPartial, "synced": false}
+			break
+		}
+	}
+	return tx.Model(&models.PurchaseOrder{}).Where("id = ?", po.ID).Updates(updates).Error
+}
+
+func goodsReceiptNotFound(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "goods receipt not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// ListGoodsReceipts returns goods receipts newest first. Filters:
+// purchase_order_id, supplier_id, product_id and start/end (YYYY-MM-DD).
+func ListGoodsReceipts(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := db.Where("is_deleted = ?", false)
+		if s := c.Query("purchase_order_id"); s != "" {
+			q = q.Where("purchase_order_id = ?", s)
+		}
+		if s := c.Query("supplier_id"); s != "" {
+			q = q.Where("supplier_id = ?", s)
+		}
+		if s := c.Query("product_id"); s != "" {
+			q = q.Where("id IN (?)", db.Model(&models.GoodsReceiptItem{}).Select("goods_receipt_id").
+				Where("product_id = ? AND is_deleted = ?", s, false))
+		}
+		if c.Query("start") != "" || c.Query("end") != "" {
+			start, end, ok := dateRangeQuery(c)
+			if !ok {
+				return
+			}
+			q = q.Where("received_at BETWEEN ? AND ?", start, end)
+		}
+		var receipts []models.GoodsReceipt
+		if err := q.Preload("Items", "is_deleted = ?", false).Order("received_at DESC").Find(&receipts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, receipts)
+	}
+}
+
+func GetGoodsReceipt(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var receipt models.GoodsReceipt
+		if err := db.Preload("Items", "is_deleted = ?", false).
+			First(&receipt, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+			goodsReceiptNotFound(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, receipt)
+	}
+}
+
+// CreateGoodsReceipt books goods arriving at this branch into stock. With
+// purchase_order_id every line names the purchase_order_item_id it delivers
+// and may not exceed what is still outstanding; the order then becomes
+// partially received or closed. Without it the receipt is ad hoc and lines
+// name a product_id and unit. Stock and product cost are updated through the
+// configured costing method in the same transaction.
+func CreateGoodsReceipt(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload goodsReceiptPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if len(payload.Items) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "items are required"})
+			return
+		}
+		now := time.Now()
+		receipt := models.GoodsReceipt{
+			ID:         uuid.NewString(),
+			SupplierID: payload.SupplierID,
+			BranchID:   cfg.BranchID,
+			InvoiceNo:  strings.TrimSpace(payload.InvoiceNo),
+			ReceivedAt: now,
+			ReceivedBy: strings.TrimSpace(payload.ReceivedBy),
+			Note:       payload.Note,
+			Synced:     false,
+		}
+		if payload.ReceivedAt != nil {
+			if payload.ReceivedAt.After(now) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "received_at cannot be in the future"})
+				return
+			}
+			receipt.ReceivedAt = *payload.ReceivedAt
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var po models.PurchaseOrder
+			if payload.PurchaseOrderID != "" {
+				var err error
+				if po, err = loadPurchaseOrder(tx, payload.PurchaseOrderID); err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return badCheckout("purchase order not found")
+					}
+					return err
+				}
+				if po.Status != models.PurchaseOrderSent && po.Status != models.PurchaseOrderPartial {
+					return &checkoutError{status: http.StatusConflict, msg: "purchase order " + po.Number + " is " + po.Status + "; only sent orders can be received"}
+				}
+				if po.BranchID != cfg.BranchID {
+					return &checkoutError{status: http.StatusConflict, msg: "purchase order " + po.Number + " is for another branch"}
+				}
+				receipt.PurchaseOrderID = po.ID
+				receipt.SupplierID = po.SupplierID
+			} else if receipt.SupplierID != "" {
+				var n int64
+				if err := tx.Model(&models.Supplier{}).Where("id = ? AND is_deleted = ?", receipt.SupplierID, false).Count(&n).Error; err != nil {
+					return err
+				}
+				if n == 0 {
+					return badCheckout("supplier not found")
+				}
+			}
+
+			// nomor lebih dulu, dicatat di kartu stok tiap baris
+			var err error
+			if receipt.Number, err = nextDocumentNumber(tx, &models.GoodsReceipt{}, "GR", receipt.BranchID, now); err != nil {
+				return err
+			}
+			ref := costing.Ref{Source: models.MovementReceipt, DocID: receipt.ID, DocNo: receipt.Number, User: posUser(c, receipt.ReceivedBy)}
+			items := make([]models.GoodsReceiptItem, 0, len(payload.Items))
+			for _, in := range payload.Items {
+				if in.Qty <= 0 {
+					return badCheckout("qty must be greater than 0")
+				}
+				if in.UnitCost < 0 {
+					return badCheckout("unit_cost must be >= 0")
+				}
+				item := models.GoodsReceiptItem{
+					ID:             uuid.NewString(),
+					GoodsReceiptID: receipt.ID,
+					Qty:            in.Qty,
+					UnitCost:       in.UnitCost,
+					BatchNo:        strings.TrimSpace(in.BatchNo),
+					Synced:         false,
+				}
+				if in.ExpiryDate != "" {
+					d, err := time.ParseInLocation("2006-01-02", in.ExpiryDate, time.Local)
+					if err != nil {
+						return badCheckout("expiry_date must be YYYY-MM-DD")
+					}
+					item.ExpiryDate = &d
+				}
+
+				if po.ID != "" {
+					if in.PurchaseOrderItemID == "" {
+						return badCheckout("purchase_order_item_id is required when receiving a purchase order")
+					}
+					if err := receivePurchaseOrderLine(tx, po, in, &item); err != nil {
+						return err
+					}
+				} else {
+					var product models.Product
+					if err := tx.First(&product, "id = ? AND is_deleted = ?", in.ProductID, false).Error; err != nil {
+						if errors.Is(err, gorm.ErrRecordNotFound) {
+							return badCheckout("product %s not found", in.ProductID)
+						}
+						return err
+					}
+					unit, err := findUnit(tx, product, in.Unit)
+					if err != nil {
+						return err
+					}
+					item.ProductID, item.Unit, item.UnitFactor = product.ID, unit.Name, unit.Factor
+				}
+
+				var product models.Product
+				if err := tx.Select("id", "cost").First(&product, "id = ?", item.ProductID).Error; err != nil {
+					return err
+				}
+				if item.UnitCost == 0 {
+					item.UnitCost = product.Cost.TimesQty(item.UnitFactor)
+				}
+				item.BaseQty = item.Qty.Mul(item.UnitFactor)
+				item.Subtotal = item.UnitCost.TimesQty(item.Qty)
+				receipt.Total += item.Subtotal
+				lot := ref
+				lot.BatchNo, lot.ExpiryDate = item.BatchNo, item.ExpiryDate
+				if err := costing.Receive(tx, cfg.CostMethod, receipt.BranchID, item.ProductID, item.BaseQty,
+					item.UnitCost.PerQty(item.UnitFactor), lot); err != nil {
+					return err
+				}
+				items = append(items, item)
+			}
+
+			if err := tx.Create(&receipt).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&items).Error; err != nil {
+				return err
+			}
+			receipt.Items = items
+			if po.ID != "" {
+				return refreshPurchaseOrderStatus(tx, po, now)
+			}
+			return nil
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, receipt)
+	}
+}
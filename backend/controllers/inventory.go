@@ -0,0 +1,257 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/inventory"
+	"shosha_mart_backend/models"
+)
+
+// Defaults for reorder suggestions.
+const (
+	defaultVelocityDays = 30 // penjualan yang dihitung
+	defaultCoverDays    = 14 // stok yang ingin dijaga di atas minimum
+)
+
+// ListReorderLevels returns the reorder levels of a branch (?branch_id,
+// default the local branch), optionally for one ?product_id.
+func ListReorderLevels(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := db.Where("branch_id = ? AND is_deleted = ?", chooseBranch(c.Query("branch_id"), cfg.BranchID), false)
+		if v := c.Query("product_id"); v != "" {
+			q = q.Where("product_id = ?", v)
+		}
+		var levels []models.ReorderLevel
+		if err := q.Order("created_at").Find(&levels).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, levels)
+	}
+}
+
+// SetReorderLevel creates or replaces a product's reorder level at a branch.
+func SetReorderLevel(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			BranchID   string     `json:"branch_id"`
+			MinStock   models.Qty `json:"min_stock"`
+			ReorderQty models.Qty `json:"reorder_qty"`
+			SupplierID string     `json:"supplier_id"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if payload.MinStock < 0 || payload.ReorderQty < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_stock and reorder_qty must be >= 0"})
+			return
+		}
+		var product models.Product
+		if err := db.First(&product, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+			productError(c, err)
+			return
+		}
+		branchID := chooseBranch(payload.BranchID, cfg.BranchID)
+
+		var level models.ReorderLevel
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := checkBranch(tx, cfg, branchID); err != nil {
+				return err
+			}
+			if payload.SupplierID != "" {
+				var n int64
+				if err := tx.Model(&models.Supplier{}).Where("id = ? AND is_deleted = ?", payload.SupplierID, false).Count(&n).Error; err != nil {
+					return err
+				}
+				if n == 0 {
+					return badCheckout("supplier not found")
+				}
+			}
+			err := tx.Where("product_id = ? AND branch_id = ? AND is_deleted = ?", product.ID, branchID, false).First(&level).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if level.ID == "" {
+				level = models.ReorderLevel{ID: uuid.NewString(), ProductID: product.ID, BranchID: branchID}
+			}
+			level.MinStock = payload.MinStock
+			level.ReorderQty = payload.ReorderQty
+			level.SupplierID = payload.SupplierID
+			level.Synced = false
+			return tx.Save(&level).Error
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, level)
+	}
+}
+
+// DeleteReorderLevel removes a product's reorder level at ?branch_id
+// (default the local branch).
+func DeleteReorderLevel(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res := db.Model(&models.ReorderLevel{}).
+			Where("product_id = ? AND branch_id = ? AND is_deleted = ?", c.Param("id"), chooseBranch(c.Query("branch_id"), cfg.BranchID), false).
+			Updates(map[string]interface{}{
+				"is_deleted": true,
+				"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+				"synced":     false,
+			})
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": res.Error.Error()})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "reorder level not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "reorder level deleted"})
+	}
+}
+
+// LowStock lists the local branch's products at or below their minimum.
+func LowStock(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items, err := inventory.LowStock(db, cfg.BranchID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, items)
+	}
+}
+
+// dayCountQuery reads a whole number of days between lo and 365 from the
+// query. It writes the 400 itself and returns false on bad input.
+func dayCountQuery(c *gin.Context, name string, fallback, lo int) (int, bool) {
+	s := c.Query(name)
+	if s == "" {
+		return fallback, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < lo || n > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be between " + strconv.Itoa(lo) + " and 365"})
+		return 0, false
+	}
+	return n, true
+}
+
+// ReorderSuggestions proposes what the local branch should order, from the
+// sales of the last ?days (default 30) to cover ?cover_days (default 14).
+// ?supplier_id keeps the products usually bought from that supplier.
+func ReorderSuggestions(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		days, ok := dayCountQuery(c, "days", defaultVelocityDays, 1)
+		if !ok {
+			return
+		}
+		cover, ok := dayCountQuery(c, "cover_days", defaultCoverDays, 0)
+		if !ok {
+			return
+		}
+		suggestions, err := inventory.Suggest(db, cfg.BranchID, time.Now(), days, cover)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if v := c.Query("supplier_id"); v != "" {
+			kept := suggestions[:0]
+			for _, s := range suggestions {
+				if s.SupplierID == v {
+					kept = append(kept, s)
+				}
+			}
+			suggestions = kept
+		}
+		c.JSON(http.StatusOK, suggestions)
+	}
+}
+
+// CreateSuggestedPurchaseOrder drafts a purchase order for the local branch
+// from the current suggestions: the given product_ids, or else every product
+// usually bought from the supplier. Lines are in base units at current cost.
+func CreateSuggestedPurchaseOrder(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			SupplierID string     `json:"supplier_id"`
+			ProductIDs []string   `json:"product_ids"`
+			Days       int        `json:"days"`
+			CoverDays  *int       `json:"cover_days"`
+			ExpectedAt *time.Time `json:"expected_at"`
+			Note       string     `json:"note"`
+			CreatedBy  string     `json:"created_by"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if payload.SupplierID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "supplier_id is required"})
+			return
+		}
+		days, cover := payload.Days, defaultCoverDays
+		if days == 0 {
+			days = defaultVelocityDays
+		}
+		if payload.CoverDays != nil {
+			cover = *payload.CoverDays
+		}
+		if days < 1 || days > 365 || cover < 0 || cover > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days and cover_days must be at most 365"})
+			return
+		}
+
+		now := time.Now()
+		suggestions, err := inventory.Suggest(db, cfg.BranchID, now, days, cover)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		wanted := map[string]bool{}
+		for _, id := range payload.ProductIDs {
+			wanted[id] = true
+		}
+		var inputs []purchaseOrderItemInput
+		for _, s := range suggestions {
+			if (len(wanted) > 0 && wanted[s.ProductID]) || (len(wanted) == 0 && s.SupplierID == payload.SupplierID) {
+				inputs = append(inputs, purchaseOrderItemInput{ProductID: s.ProductID, Qty: s.Qty})
+			}
+		}
+		if len(inputs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to reorder"})
+			return
+		}
+
+		po := models.PurchaseOrder{
+			ID:         uuid.NewString(),
+			SupplierID: payload.SupplierID,
+			BranchID:   cfg.BranchID,
+			Status:     models.PurchaseOrderDraft,
+			OrderDate:  now,
+			ExpectedAt: payload.ExpectedAt,
+			Note:       payload.Note,
+			CreatedBy:  strings.TrimSpace(payload.CreatedBy),
+			Synced:     false,
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return insertPurchaseOrder(tx, cfg, &po, inputs)
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, po)
+	}
+}
@@ -0,0 +1,504 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/catalog"
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/costing"
+	"shosha_mart_backend/models"
+)
+
+// opnameSession is an opname with its counting progress.
+type opnameSession struct {
+	models.StockOpname
+	Total     int `json:"total"`
+	Counted   int `json:"counted"`
+	Uncounted int `json:"uncounted"`
+}
+
+func newOpnameSession(o models.StockOpname) opnameSession {
+	s := opnameSession{StockOpname: o, Total: len(o.Items)}
+	for _, it := range o.Items {
+		if it.Counted {
+			s.Counted++
+		}
+	}
+	s.Uncounted = s.Total - s.Counted
+	return s
+}
+
+func loadOpname(db *gorm.DB, id string) (models.StockOpname, error) {
+	var o models.StockOpname
+	err := db.Preload("Items", func(q *gorm.DB) *gorm.DB {
+		return q.Where("is_deleted = ?", false).Order("created_at, id")
+	}).First(&o, "id = ? AND is_deleted = ?", id, false).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return o, &checkoutError{status: http.StatusNotFound, msg: "stock opname not found"}
+	}
+	return o, err
+}
+
+// openOpname loads a session of the local branch that is still counting.
+func openOpname(tx *gorm.DB, cfg config.AppConfig, id string) (models.StockOpname, error) {
+	o, err := loadOpname(tx, id)
+	if err != nil {
+		return o, err
+	}
+	if o.BranchID != cfg.BranchID {
+		return o, &checkoutError{status: http.StatusForbidden, msg: "stock opname belongs to another branch"}
+	}
+	if o.Status != models.OpnameCounting {
+		return o, &checkoutError{status: http.StatusConflict, msg: "stock opname is " + o.Status}
+	}
+	return o, nil
+}
+
+// StartStockOpname opens a counting session for the local branch and
+// snapshots the stock of every live product in scope (?category_id with its
+// subcategories, or all). Stock is left alone until the session is finalized.
+// A branch counts one session at a time.
+func StartStockOpname(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			CategoryID  string `json:"category_id"`
+			PerformedBy string `json:"performed_by"`
+			Note        string `json:"note"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		now := time.Now()
+		opname := models.StockOpname{
+			ID:          uuid.NewString(),
+			BranchID:    cfg.BranchID,
+			Status:      models.OpnameCounting,
+			CategoryID:  payload.CategoryID,
+			PerformedBy: posUser(c, strings.TrimSpace(payload.PerformedBy)),
+			Note:        payload.Note,
+			Synced:      false,
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return startOpname(tx, cfg, &opname, nil, now)
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, newOpnameSession(opname))
+	}
+}
+
+// startOpname saves opname as the branch's counting session with an item
+// per live product in scope: productIDs when given, else opname.CategoryID
+// with its subcategories, or everything.
+func startOpname(tx *gorm.DB, cfg config.AppConfig, opname *models.StockOpname, productIDs []string, now time.Time) error {
+	var open int64
+	if err := tx.Model(&models.StockOpname{}).Where("branch_id = ? AND status = ? AND is_deleted = ?", cfg.BranchID, models.OpnameCounting, false).Count(&open).Error; err != nil {
+		return err
+	}
+	if open > 0 {
+		return &checkoutError{status: http.StatusConflict, msg: "another stock opname is still counting"}
+	}
+
+	q := tx.Where("is_deleted = ?", false)
+	if productIDs != nil {
+		q = q.Where("id IN ?", productIDs)
+	} else if opname.CategoryID != "" {
+		cats, err := catalog.LoadCategories(tx)
+		if err != nil {
+			return err
+		}
+		q = q.Where("category_id IN ?", cats.Subtree(opname.CategoryID))
+	}
+	var products []models.Product
+	if err := q.Order("name").Find(&products).Error; err != nil {
+		return err
+	}
+	if len(products) == 0 {
+		return badCheckout("no products to count")
+	}
+	var stocks []models.BranchStock
+	if err := tx.Where("branch_id = ?", cfg.BranchID).Find(&stocks).Error; err != nil {
+		return err
+	}
+	onHand := make(map[string]models.Qty, len(stocks))
+	for _, s := range stocks {
+		onHand[s.ProductID] = s.Qty
+	}
+
+	var err error
+	if opname.Number, err = nextDocumentNumber(tx, &models.StockOpname{}, "SO", cfg.BranchID, now); err != nil {
+		return err
+	}
+	if err := tx.Create(opname).Error; err != nil {
+		return err
+	}
+	for _, p := range products {
+		opname.Items = append(opname.Items, models.StockOpnameItem{
+			ID:            uuid.NewString(),
+			StockOpnameID: opname.ID,
+			ProductID:     p.ID,
+			SystemQty:     onHand[p.ID],
+			Synced:        false,
+		})
+	}
+	return tx.CreateInBatches(&opname.Items, 200).Error
+}
+
+// ListStockOpnames lists opnames, newest first, without their items;
+// ?status and ?branch_id filter.
+func ListStockOpnames(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := db.Where("is_deleted = ?", false)
+		for _, param := range []string{"status", "branch_id"} {
+			if v := c.Query(param); v != "" {
+				q = q.Where(param+" = ?", v)
+			}
+		}
+		var opnames []models.StockOpname
+		if err := q.Order("created_at DESC").Find(&opnames).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, opnames)
+	}
+}
+
+// GetStockOpname returns an opname with its items and counting progress.
+func GetStockOpname(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		o, err := loadOpname(db, c.Param("id"))
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newOpnameSession(o))
+	}
+}
+
+// UncountedOpnameProducts lists the products of a session nobody has
+// counted yet, by name.
+func UncountedOpnameProducts(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := loadOpname(db, c.Param("id")); err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		rows := []struct {
+			ProductID string     `json:"product_id"`
+			SKU       string     `json:"sku"`
+			Name      string     `json:"name"`
+			Unit      string     `json:"unit"`
+			SystemQty models.Qty `json:"system_qty"`
+		}{}
+		err := db.Table("stock_opname_items").
+			Select("stock_opname_items.product_id, products.sku, products.name, products.unit, stock_opname_items.system_qty").
+			Joins("JOIN products ON products.id = stock_opname_items.product_id").
+			Where("stock_opname_items.stock_opname_id = ? AND stock_opname_items.counted = ? AND stock_opname_items.is_deleted = ?", c.Param("id"), false, false).
+			Order("products.name").
+			Scan(&rows).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, rows)
+	}
+}
+
+// ListOpnameCounts returns the counts entered in a session, latest first;
+// ?product_id narrows to one product, ?all=true includes superseded counts.
+func ListOpnameCounts(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := db.Where("stock_opname_id = ? AND is_deleted = ?", c.Param("id"), false)
+		if v := c.Query("product_id"); v != "" {
+			q = q.Where("product_id = ?", v)
+		}
+		if c.Query("all") != "true" {
+			q = q.Where("superseded = ?", false)
+		}
+		var counts []models.StockOpnameCount
+		if err := q.Order("created_at DESC").Find(&counts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, counts)
+	}
+}
+
+// recountItem sets a session item's physical qty to the sum of the product's
+// live counts and its CountedAt to the latest of them.
+func recountItem(tx *gorm.DB, opnameID, productID string) (models.StockOpnameItem, error) {
+	var item models.StockOpnameItem
+	if err := tx.First(&item, "stock_opname_id = ? AND product_id = ? AND is_deleted = ?", opnameID, productID, false).Error; err != nil {
+		return item, err
+	}
+	var counts []models.StockOpnameCount
+	if err := tx.Where("stock_opname_id = ? AND product_id = ? AND superseded = ? AND is_deleted = ?", opnameID, productID, false, false).
+		Order("created_at").Find(&counts).Error; err != nil {
+		return item, err
+	}
+	item.PhysicalQty, item.CountedAt = 0, nil
+	for i := range counts {
+		item.PhysicalQty += counts[i].BaseQty
+		item.CountedAt = &counts[i].CreatedAt
+	}
+	item.Counted = len(counts) > 0
+	item.Synced = false
+	return item, tx.Save(&item).Error
+}
+
+// AddOpnameCount records a count in a session: a product_id or a scanned
+// code, in any unit of the product (qty defaults to 1 for a scan). Counts of
+// a product add up; recount=true replaces the earlier ones.
+func AddOpnameCount(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			ProductID string      `json:"product_id"`
+			Code      string      `json:"code"`
+			Unit      string      `json:"unit"` // kosong = satuan dasar
+			Qty       *models.Qty `json:"qty"`
+			Recount   bool        `json:"recount"`
+			CountedBy string      `json:"counted_by"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		code := catalog.NormalizeBarcode(payload.Code)
+		if payload.ProductID == "" && code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "product_id or code is required"})
+			return
+		}
+		qty := models.Qty(1000)
+		if payload.Qty != nil {
+			qty = *payload.Qty
+		} else if code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "qty is required"})
+			return
+		}
+		if qty < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "qty must be >= 0"})
+			return
+		}
+
+		var count models.StockOpnameCount
+		var item models.StockOpnameItem
+		err := db.Transaction(func(tx *gorm.DB) error {
+			opname, err := openOpname(tx, cfg, c.Param("id"))
+			if err != nil {
+				return err
+			}
+			var product models.Product
+			if payload.ProductID != "" {
+				err = tx.First(&product, "id = ? AND is_deleted = ?", payload.ProductID, false).Error
+			} else {
+				product, err = productByCode(tx, code)
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &checkoutError{status: http.StatusNotFound, msg: "product not found"}
+			}
+			if err != nil {
+				return err
+			}
+			count = models.StockOpnameCount{
+				Qty:       qty,
+				Unit:      payload.Unit,
+				Barcode:   code,
+				CountedBy: posUser(c, strings.TrimSpace(payload.CountedBy)),
+				Recount:   payload.Recount,
+			}
+			item, err = addOpnameCount(tx, opname.ID, product, &count)
+			return err
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"count": count, "item": item})
+	}
+}
+
+// addOpnameCount saves count (Qty in count.Unit, "" = base unit) of product
+// in a session and returns the product's item with the new total.
+func addOpnameCount(tx *gorm.DB, opnameID string, product models.Product, count *models.StockOpnameCount) (models.StockOpnameItem, error) {
+	var n int64
+	if err := tx.Model(&models.StockOpnameItem{}).Where("stock_opname_id = ? AND product_id = ? AND is_deleted = ?", opnameID, product.ID, false).Count(&n).Error; err != nil {
+		return models.StockOpnameItem{}, err
+	}
+	if n == 0 {
+		return models.StockOpnameItem{}, badCheckout("%s is not part of this stock opname", product.Name)
+	}
+	unit, err := findUnit(tx, product, count.Unit)
+	if err != nil {
+		return models.StockOpnameItem{}, err
+	}
+
+	if count.Recount {
+		if err := tx.Model(&models.StockOpnameCount{}).
+			Where("stock_opname_id = ? AND product_id = ? AND superseded = ? AND is_deleted = ?", opnameID, product.ID, false, false).
+			Updates(map[string]interface{}{"superseded": true, "synced": false}).Error; err != nil {
+			return models.StockOpnameItem{}, err
+		}
+	}
+	count.ID = uuid.NewString()
+	count.StockOpnameID = opnameID
+	count.ProductID = product.ID
+	count.Unit, count.UnitFactor = unit.Name, unit.Factor
+	count.BaseQty = count.Qty.Mul(unit.Factor)
+	count.Synced = false
+	if err := tx.Create(count).Error; err != nil {
+		return models.StockOpnameItem{}, err
+	}
+	return recountItem(tx, opnameID, product.ID)
+}
+
+// DeleteOpnameCount removes a mistaken count (e.g. a double scan) from a
+// session that is still counting.
+func DeleteOpnameCount(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var item models.StockOpnameItem
+		err := db.Transaction(func(tx *gorm.DB) error {
+			opname, err := openOpname(tx, cfg, c.Param("id"))
+			if err != nil {
+				return err
+			}
+			var count models.StockOpnameCount
+			if err := tx.First(&count, "id = ? AND stock_opname_id = ? AND is_deleted = ?", c.Param("countId"), opname.ID, false).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return &checkoutError{status: http.StatusNotFound, msg: "count not found"}
+				}
+				return err
+			}
+			if err := tx.Model(&count).Updates(map[string]interface{}{
+				"is_deleted": true,
+				"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+				"synced":     false,
+			}).Error; err != nil {
+				return err
+			}
+			item, err = recountItem(tx, opname.ID, count.ProductID)
+			return err
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, item)
+	}
+}
+
+// movedSince is the net stock change of a product at a branch recorded
+// after t, e.g. sales rung up while the shelf was being counted.
+func movedSince(tx *gorm.DB, branchID, productID string, t time.Time) (models.Qty, error) {
+	var moved models.Qty
+	err := tx.Model(&models.StockMovement{}).
+		Select("COALESCE(SUM(delta), 0)").
+		Where("branch_id = ? AND product_id = ? AND created_at > ? AND is_deleted = ?", branchID, productID, t, false).
+		Scan(&moved).Error
+	return moved, err
+}
+
+// FinalizeStockOpname closes a session and brings stock in line with the
+// counts. Each product is compared with the stock the system had when it was
+// last counted, so sales and receipts during counting are not mistaken for
+// variance. Uncounted products are left alone unless uncounted is "zero".
+func FinalizeStockOpname(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			Uncounted   string `json:"uncounted"` // "keep" (bawaan) atau "zero"
+			PerformedBy string `json:"performed_by"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if payload.Uncounted != "" && payload.Uncounted != "keep" && payload.Uncounted != "zero" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "uncounted must be keep or zero"})
+			return
+		}
+
+		var opname models.StockOpname
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if opname, err = openOpname(tx, cfg, c.Param("id")); err != nil {
+				return err
+			}
+			return finalizeOpname(tx, cfg, &opname, payload.Uncounted == "zero", posUser(c, strings.TrimSpace(payload.PerformedBy)), time.Now())
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newOpnameSession(opname))
+	}
+}
+
+// finalizeOpname applies the counts of a loaded session and closes it;
+// zeroUncounted counts the products nobody counted as none left.
+func finalizeOpname(tx *gorm.DB, cfg config.AppConfig, opname *models.StockOpname, zeroUncounted bool, user string, now time.Time) error {
+	ref := costing.Ref{Source: models.MovementOpname, DocID: opname.ID, DocNo: opname.Number, User: user, Note: opname.Note}
+	for i := range opname.Items {
+		it := &opname.Items[i]
+		if !it.Counted {
+			if !zeroUncounted {
+				continue
+			}
+			it.PhysicalQty, it.CountedAt = 0, &now
+		}
+		onHand, err := costing.OnHand(tx, cfg.BranchID, it.ProductID)
+		if err != nil {
+			return err
+		}
+		moved, err := movedSince(tx, cfg.BranchID, it.ProductID, *it.CountedAt)
+		if err != nil {
+			return err
+		}
+		it.ExpectedQty = onHand - moved
+		it.Variance = it.PhysicalQty - it.ExpectedQty
+		it.Synced = false
+		if err := tx.Save(it).Error; err != nil {
+			return err
+		}
+		if it.Variance != 0 {
+			if err := costing.SetStock(tx, cfg.CostMethod, cfg.BranchID, it.ProductID, onHand+it.Variance, ref); err != nil {
+				return err
+			}
+		}
+	}
+	opname.Status = models.OpnameFinalized
+	opname.FinalizedBy = user
+	opname.FinalizedAt = &now
+	opname.Synced = false
+	return tx.Omit("Items").Save(opname).Error
+}
+
+// CancelStockOpname abandons a session; stock is not touched.
+func CancelStockOpname(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var opname models.StockOpname
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if opname, err = openOpname(tx, cfg, c.Param("id")); err != nil {
+				return err
+			}
+			opname.Status = models.OpnameCancelled
+			opname.Synced = false
+			return tx.Omit("Items").Save(&opname).Error
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newOpnameSession(opname))
+	}
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/models"
+)
+
+// branchPriceTier returns the tier a branch sells at. Branches created before
+// tiers existed are matched by their code ("investor"/"shosha"), like the
+// cashier screen used to do.
+func branchPriceTier(branch models.Branch) string {
+	if branch.PriceTier != "" {
+		return branch.PriceTier
+	}
+	if code := strings.ToLower(strings.TrimSpace(branch.Code)); code != "" && models.ValidPriceTier(code) {
+		return code
+	}
+	return models.PriceTierDefault
+}
+
+// lookupPriceTier returns the price tier of branchID; unknown branches use the default tier.
+func lookupPriceTier(db *gorm.DB, branchID string) (string, error) {
+	if branchID == "" {
+		return models.PriceTierDefault, nil
+	}
+	var branch models.Branch
+	if err := db.First(&branch, "id = ?", branchID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.PriceTierDefault, nil
+		}
+		return "", err
+	}
+	return branchPriceTier(branch), nil
+}
+
+// managerApproved reports whether pin matches the configured manager PIN.
+func managerApproved(cfg config.AppConfig, pin string) bool {
+	if cfg.ManagerPIN == "" || pin == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cfg.ManagerPIN), []byte(pin)) == 1
+}
+
+// resolveUnitPrice picks the price of one unit of a sale line. A requested
+// price of 0 means "use the tier price"; any other price must match it unless
+// a manager approved the override.
+func resolveUnitPrice(product models.Product, unit saleUnit, tier string, requested models.Money, approved bool) (models.Money, bool, error) {
+	tierPrice := unit.tierPrice(product, tier)
+	if requested <= 0 || requested == tierPrice {
+		return tierPrice, false, nil
+	}
+	if !approved {
+		return 0, false, fmt.Errorf("price %s for %s differs from tier price %s; manager override required", requested, product.Name, tierPrice)
+	}
+	return requested, true, nil
+}
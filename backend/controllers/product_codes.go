@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/catalog"
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/models"
+	"shosha_mart_backend/pricing"
+)
+
+// liveBarcodes preloads the barcodes that have not been removed.
+func liveBarcodes(db *gorm.DB) *gorm.DB {
+	return db.Preload("Barcodes", "is_deleted = ?", false)
+}
+
+// checkSKUFree rejects a SKU already used by another live product.
+func checkSKUFree(tx *gorm.DB, sku, productID string) error {
+	if sku == "" {
+		return nil
+	}
+	var other models.Product
+	err := tx.Where("LOWER(sku) = ? AND id <> ? AND is_deleted = ?", strings.ToLower(sku), productID, false).First(&other).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return &checkoutError{status: http.StatusConflict, msg: "sku " + sku + " is already used by " + other.Name}
+}
+
+// prepareBarcodes normalises, validates and de-duplicates barcodes.
+func prepareBarcodes(codes []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(codes))
+	for _, code := range codes {
+		code = catalog.NormalizeBarcode(code)
+		if code == "" || seen[code] {
+			continue
+		}
+		if err := catalog.ValidateBarcode(code); err != nil {
+			return nil, badCheckout("%s: %s", code, err.Error())
+		}
+		seen[code] = true
+		out = append(out, code)
+	}
+	return out, nil
+}
+
+// replaceBarcodes makes codes the live barcodes of a product: missing codes
+// are tombstoned, new ones created. A code held by another product is a 409.
+func replaceBarcodes(tx *gorm.DB, productID string, codes []string) error {
+	if len(codes) > 0 {
+		var taken models.ProductBarcode
+		err := tx.Where("code IN ? AND product_id <> ? AND is_deleted = ?", codes, productID, false).First(&taken).Error
+		if err == nil {
+			return &checkoutError{status: http.StatusConflict, msg: "barcode " + taken.Code + " is already used by another product"}
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	var current []models.ProductBarcode
+	if err := tx.Where("product_id = ? AND is_deleted = ?", productID, false).Find(&current).Error; err != nil {
+		return err
+	}
+	keep := map[string]bool{}
+	for _, code := range codes {
+		keep[code] = true
+	}
+	have := map[string]bool{}
+	for _, b := range current {
+		if keep[b.Code] {
+			have[b.Code] = true
+			continue
+		}
+		if err := tx.Model(&models.ProductBarcode{}).Where("id = ?", b.ID).Updates(map[string]interface{}{
+			"is_deleted": true,
+			"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+			"synced":     false,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	for _, code := range codes {
+		if have[code] {
+			continue
+		}
+		if err := tx.Create(&models.ProductBarcode{
+			ID:        uuid.NewString(),
+			ProductID: productID,
+			Code:      code,
+			Synced:    false,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// productByCode finds the live product a normalised scanner code belongs to:
+// a barcode first (UPC-A and EAN-13 forms are interchangeable), then the SKU.
+func productByCode(db *gorm.DB, code string) (models.Product, error) {
+	var product models.Product
+	err := liveUnits(liveBarcodes(db)).
+		Joins("JOIN product_barcodes pb ON pb.product_id = products.id AND pb.is_deleted = ?", false).
+		Where("pb.code IN ? AND products.is_deleted = ?", catalog.BarcodeVariants(code), false).
+		First(&product).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = liveUnits(liveBarcodes(db)).Where("LOWER(sku) = ? AND is_deleted = ?", strings.ToLower(code), false).First(&product).Error
+	}
+	return product, err
+}
+
+// LookupProduct resolves scanner input (?code=) to a product, priced as at
+// the local branch.
+func LookupProduct(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		code := catalog.NormalizeBarcode(c.Query("code"))
+		if code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+			return
+		}
+
+		product, err := productByCode(db, code)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "no product with code " + code})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := pricing.ForBranch(db, cfg.BranchID, &product); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, product)
+	}
+}
@@ -0,0 +1,544 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/catalog"
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/costing"
+	"shosha_mart_backend/imports"
+	"shosha_mart_backend/models"
+	"shosha_mart_backend/pricing"
+)
+
+// Import modes: all_or_nothing commits only when every row is valid,
+// skip_invalid commits the valid rows and reports the rest.
+const (
+	importAllOrNothing = "all_or_nothing"
+	importSkipInvalid  = "skip_invalid"
+)
+
+// errImportRollback undoes an import transaction (dry run or failed rows).
+var errImportRollback = errors.New("import rolled back")
+
+// importLookups resolves category and brand names of an import file.
+type importLookups struct {
+	categories map[string][]string // path atau nama (huruf kecil) -> id
+	brands     map[string]string
+	// kebalikannya, untuk menampilkan perubahan
+	categoryPath map[string]string
+	brandName    map[string]string
+}
+
+func loadImportLookups(db *gorm.DB) (importLookups, error) {
+	l := importLookups{
+		categories:   map[string][]string{},
+		brands:       map[string]string{},
+		categoryPath: map[string]string{},
+		brandName:    map[string]string{},
+	}
+	cats, err := catalog.LoadCategories(db)
+	if err != nil {
+		return l, err
+	}
+	for id, c := range cats {
+		l.categoryPath[id] = cats.Path(id)
+		path := categoryKey(cats.Path(id))
+		l.categories[path] = append(l.categories[path], id)
+		if name := categoryKey(c.Name); name != path {
+			l.categories[name] = append(l.categories[name], id)
+		}
+	}
+	var brands []models.Brand
+	if err := db.Where("is_deleted = ?", false).Find(&brands).Error; err != nil {
+		return l, err
+	}
+	for _, b := range brands {
+		l.brands[strings.ToLower(b.Name)] = b.ID
+		l.brandName[b.ID] = b.Name
+	}
+	return l, nil
+}
+
+// categoryKey normalises a category name or "Induk > Anak" path for lookup.
+func categoryKey(s string) string {
+	parts := strings.Split(strings.ToLower(s), ">")
+	for i, p := range parts {
+		parts[i] = strings.Join(strings.Fields(p), " ")
+	}
+	return strings.Join(parts, " > ")
+}
+
+func (l importLookups) category(name string) (string, error) {
+	ids := l.categories[categoryKey(name)]
+	switch {
+	case len(ids) == 0:
+		return "", badCheckout("category %s not found", name)
+	case len(ids) > 1:
+		return "", badCheckout("category %s is ambiguous; use the full path (Induk > Anak)", name)
+	}
+	return ids[0], nil
+}
+
+func (l importLookups) brand(name string) (string, error) {
+	id, ok := l.brands[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return "", badCheckout("brand %s not found", name)
+	}
+	return id, nil
+}
+
+// findImportMatch returns the live product a row updates, or nil when the
+// row creates a new one. A row with an id (as in a product export) always
+// updates that product.
+func findImportMatch(tx *gorm.DB, match string, row imports.ProductRow) (*models.Product, error) {
+	if row.ID != nil {
+		var product models.Product
+		err := tx.First(&product, "id = ? AND is_deleted = ?", *row.ID, false).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, badCheckout("product %s not found", *row.ID)
+		}
+		return &product, err
+	}
+	q := tx.Where("is_deleted = ?", false)
+	if match == "name" {
+		q = q.Where("LOWER(name) = ?", strings.ToLower(row.Name))
+	} else {
+		if row.SKU == nil {
+			return nil, badCheckout("sku is required when matching by sku")
+		}
+		q = q.Where("LOWER(sku) = ?", strings.ToLower(*row.SKU))
+	}
+	var found []models.Product
+	if err := q.Limit(2).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	switch len(found) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &found[0], nil
+	}
+	return nil, badCheckout("more than one product is named %s", row.Name)
+}
+
+// importProductRow creates or updates the product of one row and records the
+// action (and for updates the changed fields) on res.
+//
+// With stock set, a stock column that differs from an existing product's
+// stock adds a line to *stock for a correction adjustment; otherwise the
+// column only sets the opening stock of new products. The cost column only
+// prices the opening stock of new products; a row that would change the cost
+// of an existing product is rejected.
+func importProductRow(tx *gorm.DB, cfg config.AppConfig, l importLookups, match, user string, row imports.ProductRow, stock *[]adjustmentLine, res *imports.RowResult) error {
+	if len(row.Errors) > 0 {
+		return badCheckout("%s", strings.Join(row.Errors, "; "))
+	}
+	if row.Name == "" {
+		return badCheckout("name is required")
+	}
+	var barcodes []string
+	if row.Barcodes != nil {
+		var err error
+		if barcodes, err = prepareBarcodes(*row.Barcodes); err != nil {
+			return err
+		}
+	}
+	var categoryID, brandID *string
+	if row.Category != nil {
+		id, err := l.category(*row.Category)
+		if err != nil {
+			return err
+		}
+		categoryID = &id
+	}
+	if row.Brand != nil {
+		id, err := l.brand(*row.Brand)
+		if err != nil {
+			return err
+		}
+		brandID = &id
+	}
+
+	existing, err := findImportMatch(tx, match, row)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		res.Action, res.ProductID, err = importCreate(tx, cfg, user, row, categoryID, brandID, barcodes)
+		return err
+	}
+
+	product := *existing
+	res.ProductID = product.ID
+	change := func(field, from, to string) {
+		res.Changes = append(res.Changes, imports.Change{Field: field, Old: from, New: to})
+	}
+	updates := map[string]interface{}{}
+	// nama hanya diganti bila baris tidak dicocokkan lewat nama
+	if (row.ID != nil || match == "sku") && row.Name != product.Name {
+		change("name", product.Name, row.Name)
+		updates["name"] = row.Name
+	}
+	if row.Unit != nil && *row.Unit != product.Unit {
+		change("unit", product.Unit, *row.Unit)
+		updates["unit"] = *row.Unit
+	}
+	if row.SKU != nil {
+		if sku := strings.TrimSpace(*row.SKU); sku != product.SKU {
+			if err := checkSKUFree(tx, sku, product.ID); err != nil {
+				return err
+			}
+			change("sku", product.SKU, sku)
+			updates["sku"] = sku
+		}
+	}
+	if categoryID != nil && *categoryID != product.CategoryID {
+		change("category", l.categoryPath[product.CategoryID], l.categoryPath[*categoryID])
+		updates["category_id"] = *categoryID
+	}
+	if brandID != nil && *brandID != product.BrandID {
+		change("brand", l.brandName[product.BrandID], l.brandName[*brandID])
+		updates["brand_id"] = *brandID
+	}
+	// HPP bergerak lewat penerimaan dan koreksi manual, bukan lewat impor
+	if row.Cost != nil && *row.Cost != product.Cost {
+		return badCheckout("cost cannot be changed by import (%s, file has %s); correct it on the product or ignore the cost column", product.Cost, *row.Cost)
+	}
+	prices := map[string]models.Money{}
+	for _, p := range []struct {
+		field, tier string
+		from        models.Money
+		to          *models.Money
+	}{
+		{"price", models.PriceTierDefault, product.Price, row.Price},
+		{"price_investor", models.PriceTierInvestor, product.PriceInvestor, row.PriceInvestor},
+		{"price_shosha", models.PriceTierShosha, product.PriceShosha, row.PriceShosha},
+	} {
+		if p.to != nil && *p.to > 0 && *p.to != p.from {
+			change(p.field, p.from.String(), p.to.String())
+			prices[p.tier] = *p.to
+		}
+	}
+	// stok produk lama tidak ditimpa: selisihnya menjadi penyesuaian koreksi
+	var stockDiff models.Qty
+	if stock != nil && row.Stock != nil {
+		onHand, err := costing.OnHand(tx, cfg.BranchID, product.ID)
+		if err != nil {
+			return err
+		}
+		if stockDiff = *row.Stock - onHand; stockDiff != 0 {
+			change("stock", onHand.String(), row.Stock.String())
+		}
+	}
+	barcodesChanged := false
+	if row.Barcodes != nil {
+		var current []string
+		if err := tx.Model(&models.ProductBarcode{}).Where("product_id = ? AND is_deleted = ?", product.ID, false).
+			Order("code").Pluck("code", &current).Error; err != nil {
+			return err
+		}
+		sorted := append([]string(nil), barcodes...)
+		sort.Strings(sorted)
+		if from, to := strings.Join(current, ", "), strings.Join(sorted, ", "); from != to {
+			change("barcodes", from, to)
+			barcodesChanged = true
+		}
+	}
+	if len(res.Changes) == 0 {
+		res.Action = imports.ActionUnchanged
+		return nil
+	}
+	res.Action = imports.ActionUpdate
+
+	if len(updates) > 0 {
+		updates["synced"] = false
+		if err := tx.Model(&models.Product{}).Where("id = ?", product.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	if err := pricing.SetPrices(tx, product, prices, pricing.Change{
+		BranchID: cfg.BranchID,
+		Source:   pricing.SourceImport,
+	}); err != nil {
+		return err
+	}
+	if barcodesChanged {
+		if err := replaceBarcodes(tx, product.ID, barcodes); err != nil {
+			return err
+		}
+	}
+	if stockDiff != 0 {
+		*stock = append(*stock, adjustmentLine{ProductID: product.ID, Qty: stockDiff, Note: fmt.Sprintf("import baris %d", row.Line)})
+	}
+	return nil
+}
+
+// importCreate inserts a row that matched no product, with the same rules as
+// CreateProduct.
+func importCreate(tx *gorm.DB, cfg config.AppConfig, user string, row imports.ProductRow, categoryID, brandID *string, barcodes []string) (string, string, error) {
+	if row.Unit == nil {
+		return "", "", badCheckout("unit is required for a new product")
+	}
+	val := func(m *models.Money) models.Money {
+		if m == nil {
+			return 0
+		}
+		return *m
+	}
+	p := models.Product{
+		ID:            uuid.NewString(),
+		Name:          row.Name,
+		Unit:          *row.Unit,
+		Price:         val(row.Price),
+		PriceInvestor: val(row.PriceInvestor),
+		PriceShosha:   val(row.PriceShosha),
+		Cost:          val(row.Cost),
+		Synced:        false,
+		BranchID:      cfg.BranchID,
+	}
+	if p.PriceInvestor <= 0 && p.PriceShosha <= 0 {
+		return "", "", badCheckout("at least one price (investor or shosha) must be greater than 0")
+	}
+	if p.Price <= 0 {
+		if p.PriceInvestor > 0 {
+			p.Price = p.PriceInvestor
+		} else {
+			p.Price = p.PriceShosha
+		}
+	}
+	if p.PriceInvestor <= 0 {
+		p.PriceInvestor = p.Price
+	}
+	if p.PriceShosha <= 0 {
+		p.PriceShosha = p.Price
+	}
+	if row.SKU != nil {
+		p.SKU = strings.TrimSpace(*row.SKU)
+	}
+	if categoryID != nil {
+		p.CategoryID = *categoryID
+	}
+	if brandID != nil {
+		p.BrandID = *brandID
+	}
+	if err := checkSKUFree(tx, p.SKU, p.ID); err != nil {
+		return "", "", err
+	}
+	if err := tx.Create(&p).Error; err != nil {
+		return "", "", err
+	}
+	if row.Stock != nil {
+		if err := costing.Receive(tx, cfg.CostMethod, cfg.BranchID, p.ID, *row.Stock, p.Cost, costing.Ref{Source: models.MovementOpening, DocID: p.ID, User: user}); err != nil {
+			return "", "", err
+		}
+	}
+	if err := replaceBarcodes(tx, p.ID, barcodes); err != nil {
+		return "", "", err
+	}
+	return imports.ActionCreate, p.ID, nil
+}
+
+// ImportProducts imports products from an uploaded .xlsx or .csv file
+// (multipart field "file"). Form fields:
+//   - mapping: JSON object field -> column header, e.g. {"name":"Nama Produk"};
+//     unmapped fields are matched by their usual headers
+//   - match: "sku" (default) or "name", which existing product a row updates;
+//     rows with an id column (a product export) always update that product
+//   - mode: "all_or_nothing" (default) or "skip_invalid"
+//   - ignore: comma separated fields to leave alone, e.g. "barcodes" when an
+//     exported file is only used to edit prices
+//   - dry_run: "true" validates and previews without saving
+//   - adjust_stock: "true" books the stock column of existing products as
+//     one correction stock adjustment (see CreateStockAdjustment), approved
+//     with manager_pin when its value is above the approval limit
+//
+// Without adjust_stock the stock column is only the opening stock of new
+// products. The cost column never changes an existing product's cost; such
+// rows are rejected (ignore "cost" to re-import an older export).
+//
+// The response lists every row with its action, the fields an update changes
+// (old and new value) or its errors, and names an Excel report that can be
+// fetched from /api/products/import/reports/:name.
+func ImportProducts(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		match := c.DefaultPostForm("match", "sku")
+		mode := c.DefaultPostForm("mode", importAllOrNothing)
+		dryRun := c.PostForm("dry_run") == "true" || c.PostForm("dry_run") == "1"
+		adjustStock := c.PostForm("adjust_stock") == "true" || c.PostForm("adjust_stock") == "1"
+		approved := managerApproved(cfg, c.PostForm("manager_pin"))
+		if c.PostForm("manager_pin") != "" && !approved {
+			c.JSON(http.StatusForbidden, gin.H{"error": "invalid manager pin"})
+			return
+		}
+		if match != "sku" && match != "name" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "match must be sku or name"})
+			return
+		}
+		if mode != importAllOrNothing && mode != importSkipInvalid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be all_or_nothing or skip_invalid"})
+			return
+		}
+		var mapping map[string]string
+		if m := c.PostForm("mapping"); m != "" {
+			if err := json.Unmarshal([]byte(m), &mapping); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "mapping must be a JSON object"})
+				return
+			}
+		}
+		var ignore []string
+		for _, f := range strings.Split(c.PostForm("ignore"), ",") {
+			if f = strings.TrimSpace(f); f == "" {
+				continue
+			}
+			if _, ok := imports.ProductFields[f]; !ok || f == "name" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "cannot ignore field " + f})
+				return
+			}
+			ignore = append(ignore, f)
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+		table, err := imports.ReadRows(header.Filename, file)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(table.Rows) < 2 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file has no data rows"})
+			return
+		}
+		cols, err := imports.MapColumns(table.Rows[0], mapping)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		for _, f := range ignore {
+			delete(cols, f)
+		}
+		parsed := imports.ParseProducts(table, cols)
+
+		lookups, err := loadImportLookups(db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		user := posUser(c, "")
+		results := make([]imports.RowResult, 0, len(parsed))
+		counts := map[string]int{}
+		var stockLines []adjustmentLine
+		var stock *[]adjustmentLine
+		if adjustStock {
+			stock = &stockLines
+		}
+		var adjustment *models.StockAdjustment
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for _, row := range parsed {
+				res := imports.RowResult{Line: row.Line, Name: row.Name}
+				if row.SKU != nil {
+					res.SKU = *row.SKU
+				}
+				// tiap baris dalam savepoint agar baris gagal tidak meninggalkan sisa
+				err := tx.Transaction(func(rtx *gorm.DB) error {
+					return importProductRow(rtx, cfg, lookups, match, user, row, stock, &res)
+				})
+				if err != nil {
+					var ce *checkoutError
+					if !errors.As(err, &ce) {
+						return err
+					}
+					res.Action, res.ProductID, res.Changes, res.Errors = imports.ActionError, "", nil, []string{ce.msg}
+				}
+				if dryRun && res.Action == imports.ActionCreate {
+					res.ProductID = "" // id sementara, ikut dibatalkan
+				}
+				counts[res.Action]++
+				results = append(results, res)
+			}
+			if mode == importAllOrNothing && counts[imports.ActionError] > 0 {
+				return errImportRollback
+			}
+			if len(stockLines) > 0 {
+				adjustment = &models.StockAdjustment{
+					ID:        uuid.NewString(),
+					BranchID:  cfg.BranchID,
+					Reason:    models.AdjustmentCorrection,
+					Status:    models.AdjustmentPending,
+					Note:      "import " + header.Filename,
+					CreatedBy: user,
+					Synced:    false,
+				}
+				if err := createAdjustment(tx, cfg, adjustment, stockLines, approved, time.Now()); err != nil {
+					return err
+				}
+			}
+			if dryRun {
+				return errImportRollback
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errImportRollback) {
+			respondCheckoutError(c, err)
+			return
+		}
+		committed := err == nil
+
+		report, err := imports.WriteReport(cfg.ExportDir, results)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		status := http.StatusOK
+		if !committed && !dryRun {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{
+			"dry_run":   dryRun,
+			"mode":      mode,
+			"match":     match,
+			"committed": committed,
+			"total":     len(results),
+			"created":   counts[imports.ActionCreate],
+			"updated":   counts[imports.ActionUpdate],
+			"unchanged": counts[imports.ActionUnchanged],
+			"failed":    counts[imports.ActionError],
+			"rows":      results,
+			"report":    report,
+			// nil tanpa adjust_stock; status pending menunggu persetujuan manajer
+			"stock_adjustment": adjustment,
+		})
+	}
+}
+
+// ImportReport downloads an import report written by ImportProducts.
+func ImportReport(cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := filepath.Base(c.Param("name"))
+		if !strings.HasPrefix(name, "import-report-") || filepath.Ext(name) != ".xlsx" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+			return
+		}
+		c.FileAttachment(filepath.Join(cfg.ExportDir, name), name)
+	}
+}
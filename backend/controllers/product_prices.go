@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/models"
+)
+
+// ProductPrices returns a product's current tier prices, the branches' own
+// prices, its price history (newest first) and the scheduled changes that
+// have not been applied yet.
+func ProductPrices(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var product models.Product
+		if err := db.First(&product, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+			productError(c, err)
+			return
+		}
+		var history []models.ProductPriceHistory
+		if err := db.Where("product_id = ? AND is_deleted = ?", product.ID, false).
+			Order("changed_at DESC").Find(&history).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		var branchPrices []models.BranchPrice
+		if err := db.Where("product_id = ? AND is_deleted = ?", product.ID, false).
+			Order("branch_id, tier").Find(&branchPrices).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		var scheduled []models.ScheduledPriceChange
+		if err := db.Where("product_id = ? AND applied_at IS NULL AND is_deleted = ?", product.ID, false).
+			Order("effective_at").Find(&scheduled).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"current": gin.H{
+				"price":          product.Price,
+				"price_investor": product.PriceInvestor,
+				"price_shosha":   product.PriceShosha,
+			},
+			"branch_prices": branchPrices,
+			"history":       history,
+			"scheduled":     scheduled,
+		})
+	}
+}
+
+// ScheduleProductPrice schedules a tier price change for a future time,
+// for one branch or (without branch_id) for all branches.
+func ScheduleProductPrice(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			BranchID    string       `json:"branch_id"`
+			Tier        string       `json:"tier"`
+			Price       models.Money `json:"price"`
+			EffectiveAt time.Time    `json:"effective_at"`
+			CreatedBy   string       `json:"created_by"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if payload.Tier == "default" {
+			payload.Tier = models.PriceTierDefault
+		}
+		if !models.ValidPriceTier(payload.Tier) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown price tier"})
+			return
+		}
+		if payload.Price <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "price must be greater than 0"})
+			return
+		}
+		if !payload.EffectiveAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "effective_at must be in the future"})
+			return
+		}
+
+		var product models.Product
+		if err := db.First(&product, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+			productError(c, err)
+			return
+		}
+		if payload.BranchID != "" && payload.BranchID != cfg.BranchID {
+			var n int64
+			if err := db.Model(&models.Branch{}).Where("id = ?", payload.BranchID).Count(&n).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if n == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "branch not found"})
+				return
+			}
+		}
+
+		change := models.ScheduledPriceChange{
+			ID:          uuid.NewString(),
+			ProductID:   product.ID,
+			BranchID:    payload.BranchID,
+			Tier:        payload.Tier,
+			NewPrice:    payload.Price,
+			EffectiveAt: payload.EffectiveAt,
+			CreatedBy:   strings.TrimSpace(payload.CreatedBy),
+			Synced:      false,
+		}
+		if err := db.Create(&change).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, change)
+	}
+}
+
+// CancelScheduledPrice tombstones a scheduled price change that has not been
+// applied yet.
+func CancelScheduledPrice(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var change models.ScheduledPriceChange
+		if err := db.First(&change, "id = ? AND product_id = ? AND is_deleted = ?", c.Param("scheduleId"), c.Param("id"), false).Error; err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, gin.H{"error": "scheduled price change not found"})
+			return
+		}
+		if change.AppliedAt != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "price change has already been applied"})
+			return
+		}
+		if err := db.Model(&change).Updates(map[string]interface{}{
+			"is_deleted": true,
+			"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+			"synced":     false,
+		}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "scheduled price change cancelled"})
+	}
+}
+
+func productError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
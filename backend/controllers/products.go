@@ -1,25 +1,85 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"shosha_mart_backend/catalog"
 	"shosha_mart_backend/config"
+	"shosha_mart_backend/costing"
+	"shosha_mart_backend/exports"
 	"shosha_mart_backend/models"
+	"shosha_mart_backend/pricing"
 )
 
-// ListProducts returns all products ordered by update time.
+// productSortColumns are the columns ListProducts can sort by.
+var productSortColumns = map[string]string{
+	"updated_at": "updated_at",
+	"name":       "name",
+	"stock":      "stock",
+	"price":      "price",
+}
+
+// ListProducts returns products with their barcodes ordered by update time.
+// ?q searches name and SKU (ranked by relevance unless ?sort is given),
+// ?category includes the sub-categories, ?brand filters by brand.
+// Paging and sorting work as in ListSales.
 func ListProducts(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		pageNo, size, paged, ok := pageQuery(c)
+		if !ok {
+			return
+		}
+		order, ok := sortQuery(c, productSortColumns, "updated_at")
+		if !ok {
+			return
+		}
+		q := db.Model(&models.Product{}).Where("products.is_deleted = ?", false)
+		if s := strings.TrimSpace(c.Query("q")); s != "" {
+			var ranked bool
+			q, ranked = catalog.SearchProducts(db, q, s)
+			if ranked && c.Query("sort") == "" {
+				order = "fts.fts_rank ASC"
+			}
+		}
+		if id := c.Query("category"); id != "" {
+			cats, err := catalog.LoadCategories(db)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			q = q.Where("products.category_id IN ?", cats.Subtree(id))
+		}
+		if id := c.Query("brand"); id != "" {
+			q = q.Where("products.brand_id = ?", id)
+		}
+		q = liveUnits(liveBarcodes(q))
+
 		var products []models.Product
-		if err := db.Where("is_deleted = ?", false).Order("updated_at desc").Find(&products).Error; err != nil {
+		if !paged {
+			if err := q.Order(order).Find(&products).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, products)
+			return
+		}
+		var total int64
+		if err := q.Count(&total).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, products)
+		if err := q.Order(order).Order("products.id").Limit(size).Offset((pageNo - 1) * size).Find(&products).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, newPage(products, total, pageNo, size))
 	}
 }
 
@@ -27,17 +87,29 @@ func ListProducts(db *gorm.DB) gin.HandlerFunc {
 func CreateProduct(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var payload struct {
-			Name          string  `json:"name" binding:"required"`
-			Unit          string  `json:"unit" binding:"required"`
-			Stock         int     `json:"stock"`
-			Price         float64 `json:"price"` // Optional, will be set from investor/shosha
-			PriceInvestor float64 `json:"price_investor"`
-			PriceShosha   float64 `json:"price_shosha"`
+			SKU           string            `json:"sku"`
+			Barcodes      []string          `json:"barcodes"`
+			Name          string            `json:"name" binding:"required"`
+			Unit          string            `json:"unit" binding:"required"`
+			CategoryID    string            `json:"category_id"`
+			BrandID       string            `json:"brand_id"`
+			Attributes    models.Attributes `json:"attributes"`
+			Units         []unitInput       `json:"units"`
+			Stock         models.Qty        `json:"stock"`
+			Price         models.Money      `json:"price"` // Optional, will be set from investor/shosha
+			PriceInvestor models.Money      `json:"price_investor"`
+			PriceShosha   models.Money      `json:"price_shosha"`
+			Cost          models.Money      `json:"cost"` // HPP per satuan dasar untuk stok awal
+			TaxRateID     string            `json:"tax_rate_id"`
 		}
 		if err := c.ShouldBindJSON(&payload); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if payload.Cost < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cost must be >= 0"})
+			return
+		}
 
 		// Validasi: minimal salah satu harga harus > 0
 		if payload.PriceInvestor <= 0 && payload.PriceShosha <= 0 {
@@ -55,14 +127,30 @@ func CreateProduct(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 			}
 		}
 
+		barcodes, err := prepareBarcodes(payload.Barcodes)
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		units, err := prepareUnits(payload.Unit, payload.Units)
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+
 		product := models.Product{
 			ID:            uuid.NewString(),
+			SKU:           strings.TrimSpace(payload.SKU),
 			Name:          payload.Name,
 			Unit:          payload.Unit,
-			Stock:         payload.Stock,
+			CategoryID:    payload.CategoryID,
+			BrandID:       payload.BrandID,
+			Attributes:    payload.Attributes,
 			Price:         price,
 			PriceInvestor: payload.PriceInvestor,
 			PriceShosha:   payload.PriceShosha,
+			Cost:          payload.Cost,
+			TaxRateID:     payload.TaxRateID,
 			Synced:        false,
 			BranchID:      cfg.BranchID,
 		}
@@ -73,7 +161,30 @@ func CreateProduct(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 		if product.PriceShosha <= 0 {
 			product.PriceShosha = product.Price
 		}
-		if err := db.Create(&product).Error; err != nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := checkSKUFree(tx, product.SKU, product.ID); err != nil {
+				return err
+			}
+			if err := checkProductGroups(tx, product.CategoryID, product.BrandID); err != nil {
+				return err
+			}
+			if err := tx.Create(&product).Error; err != nil {
+				return err
+			}
+			// stok awal masuk sebagai lapisan HPP pertama
+			if err := costing.Receive(tx, cfg.CostMethod, cfg.BranchID, product.ID, payload.Stock, payload.Cost, costing.Ref{Source: models.MovementOpening, DocID: product.ID, User: posUser(c, "")}); err != nil {
+				return err
+			}
+			if err := replaceUnits(tx, product.ID, units); err != nil {
+				return err
+			}
+			return replaceBarcodes(tx, product.ID, barcodes)
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		if err := liveUnits(liveBarcodes(db)).First(&product, "id = ?", product.ID).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -86,12 +197,21 @@ func UpdateProduct(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 		var payload struct {
-			Name          string  `json:"name"`
-			Unit          string  `json:"unit"`
-			Stock         *int    `json:"stock"` // pointer untuk detect null
-			Price         float64 `json:"price"`
-			PriceInvestor float64 `json:"price_investor"`
-			PriceShosha   float64 `json:"price_shosha"`
+			SKU           *string            `json:"sku"`
+			Barcodes      *[]string          `json:"barcodes"` // jika dikirim, menggantikan semua barcode
+			Name          string             `json:"name"`
+			Unit          string             `json:"unit"`
+			CategoryID    *string            `json:"category_id"` // "" untuk melepas kategori
+			BrandID       *string            `json:"brand_id"`
+			Attributes    *models.Attributes `json:"attributes"` // menggantikan semua atribut
+			Units         *[]unitInput       `json:"units"`      // jika dikirim, menggantikan semua satuan tambahan
+			Stock         *models.Qty        `json:"stock"`      // hanya boleh sama dengan stok sekarang; ubah lewat penyesuaian stok
+			Price         models.Money       `json:"price"`
+			PriceInvestor models.Money       `json:"price_investor"`
+			PriceShosha   models.Money       `json:"price_shosha"`
+			Cost          *models.Money      `json:"cost"`        // koreksi HPP manual
+			TaxRateID     *string            `json:"tax_rate_id"` // "" untuk kembali ke tarif default
+			ChangedBy     string             `json:"changed_by"`  // dicatat di riwayat harga
 		}
 		if err := c.ShouldBindJSON(&payload); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
@@ -105,6 +225,12 @@ func UpdateProduct(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 			return
 		}
 
+		// stok hanya berubah lewat dokumen (penyesuaian, opname, penerimaan, ...)
+		if payload.Stock != nil && *payload.Stock != product.Stock {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stock cannot be changed here; create a stock adjustment"})
+			return
+		}
+
 		// Build updates map hanya untuk field yang dikirim
 		updates := map[string]interface{}{
 			"synced": false, // Always mark as unsynced
@@ -116,25 +242,109 @@ func UpdateProduct(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 		if payload.Unit != "" {
 			updates["unit"] = payload.Unit
 		}
-		if payload.Stock != nil {
-			updates["stock"] = *payload.Stock
+		if payload.Cost != nil {
+			if *payload.Cost < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "cost must be >= 0"})
+				return
+			}
+			updates["cost"] = *payload.Cost
 		}
+		// harga ditulis lewat pricing.SetPrices agar tercatat di riwayat
+		prices := map[string]models.Money{}
 		if payload.Price > 0 {
-			updates["price"] = payload.Price
+			prices[models.PriceTierDefault] = payload.Price
 		}
 		if payload.PriceInvestor > 0 {
-			updates["price_investor"] = payload.PriceInvestor
+			prices[models.PriceTierInvestor] = payload.PriceInvestor
 		}
 		if payload.PriceShosha > 0 {
-			updates["price_shosha"] = payload.PriceShosha
+			prices[models.PriceTierShosha] = payload.PriceShosha
+		}
+		if payload.TaxRateID != nil {
+			updates["tax_rate_id"] = *payload.TaxRateID
+		}
+		if payload.SKU != nil {
+			updates["sku"] = strings.TrimSpace(*payload.SKU)
+		}
+		if payload.CategoryID != nil {
+			updates["category_id"] = *payload.CategoryID
+		}
+		if payload.BrandID != nil {
+			updates["brand_id"] = *payload.BrandID
+		}
+		if payload.Attributes != nil {
+			updates["attributes"] = *payload.Attributes
+		}
+		var barcodes []string
+		if payload.Barcodes != nil {
+			var err error
+			if barcodes, err = prepareBarcodes(*payload.Barcodes); err != nil {
+				respondCheckoutError(c, err)
+				return
+			}
+		}
+		var units []unitInput
+		if payload.Units != nil {
+			base := product.Unit
+			if payload.Unit != "" {
+				base = payload.Unit
+			}
+			var err error
+			if units, err = prepareUnits(base, *payload.Units); err != nil {
+				respondCheckoutError(c, err)
+				return
+			}
 		}
 
-		if err := db.Model(&product).Updates(updates).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if payload.SKU != nil {
+				if err := checkSKUFree(tx, strings.TrimSpace(*payload.SKU), product.ID); err != nil {
+					return err
+				}
+			}
+			var categoryID, brandID string
+			if payload.CategoryID != nil {
+				categoryID = *payload.CategoryID
+			}
+			if payload.BrandID != nil {
+				brandID = *payload.BrandID
+			}
+			if err := checkProductGroups(tx, categoryID, brandID); err != nil {
+				return err
+			}
+			before := product
+			if err := tx.Model(&product).Updates(updates).Error; err != nil {
+				return err
+			}
+			// koreksi HPP berlaku untuk cabang ini; cabang lain memakai HPP-nya sendiri
+			if payload.Cost != nil {
+				if err := costing.SetCost(tx, cfg.BranchID, product.ID, *payload.Cost); err != nil {
+					return err
+				}
+			}
+			if err := pricing.SetPrices(tx, before, prices, pricing.Change{
+				BranchID: cfg.BranchID,
+				By:       payload.ChangedBy,
+				Source:   pricing.SourceManual,
+			}); err != nil {
+				return err
+			}
+			if payload.Units != nil {
+				if err := replaceUnits(tx, product.ID, units); err != nil {
+					return err
+				}
+			}
+			if payload.Barcodes != nil {
+				return replaceBarcodes(tx, product.ID, barcodes)
+			}
+			return nil
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
 			return
 		}
 		// Reload to return fresh values
-		if err := db.First(&product, "id = ?", id).Error; err != nil {
+		if err := liveUnits(liveBarcodes(db)).First(&product, "id = ?", id).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -160,7 +370,17 @@ func DeleteProduct(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 			"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
 			"synced":     false,
 		}
-		if err := db.Model(&product).Updates(updates).Error; err != nil {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&product).Updates(updates).Error; err != nil {
+				return err
+			}
+			if err := replaceUnits(tx, product.ID, nil); err != nil {
+				return err
+			}
+			// barcode dilepas agar bisa dipakai produk lain
+			return replaceBarcodes(tx, product.ID, nil)
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -168,15 +388,24 @@ func DeleteProduct(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 	}
 }
 
-// BulkCreateProducts inserts multiple products in one request.
+// BulkCreateProducts inserts multiple products in one request, all or none.
+// Use ImportProducts to update existing products from a spreadsheet.
 func BulkCreateProducts(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 	type Row struct {
-		Name          string  `json:"name" binding:"required"`
-		Unit          string  `json:"unit" binding:"required"`
-		Stock         int     `json:"stock"`
-		Price         float64 `json:"price"`
-		PriceInvestor float64 `json:"price_investor"`
-		PriceShosha   float64 `json:"price_shosha"`
+		SKU           string            `json:"sku"`
+		Barcodes      []string          `json:"barcodes"`
+		Name          string            `json:"name" binding:"required"`
+		Unit          string            `json:"unit" binding:"required"`
+		CategoryID    string            `json:"category_id"`
+		BrandID       string            `json:"brand_id"`
+		Attributes    models.Attributes `json:"attributes"`
+		Units         []unitInput       `json:"units"`
+		Stock         models.Qty        `json:"stock"`
+		Price         models.Money      `json:"price"`
+		PriceInvestor models.Money      `json:"price_investor"`
+		PriceShosha   models.Money      `json:"price_shosha"`
+		Cost          models.Money      `json:"cost"`
+		TaxRateID     string            `json:"tax_rate_id"`
 	}
 	return func(c *gin.Context) {
 		var rows []Row
@@ -184,50 +413,124 @@ func BulkCreateProducts(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		// satu transaksi: baris yang gagal membatalkan seluruh batch
 		created := make([]models.Product, 0, len(rows))
-		for _, r := range rows {
-			// Validasi: minimal salah satu harga > 0
-			if r.PriceInvestor <= 0 && r.PriceShosha <= 0 {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": "at least one price (investor or shosha) must be greater than 0 for product: " + r.Name,
-				})
-				return
-			}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, r := range rows {
+				// Validasi: minimal salah satu harga > 0
+				if r.PriceInvestor <= 0 && r.PriceShosha <= 0 {
+					return badCheckout("at least one price (investor or shosha) must be greater than 0 for product: %s", r.Name)
+				}
+				if r.Cost < 0 {
+					return badCheckout("cost must be >= 0 for product: %s", r.Name)
+				}
 
-			// Set price dari investor atau shosha jika tidak diisi
-			price := r.Price
-			if price <= 0 {
-				if r.PriceInvestor > 0 {
-					price = r.PriceInvestor
-				} else {
-					price = r.PriceShosha
+				// Set price dari investor atau shosha jika tidak diisi
+				price := r.Price
+				if price <= 0 {
+					if r.PriceInvestor > 0 {
+						price = r.PriceInvestor
+					} else {
+						price = r.PriceShosha
+					}
 				}
-			}
 
-			p := models.Product{
-				ID:            uuid.NewString(),
-				Name:          r.Name,
-				Unit:          r.Unit,
-				Stock:         r.Stock,
-				Price:         price,
-				PriceInvestor: r.PriceInvestor,
-				PriceShosha:   r.PriceShosha,
-				Synced:        false,
-				BranchID:      cfg.BranchID,
-			}
-			// Default values jika salah satu kosong
-			if p.PriceInvestor <= 0 {
-				p.PriceInvestor = p.Price
-			}
-			if p.PriceShosha <= 0 {
-				p.PriceShosha = p.Price
+				barcodes, err := prepareBarcodes(r.Barcodes)
+				if err != nil {
+					return err
+				}
+				units, err := prepareUnits(r.Unit, r.Units)
+				if err != nil {
+					return err
+				}
+
+				p := models.Product{
+					ID:            uuid.NewString(),
+					SKU:           strings.TrimSpace(r.SKU),
+					Name:          r.Name,
+					Unit:          r.Unit,
+					CategoryID:    r.CategoryID,
+					BrandID:       r.BrandID,
+					Attributes:    r.Attributes,
+					Price:         price,
+					PriceInvestor: r.PriceInvestor,
+					PriceShosha:   r.PriceShosha,
+					Cost:          r.Cost,
+					TaxRateID:     r.TaxRateID,
+					Synced:        false,
+					BranchID:      cfg.BranchID,
+				}
+				// Default values jika salah satu kosong
+				if p.PriceInvestor <= 0 {
+					p.PriceInvestor = p.Price
+				}
+				if p.PriceShosha <= 0 {
+					p.PriceShosha = p.Price
+				}
+				if err := checkSKUFree(tx, p.SKU, p.ID); err != nil {
+					return err
+				}
+				if err := checkProductGroups(tx, p.CategoryID, p.BrandID); err != nil {
+					return err
+				}
+				if err := tx.Create(&p).Error; err != nil {
+					return err
+				}
+				if err := costing.Receive(tx, cfg.CostMethod, cfg.BranchID, p.ID, r.Stock, r.Cost, costing.Ref{Source: models.MovementOpening, DocID: p.ID, User: posUser(c, "")}); err != nil {
+					return err
+				}
+				p.Stock = r.Stock
+				if err := replaceUnits(tx, p.ID, units); err != nil {
+					return err
+				}
+				if err := replaceBarcodes(tx, p.ID, barcodes); err != nil {
+					return err
+				}
+				created = append(created, p)
 			}
-			if err := db.Create(&p).Error; err != nil {
+			return nil
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"count": len(created), "items": created})
+	}
+}
+
+// ExportProducts downloads all products as .xlsx (default) or, with
+// ?format=csv, as CSV. The file can be edited and imported back through
+// ImportProducts to update products in bulk.
+func ExportProducts(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", "xlsx")
+		if format != "xlsx" && format != "csv" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be xlsx or csv"})
+			return
+		}
+		lines, err := exports.ProductCatalogue(db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		filename := fmt.Sprintf("products-%s.%s", time.Now().Format("20060102-150405"), format)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+		if format == "csv" {
+			c.Header("Content-Type", "text/csv; charset=utf-8")
+			if err := exports.WriteProductsCSV(c.Writer, lines); err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
 			}
-			created = append(created, p)
+			return
+		}
+		f, err := exports.ProductsXLSX(lines)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer f.Close()
+		c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		if err := f.Write(c.Writer); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
-		c.JSON(http.StatusCreated, gin.H{"count": len(created), "items": created})
 	}
 }
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/models"
+	"shosha_mart_backend/pricing"
+)
+
+type promotionPayload struct {
+	Name          string       `json:"name"`
+	Type          string       `json:"type"`
+	ProductID     string       `json:"product_id"`
+	BranchID      string       `json:"branch_id"`
+	BuyQty        int          `json:"buy_qty"`
+	FreeQty       int          `json:"free_qty"`
+	BundleQty     int          `json:"bundle_qty"`
+	BundlePrice   models.Money `json:"bundle_price"`
+	DiscountType  string       `json:"discount_type"`
+	DiscountValue float64      `json:"discount_value"`
+	MinSpend      models.Money `json:"min_spend"`
+	StartsAt      *time.Time   `json:"starts_at"`
+	EndsAt        *time.Time   `json:"ends_at"`
+	DailyStart    string       `json:"daily_start"`
+	DailyEnd      string       `json:"daily_end"`
+	Active        *bool        `json:"active"`
+}
+
+// apply copies the payload onto p, keeping p.Active when the payload omits it.
+func (payload promotionPayload) apply(p *models.Promotion) {
+	p.Name = payload.Name
+	p.Type = payload.Type
+	p.ProductID = payload.ProductID
+	p.BranchID = payload.BranchID
+	p.BuyQty = payload.BuyQty
+	p.FreeQty = payload.FreeQty
+	p.BundleQty = payload.BundleQty
+	p.BundlePrice = payload.BundlePrice
+	p.DiscountType = payload.DiscountType
+	p.DiscountValue = payload.DiscountValue
+	p.MinSpend = payload.MinSpend
+	p.StartsAt = payload.StartsAt
+	p.EndsAt = payload.EndsAt
+	p.DailyStart = payload.DailyStart
+	p.DailyEnd = payload.DailyEnd
+	if payload.Active != nil {
+		p.Active = *payload.Active
+	}
+}
+
+func validatePromotion(p models.Promotion) error {
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+	switch p.Type {
+	case models.PromoBuyXGetY:
+		if p.ProductID == "" || p.BuyQty <= 0 || p.FreeQty <= 0 {
+			return errors.New("buy_x_get_y needs product_id, buy_qty and free_qty")
+		}
+	case models.PromoBundle:
+		if p.ProductID == "" || p.BundleQty <= 1 || p.BundlePrice <= 0 {
+			return errors.New("bundle needs product_id, bundle_qty > 1 and bundle_price")
+		}
+	case models.PromoTimeWindow:
+		if p.DiscountType == "" || p.DiscountValue <= 0 {
+			return errors.New("time_window needs discount_type and discount_value")
+		}
+		if p.ProductID == "" && p.DiscountType == pricing.DiscountAmount {
+			return errors.New("time_window amount discount needs product_id")
+		}
+	case models.PromoMinSpend:
+		if p.MinSpend <= 0 || p.DiscountType == "" || p.DiscountValue <= 0 {
+			return errors.New("min_spend needs min_spend, discount_type and discount_value")
+		}
+	default:
+		return errors.New("type must be one of buy_x_get_y, bundle, time_window, min_spend")
+	}
+	if !pricing.ValidDiscountType(p.DiscountType) {
+		return errors.New("discount_type must be 'percent' or 'amount'")
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && p.EndsAt.Before(*p.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	if (p.DailyStart == "") != (p.DailyEnd == "") {
+		return errors.New("daily_start and daily_end must be set together")
+	}
+	for _, clock := range []string{p.DailyStart, p.DailyEnd} {
+		if clock == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", clock); err != nil {
+			return errors.New("daily_start/daily_end must use HH:MM")
+		}
+	}
+	return nil
+}
+
+// ListPromotions returns promotions, optionally only the active ones (?active=true).
+func ListPromotions(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := db.Where("is_deleted = ?", false)
+		if c.Query("active") == "true" {
+			query = query.Where("active = ?", true)
+		}
+		var promos []models.Promotion
+		if err := query.Order("updated_at desc").Find(&promos).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, promos)
+	}
+}
+
+// CreatePromotion stores a new promotion rule flagged as unsynced.
+func CreatePromotion(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload promotionPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		promo := models.Promotion{
+			ID:     uuid.NewString(),
+			Active: true,
+			Synced: false,
+		}
+		payload.apply(&promo)
+		if err := validatePromotion(promo); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Create(&promo).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, promo)
+	}
+}
+
+// UpdatePromotion replaces the rule of an existing promotion.
+func UpdatePromotion(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var payload promotionPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		var promo models.Promotion
+		if err := db.First(&promo, "id = ? AND is_deleted = ?", id, false).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "promotion not found"})
+			return
+		}
+
+		payload.apply(&promo)
+		if err := validatePromotion(promo); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		promo.Synced = false // Mark as unsynced when updated
+		if err := db.Save(&promo).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, promo)
+	}
+}
+
+// DeletePromotion tombstones a promotion.
+func DeletePromotion(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		var promo models.Promotion
+		if err := db.First(&promo, "id = ?", id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "promotion not found"})
+			return
+		}
+
+		updates := map[string]interface{}{
+			"is_deleted": true,
+			"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+			"synced":     false,
+		}
+		if err := db.Model(&promo).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "promotion deleted"})
+	}
+}
@@ -0,0 +1,418 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/models"
+	"shosha_mart_backend/reports"
+)
+
+type purchaseOrderItemInput struct {
+	ProductID string       `json:"product_id"`
+	Unit      string       `json:"unit"` // kosong = satuan dasar
+	Qty       models.Qty   `json:"qty"`
+	UnitCost  models.Money `json:"unit_cost"` // 0 = HPP produk saat ini
+}
+
+type purchaseOrderPayload struct {
+	SupplierID string                   `json:"supplier_id"`
+	BranchID   string                   `json:"branch_id"`
+	ExpectedAt *time.Time               `json:"expected_at"`
+	Note       string                   `json:"note"`
+	CreatedBy  string                   `json:"created_by"`
+	Items      []purchaseOrderItemInput `json:"items"`
+}
+
+// buildPurchaseOrderItems validates the ordered lines and prices them.
+func buildPurchaseOrderItems(tx *gorm.DB, orderID string, inputs []purchaseOrderItemInput) ([]models.PurchaseOrderItem, models.Money, error) {
+	items := make([]models.PurchaseOrderItem, 0, len(inputs))
+	seen := map[string]bool{}
+	var total models.Money
+	for _, in := range inputs {
+		if in.Qty <= 0 {
+			return nil, 0, badCheckout("qty must be greater than 0")
+		}
+		if in.UnitCost < 0 {
+			return nil, 0, badCheckout("unit_cost must be >= 0")
+		}
+		var product models.Product
+		if err := tx.First(&product, "id = ? AND is_deleted = ?", in.ProductID, false).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, 0, badCheckout("product %s not found", in.ProductID)
+			}
+			return nil, 0, err
+		}
+		unit, err := findUnit(tx, product, in.Unit)
+		if err != nil {
+			return nil, 0, err
+		}
+		key := product.ID + "|" + strings.ToLower(unit.Name)
+		if seen[key] {
+			return nil, 0, badCheckout("%s is ordered more than once in the same unit", product.Name)
+		}
+		seen[key] = true
+		cost := in.UnitCost
+		if cost == 0 {
+			cost = product.Cost.TimesQty(unit.Factor)
+		}
+		item := models.PurchaseOrderItem{
+			ID:              uuid.NewString(),
+			PurchaseOrderID: orderID,
+			ProductID:       product.ID,
+			Unit:            unit.Name,
+			UnitFactor:      unit.Factor,
+			Qty:             in.Qty,
+			UnitCost:        cost,
+			Subtotal:        cost.TimesQty(in.Qty),
+			Synced:          false,
+		}
+		total += item.Subtotal
+		items = append(items, item)
+	}
+	return items, total, nil
+}
+
+// nextDocumentNumber numbers the documents stored in model per kind, branch
+// and day, e.g. PO-JKT-261018-001. A branch without a code is named by its
+// id, so two branches never count the same numbers.
+func nextDocumentNumber(tx *gorm.DB, model interface{}, kind, branchID string, at time.Time) (string, error) {
+	var branch models.Branch
+	if err := tx.Select("code").Limit(1).Find(&branch, "id = ?", branchID).Error; err != nil {
+		return "", err
+	}
+	name := branchID
+	if branch.Code != "" {
+		name = strings.ToUpper(branch.Code)
+	}
+	prefix := kind + "-" + name + "-" + at.Format("060102") + "-"
+	var n int64
+	if err := tx.Model(model).Where("number LIKE ?", prefix+"%").Count(&n).Error; err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%03d", prefix, n+1), nil
+}
+
+// checkPurchaseOrderRefs verifies the supplier and branch of an order.
+func checkPurchaseOrderRefs(tx *gorm.DB, cfg config.AppConfig, supplierID, branchID string) error {
+	var n int64
+	if err := tx.Model(&models.Supplier{}).Where("id = ? AND is_deleted = ?", supplierID, false).Count(&n).Error; err != nil {
+		return err
+	}
+	if n == 0 {
+		return badCheckout("supplier not found")
+	}
+	return checkBranch(tx, cfg, branchID)
+}
+
+// checkBranch verifies that branchID is this branch or a live branch.
+func checkBranch(tx *gorm.DB, cfg config.AppConfig, branchID string) error {
+	if branchID == cfg.BranchID {
+		return nil
+	}
+	var n int64
+	if err := tx.Model(&models.Branch{}).Where("id = ? AND is_deleted = ?", branchID, false).Count(&n).Error; err != nil {
+		return err
+	}
+	if n == 0 {
+		return badCheckout("branch %s not found", branchID)
+	}
+	return nil
+}
+
+func loadPurchaseOrder(db *gorm.DB, id string) (models.PurchaseOrder, error) {
+	var po models.PurchaseOrder
+	err := db.Preload("Items", func(q *gorm.DB) *gorm.DB {
+		return q.Where("is_deleted = ?", false).Order("created_at, id")
+	}).First(&po, "id = ? AND is_deleted = ?", id, false).Error
+	return po, err
+}
+
+func purchaseOrderNotFound(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "purchase order not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// ListPurchaseOrders returns purchase orders newest first. Filters: status,
+// supplier_id, branch_id and start/end (YYYY-MM-DD, on the order date).
+func ListPurchaseOrders(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := db.Where("is_deleted = ?", false)
+		if s := c.Query("status"); s != "" {
+			q = q.Where("status = ?", s)
+		}
+		if s := c.Query("supplier_id"); s != "" {
+			q = q.Where("supplier_id = ?", s)
+		}
+		if s := c.Query("branch_id"); s != "" {
+			q = q.Where("branch_id = ?", s)
+		}
+		if c.Query("start") != "" || c.Query("end") != "" {
+			start, end, ok := dateRangeQuery(c)
+			if !ok {
+				return
+			}
+			q = q.Where("order_date BETWEEN ? AND ?", start, end)
+		}
+		var orders []models.PurchaseOrder
+		if err := q.Preload("Items", "is_deleted = ?", false).Order("order_date DESC, number DESC").Find(&orders).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, orders)
+	}
+}
+
+func GetPurchaseOrder(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		po, err := loadPurchaseOrder(db, c.Param("id"))
+		if err != nil {
+			purchaseOrderNotFound(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, po)
+	}
+}
+
+// CreatePurchaseOrder creates a draft order. Items without unit_cost are
+// priced at the product's current cost.
+func CreatePurchaseOrder(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload purchaseOrderPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		now := time.Now()
+		po := models.PurchaseOrder{
+			ID:         uuid.NewString(),
+			SupplierID: payload.SupplierID,
+			BranchID:   chooseBranch(payload.BranchID, cfg.BranchID),
+			Status:     models.PurchaseOrderDraft,
+			OrderDate:  now,
+			ExpectedAt: payload.ExpectedAt,
+			Note:       payload.Note,
+			CreatedBy:  strings.TrimSpace(payload.CreatedBy),
+			Synced:     false,
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return insertPurchaseOrder(tx, cfg, &po, payload.Items)
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, po)
+	}
+}
+
+// insertPurchaseOrder numbers and stores a new draft order with its items.
+func insertPurchaseOrder(tx *gorm.DB, cfg config.AppConfig, po *models.PurchaseOrder, inputs []purchaseOrderItemInput) error {
+	if err := checkPurchaseOrderRefs(tx, cfg, po.SupplierID, po.BranchID); err != nil {
+		return err
+	}
+	items, total, err := buildPurchaseOrderItems(tx, po.ID, inputs)
+	if err != nil {
+		return err
+	}
+	if po.Number, err = nextDocumentNumber(tx, &models.PurchaseOrder{}, "PO", po.BranchID, po.OrderDate); err != nil {
+		return err
+	}
+	po.Total = total
+	if err := tx.Create(po).Error; err != nil {
+		return err
+	}
+	if len(items) > 0 {
+		if err := tx.Create(&items).Error; err != nil {
+			return err
+		}
+	}
+	po.Items = items
+	return nil
+}
+
+// UpdatePurchaseOrder replaces the details and items of a draft order.
+func UpdatePurchaseOrder(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload purchaseOrderPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		po, err := loadPurchaseOrder(db, c.Param("id"))
+		if err != nil {
+			purchaseOrderNotFound(c, err)
+			return
+		}
+		if po.Status != models.PurchaseOrderDraft {
+			c.JSON(http.StatusConflict, gin.H{"error": "only draft purchase orders can be edited"})
+			return
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			po.SupplierID = payload.SupplierID
+			po.BranchID = chooseBranch(payload.BranchID, po.BranchID)
+			if err := checkPurchaseOrderRefs(tx, cfg, po.SupplierID, po.BranchID); err != nil {
+				return err
+			}
+			items, total, err := buildPurchaseOrderItems(tx, po.ID, payload.Items)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&models.PurchaseOrderItem{}).Where("purchase_order_id = ? AND is_deleted = ?", po.ID, false).
+				Updates(map[string]interface{}{
+					"is_deleted": true,
+					"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+					"synced":     false,
+				}).Error; err != nil {
+				return err
+			}
+			if len(items) > 0 {
+				if err := tx.Create(&items).Error; err != nil {
+					return err
+				}
+			}
+			po.ExpectedAt = payload.ExpectedAt
+			po.Note = payload.Note
+			if s := strings.TrimSpace(payload.CreatedBy); s != "" {
+				po.CreatedBy = s
+			}
+			po.Total = total
+			po.Synced = false
+			po.Items = nil
+			if err := tx.Save(&po).Error; err != nil {
+				return err
+			}
+			po.Items = items
+			return nil
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, po)
+	}
+}
+
+// DeletePurchaseOrder tombstones a draft order with its items.
+func DeletePurchaseOrder(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		po, err := loadPurchaseOrder(db, c.Param("id"))
+		if err != nil {
+			purchaseOrderNotFound(c, err)
+			return
+		}
+		if po.Status != models.PurchaseOrderDraft {
+			c.JSON(http.StatusConflict, gin.H{"error": "only draft purchase orders can be deleted; close it instead"})
+			return
+		}
+		updates := map[string]interface{}{
+			"is_deleted": true,
+			"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+			"synced":     false,
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.PurchaseOrderItem{}).Where("purchase_order_id = ?", po.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+			return tx.Model(&models.PurchaseOrder{}).Where("id = ?", po.ID).Updates(updates).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "purchase order deleted"})
+	}
+}
+
+// SendPurchaseOrder marks a draft order as sent to the supplier; from then
+// on it can only be received or closed.
+func SendPurchaseOrder(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		po, err := loadPurchaseOrder(db, c.Param("id"))
+		if err != nil {
+			purchaseOrderNotFound(c, err)
+			return
+		}
+		if po.Status != models.PurchaseOrderDraft {
+			c.JSON(http.StatusConflict, gin.H{"error": "purchase order has already been sent"})
+			return
+		}
+		if len(po.Items) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "purchase order has no items"})
+			return
+		}
+		now := time.Now()
+		if err := db.Model(&po).Updates(map[string]interface{}{
+			"status":  models.PurchaseOrderSent,
+			"sent_at": now,
+			"synced":  false,
+		}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		po.Status, po.SentAt = models.PurchaseOrderSent, &now
+		c.JSON(http.StatusOK, po)
+	}
+}
+
+// ClosePurchaseOrder closes a sent or partially received order by hand,
+// e.g. when the supplier cannot deliver the rest.
+func ClosePurchaseOrder(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		po, err := loadPurchaseOrder(db, c.Param("id"))
+		if err != nil {
+			purchaseOrderNotFound(c, err)
+			return
+		}
+		if po.Status != models.PurchaseOrderSent && po.Status != models.PurchaseOrderPartial {
+			c.JSON(http.StatusConflict, gin.H{"error": "only sent or partially received purchase orders can be closed"})
+			return
+		}
+		now := time.Now()
+		if err := db.Model(&po).Updates(map[string]interface{}{
+			"status":    models.PurchaseOrderClosed,
+			"closed_at": now,
+			"synced":    false,
+		}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		po.Status, po.ClosedAt = models.PurchaseOrderClosed, &now
+		c.JSON(http.StatusOK, po)
+	}
+}
+
+// PurchaseOrderDocument downloads the order as a printable Excel document
+// to send to the supplier.
+func PurchaseOrderDocument(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		po, err := loadPurchaseOrder(db, c.Param("id"))
+		if err != nil {
+			purchaseOrderNotFound(c, err)
+			return
+		}
+		doc, err := reports.BuildPurchaseOrderDocument(db, po)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		path, err := reports.GeneratePurchaseOrderDocument(cfg, doc)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.FileAttachment(path, filepath.Base(path))
+	}
+}
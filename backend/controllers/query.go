@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Paging limits for list endpoints.
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// page is a page of list results.
+type page struct {
+	Items interface{} `json:"items"`
+	Total int64       `json:"total"`
+	Page  int         `json:"page"`
+	Size  int         `json:"size"`
+	Pages int         `json:"pages"`
+}
+
+func newPage(items interface{}, total int64, pageNo, size int) page {
+	return page{Items: items, Total: total, Page: pageNo, Size: size, Pages: int((total + int64(size) - 1) / int64(size))}
+}
+
+// pageQuery reads ?page (1-based) and ?size. paged is false when neither is
+// given, in which case list endpoints keep returning a plain array for older
+// clients. It writes the 400 itself and returns ok=false on bad input.
+func pageQuery(c *gin.Context) (pageNo, size int, paged, ok bool) {
+	pageNo, size = 1, defaultPageSize
+	if s := c.Query("page"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive integer"})
+			return 0, 0, false, false
+		}
+		pageNo, paged = n, true
+	}
+	if s := c.Query("size"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "size must be between 1 and " + strconv.Itoa(maxPageSize)})
+			return 0, 0, false, false
+		}
+		size, paged = n, true
+	}
+	return pageNo, size, paged, true
+}
+
+// sortQuery resolves ?sort and ?order against the columns a list allows.
+func sortQuery(c *gin.Context, allowed map[string]string, fallback string) (string, bool) {
+	column := allowed[fallback]
+	if s := c.Query("sort"); s != "" {
+		col, found := allowed[s]
+		if !found {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported sort: " + s})
+			return "", false
+		}
+		column = col
+	}
+	switch c.DefaultQuery("order", "desc") {
+	case "asc":
+		return column + " ASC", true
+	case "desc":
+		return column + " DESC", true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "order must be 'asc' or 'desc'"})
+	return "", false
+}
+
+// dateRangeQuery reads the optional start/end (YYYY-MM-DD) query; the default
+// covers all time. It writes the 400 itself and returns false on bad input.
+func dateRangeQuery(c *gin.Context) (time.Time, time.Time, bool) {
+	start := time.Time{}
+	end := time.Now().AddDate(100, 0, 0)
+	if s := c.Query("start"); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start date"})
+			return start, end, false
+		}
+		start = t
+	}
+	if s := c.Query("end"); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end date"})
+			return start, end, false
+		}
+		end = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return start, end, true
+}
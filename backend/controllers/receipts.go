@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/models"
+	"shosha_mart_backend/receipt"
+)
+
+// loadReceiptTemplate returns the branch template, else the store default,
+// else the built-in one.
+func loadReceiptTemplate(db *gorm.DB, branchID string) (models.ReceiptTemplate, error) {
+	var templates []models.ReceiptTemplate
+	if err := db.Where("branch_id IN ?", []string{branchID, ""}).Find(&templates).Error; err != nil {
+		return models.ReceiptTemplate{}, err
+	}
+	var fallback *models.ReceiptTemplate
+	for i, t := range templates {
+		if t.BranchID == branchID {
+			return t, nil
+		}
+		fallback = &templates[i]
+	}
+	if fallback != nil {
+		return *fallback, nil
+	}
+	t := receipt.DefaultTemplate()
+	t.BranchID = branchID
+	return t, nil
+}
+
+// GetReceiptTemplate returns the template used for ?branch_id (empty = store default).
+func GetReceiptTemplate(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, err := loadReceiptTemplate(db, c.Query("branch_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, t)
+	}
+}
+
+// SaveReceiptTemplate creates or replaces the template of payload.branch_id.
+func SaveReceiptTemplate(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload models.ReceiptTemplate
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if payload.PaperWidth == 0 {
+			payload.PaperWidth = receipt.Paper80
+		}
+		if payload.PaperWidth != receipt.Paper58 && payload.PaperWidth != receipt.Paper80 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "paper_width must be 58 or 80"})
+			return
+		}
+
+		var existing models.ReceiptTemplate
+		err := db.First(&existing, "branch_id = ?", payload.BranchID).Error
+		switch {
+		case err == nil:
+			payload.ID = existing.ID
+			payload.CreatedAt = existing.CreatedAt
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			payload.ID = uuid.NewString()
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Save(&payload).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, payload)
+	}
+}
+
+// SaleReceipt renders a sale receipt. Query: format=text|escpos|pdf (default
+// text), width=58|80 (default from the template) and preview=1 to render
+// without counting a print. Every counted print after the first is marked as
+// a reprint.
+func SaleReceipt(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", "text")
+		if format != "text" && format != "escpos" && format != "pdf" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be text, escpos or pdf"})
+			return
+		}
+
+		var sale models.Sale
+		if err := db.First(&sale, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, gin.H{"error": "sale not found"})
+			return
+		}
+		if err := loadSaleDetails(db, &sale); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		tmpl, err := loadReceiptTemplate(db, sale.BranchID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		width := tmpl.PaperWidth
+		if w := c.Query("width"); w != "" {
+			width, err = strconv.Atoi(w)
+			if err != nil || (width != receipt.Paper58 && width != receipt.Paper80) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "width must be 58 or 80"})
+				return
+			}
+		}
+
+		productIDs := make([]string, len(sale.Items))
+		for i, item := range sale.Items {
+			productIDs[i] = item.ProductID
+		}
+		var products []models.Product
+		if err := db.Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		items := make(map[string]receipt.Item, len(products))
+		for _, p := range products {
+			items[p.ID] = receipt.Item{Name: p.Name, Unit: p.Unit}
+		}
+
+		copyNo := sale.PrintCount + 1
+		if c.Query("preview") == "" {
+			// Counter dinaikkan di DB agar cetak bersamaan tidak dihitung sekali.
+			if err := db.Model(&models.Sale{}).Where("id = ?", sale.ID).Updates(map[string]interface{}{
+				"print_count": gorm.Expr("print_count + 1"),
+				"synced":      false,
+			}).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if err := db.Model(&models.Sale{}).Select("print_count").Where("id = ?", sale.ID).Scan(&copyNo).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		cols := receipt.Columns(width)
+		lines := receipt.Layout(receipt.Receipt{Sale: sale, Items: items, Copy: copyNo}, tmpl, cols)
+		c.Header("X-Receipt-Copy", strconv.Itoa(copyNo))
+		name := fmt.Sprintf("receipt-%s", sale.ReceiptNo)
+		switch format {
+		case "escpos":
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".bin"))
+			c.Data(http.StatusOK, "application/octet-stream", receipt.ESCPOS(lines))
+		case "pdf":
+			c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", name+".pdf"))
+			c.Data(http.StatusOK, "application/pdf", receipt.PDF(lines, cols, width))
+		default:
+			c.Data(http.StatusOK, "text/plain; charset=utf-8", receipt.Text(lines, cols))
+		}
+	}
+}
@@ -95,3 +95,67 @@ func SalesReportGlobal(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 		c.FileAttachment(path, path)
 	}
 }
+
+// TaxReport generates the PPN Excel report, optionally for one branch (?branch_id=).
+func TaxReport(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startStr := c.DefaultQuery("start", time.Now().AddDate(0, 0, -7).Format("2006-01-02"))
+		endStr := c.DefaultQuery("end", time.Now().Format("2006-01-02"))
+
+		start, err := time.Parse("2006-01-02", startStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start date"})
+			return
+		}
+		end, err := time.Parse("2006-01-02", endStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end date"})
+			return
+		}
+		end = end.Add(24*time.Hour - time.Nanosecond)
+
+		path, err := reports.GenerateTaxReport(db, cfg, c.Query("branch_id"), start, end)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.FileAttachment(path, path)
+	}
+}
+
+// MarginReport returns gross margin for the period grouped by product,
+// category, branch or day (?by=, default product).
+func MarginReport(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startStr := c.DefaultQuery("start", time.Now().AddDate(0, 0, -7).Format("2006-01-02"))
+		endStr := c.DefaultQuery("end", time.Now().Format("2006-01-02"))
+
+		start, err := time.Parse("2006-01-02", startStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start date"})
+			return
+		}
+		end, err := time.Parse("2006-01-02", endStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end date"})
+			return
+		}
+		end = end.Add(24*time.Hour - time.Nanosecond)
+
+		by := c.DefaultQuery("by", reports.MarginByProduct)
+		switch by {
+		case reports.MarginByProduct, reports.MarginByCategory, reports.MarginByBranch, reports.MarginByDay:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "by must be product, category, branch or day"})
+			return
+		}
+
+		rows, err := reports.Margins(db, by, c.Query("branch_id"), start, end)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, rows)
+	}
+}
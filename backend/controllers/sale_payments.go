@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/models"
+)
+
+// paymentInput is a single tender as sent by the cashier.
+type paymentInput struct {
+	Method    string       `json:"method"`
+	Amount    models.Money `json:"amount"` // for cash this is the money handed over
+	Reference string       `json:"reference"`
+}
+
+func validPaymentMethod(method string) bool {
+	switch method {
+	case models.PaymentCash, models.PaymentTransfer, models.PaymentQRIS, models.PaymentHutang:
+		return true
+	}
+	return false
+}
+
+// allocatePayments validates tenders against the sale total and returns the
+// payment rows together with the change due to the customer. Only cash may
+// exceed the total; the surplus becomes change.
+func allocatePayments(saleID string, total models.Money, tenders []paymentInput) ([]models.SalePayment, models.Money, error) {
+	if len(tenders) == 0 {
+		return nil, 0, errors.New("at least one payment is required")
+	}
+
+	var sum, cash, nonCash models.Money
+	for _, t := range tenders {
+		if !validPaymentMethod(t.Method) {
+			return nil, 0, fmt.Errorf("invalid payment method: %s", t.Method)
+		}
+		if t.Amount <= 0 {
+			return nil, 0, fmt.Errorf("payment amount must be > 0 for %s", t.Method)
+		}
+		sum += t.Amount
+		if t.Method == models.PaymentCash {
+			cash += t.Amount
+		} else {
+			nonCash += t.Amount
+		}
+	}
+	if sum < total {
+		return nil, 0, fmt.Errorf("payments (%s) do not cover total (%s)", sum, total)
+	}
+	if nonCash > total {
+		return nil, 0, errors.New("non-cash payments cannot exceed the total")
+	}
+
+	change := sum - total
+
+	// Kembalian diambil dari tender tunai terakhir lebih dulu.
+	remainingChange := change
+	payments := make([]models.SalePayment, len(tenders))
+	for i := len(tenders) - 1; i >= 0; i-- {
+		t := tenders[i]
+		amount := t.Amount
+		if t.Method == models.PaymentCash && remainingChange > 0 {
+			used := remainingChange
+			if used > amount {
+				used = amount
+			}
+			amount -= used
+			remainingChange -= used
+		}
+		payments[i] = models.SalePayment{
+			ID:        uuid.NewString(),
+			SaleID:    saleID,
+			Method:    t.Method,
+			Amount:    amount,
+			Tendered:  t.Amount,
+			Reference: t.Reference,
+			Synced:    false,
+		}
+	}
+	return payments, change, nil
+}
+
+// paymentMethodLabel returns the value stored in Sale.PaymentMethod for a set of tenders.
+func paymentMethodLabel(payments []models.SalePayment) string {
+	method := ""
+	for _, p := range payments {
+		if p.Amount <= 0 {
+			continue
+		}
+		if method != "" && method != p.Method {
+			return models.PaymentSplit
+		}
+		method = p.Method
+	}
+	if method == "" && len(payments) > 0 {
+		return payments[0].Method
+	}
+	return method
+}
+
+// replaceSalePayments tombstones the current tenders of a sale and stores new ones.
+func replaceSalePayments(tx *gorm.DB, saleID string, payments []models.SalePayment) error {
+	if err := tx.Model(&models.SalePayment{}).
+		Where("sale_id = ? AND is_deleted = ?", saleID, false).
+		Updates(map[string]interface{}{
+			"is_deleted": true,
+			"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+			"synced":     false,
+		}).Error; err != nil {
+		return err
+	}
+	for i := range payments {
+		if err := tx.Create(&payments[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSingleTender refuses to change the items of a sale paid with several
+// tenders: there is no telling which tender should absorb the difference.
+// The payments are changed to one tender through UpdateSale first.
+func checkSingleTender(tx *gorm.DB, saleID string) error {
+	var n int64
+	if err := tx.Model(&models.SalePayment{}).Where("sale_id = ? AND is_deleted = ?", saleID, false).Count(&n).Error; err != nil {
+		return err
+	}
+	if n > 1 {
+		return &checkoutError{status: http.StatusConflict, msg: "sale is paid with several tenders; change its payments to a single tender before editing items"}
+	}
+	return nil
+}
+
+// rebalanceSinglePayment keeps a single-tender sale settled after its items change.
+// Split payments are left untouched (item edits refuse them, see checkSingleTender).
+// A hutang tender that grows is checked against the customer's credit limit
+// first, as at checkout.
+func rebalanceSinglePayment(tx *gorm.DB, saleID string) error {
+	var sale models.Sale
+	if err := tx.First(&sale, "id = ?", saleID).Error; err != nil {
+		return err
+	}
+	var payments []models.SalePayment
+	if err := tx.Where("sale_id = ? AND is_deleted = ?", saleID, false).Find(&payments).Error; err != nil {
+		return err
+	}
+	if len(payments) != 1 {
+		return nil
+	}
+	p := payments[0]
+	if p.Method == models.PaymentHutang && sale.Total > p.Amount {
+		if err := checkCreditLimit(tx, sale.CustomerID, sale.ID, sale.Total); err != nil {
+			return err
+		}
+	}
+	tendered := p.Tendered
+	if tendered < sale.Total {
+		tendered = sale.Total
+	}
+	var change models.Money
+	if p.Method == models.PaymentCash {
+		change = tendered - sale.Total
+	} else {
+		tendered = sale.Total
+	}
+	if err := tx.Model(&p).Updates(map[string]interface{}{
+		"amount":     sale.Total,
+		"tendered":   tendered,
+		"synced":     false,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&sale).Updates(map[string]interface{}{
+		"change_due": change,
+		"synced":     false,
+	}).Error
+}
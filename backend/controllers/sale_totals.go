@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/models"
+	"shosha_mart_backend/pricing"
+)
+
+// repriceSaleItem recomputes the discount and subtotal of an edited line. The
+// promotion granted at checkout is kept (without re-checking its time window),
+// so changing the qty of a "buy 2 get 1" line still honours the promotion.
+func repriceSaleItem(tx *gorm.DB, item *models.SaleItem) error {
+	var promos []models.Promotion
+	if item.PromotionID != "" {
+		var promo models.Promotion
+		err := tx.First(&promo, "id = ?", item.PromotionID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			promos = append(promos, promo)
+		}
+	}
+
+	line := pricing.Line{
+		ProductID:     item.ProductID,
+		Qty:           item.Qty,
+		Price:         item.Price,
+		DiscountType:  item.DiscountType,
+		DiscountValue: item.DiscountValue,
+	}
+	pricing.ApplyLine(&line, promos)
+	item.DiscountAmount = line.DiscountAmount
+	item.PromotionID = line.PromotionID
+	item.Subtotal = line.Subtotal
+	return nil
+}
+
+// saleTotals are the amounts of a sale derived from its items.
+type saleTotals struct {
+	Subtotal models.Money
+	Discount models.Money
+	Tax      models.Money
+	Total    models.Money
+	Lines    []pricing.TaxResult
+}
+
+// computeSaleTotals derives the totals of sale from its live items. The
+// order-level discount was fixed at checkout and is only capped so it never
+// exceeds the subtotal; line taxes use the rates snapshotted on each item.
+func computeSaleTotals(sale models.Sale, items []models.SaleItem) saleTotals {
+	var t saleTotals
+	taxable := make([]pricing.TaxableLine, len(items))
+	for i, item := range items {
+		t.Subtotal += item.LineTotal()
+		taxable[i] = pricing.TaxableLine{Amount: item.LineTotal(), Rate: item.TaxRate, Inclusive: item.TaxInclusive}
+	}
+	t.Discount = sale.DiscountAmount
+	if t.Discount > t.Subtotal {
+		t.Discount = t.Subtotal
+	}
+	var addedTax models.Money
+	t.Lines, t.Tax, addedTax = pricing.ApplyTax(taxable, t.Discount)
+	t.Total = t.Subtotal - t.Discount + addedTax
+	return t
+}
+
+// recalcSaleTotals rebuilds the subtotal, taxes and total of a sale from its
+// live items and stores them.
+func recalcSaleTotals(tx *gorm.DB, saleID string) error {
+	var sale models.Sale
+	if err := tx.First(&sale, "id = ?", saleID).Error; err != nil {
+		return err
+	}
+	var items []models.SaleItem
+	if err := tx.Where("sale_id = ? AND is_deleted = ?", saleID, false).Find(&items).Error; err != nil {
+		return err
+	}
+
+	totals := computeSaleTotals(sale, items)
+	results := totals.Lines
+	for i, item := range items {
+		if item.TaxBase == results[i].Base && item.TaxAmount == results[i].Tax {
+			continue
+		}
+		if err := tx.Model(&items[i]).Updates(map[string]interface{}{
+			"tax_base":   results[i].Base,
+			"tax_amount": results[i].Tax,
+			"synced":     false,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&sale).Updates(map[string]interface{}{
+		"subtotal":        totals.Subtotal,
+		"discount_amount": totals.Discount,
+		"tax_amount":      totals.Tax,
+		"total":           totals.Total,
+		"synced":          false,
+	}).Error
+}
@@ -3,7 +3,10 @@ package controllers
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,157 +14,27 @@ import (
 	"gorm.io/gorm"
 
 	"shosha_mart_backend/config"
+	"shosha_mart_backend/costing"
 	"shosha_mart_backend/exports"
 	"shosha_mart_backend/models"
+	"shosha_mart_backend/pricing"
 )
 
 // CreateSale records a checkout and decrements stock offline-first.
 func CreateSale(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var payload struct {
-			BranchID      string `json:"branch_id"`
-			ReceiptNo     string `json:"receipt_no"`
-			PaymentMethod string `json:"payment_method"` // "cash" or "hutang"
-			Notes         string `json:"notes"`
-			CreatedAt     string `json:"created_at"`
-			Items         []struct {
-				ProductID string  `json:"product_id"`
-				Qty       int     `json:"qty"`
-				Price     float64 `json:"price"`
-			} `json:"items"`
-		}
+		var payload saleInput
 		if err := c.ShouldBindJSON(&payload); err != nil || len(payload.Items) == 0 {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 			return
 		}
 
-		type validatedItem struct {
-			ProductID string
-			Qty       int
-			Price     float64
-		}
-		validated := make([]validatedItem, 0, len(payload.Items))
-		for _, item := range payload.Items {
-			if item.ProductID == "" || item.Qty <= 0 {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "productId required and qty must be > 0"})
-				return
-			}
-			var product models.Product
-			if err := db.First(&product, "id = ?", item.ProductID).Error; err != nil {
-				status := http.StatusInternalServerError
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					status = http.StatusBadRequest
-				}
-				c.JSON(status, gin.H{"error": fmt.Sprintf("product not found: %s", item.ProductID)})
-				return
-			}
-			price := item.Price
-			if price <= 0 {
-				price = product.Price
-			}
-			validated = append(validated, validatedItem{
-				ProductID: item.ProductID,
-				Qty:       item.Qty,
-				Price:     price,
-			})
-		}
-
-		branchID := chooseBranch(payload.BranchID, cfg.BranchID)
-		branchName := ""
-		// Pastikan branch ada agar FK tidak gagal.
-		if branchID != "" {
-			var branch models.Branch
-			if err := db.First(&branch, "id = ?", branchID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
-			if branch.ID == "" && branchID != "" {
-				// buat stub branch jika belum ada agar tidak gagal FK
-				if err := db.Create(&models.Branch{
-					ID:        branchID,
-					Name:      branchID,
-					Synced:    false,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
-				}).Error; err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-					return
-				}
-				branchName = branchID
-			} else {
-				branchName = branch.Name
-			}
-		}
-
-		paymentMethod := payload.PaymentMethod
-		if paymentMethod != "cash" && paymentMethod != "hutang" {
-			paymentMethod = "cash" // default
-		}
-
-		sale := models.Sale{
-			ID:            uuid.NewString(),
-			ReceiptNo:     payload.ReceiptNo,
-			BranchID:      branchID,
-			BranchName:    branchName,
-			PaymentMethod: paymentMethod,
-			Notes:         payload.Notes,
-			Synced:        false,
-		}
-
-		// If caller provided created_at, try to parse it and set CreatedAt accordingly.
-		if payload.CreatedAt != "" {
-			// try RFC3339 first, fallback to date-only YYYY-MM-DD
-			if t, err := time.Parse(time.RFC3339, payload.CreatedAt); err == nil {
-				sale.CreatedAt = t
-			} else if t2, err2 := time.Parse("2006-01-02", payload.CreatedAt); err2 == nil {
-				sale.CreatedAt = t2
-			}
-		}
-
-		err := db.Transaction(func(tx *gorm.DB) error {
-			// simpan sale lebih dulu agar FK sale_items -> sales tidak gagal
-			if sale.ReceiptNo == "" {
-				sale.ReceiptNo = generateReceiptNo()
-			}
-			if err := tx.Create(&sale).Error; err != nil {
-				return err
-			}
-
-			var total float64
-			for _, item := range validated {
-				saleItem := models.SaleItem{
-					ID:        uuid.NewString(),
-					SaleID:    sale.ID,
-					ProductID: item.ProductID,
-					Qty:       item.Qty,
-					Price:     item.Price,
-					Synced:    false,
-				}
-
-				if err := tx.Create(&saleItem).Error; err != nil {
-					return err
-				}
-
-				if err := tx.Model(&models.Product{}).
-					Where("id = ?", item.ProductID).
-					UpdateColumn("stock", gorm.Expr("stock - ?", item.Qty)).Error; err != nil {
-					return err
-				}
-
-				total += float64(item.Qty) * item.Price
-			}
-			sale.Total = total
-			return tx.Model(&sale).Updates(map[string]interface{}{
-				"total":      total,
-				"updated_at": time.Now(),
-			}).Error
-		})
-
+		payload.User = posUser(c, "")
+		sale, err := postSale(db, cfg, payload)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondCheckoutError(c, err)
 			return
 		}
-
 		c.JSON(http.StatusCreated, sale)
 	}
 }
@@ -173,19 +46,111 @@ func chooseBranch(payload, fallback string) string {
 	return fallback
 }
 
+// saleRef is the stock card reference of a change made by sale.
+func saleRef(sale models.Sale, source, user string) costing.Ref {
+	return costing.Ref{Source: source, DocID: sale.ID, DocNo: sale.ReceiptNo, User: user}
+}
+
 func generateReceiptNo() string {
 	return time.Now().Format("060102150405")
 }
 
-// ListSales returns recent sales with basic fields (including BranchName and Total)
+// saleSortColumns are the columns ListSales can sort by.
+var saleSortColumns = map[string]string{
+	"created_at": "created_at",
+	"total":      "total",
+	"receipt_no": "receipt_no",
+	"branch":     "branch_name",
+}
+
+// ListSales returns sales with basic fields (including BranchName and Total),
+// newest first. Filters: start/end (YYYY-MM-DD), branch_id, customer_id,
+// cash_session_id, payment_method, receipt_no (substring), product_id,
+// min_total/max_total (rupiah) and synced (true/false). Sorting: sort=
+// created_at|total|receipt_no|branch, order=asc|desc. With page/size the
+// response is a page envelope; without them a plain array, as before.
 func ListSales(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		pageNo, size, paged, ok := pageQuery(c)
+		if !ok {
+			return
+		}
+		order, ok := sortQuery(c, saleSortColumns, "created_at")
+		if !ok {
+			return
+		}
+
+		q := db.Model(&models.Sale{}).Where("is_deleted = ?", false)
+		if c.Query("start") != "" || c.Query("end") != "" {
+			start, end, ok := dateRangeQuery(c)
+			if !ok {
+				return
+			}
+			q = q.Where("created_at BETWEEN ? AND ?", start, end)
+		}
+		for param, column := range map[string]string{
+			"branch_id":       "branch_id",
+			"customer_id":     "customer_id",
+			"cash_session_id": "cash_session_id",
+		} {
+			if v := c.Query(param); v != "" {
+				q = q.Where(column+" = ?", v)
+			}
+		}
+		// Sale dengan salah satu pembayaran bermetode ini; sale lama tanpa
+		// sale_payments dicocokkan lewat payment_method.
+		if v := c.Query("payment_method"); v != "" {
+			q = q.Where("(EXISTS (SELECT 1 FROM sale_payments sp WHERE sp.sale_id = sales.id AND sp.method = ? AND sp.is_deleted = ?) "+
+				"OR (sales.payment_method = ? AND NOT EXISTS (SELECT 1 FROM sale_payments sp WHERE sp.sale_id = sales.id)))", v, false, v)
+		}
+		if v := strings.TrimSpace(c.Query("receipt_no")); v != "" {
+			q = q.Where("receipt_no LIKE ?", "%"+v+"%")
+		}
+		if v := c.Query("product_id"); v != "" {
+			q = q.Where("EXISTS (SELECT 1 FROM sale_items si WHERE si.sale_id = sales.id AND si.product_id = ? AND si.is_deleted = ?)", v, false)
+		}
+		for param, op := range map[string]string{"min_total": ">=", "max_total": "<="} {
+			v := c.Query(param)
+			if v == "" {
+				continue
+			}
+			rupiah, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param})
+				return
+			}
+			q = q.Where("total "+op+" ?", models.FromRupiah(rupiah))
+		}
+		if v := c.Query("synced"); v != "" {
+			synced, err := strconv.ParseBool(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "synced must be true or false"})
+				return
+			}
+			q = q.Where("synced = ?", synced)
+		}
+
 		var sales []models.Sale
-		if err := db.Where("is_deleted = ?", false).Order("created_at DESC").Find(&sales).Error; err != nil {
+		if !paged {
+			if err := q.Order(order).Find(&sales).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, sales)
+			return
+		}
+
+		var total int64
+		if err := q.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// id sebagai tie-breaker agar urutan antar halaman stabil
+		if err := q.Order(order).Order("id").Limit(size).Offset((pageNo - 1) * size).Find(&sales).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, sales)
+		c.JSON(http.StatusOK, newPage(sales, total, pageNo, size))
 	}
 }
 
@@ -202,25 +167,41 @@ func GetSale(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(status, gin.H{"error": "sale not found"})
 			return
 		}
-		var items []models.SaleItem
-		if err := db.Where("sale_id = ? AND is_deleted = ?", sale.ID, false).Find(&items).Error; err != nil {
+		if err := loadSaleDetails(db, &sale); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		sale.Items = items
 		c.JSON(http.StatusOK, sale)
 	}
 }
 
-// UpdateSale updates sale metadata (date, branch, payment method, notes) - cannot edit items
+// loadSaleDetails attaches the live items and payments of a sale.
+func loadSaleDetails(db *gorm.DB, sale *models.Sale) error {
+	var items []models.SaleItem
+	if err := db.Where("sale_id = ? AND is_deleted = ?", sale.ID, false).Find(&items).Error; err != nil {
+		return err
+	}
+	sale.Items = items
+	var payments []models.SalePayment
+	if err := db.Where("sale_id = ? AND is_deleted = ?", sale.ID, false).Order("created_at").Find(&payments).Error; err != nil {
+		return err
+	}
+	sale.Payments = payments
+	sale.TaxSummary = pricing.SummarizeTax(items)
+	return nil
+}
+
+// UpdateSale updates sale metadata (date, branch, customer, payments, notes) - cannot edit items
 func UpdateSale(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 		var payload struct {
-			CreatedAt     string `json:"created_at"`
-			BranchID      string `json:"branch_id"`
-			PaymentMethod string `json:"payment_method"`
-			Notes         string `json:"notes"`
+			CreatedAt     string         `json:"created_at"`
+			BranchID      string         `json:"branch_id"`
+			CustomerID    string         `json:"customer_id"`
+			PaymentMethod string         `json:"payment_method"`
+			Payments      []paymentInput `json:"payments"`
+			Notes         string         `json:"notes"`
 		}
 		if err := c.ShouldBindJSON(&payload); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
@@ -268,12 +249,46 @@ func UpdateSale(db *gorm.DB) gin.HandlerFunc {
 			updates["branch_name"] = branch.Name
 		}
 
-		if payload.PaymentMethod != "" {
-			if payload.PaymentMethod != "cash" && payload.PaymentMethod != "hutang" {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "payment_method must be 'cash' or 'hutang'"})
+		customerID := sale.CustomerID
+		if payload.CustomerID != "" {
+			var customer models.Customer
+			if err := db.First(&customer, "id = ? AND is_deleted = ?", payload.CustomerID, false).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "customer not found"})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			customerID = customer.ID
+			updates["customer_id"] = customer.ID
+			updates["customer_name"] = customer.Name
+		}
+
+		tenders := payload.Payments
+		if len(tenders) == 0 && payload.PaymentMethod != "" {
+			if !validPaymentMethod(payload.PaymentMethod) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "payment_method must be one of cash, transfer, qris, hutang"})
+				return
+			}
+			if sale.PaymentMethod == models.PaymentSplit {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "sale has split payments, send payments to change them"})
+				return
+			}
+			tenders = []paymentInput{{Method: payload.PaymentMethod, Amount: sale.Total}}
+		}
+
+		var payments []models.SalePayment
+		if len(tenders) > 0 {
+			var change models.Money
+			var err error
+			payments, change, err = allocatePayments(sale.ID, sale.Total, tenders)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			updates["payment_method"] = payload.PaymentMethod
+			updates["payment_method"] = paymentMethodLabel(payments)
+			updates["change_due"] = change
 		}
 
 		if payload.Notes != "" {
@@ -281,8 +296,32 @@ func UpdateSale(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		// Update sale
-		if err := db.Model(&sale).Updates(updates).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Hutang dicek ulang jika pelanggan atau pembayaran berubah.
+			hutang := hutangAmount(payments)
+			if payments == nil {
+				var current []models.SalePayment
+				if err := tx.Where("sale_id = ? AND is_deleted = ?", sale.ID, false).Find(&current).Error; err != nil {
+					return err
+				}
+				hutang = hutangAmount(current)
+			}
+			if customerID != sale.CustomerID || payments != nil {
+				if err := checkCreditLimit(tx, customerID, sale.ID, hutang); err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Model(&sale).Updates(updates).Error; err != nil {
+				return err
+			}
+			if payments != nil {
+				return replaceSalePayments(tx, sale.ID, payments)
+			}
+			return nil
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
 			return
 		}
 
@@ -291,20 +330,28 @@ func UpdateSale(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		if err := loadSaleDetails(db, &sale); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 
 		c.JSON(http.StatusOK, sale)
 	}
 }
 
-// UpdateSaleItem updates quantity and price of an item in a sale
-func UpdateSaleItem(db *gorm.DB) gin.HandlerFunc {
+// UpdateSaleItem updates quantity and price of an item in a sale. A new price
+// must match the sale's tier price unless a manager approves the override.
+func UpdateSaleItem(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		saleID := c.Param("id")
 		itemID := c.Param("itemId")
 
 		var payload struct {
-			Qty   int     `json:"qty"`
-			Price float64 `json:"price"`
+			Qty           models.Qty   `json:"qty"` // dalam satuan jual baris
+			Price         models.Money `json:"price"`
+			DiscountType  *string      `json:"discount_type"` // pointer untuk detect null
+			DiscountValue *float64     `json:"discount_value"`
+			ManagerPIN    string       `json:"manager_pin"`
 		}
 		if err := c.ShouldBindJSON(&payload); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
@@ -315,6 +362,15 @@ func UpdateSaleItem(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "qty must be > 0"})
 			return
 		}
+		if payload.DiscountType != nil && !pricing.ValidDiscountType(*payload.DiscountType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "discount_type must be 'percent' or 'amount'"})
+			return
+		}
+		approved := managerApproved(cfg, payload.ManagerPIN)
+		if payload.ManagerPIN != "" && !approved {
+			c.JSON(http.StatusForbidden, gin.H{"error": "invalid manager pin"})
+			return
+		}
 
 		// Check if sale exists
 		var sale models.Sale
@@ -338,58 +394,118 @@ func UpdateSaleItem(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Calculate qty difference to adjust stock
-		qtyDiff := payload.Qty - item.Qty
-		oldTotal := item.Qty * int(item.Price)
-		newTotal := payload.Qty * int(payload.Price)
-		totalDiff := float64(newTotal - oldTotal)
+		// Calculate qty difference (in base units) to adjust stock
+		newBase := payload.Qty.Mul(item.UnitFactor)
+		qtyDiff := newBase - item.BaseQty
+		oldBase := item.BaseQty
+
+		item.Qty = payload.Qty
+		item.BaseQty = newBase
+		if payload.Price > 0 && payload.Price != item.Price {
+			var product models.Product
+			if err := db.First(&product, "id = ?", item.ProductID).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if err := pricing.ForBranch(db, chooseBranch(sale.BranchID, cfg.BranchID), &product); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			unit, err := findUnit(db, product, item.Unit)
+			if err != nil {
+				// satuan sudah dihapus; harga dasar x faktor yang tercatat
+				unit = saleUnit{Name: item.Unit, Factor: item.UnitFactor}
+			}
+			price, override, err := resolveUnitPrice(product, unit, sale.PriceTier, payload.Price, approved)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			item.Price = price
+			item.PriceOverridden = override
+		}
+		if payload.DiscountType != nil {
+			item.DiscountType = *payload.DiscountType
+		}
+		if payload.DiscountValue != nil {
+			item.DiscountValue = *payload.DiscountValue
+		}
 
 		err := db.Transaction(func(tx *gorm.DB) error {
-			// Update stock
-			if err := tx.Model(&models.Product{}).
-				Where("id = ?", item.ProductID).
-				UpdateColumn("stock", gorm.Expr("stock - ?", qtyDiff)).Error; err != nil {
+			if err := checkSingleTender(tx, sale.ID); err != nil {
+				return err
+			}
+			// Update stock; extra qty is costed now and blended into the line cost
+			switch {
+			case qtyDiff > 0:
+				cost, err := costing.Issue(tx, cfg.CostMethod, cfg.BranchID, item.ProductID, qtyDiff, saleRef(sale, models.MovementSaleEdit, posUser(c, "")))
+				if err != nil {
+					return err
+				}
+				blended := float64(item.UnitCost)*float64(oldBase) + float64(cost)*float64(qtyDiff)
+				item.UnitCost = models.Money(math.Round(blended / float64(newBase)))
+			case qtyDiff < 0:
+				if err := costing.Return(tx, cfg.CostMethod, cfg.BranchID, item.ProductID, -qtyDiff, item.UnitCost, saleRef(sale, models.MovementSaleEdit, posUser(c, ""))); err != nil {
+					return err
+				}
+			}
+
+			if err := repriceSaleItem(tx, &item); err != nil {
 				return err
 			}
 
 			// Update item
 			if err := tx.Model(&item).Updates(map[string]interface{}{
-				"qty":    payload.Qty,
-				"price":  payload.Price,
-				"synced": false,
+				"qty":              item.Qty,
+				"base_qty":         item.BaseQty,
+				"unit_cost":        item.UnitCost,
+				"price":            item.Price,
+				"price_overridden": item.PriceOverridden,
+				"discount_type":    item.DiscountType,
+				"discount_value":   item.DiscountValue,
+				"discount_amount":  item.DiscountAmount,
+				"promotion_id":     item.PromotionID,
+				"subtotal":         item.Subtotal,
+				"synced":           false,
 			}).Error; err != nil {
 				return err
 			}
 
 			// Update sale total
-			if err := tx.Model(&sale).Updates(map[string]interface{}{
-				"total":  gorm.Expr("total + ?", totalDiff),
-				"synced": false,
-			}).Error; err != nil {
+			if err := recalcSaleTotals(tx, sale.ID); err != nil {
 				return err
 			}
 
-			return nil
+			return rebalanceSinglePayment(tx, sale.ID)
 		})
 
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondCheckoutError(c, err)
 			return
 		}
 
+		// Reload to include the recomputed tax
+		if err := db.First(&item, "id = ?", item.ID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusOK, item)
 	}
 }
 
-// AddSaleItem adds a new product to an existing sale
-func AddSaleItem(db *gorm.DB) gin.HandlerFunc {
+// AddSaleItem adds a new product to an existing sale, priced at the sale's tier.
+func AddSaleItem(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		saleID := c.Param("id")
 
 		var payload struct {
-			ProductID string  `json:"product_id"`
-			Qty       int     `json:"qty"`
-			Price     float64 `json:"price"`
+			ProductID     string       `json:"product_id"`
+			Qty           models.Qty   `json:"qty"`
+			Unit          string       `json:"unit"` // kosong = satuan dasar
+			Price         models.Money `json:"price"`
+			DiscountType  string       `json:"discount_type"`
+			DiscountValue float64      `json:"discount_value"`
+			ManagerPIN    string       `json:"manager_pin"`
 		}
 		if err := c.ShouldBindJSON(&payload); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
@@ -400,6 +516,10 @@ func AddSaleItem(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "product_id and qty required"})
 			return
 		}
+		if !pricing.ValidDiscountType(payload.DiscountType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "discount_type must be 'percent' or 'amount'"})
+			return
+		}
 
 		// Check if sale exists
 		var sale models.Sale
@@ -422,58 +542,101 @@ func AddSaleItem(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(status, gin.H{"error": "product not found"})
 			return
 		}
+		if err := pricing.ForBranch(db, chooseBranch(sale.BranchID, cfg.BranchID), &product); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		approved := managerApproved(cfg, payload.ManagerPIN)
+		if payload.ManagerPIN != "" && !approved {
+			c.JSON(http.StatusForbidden, gin.H{"error": "invalid manager pin"})
+			return
+		}
+		unit, err := findUnit(db, product, payload.Unit)
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		price, override, err := resolveUnitPrice(product, unit, sale.PriceTier, payload.Price, approved)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-		price := payload.Price
-		if price <= 0 {
-			price = product.Price
+		line := pricing.Line{
+			ProductID:     payload.ProductID,
+			Qty:           payload.Qty,
+			Price:         price,
+			DiscountType:  payload.DiscountType,
+			DiscountValue: payload.DiscountValue,
 		}
+		pricing.ApplyLine(&line, nil)
 
 		newItem := models.SaleItem{
-			ID:        uuid.NewString(),
-			SaleID:    saleID,
-			ProductID: payload.ProductID,
-			Qty:       payload.Qty,
-			Price:     price,
-			Synced:    false,
+			ID:              uuid.NewString(),
+			SaleID:          saleID,
+			ProductID:       payload.ProductID,
+			Qty:             payload.Qty,
+			Unit:            unit.Name,
+			UnitFactor:      unit.Factor,
+			BaseQty:         payload.Qty.Mul(unit.Factor),
+			Price:           price,
+			PriceOverridden: override,
+			DiscountType:    line.DiscountType,
+			DiscountValue:   line.DiscountValue,
+			DiscountAmount:  line.DiscountAmount,
+			Subtotal:        line.Subtotal,
+			Synced:          false,
+		}
+		taxRates, err := loadTaxRates(db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if rate, ok := taxRates.forProduct(product); ok {
+			stampTax(&newItem, rate)
 		}
 
-		itemTotal := float64(payload.Qty) * price
-
-		err := db.Transaction(func(tx *gorm.DB) error {
-			// Create item
-			if err := tx.Create(&newItem).Error; err != nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := checkSingleTender(tx, sale.ID); err != nil {
+				return err
+			}
+			// Take stock out and book its cost
+			cost, err := costing.Issue(tx, cfg.CostMethod, cfg.BranchID, payload.ProductID, newItem.BaseQty, saleRef(sale, models.MovementSaleEdit, posUser(c, "")))
+			if err != nil {
 				return err
 			}
+			newItem.UnitCost = cost
 
-			// Update stock
-			if err := tx.Model(&models.Product{}).
-				Where("id = ?", payload.ProductID).
-				UpdateColumn("stock", gorm.Expr("stock - ?", payload.Qty)).Error; err != nil {
+			// Create item
+			if err := tx.Create(&newItem).Error; err != nil {
 				return err
 			}
 
 			// Update sale total
-			if err := tx.Model(&sale).Updates(map[string]interface{}{
-				"total":  gorm.Expr("total + ?", itemTotal),
-				"synced": false,
-			}).Error; err != nil {
+			if err := recalcSaleTotals(tx, sale.ID); err != nil {
 				return err
 			}
 
-			return nil
+			return rebalanceSinglePayment(tx, sale.ID)
 		})
 
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondCheckoutError(c, err)
 			return
 		}
 
+		// Reload to include the recomputed tax
+		if err := db.First(&newItem, "id = ?", newItem.ID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusCreated, newItem)
 	}
 }
 
 // DeleteSaleItem removes a product from a sale and restores its stock
-func DeleteSaleItem(db *gorm.DB) gin.HandlerFunc {
+func DeleteSaleItem(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		saleID := c.Param("id")
 		itemID := c.Param("itemId")
@@ -500,13 +663,12 @@ func DeleteSaleItem(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		itemSubtotal := float64(item.Qty) * item.Price
-
 		err := db.Transaction(func(tx *gorm.DB) error {
-			// Restore stock
-			if err := tx.Model(&models.Product{}).
-				Where("id = ?", item.ProductID).
-				UpdateColumn("stock", gorm.Expr("stock + ?", item.Qty)).Error; err != nil {
+			if err := checkSingleTender(tx, sale.ID); err != nil {
+				return err
+			}
+			// Restore stock at the cost it was sold with
+			if err := costing.Return(tx, cfg.CostMethod, cfg.BranchID, item.ProductID, item.BaseQty, item.UnitCost, saleRef(sale, models.MovementSaleEdit, posUser(c, ""))); err != nil {
 				return err
 			}
 
@@ -520,18 +682,15 @@ func DeleteSaleItem(db *gorm.DB) gin.HandlerFunc {
 			}
 
 			// Update sale total
-			if err := tx.Model(&sale).Updates(map[string]interface{}{
-				"total":  gorm.Expr("total - ?", itemSubtotal),
-				"synced": false,
-			}).Error; err != nil {
+			if err := recalcSaleTotals(tx, sale.ID); err != nil {
 				return err
 			}
 
-			return nil
+			return rebalanceSinglePayment(tx, sale.ID)
 		})
 
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondCheckoutError(c, err)
 			return
 		}
 
@@ -556,8 +715,8 @@ func ExportSalesReport(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
-// DeleteSale removes a sale and its items, and restores product stock
-func DeleteSale(db *gorm.DB) gin.HandlerFunc {
+// DeleteSale removes a sale with its items and payments, and restores product stock
+func DeleteSale(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 
@@ -569,10 +728,13 @@ func DeleteSale(db *gorm.DB) gin.HandlerFunc {
 			}
 
 			// Restore stock for each item
+			var sale models.Sale
+			if err := tx.Limit(1).Find(&sale, "id = ?", id).Error; err != nil {
+				return err
+			}
+			sale.ID = id
 			for _, item := range items {
-				if err := tx.Model(&models.Product{}).
-					Where("id = ?", item.ProductID).
-					UpdateColumn("stock", gorm.Expr("stock + ?", item.Qty)).Error; err != nil {
+				if err := costing.Return(tx, cfg.CostMethod, cfg.BranchID, item.ProductID, item.BaseQty, item.UnitCost, saleRef(sale, models.MovementSaleVoid, posUser(c, ""))); err != nil {
 					return err
 				}
 			}
@@ -588,6 +750,16 @@ func DeleteSale(db *gorm.DB) gin.HandlerFunc {
 				return err
 			}
 
+			if err := tx.Model(&models.SalePayment{}).
+				Where("sale_id = ?", id).
+				Updates(map[string]interface{}{
+					"is_deleted": true,
+					"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+					"synced":     false,
+				}).Error; err != nil {
+				return err
+			}
+
 			if err := tx.Model(&models.Sale{}).
 				Where("id = ?", id).
 				Updates(map[string]interface{}{
@@ -602,7 +774,7 @@ func DeleteSale(db *gorm.DB) gin.HandlerFunc {
 		})
 
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondCheckoutError(c, err)
 			return
 		}
 
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/costing"
+	"shosha_mart_backend/models"
+)
+
+// testDB opens an in-memory database with the sidecar's tables. One
+// connection keeps every query on the same database.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.Product{}, &models.ProductBarcode{}, &models.ProductUnit{}, &models.CostLayer{},
+		&models.StockMovement{}, &models.StockBatch{}, &models.StockBatchAllocation{}, &models.BranchStock{}, &models.BranchPrice{},
+		&models.Branch{}, &models.Customer{}, &models.CashSession{}, &models.CashMovement{}, &models.Sale{}, &models.SaleItem{},
+		&models.SalePayment{}, &models.Promotion{}, &models.TaxRate{}, &models.Category{}, &models.StockOpname{},
+		&models.StockOpnameItem{}, &models.StockOpnameCount{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// serve sends a JSON request to handler h mounted at pattern.
+func serve(t *testing.T, method, pattern, path string, h gin.HandlerFunc, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Handle(method, pattern, h)
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, &buf))
+	return w
+}
+
+func TestSaleStockMovesAtLocalBranch(t *testing.T) {
+	db := testDB(t)
+	cfg := config.AppConfig{BranchID: "b1", CostMethod: "average"}
+	if err := db.Create(&models.Product{ID: "p1", Name: "Beras", Unit: "pcs", Price: 1000000, Cost: 800000, Stock: models.Units(10)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := costing.SyncProductStock(db, cfg.BranchID); err != nil {
+		t.Fatal(err)
+	}
+	stock := func(branchID string) float64 {
+		t.Helper()
+		q, err := costing.OnHand(db, branchID, "p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return q.Float()
+	}
+
+	// branch_id adalah cabang yang dipilih di kasir, bukan cabang sidecar
+	w := serve(t, http.MethodPost, "/api/sales", "/api/sales", CreateSale(db, cfg), map[string]interface{}{
+		"branch_id":      "b2",
+		"payment_method": "cash",
+		"items":          []map[string]interface{}{{"product_id": "p1", "qty": 3}},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create sale: %d %s", w.Code, w.Body)
+	}
+	var sale models.Sale
+	if err := json.Unmarshal(w.Body.Bytes(), &sale); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name    string
+		method  string
+		pattern string
+		path    string
+		h       gin.HandlerFunc
+		body    interface{}
+		want    float64
+	}{
+		{"sale", "", "", "", nil, nil, 7},
+		{"item added", http.MethodPost, "/api/sales/:id/items", "/api/sales/" + sale.ID + "/items", AddSaleItem(db, cfg),
+			map[string]interface{}{"product_id": "p1", "qty": 2}, 5},
+		{"item removed", http.MethodDelete, "/api/sales/:id/items/:itemId", "/api/sales/" + sale.ID + "/items/" + sale.Items[0].ID, DeleteSaleItem(db, cfg), nil, 8},
+		{"sale voided", http.MethodDelete, "/api/sales/:id", "/api/sales/" + sale.ID, DeleteSale(db, cfg), nil, 10},
+	}
+	for _, st := range steps {
+		if st.h != nil {
+			if w := serve(t, st.method, st.pattern, st.path, st.h, st.body); w.Code >= 300 {
+				t.Fatalf("%s: %d %s", st.name, w.Code, w.Body)
+			}
+		}
+		if got := stock(cfg.BranchID); got != st.want {
+			t.Errorf("%s: local stock %v, want %v", st.name, got, st.want)
+		}
+		var p models.Product
+		if err := db.First(&p, "id = ?", "p1").Error; err != nil {
+			t.Fatal(err)
+		}
+		if p.Stock.Float() != st.want {
+			t.Errorf("%s: products.stock %v, want %v", st.name, p.Stock.Float(), st.want)
+		}
+		var others int64
+		if err := db.Model(&models.BranchStock{}).Where("branch_id <> ?", cfg.BranchID).Count(&others).Error; err != nil {
+			t.Fatal(err)
+		}
+		if others != 0 {
+			t.Errorf("%s: %d stock rows created for other branches", st.name, others)
+		}
+	}
+}
+
+func TestItemEditsNeedASingleTender(t *testing.T) {
+	db := testDB(t)
+	cfg := config.AppConfig{BranchID: "b1", CostMethod: "average"}
+	if err := db.Create(&models.Product{ID: "p1", Name: "Beras", Unit: "pcs", Price: 1000000, Stock: models.Units(10)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := costing.SyncProductStock(db, cfg.BranchID); err != nil {
+		t.Fatal(err)
+	}
+	w := serve(t, http.MethodPost, "/api/sales", "/api/sales", CreateSale(db, cfg), map[string]interface{}{
+		"items":    []map[string]interface{}{{"product_id": "p1", "qty": 2}},
+		"payments": []map[string]interface{}{{"method": "cash", "amount": 5000}, {"method": "transfer", "amount": 15000}},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create sale: %d %s", w.Code, w.Body)
+	}
+	var sale models.Sale
+	if err := json.Unmarshal(w.Body.Bytes(), &sale); err != nil {
+		t.Fatal(err)
+	}
+	itemPath := "/api/sales/" + sale.ID + "/items/" + sale.Items[0].ID
+	edits := []struct {
+		name    string
+		method  string
+		pattern string
+		path    string
+		h       gin.HandlerFunc
+		body    interface{}
+	}{
+		{"add", http.MethodPost, "/api/sales/:id/items", "/api/sales/" + sale.ID + "/items", AddSaleItem(db, cfg), map[string]interface{}{"product_id": "p1", "qty": 1}},
+		{"update", http.MethodPut, "/api/sales/:id/items/:itemId", itemPath, UpdateSaleItem(db, cfg), map[string]interface{}{"qty": 3}},
+		{"delete", http.MethodDelete, "/api/sales/:id/items/:itemId", itemPath, DeleteSaleItem(db, cfg), nil},
+	}
+	for _, e := range edits {
+		if w := serve(t, e.method, e.pattern, e.path, e.h, e.body); w.Code != http.StatusConflict {
+			t.Errorf("%s on a split sale: %d %s, want 409", e.name, w.Code, w.Body)
+		}
+	}
+	if got, _ := costing.OnHand(db, cfg.BranchID, "p1"); got != models.Units(8) {
+		t.Errorf("stock after refused edits = %s, want 8", got)
+	}
+
+	// satu tender dulu, lalu item boleh diubah dan pembayaran ikut
+	if w := serve(t, http.MethodPut, "/api/sales/:id", "/api/sales/"+sale.ID, UpdateSale(db), map[string]interface{}{
+		"payments": []map[string]interface{}{{"method": "transfer", "amount": 20000}},
+	}); w.Code != http.StatusOK {
+		t.Fatalf("single tender: %d %s", w.Code, w.Body)
+	}
+	if w := serve(t, edits[0].method, edits[0].pattern, edits[0].path, edits[0].h, edits[0].body); w.Code >= 300 {
+		t.Fatalf("add after single tender: %d %s", w.Code, w.Body)
+	}
+	var payments []models.SalePayment
+	if err := db.Where("sale_id = ? AND is_deleted = ?", sale.ID, false).Find(&payments).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(payments) != 1 || payments[0].Amount != 3000000 {
+		t.Errorf("payments after edit = %+v, want one of Rp30.000", payments)
+	}
+}
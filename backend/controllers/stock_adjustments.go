@@ -0,0 +1,380 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/costing"
+	"shosha_mart_backend/models"
+	"shosha_mart_backend/reports"
+)
+
+var adjustmentReasons = map[string]bool{
+	models.AdjustmentDamaged:     true,
+	models.AdjustmentExpired:     true,
+	models.AdjustmentTheft:       true,
+	models.AdjustmentSample:      true,
+	models.AdjustmentInternalUse: true,
+	models.AdjustmentCorrection:  true,
+}
+
+func loadAdjustment(db *gorm.DB, id string) (models.StockAdjustment, error) {
+	var a models.StockAdjustment
+	err := db.Preload("Items", func(q *gorm.DB) *gorm.DB {
+		return q.Where("is_deleted = ?", false).Order("created_at, id")
+	}).First(&a, "id = ? AND is_deleted = ?", id, false).Error
+	return a, err
+}
+
+func adjustmentNotFound(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "stock adjustment not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// postAdjustment changes stock for every line of a, booking each at the cost
+// it leaves (or enters) with, and marks a posted.
+func postAdjustment(tx *gorm.DB, cfg config.AppConfig, a *models.StockAdjustment, user string, now time.Time) error {
+	a.LossValue = 0
+	for i := range a.Items {
+		it := &a.Items[i]
+		ref := costing.Ref{Source: models.MovementAdjustment, DocID: a.ID, DocNo: a.Number, User: user, Note: a.Reason}
+		if it.Note != "" {
+			ref.Note += ": " + it.Note
+		}
+		if it.BaseQty < 0 {
+			cost, err := costing.Issue(tx, cfg.CostMethod, a.BranchID, it.ProductID, -it.BaseQty, ref)
+			if err != nil {
+				return err
+			}
+			it.UnitCost = cost
+		} else if err := costing.Receive(tx, cfg.CostMethod, a.BranchID, it.ProductID, it.BaseQty, it.UnitCost, ref); err != nil {
+			return err
+		}
+		it.Value = it.UnitCost.TimesQty(it.BaseQty)
+		a.LossValue -= it.Value
+		if err := tx.Model(&models.StockAdjustmentItem{}).Where("id = ?", it.ID).Updates(map[string]interface{}{
+			"unit_cost": it.UnitCost,
+			"value":     it.Value,
+			"synced":    false,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	a.Status, a.PostedAt = models.AdjustmentPosted, &now
+	return tx.Model(&models.StockAdjustment{}).Where("id = ?", a.ID).Updates(map[string]interface{}{
+		"status":      a.Status,
+		"posted_at":   a.PostedAt,
+		"approved_by": a.ApprovedBy,
+		"approved_at": a.ApprovedAt,
+		"loss_value":  a.LossValue,
+		"synced":      false,
+	}).Error
+}
+
+// ListStockAdjustments returns adjustments newest first. Filters: status,
+// reason, branch_id and start/end on the creation date.
+func ListStockAdjustments(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := db.Where("is_deleted = ?", false)
+		for _, param := range []string{"status", "reason", "branch_id"} {
+			if v := c.Query(param); v != "" {
+				q = q.Where(param+" = ?", v)
+			}
+		}
+		if c.Query("start") != "" || c.Query("end") != "" {
+			start, end, ok := dateRangeQuery(c)
+			if !ok {
+				return
+			}
+			q = q.Where("created_at BETWEEN ? AND ?", start, end)
+		}
+		var adjustments []models.StockAdjustment
+		if err := q.Preload("Items", "is_deleted = ?", false).Order("created_at DESC").Find(&adjustments).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, adjustments)
+	}
+}
+
+func GetStockAdjustment(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		a, err := loadAdjustment(db, c.Param("id"))
+		if err != nil {
+			adjustmentNotFound(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, a)
+	}
+}
+
+// adjustmentLine is one product to adjust; Qty is signed and in Unit.
+type adjustmentLine struct {
+	ProductID string     `json:"product_id"`
+	Unit      string     `json:"unit"` // kosong = satuan dasar
+	Qty       models.Qty `json:"qty"`  // negatif = stok keluar
+	Note      string     `json:"note"`
+}
+
+// createAdjustment numbers and saves a with a line per input, valued at the
+// current cost, then posts it unless no manager approved it and the lines
+// together move more than the approval limit (a stays pending). Gains and
+// losses are added up apart, so a positive correction line cannot hide a
+// loss and a large write-up needs approval too.
+func createAdjustment(tx *gorm.DB, cfg config.AppConfig, a *models.StockAdjustment, lines []adjustmentLine, approved bool, now time.Time) error {
+	var gross models.Money
+	for _, in := range lines {
+		if in.Qty == 0 {
+			return badCheckout("qty must not be 0")
+		}
+		if in.Qty > 0 && a.Reason != models.AdjustmentCorrection {
+			return badCheckout("only corrections may add stock; use a negative qty to take stock out")
+		}
+		var product models.Product
+		if err := tx.First(&product, "id = ? AND is_deleted = ?", in.ProductID, false).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return badCheckout("product %s not found", in.ProductID)
+			}
+			return err
+		}
+		unit, err := findUnit(tx, product, in.Unit)
+		if err != nil {
+			return err
+		}
+		it := models.StockAdjustmentItem{
+			ID:                uuid.NewString(),
+			StockAdjustmentID: a.ID,
+			ProductID:         product.ID,
+			Unit:              unit.Name,
+			UnitFactor:        unit.Factor,
+			Qty:               in.Qty,
+			BaseQty:           in.Qty.Mul(unit.Factor),
+			UnitCost:          product.Cost,
+			Note:              strings.TrimSpace(in.Note),
+			Synced:            false,
+		}
+		it.Value = it.UnitCost.TimesQty(it.BaseQty)
+		a.LossValue -= it.Value
+		if it.Value < 0 {
+			gross -= it.Value
+		} else {
+			gross += it.Value
+		}
+		a.Items = append(a.Items, it)
+	}
+
+	var err error
+	if a.Number, err = nextDocumentNumber(tx, &models.StockAdjustment{}, "ADJ", a.BranchID, now); err != nil {
+		return err
+	}
+	items := a.Items
+	a.Items = nil
+	if err := tx.Create(a).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(&items).Error; err != nil {
+		return err
+	}
+	a.Items = items
+
+	if gross > models.FromRupiah(cfg.AdjustmentApprovalLimit) && !approved {
+		return nil
+	}
+	if approved {
+		a.ApprovedBy, a.ApprovedAt = a.CreatedBy, &now
+	}
+	return postAdjustment(tx, cfg, a, a.CreatedBy, now)
+}
+
+// CreateStockAdjustment records an adjustment at the local branch. Item qty
+// is signed (negative takes stock out); only corrections may add stock. An
+// adjustment whose value at current cost (stock out plus stock in) is above
+// the approval limit waits as pending (202) unless a valid manager_pin is
+// sent; otherwise it is posted.
+func CreateStockAdjustment(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			Reason     string           `json:"reason"`
+			Note       string           `json:"note"`
+			CreatedBy  string           `json:"created_by"`
+			ManagerPIN string           `json:"manager_pin"`
+			Items      []adjustmentLine `json:"items"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if !adjustmentReasons[payload.Reason] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reason must be damaged, expired, theft, sample, internal_use or correction"})
+			return
+		}
+		if len(payload.Items) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "items are required"})
+			return
+		}
+		approved := managerApproved(cfg, payload.ManagerPIN)
+		if payload.ManagerPIN != "" && !approved {
+			c.JSON(http.StatusForbidden, gin.H{"error": "invalid manager pin"})
+			return
+		}
+
+		now := time.Now()
+		user := posUser(c, payload.CreatedBy)
+		a := models.StockAdjustment{
+			ID:        uuid.NewString(),
+			BranchID:  cfg.BranchID,
+			Reason:    payload.Reason,
+			Status:    models.AdjustmentPending,
+			Note:      payload.Note,
+			CreatedBy: user,
+			Synced:    false,
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return createAdjustment(tx, cfg, &a, payload.Items, approved, now)
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		if a.Status == models.AdjustmentPending {
+			c.JSON(http.StatusAccepted, a)
+			return
+		}
+		c.JSON(http.StatusCreated, a)
+	}
+}
+
+// decideAdjustment loads a pending adjustment of the local branch for a
+// manager to approve or reject.
+func decideAdjustment(tx *gorm.DB, cfg config.AppConfig, id, pin string) (models.StockAdjustment, error) {
+	if !managerApproved(cfg, pin) {
+		return models.StockAdjustment{}, &checkoutError{status: http.StatusForbidden, msg: "a valid manager pin is required"}
+	}
+	a, err := loadAdjustment(tx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return a, &checkoutError{status: http.StatusNotFound, msg: "stock adjustment not found"}
+		}
+		return a, err
+	}
+	if a.BranchID != cfg.BranchID {
+		return a, &checkoutError{status: http.StatusConflict, msg: "stock adjustment " + a.Number + " belongs to another branch"}
+	}
+	if a.Status != models.AdjustmentPending {
+		return a, &checkoutError{status: http.StatusConflict, msg: "stock adjustment " + a.Number + " is " + a.Status}
+	}
+	return a, nil
+}
+
+type adjustmentDecision struct {
+	ManagerPIN string `json:"manager_pin"`
+	ApprovedBy string `json:"approved_by"`
+}
+
+// ApproveStockAdjustment posts a pending adjustment with a manager's PIN.
+// Costs are taken when it is posted, so the loss may differ from the
+// estimate.
+func ApproveStockAdjustment(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload adjustmentDecision
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		var a models.StockAdjustment
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if a, err = decideAdjustment(tx, cfg, c.Param("id"), payload.ManagerPIN); err != nil {
+				return err
+			}
+			now := time.Now()
+			a.ApprovedBy, a.ApprovedAt = posUser(c, payload.ApprovedBy), &now
+			return postAdjustment(tx, cfg, &a, a.ApprovedBy, now)
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, a)
+	}
+}
+
+// RejectStockAdjustment closes a pending adjustment without touching stock.
+func RejectStockAdjustment(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload adjustmentDecision
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		var a models.StockAdjustment
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if a, err = decideAdjustment(tx, cfg, c.Param("id"), payload.ManagerPIN); err != nil {
+				return err
+			}
+			now := time.Now()
+			a.Status, a.ApprovedBy, a.ApprovedAt = models.AdjustmentRejected, posUser(c, payload.ApprovedBy), &now
+			return tx.Model(&models.StockAdjustment{}).Where("id = ?", a.ID).Updates(map[string]interface{}{
+				"status":      a.Status,
+				"approved_by": a.ApprovedBy,
+				"approved_at": a.ApprovedAt,
+				"synced":      false,
+			}).Error
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, a)
+	}
+}
+
+// StockAdjustmentReport returns the adjustments posted between ?start and
+// ?end (default the last 7 days), optionally for ?branch_id, with the loss
+// per reason; ?format=xlsx downloads it as Excel.
+func StockAdjustmentReport(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startStr := c.DefaultQuery("start", time.Now().AddDate(0, 0, -7).Format("2006-01-02"))
+		endStr := c.DefaultQuery("end", time.Now().Format("2006-01-02"))
+
+		start, err := time.Parse("2006-01-02", startStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start date"})
+			return
+		}
+		end, err := time.Parse("2006-01-02", endStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end date"})
+			return
+		}
+		end = end.Add(24*time.Hour - time.Nanosecond)
+
+		rep, err := reports.StockAdjustments(db, c.Query("branch_id"), start, end)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if c.Query("format") == "xlsx" {
+			path, err := reports.GenerateAdjustmentReport(cfg, rep)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.FileAttachment(path, filepath.Base(path))
+			return
+		}
+		c.JSON(http.StatusOK, rep)
+	}
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/costing"
+	"shosha_mart_backend/models"
+	"shosha_mart_backend/reports"
+)
+
+// posUser is the operator named in the X-POS-User header, or fallback (a
+// name sent in the payload) when the client does not send one.
+func posUser(c *gin.Context, fallback string) string {
+	if u := strings.TrimSpace(c.GetHeader("X-POS-User")); u != "" {
+		return u
+	}
+	return strings.TrimSpace(fallback)
+}
+
+// ListProductMovements returns a product's stock movements at a branch
+// (?branch_id, default the local branch), newest first, optionally filtered by
+// ?source and ?start/?end. ?page/?size switch to a paged response.
+func ListProductMovements(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var product models.Product
+		if err := db.First(&product, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+			productError(c, err)
+			return
+		}
+		pageNo, size, paged, ok := pageQuery(c)
+		if !ok {
+			return
+		}
+		start, end, ok := dateRangeQuery(c)
+		if !ok {
+			return
+		}
+
+		q := db.Model(&models.StockMovement{}).
+			Where("product_id = ? AND branch_id = ? AND is_deleted = ?", product.ID, chooseBranch(c.Query("branch_id"), cfg.BranchID), false).
+			Where("created_at BETWEEN ? AND ?", start, end)
+		if v := c.Query("source"); v != "" {
+			q = q.Where("source = ?", v)
+		}
+
+		var movements []models.StockMovement
+		if !paged {
+			if err := q.Order("created_at DESC").Order("id").Find(&movements).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, movements)
+			return
+		}
+		var total int64
+		if err := q.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := q.Order("created_at DESC").Order("id").Limit(size).Offset((pageNo - 1) * size).Find(&movements).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, newPage(movements, total, pageNo, size))
+	}
+}
+
+// StockCardReport exports a product's stock card (opening balance, movements
+// and closing balance) for ?start/?end at ?branch_id as Excel.
+func StockCardReport(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var product models.Product
+		if err := db.First(&product, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+			productError(c, err)
+			return
+		}
+		start, end, ok := dateRangeQuery(c)
+		if !ok {
+			return
+		}
+		branchID := chooseBranch(c.Query("branch_id"), cfg.BranchID)
+		onHand, err := costing.OnHand(db, branchID, product.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		card, err := reports.BuildStockCard(db, product, branchID, onHand, start, end)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		path, err := reports.GenerateStockCard(cfg, card)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.FileAttachment(path, filepath.Base(path))
+	}
+}
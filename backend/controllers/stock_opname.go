@@ -1,7 +1,10 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -11,61 +14,95 @@ import (
 	"shosha_mart_backend/models"
 )
 
+// CreateStockOpname records a stock take in one request: it starts a
+// counting session for the listed products, enters each line as a count and
+// finalizes it, so stock moves exactly as with /api/stock-opname/sessions.
+// Counts may be given in any configured unit of the product; several lines
+// of one product (e.g. 3 "sak" and 12.5 kg) add up. The system quantity sent
+// by older clients is ignored; the session takes it from the branch's stock.
+//
+// Deprecated: use the counting session endpoints.
 func CreateStockOpname(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var payload struct {
 			PerformedBy string `json:"performedBy"`
 			Note        string `json:"note"`
 			Items       []struct {
-				ProductID   string `json:"productId"`
-				SystemQty   int    `json:"systemQty"`
-				PhysicalQty int    `json:"physicalQty"`
+				ProductID   string      `json:"productId"`
+				Unit        string      `json:"unit"` // kosong = satuan dasar
+				PhysicalQty *models.Qty `json:"physicalQty"`
+				// nama field yang dikirim renderer
+				ProductIDSnake string      `json:"product_id"`
+				QtyPhysical    *models.Qty `json:"qty_physical"`
 			} `json:"items"`
 		}
 		if err := c.ShouldBindJSON(&payload); err != nil || len(payload.Items) == 0 {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 			return
 		}
+		for i := range payload.Items {
+			it := &payload.Items[i]
+			if it.ProductID == "" {
+				it.ProductID = it.ProductIDSnake
+			}
+			if it.PhysicalQty == nil {
+				it.PhysicalQty = it.QtyPhysical
+			}
+			if it.PhysicalQty == nil || *it.PhysicalQty < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "physical qty must be >= 0"})
+				return
+			}
+		}
+		c.Header("Deprecation", "true")
+		c.Header("Link", `</api/stock-opname/sessions>; rel="successor-version"`)
 
+		now := time.Now()
+		user := posUser(c, strings.TrimSpace(payload.PerformedBy))
 		opname := models.StockOpname{
 			ID:          uuid.NewString(),
 			BranchID:    cfg.BranchID,
-			PerformedBy: payload.PerformedBy,
+			Status:      models.OpnameCounting,
+			PerformedBy: user,
 			Note:        payload.Note,
 			Synced:      false,
 		}
-
 		err := db.Transaction(func(tx *gorm.DB) error {
-			if err := tx.Create(&opname).Error; err != nil {
-				return err
-			}
+			products := map[string]models.Product{}
+			var ids []string
 			for _, item := range payload.Items {
-				detail := models.StockOpnameItem{
-					ID:            uuid.NewString(),
-					StockOpnameID: opname.ID,
-					ProductID:     item.ProductID,
-					SystemQty:     item.SystemQty,
-					PhysicalQty:   item.PhysicalQty,
-					Synced:        false,
+				id := item.ProductID
+				if _, ok := products[id]; ok {
+					continue
 				}
-				if err := tx.Create(&detail).Error; err != nil {
+				var product models.Product
+				if err := tx.First(&product, "id = ? AND is_deleted = ?", id, false).Error; err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return badCheckout("product not found: %s", id)
+					}
 					return err
 				}
-				// Bring stock in line with physical count.
-				if err := tx.Model(&models.Product{}).
-					Where("id = ?", item.ProductID).
-					UpdateColumn("stock", item.PhysicalQty).Error; err != nil {
+				products[id] = product
+				ids = append(ids, id)
+			}
+			if err := startOpname(tx, cfg, &opname, ids, now); err != nil {
+				return err
+			}
+			for _, item := range payload.Items {
+				count := models.StockOpnameCount{Qty: *item.PhysicalQty, Unit: item.Unit, CountedBy: user}
+				if _, err := addOpnameCount(tx, opname.ID, products[item.ProductID], &count); err != nil {
 					return err
 				}
 			}
-			return nil
+			var err error
+			if opname, err = loadOpname(tx, opname.ID); err != nil {
+				return err
+			}
+			return finalizeOpname(tx, cfg, &opname, false, user, time.Now())
 		})
-
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondCheckoutError(c, err)
 			return
 		}
-
-		c.JSON(http.StatusCreated, opname)
+		c.JSON(http.StatusCreated, newOpnameSession(opname))
 	}
 }
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/costing"
+	"shosha_mart_backend/models"
+)
+
+func TestCreateStockOpnameRunsASession(t *testing.T) {
+	cfg := config.AppConfig{BranchID: "b1", CostMethod: "average"}
+	tests := []struct {
+		name       string
+		open       bool // a counting session is already running
+		items      []map[string]interface{}
+		wantStatus int
+		want       map[string]float64
+	}{
+		{
+			name: "renderer payload",
+			items: []map[string]interface{}{
+				{"product_id": "p1", "qty_system": 10, "qty_physical": 8},
+				{"product_id": "p2", "qty_system": 5, "qty_physical": 5},
+			},
+			wantStatus: http.StatusCreated,
+			want:       map[string]float64{"p1": 8, "p2": 5, "p3": 4},
+		},
+		{
+			name: "legacy payload, lines of one product add up",
+			items: []map[string]interface{}{
+				{"productId": "p1", "physicalQty": 2, "unit": "sak"},
+				{"productId": "p1", "physicalQty": 1.5},
+			},
+			wantStatus: http.StatusCreated,
+			want:       map[string]float64{"p1": 11.5, "p2": 5, "p3": 4},
+		},
+		{
+			name:       "unknown product",
+			items:      []map[string]interface{}{{"product_id": "nope", "qty_physical": 1}},
+			wantStatus: http.StatusBadRequest,
+			want:       map[string]float64{"p1": 10},
+		},
+		{
+			name:       "another session is counting",
+			open:       true,
+			items:      []map[string]interface{}{{"product_id": "p1", "qty_physical": 1}},
+			wantStatus: http.StatusConflict,
+			want:       map[string]float64{"p1": 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t)
+			for _, p := range []models.Product{
+				{ID: "p1", Name: "Beras", Unit: "kg", Stock: models.Units(10)},
+				{ID: "p2", Name: "Gula", Unit: "kg", Stock: models.Units(5)},
+				{ID: "p3", Name: "Kopi", Unit: "pcs", Stock: models.Units(4)},
+			} {
+				if err := db.Create(&p).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := db.Create(&models.ProductUnit{ID: "u1", ProductID: "p1", Name: "sak", Factor: models.Units(5)}).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := costing.SyncProductStock(db, cfg.BranchID); err != nil {
+				t.Fatal(err)
+			}
+			if tt.open {
+				if err := db.Create(&models.StockOpname{ID: "so0", BranchID: "b1", Status: models.OpnameCounting}).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			w := serve(t, http.MethodPost, "/api/stock-opname", "/api/stock-opname", CreateStockOpname(db, cfg), map[string]interface{}{
+				"note": "opname bulanan", "items": tt.items,
+			})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d %s, want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			if w.Code == http.StatusCreated {
+				var got opnameSession
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatal(err)
+				}
+				if got.Status != models.OpnameFinalized || got.Number == "" || got.Uncounted != 0 {
+					t.Errorf("opname %+v, want a finalized, numbered session with everything counted", got.StockOpname)
+				}
+			}
+			for id, want := range tt.want {
+				got, err := costing.OnHand(db, cfg.BranchID, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != models.QtyFromFloat(want) {
+					t.Errorf("stock of %s = %s, want %v", id, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/models"
+)
+
+type supplierPayload struct {
+	Code         string `json:"code"`
+	Name         string `json:"name"`
+	ContactName  string `json:"contact_name"`
+	Phone        string `json:"phone"`
+	Email        string `json:"email"`
+	Address      string `json:"address"`
+	NPWP         string `json:"npwp"`
+	PaymentTerms int    `json:"payment_terms"`
+	Note         string `json:"note"`
+}
+
+func (p supplierPayload) validate() string {
+	if strings.TrimSpace(p.Name) == "" {
+		return "name is required"
+	}
+	if p.PaymentTerms < 0 {
+		return "payment_terms must be >= 0"
+	}
+	return ""
+}
+
+func (p supplierPayload) apply(s *models.Supplier) {
+	s.Code = strings.TrimSpace(p.Code)
+	s.Name = strings.TrimSpace(p.Name)
+	s.ContactName = p.ContactName
+	s.Phone = p.Phone
+	s.Email = strings.TrimSpace(p.Email)
+	s.Address = p.Address
+	s.NPWP = p.NPWP
+	s.PaymentTerms = p.PaymentTerms
+	s.Note = p.Note
+	s.Synced = false
+}
+
+// ListSuppliers returns live suppliers, optionally filtered by ?q on name,
+// code or phone.
+func ListSuppliers(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := db.Where("is_deleted = ?", false)
+		if s := strings.TrimSpace(c.Query("q")); s != "" {
+			like := "%" + strings.ToLower(s) + "%"
+			q = q.Where("LOWER(name) LIKE ? OR LOWER(code) LIKE ? OR phone LIKE ?", like, like, like)
+		}
+		var suppliers []models.Supplier
+		if err := q.Order("name").Find(&suppliers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, suppliers)
+	}
+}
+
+func GetSupplier(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var supplier models.Supplier
+		if err := db.First(&supplier, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+			supplierNotFound(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, supplier)
+	}
+}
+
+func CreateSupplier(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload supplierPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if msg := payload.validate(); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		supplier := models.Supplier{ID: uuid.NewString()}
+		payload.apply(&supplier)
+		if err := db.Create(&supplier).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, supplier)
+	}
+}
+
+// UpdateSupplier replaces the supplier's details.
+func UpdateSupplier(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload supplierPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if msg := payload.validate(); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		var supplier models.Supplier
+		if err := db.First(&supplier, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+			supplierNotFound(c, err)
+			return
+		}
+		payload.apply(&supplier)
+		if err := db.Save(&supplier).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, supplier)
+	}
+}
+
+// DeleteSupplier tombstones a supplier. Suppliers with purchase orders that
+// are still open cannot be removed.
+func DeleteSupplier(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var supplier models.Supplier
+		if err := db.First(&supplier, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+			supplierNotFound(c, err)
+			return
+		}
+		var open int64
+		if err := db.Model(&models.PurchaseOrder{}).
+			Where("supplier_id = ? AND is_deleted = ? AND status IN ?", supplier.ID, false,
+				[]string{models.PurchaseOrderSent, models.PurchaseOrderPartial}).
+			Count(&open).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if open > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "supplier has open purchase orders"})
+			return
+		}
+		updates := map[string]interface{}{
+			"is_deleted": true,
+			"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+			"synced":     false,
+		}
+		if err := db.Model(&supplier).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "supplier deleted"})
+	}
+}
+
+func supplierNotFound(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "supplier not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -15,7 +15,7 @@ import (
 func SyncSummary(db *gorm.DB, cfg config.AppConfig, worker *syncsvc.Worker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		status, lastErr, _ := worker.Status()
-		summary, err := syncsvc.Build(db, cfg.DBPath, status, lastErr)
+		summary, err := syncsvc.Build(db, cfg.DBPath, cfg.BranchID, status, lastErr)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -46,6 +46,10 @@ func PruneDeleted(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.SalePayment{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.Sale{}).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -58,6 +62,26 @@ func PruneDeleted(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.ProductBarcode{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.ProductUnit{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.CostLayer{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.ProductPriceHistory{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.ScheduledPriceChange{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.Product{}).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -66,6 +90,98 @@ func PruneDeleted(db *gorm.DB) gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.Promotion{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.TaxRate{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.Customer{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.CashMovement{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.CashSession{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.Category{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.Brand{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.Supplier{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.PurchaseOrder{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.PurchaseOrderItem{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.GoodsReceipt{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.GoodsReceiptItem{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockTransfer{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockTransferItem{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockMovement{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.ReorderLevel{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockBatch{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockBatchAllocation{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockAdjustment{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockAdjustmentItem{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockOpnameCount{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.BranchStock{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.BranchPrice{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{"status": "pruned"})
 	}
 }
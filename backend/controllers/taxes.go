@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/models"
+)
+
+// taxRateSet resolves the tax rate that applies to a product.
+type taxRateSet struct {
+	byID map[string]models.TaxRate
+	def  *models.TaxRate
+}
+
+func loadTaxRates(db *gorm.DB) (taxRateSet, error) {
+	var rates []models.TaxRate
+	if err := db.Where("is_deleted = ?", false).Find(&rates).Error; err != nil {
+		return taxRateSet{}, err
+	}
+	set := taxRateSet{byID: make(map[string]models.TaxRate, len(rates))}
+	for i, r := range rates {
+		set.byID[r.ID] = r
+		if r.IsDefault && set.def == nil {
+			set.def = &rates[i]
+		}
+	}
+	return set, nil
+}
+
+// forProduct returns the product's own rate, else the default rate.
+func (s taxRateSet) forProduct(p models.Product) (models.TaxRate, bool) {
+	if r, ok := s.byID[p.TaxRateID]; ok {
+		return r, true
+	}
+	if s.def != nil {
+		return *s.def, true
+	}
+	return models.TaxRate{}, false
+}
+
+// stampTax snapshots the tax settings of rate onto a sale item.
+func stampTax(item *models.SaleItem, rate models.TaxRate) {
+	item.TaxRateID = rate.ID
+	item.TaxCode = rate.Code
+	item.TaxRate = rate.Rate
+	item.TaxInclusive = rate.Inclusive
+}
+
+type taxRatePayload struct {
+	Code      string  `json:"code"`
+	Name      string  `json:"name"`
+	Rate      float64 `json:"rate"`
+	Inclusive bool    `json:"inclusive"`
+	IsDefault bool    `json:"is_default"`
+}
+
+// clearDefaultTaxRate unsets the default flag on every rate except keepID.
+func clearDefaultTaxRate(tx *gorm.DB, keepID string) error {
+	return tx.Model(&models.TaxRate{}).
+		Where("is_default = ? AND id <> ?", true, keepID).
+		Updates(map[string]interface{}{"is_default": false, "synced": false}).Error
+}
+
+func ListTaxRates(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var rates []models.TaxRate
+		if err := db.Where("is_deleted = ?", false).Order("code").Find(&rates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, rates)
+	}
+}
+
+// CreateTaxRate adds a tax rate; marking it default unsets the previous default.
+func CreateTaxRate(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload taxRatePayload
+		if err := c.ShouldBindJSON(&payload); err != nil || payload.Code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if payload.Rate < 0 || payload.Rate > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rate must be between 0 and 100"})
+			return
+		}
+		rate := models.TaxRate{
+			ID:        uuid.NewString(),
+			Code:      payload.Code,
+			Name:      payload.Name,
+			Rate:      payload.Rate,
+			Inclusive: payload.Inclusive,
+			IsDefault: payload.IsDefault,
+			Synced:    false,
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if rate.IsDefault {
+				if err := clearDefaultTaxRate(tx, rate.ID); err != nil {
+					return err
+				}
+			}
+			return tx.Create(&rate).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, rate)
+	}
+}
+
+// UpdateTaxRate changes a tax rate. Sales keep the rate snapshotted at checkout.
+func UpdateTaxRate(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var payload taxRatePayload
+		if err := c.ShouldBindJSON(&payload); err != nil || payload.Code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if payload.Rate < 0 || payload.Rate > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rate must be between 0 and 100"})
+			return
+		}
+
+		var rate models.TaxRate
+		if err := db.First(&rate, "id = ? AND is_deleted = ?", id, false).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tax rate not found"})
+			return
+		}
+
+		rate.Code = payload.Code
+		rate.Name = payload.Name
+		rate.Rate = payload.Rate
+		rate.Inclusive = payload.Inclusive
+		rate.IsDefault = payload.IsDefault
+		rate.Synced = false // Mark as unsynced when updated
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if rate.IsDefault {
+				if err := clearDefaultTaxRate(tx, rate.ID); err != nil {
+					return err
+				}
+			}
+			return tx.Save(&rate).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, rate)
+	}
+}
+
+// DeleteTaxRate tombstones a tax rate; products using it fall back to the default.
+func DeleteTaxRate(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		var rate models.TaxRate
+		if err := db.First(&rate, "id = ?", id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tax rate not found"})
+			return
+		}
+
+		updates := map[string]interface{}{
+			"is_deleted": true,
+			"is_default": false,
+			"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+			"synced":     false,
+		}
+		if err := db.Model(&rate).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "tax rate deleted"})
+	}
+}
@@ -0,0 +1,361 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/costing"
+	"shosha_mart_backend/models"
+)
+
+// transferLineInput sets the quantity (and note) of one transfer item when
+// dispatching or receiving; items left out keep their expected quantity.
+type transferLineInput struct {
+	ID   string     `json:"id"`
+	Qty  models.Qty `json:"qty"`
+	Note string     `json:"note"`
+}
+
+func loadTransfer(db *gorm.DB, id string) (models.StockTransfer, error) {
+	var t models.StockTransfer
+	err := db.Preload("Items", func(q *gorm.DB) *gorm.DB {
+		return q.Where("is_deleted = ?", false).Order("created_at, id")
+	}).First(&t, "id = ? AND is_deleted = ?", id, false).Error
+	return t, err
+}
+
+func transferNotFound(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "transfer not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// transferLines indexes the given lines by item id, rejecting unknown items
+// and negative quantities.
+func transferLines(t models.StockTransfer, lines []transferLineInput) (map[string]transferLineInput, error) {
+	known := map[string]bool{}
+	for _, it := range t.Items {
+		known[it.ID] = true
+	}
+	out := map[string]transferLineInput{}
+	for _, l := range lines {
+		if !known[l.ID] {
+			return nil, badCheckout("item %s is not on transfer %s", l.ID, t.Number)
+		}
+		if l.Qty < 0 {
+			return nil, badCheckout("qty must be >= 0")
+		}
+		out[l.ID] = l
+	}
+	return out, nil
+}
+
+// ListTransfers returns transfers newest first. Filters: status, and
+// direction=incoming|outgoing for transfers to or from this branch.
+func ListTransfers(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := db.Where("is_deleted = ?", false)
+		if s := c.Query("status"); s != "" {
+			q = q.Where("status = ?", s)
+		}
+		switch c.Query("direction") {
+		case "":
+		case "incoming":
+			q = q.Where("to_branch_id = ?", cfg.BranchID)
+		case "outgoing":
+			q = q.Where("from_branch_id = ?", cfg.BranchID)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "direction must be incoming or outgoing"})
+			return
+		}
+		var transfers []models.StockTransfer
+		if err := q.Preload("Items", "is_deleted = ?", false).Order("requested_at DESC").Find(&transfers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, transfers)
+	}
+}
+
+func GetTransfer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, err := loadTransfer(db, c.Param("id"))
+		if err != nil {
+			transferNotFound(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, t)
+	}
+}
+
+// CreateTransfer requests goods from another branch. to_branch_id defaults
+// to this branch. No stock moves until the source dispatches.
+func CreateTransfer(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			FromBranchID string `json:"from_branch_id"`
+			ToBranchID   string `json:"to_branch_id"`
+			RequestedBy  string `json:"requested_by"`
+			Note         string `json:"note"`
+			Items        []struct {
+				ProductID string     `json:"product_id"`
+				Unit      string     `json:"unit"`
+				Qty       models.Qty `json:"qty"`
+			} `json:"items"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if len(payload.Items) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "items are required"})
+			return
+		}
+		now := time.Now()
+		t := models.StockTransfer{
+			ID:           uuid.NewString(),
+			FromBranchID: payload.FromBranchID,
+			ToBranchID:   chooseBranch(payload.ToBranchID, cfg.BranchID),
+			Status:       models.TransferRequested,
+			Note:         payload.Note,
+			RequestedBy:  strings.TrimSpace(payload.RequestedBy),
+			RequestedAt:  now,
+			Synced:       false,
+		}
+		if t.FromBranchID == "" || t.FromBranchID == t.ToBranchID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from_branch_id must be another branch"})
+			return
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := checkBranch(tx, cfg, t.FromBranchID); err != nil {
+				return err
+			}
+			if err := checkBranch(tx, cfg, t.ToBranchID); err != nil {
+				return err
+			}
+			seen := map[string]bool{}
+			for _, in := range payload.Items {
+				if in.Qty <= 0 {
+					return badCheckout("qty must be greater than 0")
+				}
+				var product models.Product
+				if err := tx.First(&product, "id = ? AND is_deleted = ?", in.ProductID, false).Error; err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return badCheckout("product %s not found", in.ProductID)
+					}
+					return err
+				}
+				unit, err := findUnit(tx, product, in.Unit)
+				if err != nil {
+					return err
+				}
+				key := product.ID + "|" + strings.ToLower(unit.Name)
+				if seen[key] {
+					return badCheckout("%s is requested more than once in the same unit", product.Name)
+				}
+				seen[key] = true
+				t.Items = append(t.Items, models.StockTransferItem{
+					ID:              uuid.NewString(),
+					StockTransferID: t.ID,
+					ProductID:       product.ID,
+					Unit:            unit.Name,
+					UnitFactor:      unit.Factor,
+					RequestedQty:    in.Qty,
+					Synced:          false,
+				})
+			}
+			var err error
+			if t.Number, err = nextDocumentNumber(tx, &models.StockTransfer{}, "TR", cfg.BranchID, now); err != nil {
+				return err
+			}
+			return tx.Create(&t).Error
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, t)
+	}
+}
+
+// DispatchTransfer sends a requested transfer from this branch. Each item
+// leaves stock with the dispatched quantity (default: the requested one; 0
+// when the item cannot be sent) at its current cost.
+func DispatchTransfer(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			DispatchedBy string              `json:"dispatched_by"`
+			Items        []transferLineInput `json:"items"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		t, err := loadTransfer(db, c.Param("id"))
+		if err != nil {
+			transferNotFound(c, err)
+			return
+		}
+		if t.Status != models.TransferRequested {
+			c.JSON(http.StatusConflict, gin.H{"error": "transfer is " + t.Status + "; only requested transfers can be dispatched"})
+			return
+		}
+		if t.FromBranchID != cfg.BranchID {
+			c.JSON(http.StatusConflict, gin.H{"error": "transfer must be dispatched by the source branch"})
+			return
+		}
+		lines, err := transferLines(t, payload.Items)
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		now := time.Now()
+		err = db.Transaction(func(tx *gorm.DB) error {
+			total := models.Qty(0)
+			for i := range t.Items {
+				it := &t.Items[i]
+				it.DispatchedQty = it.RequestedQty
+				if l, ok := lines[it.ID]; ok {
+					it.DispatchedQty = l.Qty
+				}
+				total += it.DispatchedQty
+				cost, err := costing.Issue(tx, cfg.CostMethod, t.FromBranchID, it.ProductID, it.DispatchedQty.Mul(it.UnitFactor),
+					costing.Ref{Source: models.MovementTransferOut, DocID: t.ID, DocNo: t.Number, User: posUser(c, payload.DispatchedBy)})
+				if err != nil {
+					return err
+				}
+				it.UnitCost = cost
+				if err := tx.Model(&models.StockTransferItem{}).Where("id = ?", it.ID).Updates(map[string]interface{}{
+					"dispatched_qty": it.DispatchedQty,
+					"unit_cost":      it.UnitCost,
+					"synced":         false,
+				}).Error; err != nil {
+					return err
+				}
+			}
+			if total == 0 {
+				return badCheckout("nothing to dispatch; cancel the transfer instead")
+			}
+			t.Status, t.DispatchedBy, t.DispatchedAt = models.TransferDispatched, strings.TrimSpace(payload.DispatchedBy), &now
+			return tx.Model(&models.StockTransfer{}).Where("id = ?", t.ID).Updates(map[string]interface{}{
+				"status":        t.Status,
+				"dispatched_by": t.DispatchedBy,
+				"dispatched_at": now,
+				"synced":        false,
+			}).Error
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, t)
+	}
+}
+
+// ReceiveTransfer books a dispatched transfer into this branch's stock. Items
+// default to the dispatched quantity; a different quantity is recorded as a
+// discrepancy (with an optional note) and only what arrived is added, at the
+// cost it left the source with.
+func ReceiveTransfer(db *gorm.DB, cfg config.AppConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			ReceivedBy string              `json:"received_by"`
+			Items      []transferLineInput `json:"items"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		t, err := loadTransfer(db, c.Param("id"))
+		if err != nil {
+			transferNotFound(c, err)
+			return
+		}
+		if t.Status != models.TransferDispatched {
+			c.JSON(http.StatusConflict, gin.H{"error": "transfer is " + t.Status + "; only dispatched transfers can be received"})
+			return
+		}
+		if t.ToBranchID != cfg.BranchID {
+			c.JSON(http.StatusConflict, gin.H{"error": "transfer must be received by the destination branch"})
+			return
+		}
+		lines, err := transferLines(t, payload.Items)
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		now := time.Now()
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for i := range t.Items {
+				it := &t.Items[i]
+				it.ReceivedQty = it.DispatchedQty
+				if l, ok := lines[it.ID]; ok {
+					it.ReceivedQty = l.Qty
+					it.DiscrepancyNote = strings.TrimSpace(l.Note)
+				}
+				it.Discrepancy = it.DispatchedQty - it.ReceivedQty
+				if it.Discrepancy != 0 {
+					t.HasDiscrepancy = true
+				}
+				if err := costing.Receive(tx, cfg.CostMethod, t.ToBranchID, it.ProductID, it.ReceivedQty.Mul(it.UnitFactor), it.UnitCost,
+					costing.Ref{Source: models.MovementTransferIn, DocID: t.ID, DocNo: t.Number, User: posUser(c, payload.ReceivedBy), Note: it.DiscrepancyNote}); err != nil {
+					return err
+				}
+				if err := tx.Model(&models.StockTransferItem{}).Where("id = ?", it.ID).Updates(map[string]interface{}{
+					"received_qty":     it.ReceivedQty,
+					"discrepancy":      it.Discrepancy,
+					"discrepancy_note": it.DiscrepancyNote,
+					"synced":           false,
+				}).Error; err != nil {
+					return err
+				}
+			}
+			t.Status, t.ReceivedBy, t.ReceivedAt = models.TransferReceived, strings.TrimSpace(payload.ReceivedBy), &now
+			return tx.Model(&models.StockTransfer{}).Where("id = ?", t.ID).Updates(map[string]interface{}{
+				"status":          t.Status,
+				"received_by":     t.ReceivedBy,
+				"received_at":     now,
+				"has_discrepancy": t.HasDiscrepancy,
+				"synced":          false,
+			}).Error
+		})
+		if err != nil {
+			respondCheckoutError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, t)
+	}
+}
+
+// CancelTransfer cancels a transfer that has not been dispatched yet.
+func CancelTransfer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, err := loadTransfer(db, c.Param("id"))
+		if err != nil {
+			transferNotFound(c, err)
+			return
+		}
+		if t.Status != models.TransferRequested {
+			c.JSON(http.StatusConflict, gin.H{"error": "only requested transfers can be cancelled"})
+			return
+		}
+		if err := db.Model(&models.StockTransfer{}).Where("id = ?", t.ID).Updates(map[string]interface{}{
+			"status": models.TransferCancelled,
+			"synced": false,
+		}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		t.Status = models.TransferCancelled
+		c.JSON(http.StatusOK, t)
+	}
+}
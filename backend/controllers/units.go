@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/models"
+)
+
+// unitInput is a product unit as sent in product payloads.
+type unitInput struct {
+	Name   string       `json:"name"`
+	Factor models.Qty   `json:"factor"`
+	Price  models.Money `json:"price"`
+}
+
+// liveUnits preloads the units that have not been removed.
+func liveUnits(db *gorm.DB) *gorm.DB {
+	return db.Preload("Units", "is_deleted = ?", false)
+}
+
+// prepareUnits validates the extra units of a product whose base unit is
+// base: names must be unique and differ from the base unit, factors > 0.
+func prepareUnits(base string, units []unitInput) ([]unitInput, error) {
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(base)): true}
+	out := make([]unitInput, 0, len(units))
+	for _, u := range units {
+		u.Name = strings.TrimSpace(u.Name)
+		if u.Name == "" {
+			return nil, badCheckout("unit name is required")
+		}
+		key := strings.ToLower(u.Name)
+		if seen[key] {
+			return nil, badCheckout("unit %s is listed twice or is the base unit", u.Name)
+		}
+		if u.Factor <= 0 {
+			return nil, badCheckout("unit %s: factor must be > 0", u.Name)
+		}
+		if u.Price < 0 {
+			return nil, badCheckout("unit %s: price must be >= 0", u.Name)
+		}
+		seen[key] = true
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+// replaceUnits makes units the live extra units of a product, matching
+// existing ones by name: matches are updated, the rest tombstoned or created.
+func replaceUnits(tx *gorm.DB, productID string, units []unitInput) error {
+	var current []models.ProductUnit
+	if err := tx.Where("product_id = ? AND is_deleted = ?", productID, false).Find(&current).Error; err != nil {
+		return err
+	}
+	byName := map[string]models.ProductUnit{}
+	for _, u := range current {
+		byName[strings.ToLower(u.Name)] = u
+	}
+	for _, in := range units {
+		key := strings.ToLower(in.Name)
+		if old, ok := byName[key]; ok {
+			delete(byName, key)
+			if old.Name == in.Name && old.Factor == in.Factor && old.Price == in.Price {
+				continue
+			}
+			if err := tx.Model(&models.ProductUnit{}).Where("id = ?", old.ID).Updates(map[string]interface{}{
+				"name":   in.Name,
+				"factor": in.Factor,
+				"price":  in.Price,
+				"synced": false,
+			}).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Create(&models.ProductUnit{
+			ID:        uuid.NewString(),
+			ProductID: productID,
+			Name:      in.Name,
+			Factor:    in.Factor,
+			Price:     in.Price,
+			Synced:    false,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	for _, old := range byName {
+		if err := tx.Model(&models.ProductUnit{}).Where("id = ?", old.ID).Updates(map[string]interface{}{
+			"is_deleted": true,
+			"deleted_at": gorm.Expr("CURRENT_TIMESTAMP"),
+			"synced":     false,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saleUnit is the unit a sale line or count is expressed in.
+type saleUnit struct {
+	Name   string       // kosong = satuan dasar
+	Factor models.Qty   // satuan dasar per satuan ini
+	Price  models.Money // harga khusus satuan ini, 0 = dari harga dasar
+}
+
+// baseUnit is the product's own unit.
+var baseUnit = saleUnit{Factor: models.Units(1)}
+
+// findUnit resolves name to one of the product's units. An empty name or
+// the product's base unit name is the base unit.
+func findUnit(db *gorm.DB, product models.Product, name string) (saleUnit, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.EqualFold(name, product.Unit) {
+		return baseUnit, nil
+	}
+	var u models.ProductUnit
+	err := db.Where("product_id = ? AND LOWER(name) = ? AND is_deleted = ?", product.ID, strings.ToLower(name), false).First(&u).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return saleUnit{}, badCheckout("unit %s is not configured for %s", name, product.Name)
+	}
+	if err != nil {
+		return saleUnit{}, err
+	}
+	return saleUnit{Name: u.Name, Factor: u.Factor, Price: u.Price}, nil
+}
+
+// tierPrice is the price of one of u at tier: the unit's own price when set,
+// otherwise the tier price of the base unit times the factor.
+func (u saleUnit) tierPrice(p models.Product, tier string) models.Money {
+	if u.Price > 0 {
+		return u.Price
+	}
+	return p.TierPrice(tier).TimesQty(u.Factor)
+}
@@ -0,0 +1,134 @@
+package costing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"shosha_mart_backend/models"
+)
+
+// receiveBatch records received stock as a batch when ref names a lot.
+func receiveBatch(tx *gorm.DB, branchID, productID string, qty models.Qty, unitCost models.Money, ref Ref) error {
+	if ref.BatchNo == "" && ref.ExpiryDate == nil {
+		return nil
+	}
+	return tx.Create(&models.StockBatch{
+		ID:         uuid.NewString(),
+		ProductID:  productID,
+		BranchID:   branchID,
+		BatchNo:    ref.BatchNo,
+		ExpiryDate: ref.ExpiryDate,
+		Qty:        qty,
+		Remaining:  qty,
+		UnitCost:   unitCost,
+		Source:     ref.Source,
+		DocID:      ref.DocID,
+		DocNo:      ref.DocNo,
+		Synced:     false,
+	}).Error
+}
+
+// allocateBatches takes qty from the product's batches at the branch, the
+// one expiring first first (FEFO). Expired batches are only used once the
+// good ones run out; stock beyond the batches is untracked.
+func allocateBatches(tx *gorm.DB, branchID, productID string, qty models.Qty, ref Ref) error {
+	today := startOfDay(time.Now())
+	var batches []models.StockBatch
+	if err := tx.Where("product_id = ? AND branch_id = ? AND remaining > 0 AND is_deleted = ?", productID, branchID, false).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "CASE WHEN expiry_date < ? THEN 2 WHEN expiry_date IS NULL THEN 1 ELSE 0 END, expiry_date, created_at, id",
+			Vars: []interface{}{today},
+		}}).
+		Find(&batches).Error; err != nil {
+		return err
+	}
+	left := qty
+	for _, b := range batches {
+		if left == 0 {
+			break
+		}
+		take := b.Remaining
+		if take > left {
+			take = left
+		}
+		if err := tx.Model(&models.StockBatch{}).Where("id = ?", b.ID).Updates(map[string]interface{}{
+			"remaining": b.Remaining - take,
+			"synced":    false,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.StockBatchAllocation{
+			ID:        uuid.NewString(),
+			BatchID:   b.ID,
+			ProductID: productID,
+			DocID:     ref.DocID,
+			Qty:       take,
+			Synced:    false,
+		}).Error; err != nil {
+			return err
+		}
+		left -= take
+	}
+	return nil
+}
+
+// releaseBatches puts stock coming back for docID into the batches it was
+// taken from, latest allocation first.
+func releaseBatches(tx *gorm.DB, productID, docID string, qty models.Qty) error {
+	if docID == "" {
+		return nil
+	}
+	var allocations []models.StockBatchAllocation
+	if err := tx.Where("doc_id = ? AND product_id = ? AND qty > 0 AND is_deleted = ?", docID, productID, false).
+		Order("created_at DESC, id DESC").Find(&allocations).Error; err != nil {
+		return err
+	}
+	left := qty
+	for _, a := range allocations {
+		if left == 0 {
+			break
+		}
+		back := a.Qty
+		if back > left {
+			back = left
+		}
+		if err := tx.Model(&models.StockBatchAllocation{}).Where("id = ?", a.ID).Updates(map[string]interface{}{
+			"qty":    a.Qty - back,
+			"synced": false,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.StockBatch{}).Where("id = ?", a.BatchID).Updates(map[string]interface{}{
+			"remaining": gorm.Expr("remaining + ?", back),
+			"synced":    false,
+		}).Error; err != nil {
+			return err
+		}
+		left -= back
+	}
+	return nil
+}
+
+// WriteOff removes qty of a batch from stock, e.g. when it has expired, and
+// returns the unit cost booked against it. The movement is recorded under ref.
+func WriteOff(tx *gorm.DB, method string, batch models.StockBatch, qty models.Qty, ref Ref) (models.Money, error) {
+	if qty <= 0 || qty > batch.Remaining {
+		return 0, fmt.Errorf("write-off qty must be greater than 0 and at most %s", batch.Remaining)
+	}
+	if err := tx.Model(&models.StockBatch{}).Where("id = ?", batch.ID).Updates(map[string]interface{}{
+		"remaining": batch.Remaining - qty,
+		"synced":    false,
+	}).Error; err != nil {
+		return 0, err
+	}
+	return issue(tx, method, batch.BranchID, batch.ProductID, qty, ref, false)
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,114 @@
+package costing
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/models"
+)
+
+// receiveLots receives 5 units of p1 at b1 in each of the lots fresh (30
+// days left), soon (10 days), expired (since yesterday) and open (no expiry
+// date).
+func receiveLots(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	now := time.Now()
+	days := func(n int) *time.Time {
+		d := now.AddDate(0, 0, n)
+		return &d
+	}
+	for _, lot := range []struct {
+		no     string
+		expiry *time.Time
+	}{{"fresh", days(30)}, {"soon", days(10)}, {"expired", days(-1)}, {"open", nil}} {
+		ref := Ref{Source: models.MovementReceipt, DocID: "gr-" + lot.no, BatchNo: lot.no, ExpiryDate: lot.expiry}
+		if err := Receive(db, Average, "b1", "p1", models.Units(5), 100000, ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func remaining(t *testing.T, db *gorm.DB) map[string]float64 {
+	t.Helper()
+	var batches []models.StockBatch
+	if err := db.Find(&batches).Error; err != nil {
+		t.Fatal(err)
+	}
+	left := map[string]float64{}
+	for _, b := range batches {
+		left[b.BatchNo] = b.Remaining.Float()
+	}
+	return left
+}
+
+func TestIssueTakesBatchesFEFO(t *testing.T) {
+	tests := []struct {
+		name   string
+		issue  float64
+		back   float64 // returned for the same document afterwards
+		wantOf map[string]float64
+	}{
+		{"first expiring first", 3, 0, map[string]float64{"soon": 2, "fresh": 5, "open": 5, "expired": 5}},
+		{"then the next expiry", 7, 0, map[string]float64{"soon": 0, "fresh": 3, "open": 5, "expired": 5}},
+		{"undated after dated", 12, 0, map[string]float64{"soon": 0, "fresh": 0, "open": 3, "expired": 5}},
+		{"expired only when the rest ran out", 17, 0, map[string]float64{"soon": 0, "fresh": 0, "open": 0, "expired": 3}},
+		{"beyond the batches is untracked", 22, 0, map[string]float64{"soon": 0, "fresh": 0, "open": 0, "expired": 0}},
+		{"return refills the last batch taken first", 12, 3, map[string]float64{"soon": 0, "fresh": 1, "open": 5, "expired": 5}},
+		{"return of everything", 7, 7, map[string]float64{"soon": 5, "fresh": 5, "open": 5, "expired": 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t)
+			receiveLots(t, db)
+			ref := Ref{Source: models.MovementSale, DocID: "sale-1"}
+			if _, err := Issue(db, FIFO, "b1", "p1", models.QtyFromFloat(tt.issue), ref); err != nil {
+				t.Fatal(err)
+			}
+			if tt.back > 0 {
+				if err := Return(db, FIFO, "b1", "p1", models.QtyFromFloat(tt.back), 100000, ref); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := remaining(t, db)
+			for lot, want := range tt.wantOf {
+				if got[lot] != want {
+					t.Errorf("batch %s has %v left, want %v", lot, got[lot], want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteOff(t *testing.T) {
+	db := testDB(t)
+	receiveLots(t, db)
+	var expired models.StockBatch
+	if err := db.First(&expired, "batch_no = ?", "expired").Error; err != nil {
+		t.Fatal(err)
+	}
+	ref := Ref{Source: models.MovementWriteOff, DocID: "wo-1"}
+	for _, qty := range []models.Qty{0, models.Units(6)} {
+		if _, err := WriteOff(db, Average, expired, qty, ref); err == nil {
+			t.Errorf("WriteOff(%s) of a batch holding 5 succeeded", qty)
+		}
+	}
+	if _, err := WriteOff(db, Average, expired, models.Units(5), ref); err != nil {
+		t.Fatal(err)
+	}
+	got := remaining(t, db)
+	want := map[string]float64{"soon": 5, "fresh": 5, "open": 5, "expired": 0}
+	for lot, w := range want {
+		if got[lot] != w {
+			t.Errorf("batch %s has %v left, want %v", lot, got[lot], w)
+		}
+	}
+	onHand, err := OnHand(db, "b1", "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if onHand != models.Units(15) {
+		t.Errorf("stock = %s, want 15", onHand)
+	}
+}
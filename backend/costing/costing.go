@@ -0,0 +1,209 @@
+package costing
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/models"
+)
+
+// Costing methods for the cost of goods sold.
+const (
+	Average = "average" // rata-rata tertimbang per cabang, diperbarui tiap penerimaan
+	FIFO    = "fifo"    // lapisan tertua keluar lebih dulu
+)
+
+// Ref names the document behind a stock change, recorded on its movement.
+type Ref struct {
+	Source string // lihat models.Movement*
+	DocID  string
+	DocNo  string
+	User   string
+	Note   string
+	// lot yang diterima; bila salah satu diisi stok dicatat sebagai batch
+	BatchNo    string
+	ExpiryDate *time.Time
+}
+
+// Valid reports whether method is a known costing method.
+func Valid(method string) bool {
+	return method == Average || method == FIFO
+}
+
+// Receive adds qty base units bought at unitCost to a product's stock. A
+// cost layer is always recorded so the method can be switched later; under
+// Average the branch's cost becomes the weighted average of its stock on hand
+// and the new lot, under FIFO it is the cost of the latest lot.
+func Receive(tx *gorm.DB, method, branchID, productID string, qty models.Qty, unitCost models.Money, ref Ref) error {
+	return receive(tx, method, branchID, productID, qty, unitCost, ref, true)
+}
+
+// receive records the layer; latest says whether the lot is a new purchase
+// whose cost becomes the FIFO branch cost (returns are not).
+func receive(tx *gorm.DB, method, branchID, productID string, qty models.Qty, unitCost models.Money, ref Ref, latest bool) error {
+	if qty <= 0 {
+		return nil
+	}
+	current, err := CurrentCost(tx, branchID, productID)
+	if err != nil {
+		return err
+	}
+	onHand, err := OnHand(tx, branchID, productID)
+	if err != nil {
+		return err
+	}
+	cost := unitCost
+	if method == FIFO && !latest {
+		cost = current
+	}
+	if method != FIFO && onHand > 0 {
+		total := float64(onHand)*float64(current) + float64(qty)*float64(unitCost)
+		cost = models.Money(math.Round(total / float64(onHand+qty)))
+	}
+	if err := tx.Create(&models.CostLayer{
+		ID:        uuid.NewString(),
+		ProductID: productID,
+		BranchID:  branchID,
+		Source:    ref.Source,
+		Qty:       qty,
+		Remaining: qty,
+		UnitCost:  unitCost,
+		Synced:    false,
+	}).Error; err != nil {
+		return err
+	}
+	balance, err := addStock(tx, branchID, productID, qty)
+	if err != nil {
+		return err
+	}
+	if err := SetCost(tx, branchID, productID, cost); err != nil {
+		return err
+	}
+	if latest {
+		err = receiveBatch(tx, branchID, productID, qty, unitCost, ref)
+	} else {
+		err = releaseBatches(tx, productID, ref.DocID, qty)
+	}
+	if err != nil {
+		return err
+	}
+	return recordMovement(tx, branchID, productID, qty, balance, unitCost, ref)
+}
+
+// Return puts sold stock back at the cost it left with. Lines sold before
+// costing existed carry no cost and come back at the branch's current cost.
+func Return(tx *gorm.DB, method, branchID, productID string, qty models.Qty, unitCost models.Money, ref Ref) error {
+	if unitCost == 0 {
+		var err error
+		if unitCost, err = CurrentCost(tx, branchID, productID); err != nil {
+			return err
+		}
+	}
+	return receive(tx, method, branchID, productID, qty, unitCost, ref, false)
+}
+
+// Issue takes qty base units out of a product's stock and returns the unit
+// cost to book against them: the branch's cost under Average, or the weighted
+// cost of the branch's oldest layers under FIFO. Stock without layers (counted
+// before costing existed, or sold below zero) is costed at the branch's cost.
+func Issue(tx *gorm.DB, method, branchID, productID string, qty models.Qty, ref Ref) (models.Money, error) {
+	return issue(tx, method, branchID, productID, qty, ref, true)
+}
+
+// issue takes the stock out; fefo says whether it is taken from the batches
+// that expire first (a write-off takes its own batch instead).
+func issue(tx *gorm.DB, method, branchID, productID string, qty models.Qty, ref Ref, fefo bool) (models.Money, error) {
+	current, err := CurrentCost(tx, branchID, productID)
+	if err != nil {
+		return 0, err
+	}
+	if qty <= 0 {
+		return current, nil
+	}
+
+	var layers []models.CostLayer
+	if err := tx.Where("product_id = ? AND branch_id = ? AND remaining > 0 AND is_deleted = ?", productID, branchID, false).
+		Order("created_at, id").Find(&layers).Error; err != nil {
+		return 0, err
+	}
+	left := qty
+	var total float64
+	for _, l := range layers {
+		if left == 0 {
+			break
+		}
+		take := l.Remaining
+		if take > left {
+			take = left
+		}
+		if err := tx.Model(&models.CostLayer{}).Where("id = ?", l.ID).Updates(map[string]interface{}{
+			"remaining": l.Remaining - take,
+			"synced":    false,
+		}).Error; err != nil {
+			return 0, err
+		}
+		total += float64(take) * float64(l.UnitCost)
+		left -= take
+	}
+	total += float64(left) * float64(current)
+
+	balance, err := addStock(tx, branchID, productID, -qty)
+	if err != nil {
+		return 0, err
+	}
+	if fefo {
+		if err := allocateBatches(tx, branchID, productID, qty, ref); err != nil {
+			return 0, err
+		}
+	}
+	cost := current
+	if method == FIFO {
+		cost = models.Money(math.Round(total / float64(qty)))
+	}
+	if err := recordMovement(tx, branchID, productID, -qty, balance, cost, ref); err != nil {
+		return 0, err
+	}
+	return cost, nil
+}
+
+// SetStock brings a product's stock at a branch to qty, as after a stock
+// count. Gains are received at the branch's current cost; losses are issued
+// like sales.
+func SetStock(tx *gorm.DB, method, branchID, productID string, qty models.Qty, ref Ref) error {
+	current, err := CurrentCost(tx, branchID, productID)
+	if err != nil {
+		return err
+	}
+	onHand, err := OnHand(tx, branchID, productID)
+	if err != nil {
+		return err
+	}
+	diff := qty - onHand
+	if diff > 0 {
+		return Receive(tx, method, branchID, productID, diff, current, ref)
+	}
+	_, err = Issue(tx, method, branchID, productID, -diff, ref)
+	return err
+}
+
+// recordMovement writes the stock card line for a change of delta base units,
+// with the branch's stock after it as the balance.
+func recordMovement(tx *gorm.DB, branchID, productID string, delta, balance models.Qty, unitCost models.Money, ref Ref) error {
+	return tx.Create(&models.StockMovement{
+		ID:        uuid.NewString(),
+		ProductID: productID,
+		BranchID:  branchID,
+		Source:    ref.Source,
+		DocID:     ref.DocID,
+		DocNo:     ref.DocNo,
+		Delta:     delta,
+		Balance:   balance,
+		UnitCost:  unitCost,
+		CreatedBy: ref.User,
+		Note:      ref.Note,
+		Synced:    false,
+	}).Error
+}
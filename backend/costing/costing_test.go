@@ -0,0 +1,242 @@
+package costing
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"shosha_mart_backend/models"
+)
+
+// testDB opens an in-memory database holding product p1. One connection
+// keeps every query on the same database.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.Product{}, &models.BranchStock{}, &models.CostLayer{}, &models.StockMovement{},
+		&models.StockBatch{}, &models.StockBatchAllocation{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Product{ID: "p1", Name: "Beras", Unit: "kg"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func productCost(t *testing.T, db *gorm.DB) models.Money {
+	t.Helper()
+	var p models.Product
+	if err := db.First(&p, "id = ?", "p1").Error; err != nil {
+		t.Fatal(err)
+	}
+	return p.Cost
+}
+
+// step is one stock change in a costing scenario.
+type step struct {
+	op   string // receive, return, issue or set
+	qty  float64
+	cost float64 // rupiah per unit; for issue the cost it must be booked at
+}
+
+func TestCosting(t *testing.T) {
+	receipts := []step{{"receive", 10, 1000}, {"receive", 10, 2000}}
+	tests := []struct {
+		name      string
+		method    string
+		steps     []step
+		wantCost  float64 // product cost afterwards, rupiah
+		wantStock float64
+	}{
+		{"average of two lots", Average, receipts, 1500, 20},
+		{"fifo keeps the latest lot cost", FIFO, receipts, 2000, 20},
+		{"average issue", Average, append(receipts, step{"issue", 15, 1500}), 1500, 5},
+		{"fifo issue spans layers", FIFO, append(receipts, step{"issue", 15, 1333.33}), 2000, 5},
+		{"fifo issue beyond layers at product cost", FIFO,
+			append(receipts, step{"issue", 15, 1333.33}, step{"issue", 10, 2000}), 2000, -5},
+		{"fractional issue", Average, []step{{"receive", 2.5, 12000}, {"issue", 0.75, 12000}}, 12000, 1.75},
+		{"average return at its own cost", Average, append(receipts, step{"return", 2, 1000}), 1454.55, 22},
+		{"fifo return keeps the product cost", FIFO, append(receipts, step{"return", 2, 1000}), 2000, 22},
+		{"count down issues the difference", Average, append(receipts, step{"set", 7, 1500}), 1500, 7},
+		{"count up receives at product cost", FIFO, append(receipts, step{"set", 25, 2000}), 2000, 25},
+		{"receiving nothing is a no-op", Average, []step{{"receive", 0, 5000}}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t)
+			ref := Ref{Source: models.MovementAdjustment, DocID: "doc"}
+			for i, s := range tt.steps {
+				qty, cost := models.QtyFromFloat(s.qty), models.FromRupiah(s.cost)
+				var err error
+				switch s.op {
+				case "receive":
+					err = Receive(db, tt.method, "b1", "p1", qty, cost, ref)
+				case "return":
+					err = Return(db, tt.method, "b1", "p1", qty, cost, ref)
+				case "set":
+					err = SetStock(db, tt.method, "b1", "p1", qty, ref)
+				case "issue":
+					var got models.Money
+					got, err = Issue(db, tt.method, "b1", "p1", qty, ref)
+					if err == nil && got != cost {
+						t.Errorf("step %d: issued at %s, want %s", i, got, cost)
+					}
+				}
+				if err != nil {
+					t.Fatalf("step %d (%s): %v", i, s.op, err)
+				}
+			}
+
+			if got, want := productCost(t, db), models.FromRupiah(tt.wantCost); got != want {
+				t.Errorf("product cost = %s, want %s", got, want)
+			}
+			onHand, err := OnHand(db, "b1", "p1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := models.QtyFromFloat(tt.wantStock); onHand != want {
+				t.Errorf("stock = %s, want %s", onHand, want)
+			}
+			var last models.StockMovement
+			if err := db.Order("created_at DESC").Limit(1).Find(&last).Error; err != nil {
+				t.Fatal(err)
+			}
+			if last.ID != "" && last.Balance != onHand {
+				t.Errorf("last movement balance = %s, want %s", last.Balance, onHand)
+			}
+			var p models.Product
+			if err := db.First(&p, "id = ?", "p1").Error; err != nil {
+				t.Fatal(err)
+			}
+			if p.Stock != onHand {
+				t.Errorf("product stock mirror = %s, want %s", p.Stock, onHand)
+			}
+		})
+	}
+}
+
+func TestStockIsKeptPerBranch(t *testing.T) {
+	db := testDB(t)
+	ref := Ref{Source: models.MovementAdjustment}
+	if err := Receive(db, Average, "b1", "p1", models.Units(10), 100000, ref); err != nil {
+		t.Fatal(err)
+	}
+	if err := Receive(db, Average, "b2", "p1", models.Units(3), 100000, ref); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Issue(db, FIFO, "b2", "p1", models.Units(1), ref); err != nil {
+		t.Fatal(err)
+	}
+	for branch, want := range map[string]models.Qty{"b1": models.Units(10), "b2": models.Units(2), "b3": 0} {
+		got, err := OnHand(db, branch, "p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("stock at %s = %s, want %s", branch, got, want)
+		}
+	}
+	var layer models.CostLayer
+	if err := db.First(&layer, "branch_id = ?", "b2").Error; err != nil {
+		t.Fatal(err)
+	}
+	if layer.Remaining != models.Units(2) {
+		t.Errorf("b2 layer remaining = %s, want 2", layer.Remaining)
+	}
+}
+
+func TestCostIsKeptPerBranch(t *testing.T) {
+	db := testDB(t)
+	if err := db.Model(&models.Product{}).Where("id = ?", "p1").Update("synced", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	ref := Ref{Source: models.MovementReceipt}
+	for _, r := range []struct {
+		branch string
+		qty    int
+		cost   float64
+	}{{"b1", 10, 1000}, {"b2", 10, 3000}, {"b1", 10, 2000}} {
+		if err := Receive(db, Average, r.branch, "p1", models.Units(r.qty), models.FromRupiah(r.cost), ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		branch string
+		want   float64
+	}{
+		{"b1", 1500},
+		{"b2", 3000}, // tidak tertimpa rata-rata b1
+	}
+	for _, tt := range tests {
+		got, err := CurrentCost(db, tt.branch, "p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != models.FromRupiah(tt.want) {
+			t.Errorf("cost at %s = %s, want %v", tt.branch, got, tt.want)
+		}
+		issued, err := Issue(db, Average, tt.branch, "p1", models.Units(1), Ref{Source: models.MovementSale})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if issued != models.FromRupiah(tt.want) {
+			t.Errorf("issued at %s for %s, want %v", tt.branch, issued, tt.want)
+		}
+	}
+	// cabang yang belum pernah menerima memakai HPP produk
+	if got, err := CurrentCost(db, "b3", "p1"); err != nil || got != productCost(t, db) {
+		t.Errorf("cost at b3 = %s (%v), want the product cost %s", got, err, productCost(t, db))
+	}
+	var p models.Product
+	if err := db.First(&p, "id = ?", "p1").Error; err != nil {
+		t.Fatal(err)
+	}
+	if !p.Synced {
+		t.Error("receiving stock queued the product for upload")
+	}
+}
+
+func TestSyncProductStock(t *testing.T) {
+	db := testDB(t)
+	// stok lama di products sebelum stok per cabang
+	if err := db.Model(&models.Product{}).Where("id = ?", "p1").Update("stock", models.Units(7)).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := SyncProductStock(db, "b1"); err != nil {
+			t.Fatal(err)
+		}
+		got, err := OnHand(db, "b1", "p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != models.Units(7) {
+			t.Errorf("run %d: seeded stock = %s, want 7", i+1, got)
+		}
+	}
+	// a downloaded row of the branch wins over the mirror
+	if err := db.Model(&models.BranchStock{}).Where("id = ?", StockID("b1", "p1")).Update("qty", models.Units(4)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := SyncProductStock(db, "b1"); err != nil {
+		t.Fatal(err)
+	}
+	var p models.Product
+	if err := db.First(&p, "id = ?", "p1").Error; err != nil {
+		t.Fatal(err)
+	}
+	if p.Stock != models.Units(4) {
+		t.Errorf("product stock = %s, want 4", p.Stock)
+	}
+}
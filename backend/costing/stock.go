@@ -0,0 +1,111 @@
+package costing
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"shosha_mart_backend/models"
+)
+
+// StockID is the id of a product's stock row at a branch. Every sidecar
+// derives the same id, so rows never duplicate across sync.
+func StockID(branchID, productID string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(branchID+"/"+productID)).String()
+}
+
+// OnHand is a product's stock at a branch, in base units.
+func OnHand(tx *gorm.DB, branchID, productID string) (models.Qty, error) {
+	var row models.BranchStock
+	err := tx.Select("qty").Limit(1).Find(&row, "id = ?", StockID(branchID, productID)).Error
+	return row.Qty, err
+}
+
+// CurrentCost is a product's unit cost at a branch: the branch's own cost
+// once it has received the product, else the catalogue Product.Cost.
+func CurrentCost(tx *gorm.DB, branchID, productID string) (models.Money, error) {
+	var row models.BranchStock
+	if err := tx.Select("cost").Limit(1).Find(&row, "id = ?", StockID(branchID, productID)).Error; err != nil {
+		return 0, err
+	}
+	if row.Cost > 0 {
+		return row.Cost, nil
+	}
+	var product models.Product
+	err := tx.Select("id", "cost").First(&product, "id = ?", productID).Error
+	return product.Cost, err
+}
+
+// SetCost sets a product's unit cost at a branch. Product.Cost mirrors it
+// for the sidecar's own branch without being uploaded, as it does the stock:
+// each branch keeps its own cost.
+func SetCost(tx *gorm.DB, branchID, productID string, cost models.Money) error {
+	row := models.BranchStock{ID: StockID(branchID, productID), ProductID: productID, BranchID: branchID, Cost: cost, Synced: false}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"cost":       cost,
+			"synced":     false,
+			"updated_at": gorm.Expr("CURRENT_TIMESTAMP"),
+		}),
+	}).Create(&row).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Product{}).Where("id = ?", productID).UpdateColumn("cost", cost).Error
+}
+
+// addStock moves a product's stock at a branch by delta and returns the new
+// balance. Product.Stock mirrors the balance for the sidecar's own branch;
+// stock is only ever moved at the branch the sidecar runs for.
+func addStock(tx *gorm.DB, branchID, productID string, delta models.Qty) (models.Qty, error) {
+	row := models.BranchStock{ID: StockID(branchID, productID), ProductID: productID, BranchID: branchID, Qty: delta, Synced: false}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"qty":        gorm.Expr("branch_stocks.qty + ?", delta),
+			"synced":     false,
+			"updated_at": gorm.Expr("CURRENT_TIMESTAMP"),
+		}),
+	}).Create(&row).Error; err != nil {
+		return 0, err
+	}
+	balance, err := OnHand(tx, branchID, productID)
+	if err != nil {
+		return 0, err
+	}
+	return balance, tx.Model(&models.Product{}).Where("id = ?", productID).UpdateColumn("stock", balance).Error
+}
+
+// SyncProductStock keeps Product.Stock and Product.Cost in step with
+// branchID's stock rows: products without a row yet (stock kept before it
+// was per branch) get one holding their Product.Stock and Product.Cost, then
+// every product takes its row's qty and, once set, its cost. It runs at
+// startup and after stock rows are downloaded.
+func SyncProductStock(db *gorm.DB, branchID string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var products []models.Product
+		if err := tx.Select("id", "stock", "cost").
+			Where("NOT EXISTS (SELECT 1 FROM branch_stocks bs WHERE bs.product_id = products.id AND bs.branch_id = ?)", branchID).
+			Find(&products).Error; err != nil {
+			return err
+		}
+		for _, p := range products {
+			if err := tx.Create(&models.BranchStock{
+				ID:        StockID(branchID, p.ID),
+				ProductID: p.ID,
+				BranchID:  branchID,
+				Qty:       p.Stock,
+				Cost:      p.Cost,
+				Synced:    false,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("UPDATE products SET stock = (SELECT bs.qty FROM branch_stocks bs WHERE bs.product_id = products.id AND bs.branch_id = ?) "+
+			"WHERE EXISTS (SELECT 1 FROM branch_stocks bs WHERE bs.product_id = products.id AND bs.branch_id = ?)", branchID, branchID).Error; err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE products SET cost = (SELECT bs.cost FROM branch_stocks bs WHERE bs.product_id = products.id AND bs.branch_id = ?) "+
+			"WHERE EXISTS (SELECT 1 FROM branch_stocks bs WHERE bs.product_id = products.id AND bs.branch_id = ? AND bs.cost > 0)", branchID, branchID).Error
+	})
+}
@@ -0,0 +1,139 @@
+package exports
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/catalog"
+	"shosha_mart_backend/models"
+)
+
+// ProductHeaders are the columns of a product export. They are the headers
+// POST /api/products/import recognises, so an edited export can be imported
+// back; the ID column makes each row update the product it came from.
+var ProductHeaders = []string{"ID", "SKU", "Nama", "Satuan", "Stok", "Harga", "Harga Investor", "Harga Shosha", "HPP", "Barcode", "Kategori", "Merek"}
+
+// ProductLine is one product of an export.
+type ProductLine struct {
+	Product  models.Product
+	Barcodes []string
+	Category string // path, mis. "Minuman > Kopi"
+	Brand    string
+}
+
+// ProductCatalogue loads all live products ordered by name, with their
+// barcodes, category path and brand name.
+func ProductCatalogue(db *gorm.DB) ([]ProductLine, error) {
+	var products []models.Product
+	if err := db.Where("is_deleted = ?", false).Order("name, id").Find(&products).Error; err != nil {
+		return nil, err
+	}
+	var barcodes []models.ProductBarcode
+	if err := db.Where("is_deleted = ?", false).Order("code").Find(&barcodes).Error; err != nil {
+		return nil, err
+	}
+	codes := map[string][]string{}
+	for _, b := range barcodes {
+		codes[b.ProductID] = append(codes[b.ProductID], b.Code)
+	}
+	cats, err := catalog.LoadCategories(db)
+	if err != nil {
+		return nil, err
+	}
+	var brands []models.Brand
+	if err := db.Find(&brands).Error; err != nil {
+		return nil, err
+	}
+	brandName := map[string]string{}
+	for _, b := range brands {
+		brandName[b.ID] = b.Name
+	}
+
+	lines := make([]ProductLine, len(products))
+	for i, p := range products {
+		lines[i] = ProductLine{Product: p, Barcodes: codes[p.ID], Brand: brandName[p.BrandID]}
+		if _, ok := cats[p.CategoryID]; ok {
+			lines[i].Category = cats.Path(p.CategoryID)
+		}
+		sort.Strings(lines[i].Barcodes)
+	}
+	return lines, nil
+}
+
+// cells returns the export columns of l. Amounts and quantities are plain
+// decimals so they read back exactly.
+func (l ProductLine) cells() []string {
+	p := l.Product
+	return []string{
+		p.ID, p.SKU, p.Name, p.Unit, p.Stock.String(),
+		p.Price.String(), p.PriceInvestor.String(), p.PriceShosha.String(), p.Cost.String(),
+		strings.Join(l.Barcodes, ", "), l.Category, l.Brand,
+	}
+}
+
+// ProductsXLSX writes lines to a workbook with a single "Produk" sheet.
+func ProductsXLSX(lines []ProductLine) (*excelize.File, error) {
+	f := excelize.NewFile()
+	sheet := "Produk"
+	f.SetSheetName("Sheet1", sheet)
+	for col, h := range ProductHeaders {
+		cell, _ := excelize.CoordinatesToCellName(col+1, 1)
+		f.SetCellValue(sheet, cell, h)
+	}
+	for i, l := range lines {
+		row := i + 2
+		p := l.Product
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), p.ID)
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), p.SKU)
+		f.SetCellValue(sheet, fmt.Sprintf("C%d", row), p.Name)
+		f.SetCellValue(sheet, fmt.Sprintf("D%d", row), p.Unit)
+		f.SetCellValue(sheet, fmt.Sprintf("E%d", row), p.Stock.Float())
+		f.SetCellValue(sheet, fmt.Sprintf("F%d", row), p.Price.Rupiah())
+		f.SetCellValue(sheet, fmt.Sprintf("G%d", row), p.PriceInvestor.Rupiah())
+		f.SetCellValue(sheet, fmt.Sprintf("H%d", row), p.PriceShosha.Rupiah())
+		f.SetCellValue(sheet, fmt.Sprintf("I%d", row), p.Cost.Rupiah())
+		f.SetCellValue(sheet, fmt.Sprintf("J%d", row), strings.Join(l.Barcodes, ", "))
+		f.SetCellValue(sheet, fmt.Sprintf("K%d", row), l.Category)
+		f.SetCellValue(sheet, fmt.Sprintf("L%d", row), l.Brand)
+	}
+	// SKU dan barcode sebagai teks agar nol di depan tidak hilang saat diedit
+	if len(lines) > 0 {
+		text, err := f.NewStyle(&excelize.Style{NumFmt: 49})
+		if err != nil {
+			return nil, err
+		}
+		last := len(lines) + 1
+		f.SetCellStyle(sheet, "B2", fmt.Sprintf("B%d", last), text)
+		f.SetCellStyle(sheet, "J2", fmt.Sprintf("J%d", last), text)
+	}
+	f.SetColWidth(sheet, "A", "A", 38)
+	f.SetColWidth(sheet, "C", "C", 32)
+	f.SetColWidth(sheet, "J", "L", 24)
+	f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+	return f, nil
+}
+
+// WriteProductsCSV writes lines as comma separated CSV with a UTF-8 BOM, so
+// Excel shows product names correctly.
+func WriteProductsCSV(w io.Writer, lines []ProductLine) error {
+	if _, err := io.WriteString(w, "\xef\xbb\xbf"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(ProductHeaders); err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if err := cw.Write(l.cells()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
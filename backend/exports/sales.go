@@ -69,7 +69,7 @@ func ExportSalesByBranch(db *gorm.DB) (*excelize.File, error) {
 		}
 
 		// Write header
-		headers := []string{"Tanggal", "No. Invoice", "Metode", "Total", "Nama Barang", "Qty", "Harga", "Jumlah"}
+		headers := []string{"Tanggal", "No. Invoice", "Metode", "Total", "Nama Barang", "Qty", "Satuan", "Harga", "Diskon", "Jumlah"}
 		for col, h := range headers {
 			cell, _ := excelize.CoordinatesToCellName(col+1, 1)
 			f.SetCellValue(sheetName, cell, h)
@@ -84,7 +84,7 @@ func ExportSalesByBranch(db *gorm.DB) (*excelize.File, error) {
 				f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), dateStr)
 				f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), sale.ReceiptNo)
 				f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), sale.PaymentMethod)
-				f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), sale.Total)
+				f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), sale.Total.Rupiah())
 				row++
 			} else {
 				for idx, item := range items {
@@ -93,20 +93,22 @@ func ExportSalesByBranch(db *gorm.DB) (*excelize.File, error) {
 					if productName == "" {
 						productName = item.ProductID
 					}
-					subtotal := float64(item.Qty) * item.Price
+					subtotal := item.LineTotal()
 
 					if idx == 0 {
 						// First row shows sale header
 						f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), dateStr)
 						f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), sale.ReceiptNo)
 						f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), sale.PaymentMethod)
-						f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), sale.Total)
+						f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), sale.Total.Rupiah())
 					}
 					// Item details
 					f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), productName)
-					f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), item.Qty)
-					f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), item.Price)
-					f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), subtotal)
+					f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), item.Qty.Float())
+					f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), item.QtyLabel(productMap[item.ProductID].Unit))
+					f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), item.Price.Rupiah())
+					f.SetCellValue(sheetName, fmt.Sprintf("I%d", row), (item.GrossAmount() - subtotal).Rupiah())
+					f.SetCellValue(sheetName, fmt.Sprintf("J%d", row), subtotal.Rupiah())
 					row++
 				}
 			}
@@ -0,0 +1,228 @@
+package imports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+
+	"shosha_mart_backend/models"
+)
+
+// ProductFields maps each importable product field to the column headers it
+// is recognised by when no explicit mapping is given (compared lower-case,
+// with "_" read as a space).
+var ProductFields = map[string][]string{
+	"id":             {"id", "product id", "id produk"},
+	"sku":            {"sku", "kode", "kode barang"},
+	"name":           {"name", "nama", "nama barang", "produk"},
+	"unit":           {"unit", "satuan"},
+	"stock":          {"stock", "stok"},
+	"price":          {"price", "harga", "harga umum"},
+	"price_investor": {"price investor", "harga investor"},
+	"price_shosha":   {"price shosha", "harga shosha"},
+	"cost":           {"cost", "hpp", "harga pokok"},
+	"barcodes":       {"barcodes", "barcode"},
+	"category":       {"category", "kategori"},
+	"brand":          {"brand", "merek", "merk"},
+}
+
+// Table is the content of an import file, header row first.
+type Table struct {
+	Rows [][]string
+	// DecimalComma is set for semicolon separated CSV, which Excel writes
+	// with Indonesian number formats ("1.250.000,50").
+	DecimalComma bool
+}
+
+// ReadRows reads the first sheet of an .xlsx file or a .csv file (comma or
+// semicolon separated). Spreadsheet cells are read raw so numbers are not
+// affected by display formats.
+func ReadRows(filename string, r io.Reader) (Table, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xlsx":
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return Table{}, err
+		}
+		defer f.Close()
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return Table{}, errors.New("workbook has no sheets")
+		}
+		rows, err := f.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+		return Table{Rows: rows}, err
+	case ".csv":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return Table{}, err
+		}
+		data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // BOM dari Excel
+		cr := csv.NewReader(bytes.NewReader(data))
+		cr.FieldsPerRecord = -1
+		cr.TrimLeadingSpace = true
+		// Excel berbahasa Indonesia menyimpan CSV dengan titik koma
+		semicolon := false
+		if line, _, _ := bytes.Cut(data, []byte("\n")); bytes.Count(line, []byte(";")) > bytes.Count(line, []byte(",")) {
+			cr.Comma = ';'
+			semicolon = true
+		}
+		rows, err := cr.ReadAll()
+		return Table{Rows: rows, DecimalComma: semicolon}, err
+	}
+	return Table{}, fmt.Errorf("unsupported file type %q (use .xlsx or .csv)", filepath.Ext(filename))
+}
+
+func normHeader(h string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(strings.ToLower(h), "_", " ")), " ")
+}
+
+// MapColumns resolves product fields to column indexes of header. explicit
+// maps field -> header and takes precedence; other fields are matched by
+// ProductFields. A name column is required.
+func MapColumns(header []string, explicit map[string]string) (map[string]int, error) {
+	index := map[string]int{}
+	for i, h := range header {
+		if _, dup := index[normHeader(h)]; !dup && normHeader(h) != "" {
+			index[normHeader(h)] = i
+		}
+	}
+	cols := map[string]int{}
+	for field, h := range explicit {
+		if _, ok := ProductFields[field]; !ok {
+			return nil, fmt.Errorf("unknown field %q in mapping", field)
+		}
+		i, ok := index[normHeader(h)]
+		if !ok {
+			return nil, fmt.Errorf("column %q (mapped to %s) not found", h, field)
+		}
+		cols[field] = i
+	}
+	for field, aliases := range ProductFields {
+		if _, ok := cols[field]; ok {
+			continue
+		}
+		for _, a := range aliases {
+			if i, ok := index[a]; ok {
+				cols[field] = i
+				break
+			}
+		}
+	}
+	if _, ok := cols["name"]; !ok {
+		return nil, errors.New("no name column; map one with mapping.name")
+	}
+	return cols, nil
+}
+
+// ProductRow is one parsed data row. Pointer fields are nil when the column
+// is absent or the cell is empty, so an update leaves those values alone.
+type ProductRow struct {
+	Line          int // baris di file, header = 1
+	ID            *string
+	SKU           *string
+	Name          string
+	Unit          *string
+	Stock         *models.Qty
+	Price         *models.Money
+	PriceInvestor *models.Money
+	PriceShosha   *models.Money
+	Cost          *models.Money
+	Barcodes      *[]string
+	Category      *string
+	Brand         *string
+	Errors        []string
+}
+
+// ParseProducts turns the data rows after the header into ProductRows,
+// skipping blank lines. Cell-level problems are recorded on the row.
+func ParseProducts(t Table, cols map[string]int) []ProductRow {
+	var out []ProductRow
+	for n, cells := range t.Rows[1:] {
+		cell := func(field string) (string, bool) {
+			i, ok := cols[field]
+			if !ok || i >= len(cells) {
+				return "", false
+			}
+			v := strings.TrimSpace(cells[i])
+			return v, v != ""
+		}
+		blank := true
+		for _, c := range cells {
+			if strings.TrimSpace(c) != "" {
+				blank = false
+				break
+			}
+		}
+		if blank {
+			continue
+		}
+
+		row := ProductRow{Line: n + 2}
+		row.Name, _ = cell("name")
+		str := func(field string) *string {
+			if v, ok := cell(field); ok {
+				return &v
+			}
+			return nil
+		}
+		row.ID, row.SKU, row.Unit, row.Category, row.Brand = str("id"), str("sku"), str("unit"), str("category"), str("brand")
+		money := func(field string) *models.Money {
+			v, ok := cell(field)
+			if !ok {
+				return nil
+			}
+			var m models.Money
+			if err := m.UnmarshalJSON([]byte(normNumber(v, t.DecimalComma))); err != nil || m < 0 {
+				row.Errors = append(row.Errors, fmt.Sprintf("%s: %q is not a valid amount", field, v))
+				return nil
+			}
+			return &m
+		}
+		row.Price, row.PriceInvestor, row.PriceShosha, row.Cost = money("price"), money("price_investor"), money("price_shosha"), money("cost")
+		if v, ok := cell("stock"); ok {
+			var q models.Qty
+			if err := q.UnmarshalJSON([]byte(normNumber(v, t.DecimalComma))); err != nil || q < 0 {
+				row.Errors = append(row.Errors, fmt.Sprintf("stock: %q is not a valid quantity", v))
+			} else {
+				row.Stock = &q
+			}
+		}
+		if v, ok := cell("barcodes"); ok {
+			codes := strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ';' || r == ' ' || r == '|' })
+			row.Barcodes = &codes
+		}
+		out = append(out, row)
+	}
+	return out
+}
+
+// enThousands matches numbers grouped with commas such as "12,500" or
+// "1,250,000.50".
+var enThousands = regexp.MustCompile(`^-?\d{1,3}(,\d{3})+(\.\d+)?$`)
+
+// normNumber strips a "Rp" prefix and thousands separators and returns a
+// plain decimal. With decimalComma "." groups thousands and "," is the
+// decimal point; otherwise "." is the decimal point and a lone "," (as in
+// "7,5" typed into a text cell) is read as one too.
+func normNumber(s string, decimalComma bool) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "Rp"), "rp")
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	if decimalComma {
+		return strings.Replace(strings.ReplaceAll(s, ".", ""), ",", ".", 1)
+	}
+	if enThousands.MatchString(s) {
+		return strings.ReplaceAll(s, ",", "")
+	}
+	if strings.Count(s, ",") == 1 && !strings.Contains(s, ".") {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	return s
+}
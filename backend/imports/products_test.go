@@ -0,0 +1,160 @@
+package imports
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"shosha_mart_backend/models"
+)
+
+func TestNormNumber(t *testing.T) {
+	tests := []struct {
+		in           string
+		decimalComma bool
+		want         string
+	}{
+		{"12500", false, "12500"},
+		{"Rp 12,500", false, "12500"},
+		{"1,250,000.50", false, "1250000.50"},
+		{"7,5", false, "7.5"},
+		{"7.5", false, "7.5"},
+		{"rp12 500", false, "12500"},
+		{"1.250.000,50", true, "1250000.50"},
+		{"Rp 12.500", true, "12500"},
+		{"7,5", true, "7.5"},
+		{"abc", false, "abc"},
+	}
+	for _, tt := range tests {
+		if got := normNumber(tt.in, tt.decimalComma); got != tt.want {
+			t.Errorf("normNumber(%q, %v) = %q, want %q", tt.in, tt.decimalComma, got, tt.want)
+		}
+	}
+}
+
+func TestReadRowsCSV(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		want         [][]string
+		decimalComma bool
+	}{
+		{"comma", "nama,harga\nBeras,\"12,500\"\n", [][]string{{"nama", "harga"}, {"Beras", "12,500"}}, false},
+		{"semicolon from Excel", "\xef\xbb\xbfnama;harga\nBeras;12.500,50\n", [][]string{{"nama", "harga"}, {"Beras", "12.500,50"}}, true},
+		{"ragged rows", "nama,harga,stok\nBeras\n", [][]string{{"nama", "harga", "stok"}, {"Beras"}}, false},
+	}
+	for _, tt := range tests {
+		got, err := ReadRows("produk.CSV", strings.NewReader(tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got.Rows, tt.want) || got.DecimalComma != tt.decimalComma {
+			t.Errorf("%s: %q decimal comma %v, want %q decimal comma %v", tt.name, got.Rows, got.DecimalComma, tt.want, tt.decimalComma)
+		}
+	}
+	if _, err := ReadRows("produk.xls", strings.NewReader("")); err == nil {
+		t.Error("ReadRows accepted an .xls file")
+	}
+}
+
+func TestMapColumns(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   []string
+		explicit map[string]string
+		want     map[string]int
+		wantErr  bool
+	}{
+		{
+			name:   "aliases",
+			header: []string{"Kode Barang", "Nama_Barang", "HARGA", "hpp", "Lain"},
+			want:   map[string]int{"sku": 0, "name": 1, "price": 2, "cost": 3},
+		},
+		{
+			name:     "explicit mapping wins",
+			header:   []string{"Nama", "Deskripsi", "Harga Jual"},
+			explicit: map[string]string{"name": "deskripsi", "price": "Harga Jual"},
+			want:     map[string]int{"name": 1, "price": 2},
+		},
+		{
+			name:   "first of duplicate headers",
+			header: []string{"nama", "nama", "stok"},
+			want:   map[string]int{"name": 0, "stock": 2},
+		},
+		{name: "no name column", header: []string{"sku", "harga"}, wantErr: true},
+		{name: "unknown field", header: []string{"nama"}, explicit: map[string]string{"colour": "nama"}, wantErr: true},
+		{name: "mapped column missing", header: []string{"nama"}, explicit: map[string]string{"price": "harga"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := MapColumns(tt.header, tt.explicit)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseProducts(t *testing.T) {
+	str := func(s string) *string { return &s }
+	money := func(m models.Money) *models.Money { return &m }
+	qty := func(q models.Qty) *models.Qty { return &q }
+	codes := func(c ...string) *[]string { return &c }
+
+	header := []string{"sku", "nama", "harga", "stok", "barcode", "hpp"}
+	cols, err := MapColumns(header, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		cells        []string
+		decimalComma bool
+		want         ProductRow // Line is checked separately
+	}{
+		{
+			name:  "every column",
+			cells: []string{"BR-5", "Beras 5kg", "Rp 72,500", "12.5", "8991234567891;0036000291452", "65000"},
+			want: ProductRow{SKU: str("BR-5"), Name: "Beras 5kg", Price: money(7250000), Stock: qty(12500),
+				Barcodes: codes("8991234567891", "0036000291452"), Cost: money(6500000)},
+		},
+		{
+			name:  "empty cells leave fields alone",
+			cells: []string{"", "Gula", " ", ""},
+			want:  ProductRow{Name: "Gula"},
+		},
+		{
+			name:         "Indonesian number format",
+			cells:        []string{"", "Minyak", "1.250.000,50", "2,5"},
+			decimalComma: true,
+			want:         ProductRow{Name: "Minyak", Price: money(125000050), Stock: qty(2500)},
+		},
+		{
+			name:  "bad cells are reported",
+			cells: []string{"", "Teh", "murah", "-1", "", "-500"},
+			want: ProductRow{Name: "Teh", Errors: []string{
+				`price: "murah" is not a valid amount`,
+				`cost: "-500" is not a valid amount`,
+				`stock: "-1" is not a valid quantity`,
+			}},
+		},
+	}
+	for _, tt := range tests {
+		rows := ParseProducts(Table{Rows: [][]string{header, {"", " "}, tt.cells}, DecimalComma: tt.decimalComma}, cols)
+		if len(rows) != 1 {
+			t.Errorf("%s: %d rows, want 1 (blank line skipped)", tt.name, len(rows))
+			continue
+		}
+		got := rows[0]
+		if got.Line != 3 {
+			t.Errorf("%s: line %d, want 3", tt.name, got.Line)
+		}
+		got.Line = 0
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
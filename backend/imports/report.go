@@ -0,0 +1,74 @@
+package imports
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Import actions reported per row.
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+	ActionError     = "error"
+)
+
+// Change is one field an update row changes, formatted for display.
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// RowResult is the outcome of one imported row.
+type RowResult struct {
+	Line      int      `json:"line"`
+	Action    string   `json:"action"`
+	ProductID string   `json:"product_id,omitempty"`
+	SKU       string   `json:"sku"`
+	Name      string   `json:"name"`
+	Changes   []Change `json:"changes,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// WriteReport writes the per-row results to an Excel file in dir and
+// returns its file name.
+func WriteReport(dir string, results []RowResult) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := "Import"
+	f.SetSheetName("Sheet1", sheet)
+	for i, h := range []string{"Baris", "Aksi", "SKU", "Nama", "Perubahan", "Keterangan"} {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(sheet, cell, h)
+	}
+	for i, r := range results {
+		row := i + 2
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), r.Line)
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), r.Action)
+		f.SetCellValue(sheet, fmt.Sprintf("C%d", row), r.SKU)
+		f.SetCellValue(sheet, fmt.Sprintf("D%d", row), r.Name)
+		changes := make([]string, len(r.Changes))
+		for j, ch := range r.Changes {
+			changes[j] = fmt.Sprintf("%s: %s → %s", ch.Field, ch.Old, ch.New)
+		}
+		f.SetCellValue(sheet, fmt.Sprintf("E%d", row), strings.Join(changes, "; "))
+		f.SetCellValue(sheet, fmt.Sprintf("F%d", row), strings.Join(r.Errors, "; "))
+	}
+	f.SetColWidth(sheet, "C", "D", 28)
+	f.SetColWidth(sheet, "E", "F", 60)
+
+	name := fmt.Sprintf("import-report-%s.xlsx", time.Now().Format("20060102-150405.000"))
+	if err := f.SaveAs(filepath.Join(dir, name)); err != nil {
+		return "", err
+	}
+	return name, nil
+}
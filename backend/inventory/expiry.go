@@ -0,0 +1,58 @@
+package inventory
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/models"
+)
+
+// Expiry states of a batch with stock left.
+const (
+	Expired    = "expired"
+	NearExpiry = "near_expiry"
+)
+
+// ExpiryRow is a batch with stock left that has expired or expires soon.
+type ExpiryRow struct {
+	BatchID    string       `json:"batch_id"`
+	ProductID  string       `json:"product_id"`
+	SKU        string       `json:"sku"`
+	Name       string       `json:"name"`
+	Unit       string       `json:"unit"`
+	BatchNo    string       `json:"batch_no"`
+	ExpiryDate time.Time    `json:"expiry_date"`
+	DaysLeft   int          `json:"days_left"` // negatif = sudah lewat
+	Remaining  models.Qty   `json:"remaining"`
+	UnitCost   models.Money `json:"unit_cost"`
+	Value      models.Money `json:"value"` // Remaining x UnitCost
+	Status     string       `json:"status"`
+}
+
+// ExpiringBatches lists the batches at branchID with stock left that expired
+// before today or expire within days of it, soonest first.
+func ExpiringBatches(db *gorm.DB, branchID string, today time.Time, days int) ([]ExpiryRow, error) {
+	y, m, d := today.Date()
+	today = time.Date(y, m, d, 0, 0, 0, 0, today.Location())
+	var rows []ExpiryRow
+	err := db.Table("stock_batches").
+		Select("stock_batches.id AS batch_id, stock_batches.product_id, products.sku, products.name, products.unit, "+
+			"stock_batches.batch_no, stock_batches.expiry_date, stock_batches.remaining, stock_batches.unit_cost").
+		Joins("JOIN products ON products.id = stock_batches.product_id").
+		Where("stock_batches.branch_id = ? AND stock_batches.remaining > 0 AND stock_batches.is_deleted = ?", branchID, false).
+		Where("stock_batches.expiry_date IS NOT NULL AND stock_batches.expiry_date < ?", today.AddDate(0, 0, days+1)).
+		Order("stock_batches.expiry_date, products.name").
+		Scan(&rows).Error
+	for i := range rows {
+		r := &rows[i]
+		r.DaysLeft = int(math.Round(r.ExpiryDate.Sub(today).Hours() / 24))
+		r.Value = r.UnitCost.TimesQty(r.Remaining)
+		r.Status = NearExpiry
+		if r.ExpiryDate.Before(today) {
+			r.Status = Expired
+		}
+	}
+	return rows, err
+}
@@ -0,0 +1,179 @@
+package inventory
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/models"
+)
+
+// LowStockItem is a product whose stock at a branch is at or below its
+// minimum.
+type LowStockItem struct {
+	ProductID  string     `json:"product_id"`
+	SKU        string     `json:"sku"`
+	Name       string     `json:"name"`
+	Unit       string     `json:"unit"`
+	Stock      models.Qty `json:"stock"`
+	MinStock   models.Qty `json:"min_stock"`
+	ReorderQty models.Qty `json:"reorder_qty"`
+	SupplierID string     `json:"supplier_id"`
+	Shortage   models.Qty `json:"shortage"` // MinStock - Stock
+}
+
+// withBranchStock joins reorder_levels to their products and the products'
+// stock at the level's branch (no stock row means none on hand).
+func withBranchStock(db *gorm.DB, branchID string) *gorm.DB {
+	return db.Table("reorder_levels").
+		Joins("JOIN products ON products.id = reorder_levels.product_id").
+		Joins("LEFT JOIN branch_stocks bs ON bs.product_id = reorder_levels.product_id AND bs.branch_id = reorder_levels.branch_id").
+		Where("reorder_levels.branch_id = ? AND reorder_levels.is_deleted = ? AND products.is_deleted = ?", branchID, false, false)
+}
+
+// lowStock selects the live products at or below a minimum set for branchID.
+// A minimum of zero means no alert.
+func lowStock(db *gorm.DB, branchID string) *gorm.DB {
+	return withBranchStock(db, branchID).
+		Where("reorder_levels.min_stock > 0 AND COALESCE(bs.qty, 0) <= reorder_levels.min_stock")
+}
+
+// LowStock lists the products running out at branchID, shortest first.
+func LowStock(db *gorm.DB, branchID string) ([]LowStockItem, error) {
+	var items []LowStockItem
+	err := lowStock(db, branchID).
+		Select("products.id AS product_id, products.sku, products.name, products.unit, COALESCE(bs.qty, 0) AS stock, " +
+			"reorder_levels.min_stock, reorder_levels.reorder_qty, reorder_levels.supplier_id").
+		Order("COALESCE(bs.qty, 0) - reorder_levels.min_stock, products.name").
+		Scan(&items).Error
+	for i := range items {
+		items[i].Shortage = items[i].MinStock - items[i].Stock
+	}
+	return items, err
+}
+
+// CountLowStock is the number of products LowStock would list.
+func CountLowStock(db *gorm.DB, branchID string) (int64, error) {
+	var n int64
+	err := lowStock(db, branchID).Count(&n).Error
+	return n, err
+}
+
+// SalesVelocity is the average base units sold per day of each product at
+// branchID over the days before now.
+func SalesVelocity(db *gorm.DB, branchID string, now time.Time, days int) (map[string]float64, error) {
+	var rows []struct {
+		ProductID string
+		Qty       models.Qty
+	}
+	err := db.Table("sale_items").
+		Select("sale_items.product_id, SUM(sale_items.base_qty) AS qty").
+		Joins("JOIN sales ON sales.id = sale_items.sale_id").
+		Where("sales.branch_id = ? AND sales.created_at >= ? AND sales.is_deleted = ? AND sale_items.is_deleted = ?",
+			branchID, now.AddDate(0, 0, -days), false, false).
+		Group("sale_items.product_id").
+		Scan(&rows).Error
+	velocity := make(map[string]float64, len(rows))
+	for _, r := range rows {
+		velocity[r.ProductID] = r.Qty.Float() / float64(days)
+	}
+	return velocity, err
+}
+
+// OnOrder is the base quantity of each product still expected at branchID
+// from purchase orders that are drafted, sent or partially received.
+func OnOrder(db *gorm.DB, branchID string) (map[string]models.Qty, error) {
+	var items []models.PurchaseOrderItem
+	err := db.Table("purchase_order_items").
+		Select("purchase_order_items.*").
+		Joins("JOIN purchase_orders ON purchase_orders.id = purchase_order_items.purchase_order_id").
+		Where("purchase_orders.branch_id = ? AND purchase_orders.status IN ? AND purchase_orders.is_deleted = ? AND purchase_order_items.is_deleted = ?",
+			branchID, []string{models.PurchaseOrderDraft, models.PurchaseOrderSent, models.PurchaseOrderPartial}, false, false).
+		Find(&items).Error
+	open := map[string]models.Qty{}
+	for _, it := range items {
+		if left := it.Qty - it.ReceivedQty; left > 0 {
+			open[it.ProductID] += left.Mul(it.UnitFactor)
+		}
+	}
+	return open, err
+}
+
+// Suggestion is a product worth reordering and how much of it, in base
+// units.
+type Suggestion struct {
+	ProductID  string       `json:"product_id"`
+	SKU        string       `json:"sku"`
+	Name       string       `json:"name"`
+	Unit       string       `json:"unit"`
+	SupplierID string       `json:"supplier_id"`
+	Stock      models.Qty   `json:"stock"`
+	MinStock   models.Qty   `json:"min_stock"`
+	ReorderQty models.Qty   `json:"reorder_qty"`
+	OnOrder    models.Qty   `json:"on_order"`
+	DailySales float64      `json:"daily_sales"`
+	Qty        models.Qty   `json:"qty"`
+	UnitCost   models.Money `json:"unit_cost"` // HPP per satuan dasar
+}
+
+// Suggest proposes orders for the products with a reorder level at branchID.
+// A product needs enough to stay above its minimum for coverDays at the rate
+// it sold over the last days, counting what is already on order; the order is
+// rounded up to whole base units and is at least its reorder quantity.
+func Suggest(db *gorm.DB, branchID string, now time.Time, days, coverDays int) ([]Suggestion, error) {
+	var levels []struct {
+		models.ReorderLevel
+		SKU   string
+		Name  string
+		Unit  string
+		Stock models.Qty
+		Cost  models.Money
+	}
+	if err := withBranchStock(db, branchID).
+		Select("reorder_levels.*, products.sku, products.name, products.unit, COALESCE(bs.qty, 0) AS stock, COALESCE(NULLIF(bs.cost, 0), products.cost) AS cost").
+		Scan(&levels).Error; err != nil {
+		return nil, err
+	}
+	velocity, err := SalesVelocity(db, branchID, now, days)
+	if err != nil {
+		return nil, err
+	}
+	onOrder, err := OnOrder(db, branchID)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := []Suggestion{}
+	for _, l := range levels {
+		daily := velocity[l.ProductID]
+		demand := models.Qty(math.Ceil(daily * float64(coverDays) * 1000))
+		need := l.MinStock + demand - l.Stock - onOrder[l.ProductID]
+		if need <= 0 {
+			continue
+		}
+		qty := models.Qty(math.Ceil(float64(need)/1000) * 1000) // satuan dasar utuh
+		if qty < l.ReorderQty {
+			qty = l.ReorderQty
+		}
+		suggestions = append(suggestions, Suggestion{
+			ProductID:  l.ProductID,
+			SKU:        l.SKU,
+			Name:       l.Name,
+			Unit:       l.Unit,
+			SupplierID: l.SupplierID,
+			Stock:      l.Stock,
+			MinStock:   l.MinStock,
+			ReorderQty: l.ReorderQty,
+			OnOrder:    onOrder[l.ProductID],
+			DailySales: math.Round(daily*1000) / 1000,
+			Qty:        qty,
+			UnitCost:   l.Cost,
+		})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].Name < suggestions[j].Name
+	})
+	return suggestions, nil
+}
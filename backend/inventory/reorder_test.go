@@ -0,0 +1,150 @@
+package inventory
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"shosha_mart_backend/models"
+)
+
+// testDB opens an in-memory database. One connection keeps every query on
+// the same database.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.Product{}, &models.BranchStock{}, &models.ReorderLevel{}, &models.Sale{}, &models.SaleItem{},
+		&models.PurchaseOrder{}, &models.PurchaseOrderItem{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, rows ...interface{}) {
+	t.Helper()
+	for _, r := range rows {
+		if err := db.Create(r).Error; err != nil {
+			t.Fatalf("create %T: %v", r, err)
+		}
+	}
+}
+
+// product adds a product with its stock and reorder level at b1.
+func product(t *testing.T, db *gorm.DB, id, name string, stock, min, reorder float64) {
+	t.Helper()
+	mustCreate(t, db,
+		&models.Product{ID: id, Name: name, Unit: "pcs", Cost: 500000},
+		&models.BranchStock{ID: "b1/" + id, ProductID: id, BranchID: "b1", Qty: models.QtyFromFloat(stock)},
+		&models.ReorderLevel{ID: "b1/" + id, ProductID: id, BranchID: "b1", MinStock: models.QtyFromFloat(min), ReorderQty: models.QtyFromFloat(reorder)},
+	)
+}
+
+func sold(t *testing.T, db *gorm.DB, id, branchID, productID string, qty float64, at time.Time) {
+	t.Helper()
+	mustCreate(t, db,
+		&models.Sale{ID: id, BranchID: branchID, CreatedAt: at},
+		&models.SaleItem{ID: id + "-1", SaleID: id, ProductID: productID, BaseQty: models.QtyFromFloat(qty)},
+	)
+}
+
+func TestReorder(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	db := testDB(t)
+	product(t, db, "p1", "Beras", 4, 10, 0)
+	product(t, db, "p2", "Gula", 3, 5, 24)
+	product(t, db, "p3", "Kopi", 10, 5, 0)
+	product(t, db, "p4", "Minyak", 2, 10, 0)
+	product(t, db, "p5", "Teh", 0, 2, 0)
+	product(t, db, "p6", "Tepung", 0, 1.5, 0)
+	product(t, db, "p7", "Sabun", 0, 5, 0)
+	// Teh has no stock row at all; Sabun is no longer sold
+	mustCreate(t, db, &models.ReorderLevel{ID: "b2/p3", ProductID: "p3", BranchID: "b2", MinStock: models.Units(50)})
+	if err := db.Delete(&models.BranchStock{}, "id = ?", "b1/p5").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.Product{}).Where("id = ?", "p7").Update("is_deleted", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	sold(t, db, "s1", "b1", "p1", 60, now.AddDate(0, 0, -5))
+	sold(t, db, "s2", "b1", "p5", 1, now.AddDate(0, 0, -40)) // di luar jendela 30 hari
+	sold(t, db, "s3", "b2", "p2", 300, now.AddDate(0, 0, -1))
+	mustCreate(t, db,
+		&models.PurchaseOrder{ID: "po1", BranchID: "b1", Status: models.PurchaseOrderSent},
+		&models.PurchaseOrderItem{ID: "po1-1", PurchaseOrderID: "po1", ProductID: "p4", Qty: models.Units(2), UnitFactor: models.Units(5)},
+		&models.PurchaseOrder{ID: "po2", BranchID: "b1", Status: models.PurchaseOrderClosed},
+		&models.PurchaseOrderItem{ID: "po2-1", PurchaseOrderID: "po2", ProductID: "p1", Qty: models.Units(100), UnitFactor: models.Units(1)},
+	)
+
+	tests := []struct {
+		name     string
+		low      bool    // listed by LowStock
+		shortage float64 // MinStock - Stock
+		suggest  float64 // 0 = not suggested
+	}{
+		{"Beras", true, 6, 34},   // 10 + 2/hari x 14 - 4
+		{"Gula", true, 2, 24},    // kurang 2, pesanan minimum 24
+		{"Kopi", false, 0, 0},    // stok di atas minimum
+		{"Minyak", true, 8, 0},   // 10 sudah dipesan
+		{"Teh", true, 2, 2},      // tanpa baris stok = 0
+		{"Tepung", true, 1.5, 2}, // dibulatkan ke satuan utuh
+		{"Sabun", false, 0, 0},   // produk dihapus
+	}
+
+	low, err := LowStock(db, "b1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lowByName := map[string]LowStockItem{}
+	for _, it := range low {
+		lowByName[it.Name] = it
+	}
+	n, err := CountLowStock(db, "b1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(low)) {
+		t.Errorf("CountLowStock = %d, LowStock lists %d", n, len(low))
+	}
+
+	suggestions, err := Suggest(db, "b1", now, 30, 14)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]Suggestion{}
+	for _, s := range suggestions {
+		byName[s.Name] = s
+	}
+
+	for _, tt := range tests {
+		it, ok := lowByName[tt.name]
+		if ok != tt.low {
+			t.Errorf("%s: low stock = %v, want %v", tt.name, ok, tt.low)
+		} else if ok && it.Shortage != models.QtyFromFloat(tt.shortage) {
+			t.Errorf("%s: shortage = %s, want %v", tt.name, it.Shortage, tt.shortage)
+		}
+		s, ok := byName[tt.name]
+		if ok != (tt.suggest > 0) {
+			t.Errorf("%s: suggested = %v, want %v", tt.name, ok, tt.suggest > 0)
+		} else if ok && s.Qty != models.QtyFromFloat(tt.suggest) {
+			t.Errorf("%s: suggested qty = %s, want %v", tt.name, s.Qty, tt.suggest)
+		}
+	}
+	if len(low) > 0 && low[0].Name != "Minyak" {
+		t.Errorf("LowStock starts with %s, want the biggest shortage (Minyak)", low[0].Name)
+	}
+	if s := byName["Minyak"]; s.OnOrder != 0 {
+		t.Errorf("Minyak was suggested with %s on order", s.OnOrder)
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/joho/godotenv"
 
 	"shosha_mart_backend/config"
+	"shosha_mart_backend/costing"
+	"shosha_mart_backend/pricing"
 	"shosha_mart_backend/routes"
 	"shosha_mart_backend/services"
 	syncsvc "shosha_mart_backend/sync"
@@ -25,6 +27,9 @@ func main() {
 	}
 
 	cfg := config.Load()
+	if !costing.Valid(cfg.CostMethod) {
+		log.Fatalf("POS_COST_METHOD must be %q or %q, got %q", costing.Average, costing.FIFO, cfg.CostMethod)
+	}
 
 	db, err := services.Connect(cfg)
 	if err != nil {
@@ -34,6 +39,14 @@ func main() {
 	worker := syncsvc.NewWorker(db, cfg)
 	worker.StartBackground()
 
+	// terapkan perubahan harga terjadwal cabang ini; perubahan untuk semua
+	// cabang diterapkan upstream, atau di sini bila tanpa upstream
+	branches := []string{cfg.BranchID}
+	if cfg.Upstream == "" {
+		branches = append(branches, "")
+	}
+	pricing.StartScheduler(db, time.Minute, branches...)
+
 	r := gin.Default()
 	routes.Register(r, db, cfg, worker)
 
@@ -0,0 +1,123 @@
+// Package migrations holds one-off data migrations that AutoMigrate cannot
+// express. Both the sidecar (SQLite) and the upstream (Postgres) run them
+// before AutoMigrate.
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SchemaMigration records a data migration that has been applied.
+type SchemaMigration struct {
+	ID        string    `gorm:"primaryKey"`
+	AppliedAt time.Time `gorm:"autoCreateTime"`
+}
+
+type migration struct {
+	id  string
+	run func(tx *gorm.DB) error
+}
+
+var all = []migration{
+	{id: "0001_money_minor_units", run: moneyToMinorUnits},
+	{id: "0002_qty_milli_units", run: qtyToMilliUnits},
+}
+
+// Run applies pending data migrations in order, each in its own transaction.
+func Run(db *gorm.DB) error {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return fmt.Errorf("schema_migrations: %w", err)
+	}
+	for _, m := range all {
+		var count int64
+		if err := db.Model(&SchemaMigration{}).Where("id = ?", m.id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.run(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{ID: m.id}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", m.id, err)
+		}
+	}
+	return nil
+}
+
+// moneyColumns lists every column that moved from float rupiah to integer sen.
+var moneyColumns = map[string][]string{
+	"products":      {"price", "price_investor", "price_shosha"},
+	"sales":         {"subtotal", "discount_amount", "tax_amount", "total", "change_due"},
+	"sale_items":    {"price", "discount_amount", "subtotal", "tax_base", "tax_amount"},
+	"sale_payments": {"amount", "tendered"},
+	"promotions":    {"bundle_price", "min_spend"},
+}
+
+// moneyToMinorUnits rescales existing rupiah amounts to sen. On a fresh
+// database the tables do not exist yet and there is nothing to convert;
+// AutoMigrate then changes the column types to integer.
+func moneyToMinorUnits(tx *gorm.DB) error {
+	for table, columns := range moneyColumns {
+		if !tx.Migrator().HasTable(table) {
+			continue
+		}
+		for _, col := range columns {
+			if !tx.Migrator().HasColumn(table, col) {
+				continue
+			}
+			sql := fmt.Sprintf("UPDATE %s SET %s = CAST(ROUND(%s * 100) AS BIGINT) WHERE %s IS NOT NULL", table, col, col, col)
+			if err := tx.Exec(sql).Error; err != nil {
+				return fmt.Errorf("%s.%s: %w", table, col, err)
+			}
+		}
+	}
+	return nil
+}
+
+// qtyColumns lists every column that moved from whole units to thousandths.
+var qtyColumns = map[string][]string{
+	"products":           {"stock"},
+	"sale_items":         {"qty"},
+	"draft_sale_items":   {"qty"},
+	"stock_opname_items": {"system_qty", "physical_qty"},
+}
+
+// qtyToMilliUnits rescales existing quantities to thousandths of a unit and
+// fills the unit columns of old sale lines (sold in the base unit). The new
+// sale_items columns are added here because AutoMigrate runs afterwards.
+func qtyToMilliUnits(tx *gorm.DB) error {
+	for table, columns := range qtyColumns {
+		if !tx.Migrator().HasTable(table) {
+			continue
+		}
+		for _, col := range columns {
+			if !tx.Migrator().HasColumn(table, col) {
+				continue
+			}
+			sql := fmt.Sprintf("UPDATE %s SET %s = %s * 1000 WHERE %s IS NOT NULL", table, col, col, col)
+			if err := tx.Exec(sql).Error; err != nil {
+				return fmt.Errorf("%s.%s: %w", table, col, err)
+			}
+		}
+	}
+	if !tx.Migrator().HasTable("sale_items") {
+		return nil
+	}
+	for _, col := range []string{"unit_factor", "base_qty"} {
+		if tx.Migrator().HasColumn("sale_items", col) {
+			continue
+		}
+		if err := tx.Exec(fmt.Sprintf("ALTER TABLE sale_items ADD COLUMN %s BIGINT", col)).Error; err != nil {
+			return fmt.Errorf("sale_items.%s: %w", col, err)
+		}
+	}
+	return tx.Exec("UPDATE sale_items SET unit_factor = 1000, base_qty = qty").Error
+}
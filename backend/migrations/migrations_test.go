@@ -0,0 +1,231 @@
+package migrations
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"shosha_mart_backend/models"
+)
+
+// openDB opens an empty in-memory database. One connection keeps every
+// query on the same database.
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func mustExec(t *testing.T, db *gorm.DB, stmts ...string) {
+	t.Helper()
+	for _, s := range stmts {
+		if err := db.Exec(s).Error; err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+	}
+}
+
+// value reads one column of one row; NULL reads as ok == false.
+func value(t *testing.T, db *gorm.DB, table, column, id string) (v float64, ok bool) {
+	t.Helper()
+	var got sql.NullFloat64
+	if err := db.Raw(fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", column, table), id).Row().Scan(&got); err != nil {
+		t.Fatalf("%s.%s of %s: %v", table, column, id, err)
+	}
+	return got.Float64, got.Valid
+}
+
+func TestRunFreshDatabase(t *testing.T) {
+	db := openDB(t)
+	if err := Run(db); err != nil {
+		t.Fatal(err)
+	}
+	var n int64
+	if err := db.Model(&SchemaMigration{}).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(all)) {
+		t.Errorf("recorded %d migrations, want %d", n, len(all))
+	}
+}
+
+func TestMoneyToMinorUnits(t *testing.T) {
+	db := openDB(t)
+	mustExec(t, db,
+		"CREATE TABLE products (id TEXT PRIMARY KEY, price REAL, price_investor REAL, price_shosha REAL)",
+		"CREATE TABLE sale_payments (id TEXT PRIMARY KEY, amount REAL, tendered REAL)",
+		"INSERT INTO products VALUES ('p1', 12500.5, 12000, NULL)",
+		"INSERT INTO products VALUES ('p2', 0.1, 0, 7)",
+		"INSERT INTO sale_payments VALUES ('s1', 50000, 100000)",
+	)
+	if err := Run(db); err != nil {
+		t.Fatal(err)
+	}
+	// a second run must not scale the amounts again
+	if err := Run(db); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		table, column, id string
+		want              float64
+		null              bool
+	}{
+		{"products", "price", "p1", 1250050, false},
+		{"products", "price_investor", "p1", 1200000, false},
+		{"products", "price_shosha", "p1", 0, true},
+		{"products", "price", "p2", 10, false},
+		{"products", "price_investor", "p2", 0, false},
+		{"products", "price_shosha", "p2", 700, false},
+		{"sale_payments", "amount", "s1", 5000000, false},
+		{"sale_payments", "tendered", "s1", 10000000, false},
+	}
+	for _, tt := range tests {
+		got, ok := value(t, db, tt.table, tt.column, tt.id)
+		if ok == tt.null {
+			t.Errorf("%s.%s of %s: null = %v, want %v", tt.table, tt.column, tt.id, !ok, tt.null)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s.%s of %s = %v, want %v", tt.table, tt.column, tt.id, got, tt.want)
+		}
+	}
+}
+
+// TestMigratedMoneyRoundTrips loads amounts migrated from a float rupiah
+// schema through the models after AutoMigrate, as the sidecar does at start.
+func TestMigratedMoneyRoundTrips(t *testing.T) {
+	db := openDB(t)
+	mustExec(t, db,
+		"CREATE TABLE products (id TEXT PRIMARY KEY, name TEXT, price REAL, price_investor REAL, price_shosha REAL, stock INTEGER)",
+		"CREATE TABLE sales (id TEXT PRIMARY KEY, receipt_no TEXT, subtotal REAL, discount_amount REAL, tax_amount REAL, total REAL, change_due REAL, payment_method TEXT)",
+		"INSERT INTO products VALUES ('p1', 'Beras', 12500.5, 12000, NULL, 12)",
+		"INSERT INTO sales VALUES ('s1', 'R1', 27500, 2500, 2750, 27750, 2250.75, 'cash')",
+	)
+	if err := Run(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Product{}, &models.Sale{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var p models.Product
+	if err := db.First(&p, "id = ?", "p1").Error; err != nil {
+		t.Fatal(err)
+	}
+	var s models.Sale
+	if err := db.First(&s, "id = ?", "s1").Error; err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		got  models.Money
+		want models.Money
+	}{
+		{"product price", p.Price, 1250050},
+		{"product investor price", p.PriceInvestor, 1200000},
+		{"product shosha price left empty", p.PriceShosha, 0},
+		{"sale subtotal", s.Subtotal, 2750000},
+		{"sale discount", s.DiscountAmount, 250000},
+		{"sale tax", s.TaxAmount, 275000},
+		{"sale total", s.Total, 2775000},
+		{"sale change", s.ChangeDue, 225075},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %d sen, want %d", tt.name, int64(tt.got), int64(tt.want))
+		}
+	}
+	if p.Stock != models.Units(12) {
+		t.Errorf("product stock = %s, want 12", p.Stock)
+	}
+
+	// JSON tetap dalam rupiah seperti sebelum migrasi
+	for _, tt := range []struct {
+		v    interface{}
+		key  string
+		want float64
+	}{
+		{p, "price", 12500.5},
+		{p, "price_investor", 12000},
+		{s, "total", 27750},
+		{s, "change_due", 2250.75},
+	} {
+		raw, err := json.Marshal(tt.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			t.Fatal(err)
+		}
+		if fields[tt.key] != tt.want {
+			t.Errorf("JSON %s = %v, want %v", tt.key, fields[tt.key], tt.want)
+		}
+	}
+
+	// a write through the model stays in sen
+	if err := db.Model(&p).Update("price", models.FromRupiah(13000)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := value(t, db, "products", "price", "p1"); got != 1300000 {
+		t.Errorf("price written through the model = %v, want 1300000", got)
+	}
+}
+
+func TestQtyToMilliUnits(t *testing.T) {
+	db := openDB(t)
+	mustExec(t, db,
+		"CREATE TABLE products (id TEXT PRIMARY KEY, price REAL, stock INTEGER)",
+		"CREATE TABLE sale_items (id TEXT PRIMARY KEY, qty INTEGER, price REAL)",
+		"CREATE TABLE stock_opname_items (id TEXT PRIMARY KEY, system_qty INTEGER, physical_qty INTEGER)",
+		"INSERT INTO products VALUES ('p1', 1000, 12)",
+		"INSERT INTO products VALUES ('p2', 1000, NULL)",
+		"INSERT INTO sale_items VALUES ('i1', 3, 1000)",
+		"INSERT INTO stock_opname_items VALUES ('o1', 10, 8)",
+	)
+	if err := Run(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := Run(db); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		table, column, id string
+		want              float64
+		null              bool
+	}{
+		{"products", "stock", "p1", 12000, false},
+		{"products", "stock", "p2", 0, true},
+		{"sale_items", "qty", "i1", 3000, false},
+		{"sale_items", "unit_factor", "i1", 1000, false},
+		{"sale_items", "base_qty", "i1", 3000, false},
+		{"stock_opname_items", "system_qty", "o1", 10000, false},
+		{"stock_opname_items", "physical_qty", "o1", 8000, false},
+	}
+	for _, tt := range tests {
+		got, ok := value(t, db, tt.table, tt.column, tt.id)
+		if ok == tt.null {
+			t.Errorf("%s.%s of %s: null = %v, want %v", tt.table, tt.column, tt.id, !ok, tt.null)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s.%s of %s = %v, want %v", tt.table, tt.column, tt.id, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Attributes are free-form product properties such as ukuran, warna or rasa.
+// They are stored as a JSON object in a text column so both SQLite and the
+// upstream Postgres keep them without extra tables.
+type Attributes map[string]string
+
+// GormDataType keeps the column a plain text column on every dialect.
+func (Attributes) GormDataType() string {
+	return "text"
+}
+
+// Value implements driver.Valuer.
+func (a Attributes) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return "{}", nil
+	}
+	b, err := json.Marshal(map[string]string(a))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner.
+func (a *Attributes) Scan(src interface{}) error {
+	var raw []byte
+	switch v := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return fmt.Errorf("attributes: cannot scan %T", src)
+	}
+	if len(raw) == 0 {
+		*a = nil
+		return nil
+	}
+	m := map[string]string{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return fmt.Errorf("attributes: %w", err)
+	}
+	*a = m
+	return nil
+}
@@ -4,19 +4,289 @@ import "time"
 
 // Product represents an inventory item stored locally first.
 type Product struct {
-	ID            string     `json:"id" gorm:"primaryKey"`
-	Name          string     `json:"name"`
-	Unit          string     `json:"unit"` // Satuan (kg, pcs, liter, dll)
-	Stock         int        `json:"stock"`
-	Price         float64    `json:"price"`          // Legacy/default price used by existing sales logic
-	PriceInvestor float64    `json:"price_investor"` // Harga untuk Investor
-	PriceShosha   float64    `json:"price_shosha"`   // Harga untuk SHOSHA
-	Synced        bool       `json:"synced"`
-	BranchID      string     `json:"branch_id"`
-	IsDeleted     bool       `json:"is_deleted" gorm:"default:false"`
-	DeletedAt     *time.Time `json:"deleted_at"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	ID            string           `json:"id" gorm:"primaryKey"`
+	SKU           string           `json:"sku" gorm:"index"` // kode barang internal, unik di antara produk aktif
+	Name          string           `json:"name"`
+	Unit          string           `json:"unit"`                     // Satuan dasar stok (kg, pcs, liter, dll)
+	CategoryID    string           `json:"category_id" gorm:"index"` // kosong = tanpa kategori
+	BrandID       string           `json:"brand_id" gorm:"index"`
+	Attributes    Attributes       `json:"attributes"`
+	Stock         Qty              `json:"stock"`          // stok cabang sidecar ini (lihat BranchStock), satuan dasar; tidak disinkronkan
+	Price         Money            `json:"price"`          // Legacy/default price used by existing sales logic
+	PriceInvestor Money            `json:"price_investor"` // Harga untuk Investor
+	PriceShosha   Money            `json:"price_shosha"`   // Harga untuk SHOSHA
+	Cost          Money            `json:"cost"`           // HPP per satuan dasar (rata-rata, atau lapisan FIFO terakhir)
+	TaxRateID     string           `json:"tax_rate_id"`    // kosong = tarif default
+	Synced        bool             `json:"synced"`
+	BranchID      string           `json:"branch_id"`
+	IsDeleted     bool             `json:"is_deleted" gorm:"default:false"`
+	DeletedAt     *time.Time       `json:"deleted_at"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+	Barcodes      []ProductBarcode `json:"barcodes,omitempty"`
+	Units         []ProductUnit    `json:"units,omitempty"`
+}
+
+// ProductUnit is another unit a product is sold or counted in, e.g. a 25 kg
+// "sak" of rice or a "slop" of 10 packs. Factor is the number of base units
+// (Product.Unit) in one of this unit.
+type ProductUnit struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	ProductID string     `json:"product_id" gorm:"index"`
+	Name      string     `json:"name"`
+	Factor    Qty        `json:"factor"`
+	Price     Money      `json:"price"` // 0 = harga satuan dasar x Factor
+	Synced    bool       `json:"synced"`
+	IsDeleted bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// CostLayer is a lot of stock received at one unit cost. Under FIFO costing
+// sales consume the oldest layers first; Remaining is what is left of Qty.
+type CostLayer struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	ProductID string     `json:"product_id" gorm:"index"`
+	BranchID  string     `json:"branch_id"`
+	Source    string     `json:"source"` // asal stok: lihat Movement*
+	Qty       Qty        `json:"qty"`    // satuan dasar
+	Remaining Qty        `json:"remaining"`
+	UnitCost  Money      `json:"unit_cost"`
+	Synced    bool       `json:"synced"`
+	IsDeleted bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Stock movement sources: the kind of document that changed the stock.
+const (
+	MovementOpening     = "opening"      // stok awal produk baru
+	MovementAdjustment  = "adjustment"   // penyesuaian stok
+	MovementImport      = "import"       // impor produk
+	MovementSale        = "sale"         // penjualan
+	MovementSaleEdit    = "sale_edit"    // item penjualan diubah, ditambah atau dihapus
+	MovementSaleVoid    = "sale_void"    // penjualan dihapus
+	MovementReceipt     = "receipt"      // penerimaan barang
+	MovementTransferOut = "transfer_out" // mutasi keluar ke cabang lain
+	MovementTransferIn  = "transfer_in"  // mutasi masuk dari cabang lain
+	MovementOpname      = "opname"       // stock opname
+	MovementWriteOff    = "write_off"    // batch kedaluwarsa dihapusbukukan
+)
+
+// StockMovement is one line of a product's stock card: a change of Delta base
+// units at a branch, the document behind it and the stock after it.
+type StockMovement struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	ProductID string     `json:"product_id" gorm:"index"`
+	BranchID  string     `json:"branch_id" gorm:"index"`
+	Source    string     `json:"source"`
+	DocID     string     `json:"doc_id" gorm:"index"`
+	DocNo     string     `json:"doc_no"`
+	Delta     Qty        `json:"delta"`   // satuan dasar; negatif = keluar
+	Balance   Qty        `json:"balance"` // stok cabang setelah perubahan
+	UnitCost  Money      `json:"unit_cost"`
+	CreatedBy string     `json:"created_by"` // operator: header X-POS-User atau nama di dokumen
+	Note      string     `json:"note"`
+	Synced    bool       `json:"synced"`
+	IsDeleted bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"index"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// StockBatch is a lot of a product received at a branch with its batch number
+// and expiry date. Stock leaves from the batch that expires first (FEFO);
+// Remaining is what is left of Qty. Stock received without a batch number or
+// expiry date is not tracked in batches.
+type StockBatch struct {
+	ID         string     `json:"id" gorm:"primaryKey"`
+	ProductID  string     `json:"product_id" gorm:"index"`
+	BranchID   string     `json:"branch_id" gorm:"index"`
+	BatchNo    string     `json:"batch_no"`
+	ExpiryDate *time.Time `json:"expiry_date" gorm:"index"`
+	Qty        Qty        `json:"qty"` // satuan dasar
+	Remaining  Qty        `json:"remaining"`
+	UnitCost   Money      `json:"unit_cost"` // per satuan dasar
+	Source     string     `json:"source"`    // lihat Movement*
+	DocID      string     `json:"doc_id"`
+	DocNo      string     `json:"doc_no"`
+	Synced     bool       `json:"synced"`
+	IsDeleted  bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt  *time.Time `json:"deleted_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// StockBatchAllocation is the part of a batch taken by a document (a sale, a
+// transfer, ...), so stock coming back for that document returns to the same
+// batch. Qty drops as it does.
+type StockBatchAllocation struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	BatchID   string     `json:"batch_id" gorm:"index"`
+	ProductID string     `json:"product_id"`
+	DocID     string     `json:"doc_id" gorm:"index"`
+	Qty       Qty        `json:"qty"` // satuan dasar
+	Synced    bool       `json:"synced"`
+	IsDeleted bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// BranchStock is a product's stock at one branch, kept by the costing
+// package. Only the branch itself moves it; other branches and the upstream
+// see it through sync. Cost is the branch's own unit cost (moving average, or
+// the latest lot under FIFO), since branches buy at different prices.
+type BranchStock struct {
+	ID        string     `json:"id" gorm:"primaryKey"` // costing.StockID(branch, produk)
+	ProductID string     `json:"product_id" gorm:"index"`
+	BranchID  string     `json:"branch_id" gorm:"index"`
+	Qty       Qty        `json:"qty"`  // satuan dasar
+	Cost      Money      `json:"cost"` // HPP cabang ini; 0 = belum ada, pakai Product.Cost
+	Synced    bool       `json:"synced"`
+	IsDeleted bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// ReorderLevel is the minimum stock of a product at a branch and how much to
+// order when stock falls to it. SupplierID is the usual supplier, if any.
+type ReorderLevel struct {
+	ID         string     `json:"id" gorm:"primaryKey"`
+	ProductID  string     `json:"product_id" gorm:"index"`
+	BranchID   string     `json:"branch_id" gorm:"index"`
+	MinStock   Qty        `json:"min_stock"`   // satuan dasar
+	ReorderQty Qty        `json:"reorder_qty"` // satuan dasar; pesanan minimum
+	SupplierID string     `json:"supplier_id"`
+	Synced     bool       `json:"synced"`
+	IsDeleted  bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt  *time.Time `json:"deleted_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// ProductBarcode is one scannable code of a product; a product may carry
+// several (e.g. per supplier). Codes are unique among live barcodes.
+type ProductBarcode struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	ProductID string     `json:"product_id" gorm:"index"`
+	Code      string     `json:"code" gorm:"index"`
+	Synced    bool       `json:"synced"`
+	IsDeleted bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Category groups products. Categories nest through ParentID; an empty
+// ParentID is a top-level category.
+type Category struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	ParentID  string     `json:"parent_id" gorm:"index"`
+	Name      string     `json:"name"`
+	Path      string     `json:"path" gorm:"-"` // "Induk > Anak", diisi saat dibaca
+	Synced    bool       `json:"synced"`
+	IsDeleted bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Brand is the merek of a product.
+type Brand struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	Name      string     `json:"name"`
+	Synced    bool       `json:"synced"`
+	IsDeleted bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// ProductPriceHistory records one change of one tier price of a product.
+type ProductPriceHistory struct {
+	ID         string     `json:"id" gorm:"primaryKey"`
+	ProductID  string     `json:"product_id" gorm:"index"`
+	BranchID   string     `json:"branch_id"` // cabang tempat perubahan dibuat
+	Tier       string     `json:"tier"`      // kosong = harga umum
+	OldPrice   Money      `json:"old_price"`
+	NewPrice   Money      `json:"new_price"`
+	ChangedBy  string     `json:"changed_by"`
+	Source     string     `json:"source"`      // manual, schedule atau import
+	ScheduleID string     `json:"schedule_id"` // ScheduledPriceChange yang menerapkan
+	ChangedAt  time.Time  `json:"changed_at" gorm:"index"`
+	Synced     bool       `json:"synced"`
+	IsDeleted  bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt  *time.Time `json:"deleted_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// ScheduledPriceChange sets a tier price of a product once EffectiveAt has
+// passed. The sidecar of BranchID applies it as a BranchPrice; an empty
+// BranchID changes the product's own price and is applied by the upstream
+// server (or by the sidecar itself when it runs without one).
+type ScheduledPriceChange struct {
+	ID          string     `json:"id" gorm:"primaryKey"`
+	ProductID   string     `json:"product_id" gorm:"index"`
+	BranchID    string     `json:"branch_id"` // kosong = semua cabang
+	Tier        string     `json:"tier"`
+	NewPrice    Money      `json:"new_price"`
+	EffectiveAt time.Time  `json:"effective_at" gorm:"index"`
+	CreatedBy   string     `json:"created_by"`
+	AppliedAt   *time.Time `json:"applied_at"` // nil = belum diterapkan
+	Synced      bool       `json:"synced"`
+	IsDeleted   bool       `json:"is_deleted" gorm:"default:false"` // dibatalkan
+	DeletedAt   *time.Time `json:"deleted_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// BranchPrice is a branch's own price for one tier of a product, set by a
+// scheduled price change for that branch. It wins over the product's tier
+// price in sales at the branch.
+type BranchPrice struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	ProductID string     `json:"product_id" gorm:"index"`
+	BranchID  string     `json:"branch_id" gorm:"index"`
+	Tier      string     `json:"tier"` // kosong = harga umum
+	Price     Money      `json:"price"`
+	Synced    bool       `json:"synced"`
+	IsDeleted bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Price tiers select which product price a sale is charged at.
+const (
+	PriceTierDefault  = ""
+	PriceTierInvestor = "investor"
+	PriceTierShosha   = "shosha"
+)
+
+// ValidPriceTier reports whether tier is a known price tier.
+func ValidPriceTier(tier string) bool {
+	return tier == PriceTierDefault || tier == PriceTierInvestor || tier == PriceTierShosha
+}
+
+// TierPrice returns the product price for tier, falling back to Price when
+// the tier price is not set.
+func (p Product) TierPrice(tier string) Money {
+	switch tier {
+	case PriceTierInvestor:
+		if p.PriceInvestor > 0 {
+			return p.PriceInvestor
+		}
+	case PriceTierShosha:
+		if p.PriceShosha > 0 {
+			return p.PriceShosha
+		}
+	}
+	return p.Price
 }
 
 // Branch represents a store branch entry.
@@ -26,6 +296,7 @@ type Branch struct {
 	Name      string     `json:"name"`
 	Address   string     `json:"address"`
 	Phone     string     `json:"phone"`
+	PriceTier string     `json:"price_tier"` // "investor", "shosha" atau kosong (harga umum)
 	Synced    bool       `json:"synced"`
 	IsDeleted bool       `json:"is_deleted" gorm:"default:false"`
 	DeletedAt *time.Time `json:"deleted_at"`
@@ -33,30 +304,237 @@ type Branch struct {
 	UpdatedAt time.Time  `json:"updated_at"`
 }
 
+// Customer is a buyer known to the store, used for hutang (credit) sales
+// and purchase history.
+type Customer struct {
+	ID          string     `json:"id" gorm:"primaryKey"`
+	Name        string     `json:"name"`
+	Phone       string     `json:"phone"`
+	Address     string     `json:"address"`
+	NPWP        string     `json:"npwp"`
+	PriceTier   string     `json:"price_tier"`   // kosong = ikut tier cabang
+	CreditLimit Money      `json:"credit_limit"` // batas hutang, 0 = tanpa batas
+	Synced      bool       `json:"synced"`
+	IsDeleted   bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt   *time.Time `json:"deleted_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
 // Sale captures a checkout transaction.
 type Sale struct {
+	ID             string        `json:"id" gorm:"primaryKey"`
+	ReceiptNo      string        `json:"receipt_no" gorm:"index"`
+	BranchID       string        `json:"branch_id" gorm:"index"`
+	BranchName     string        `json:"branch_name"`
+	CustomerID     string        `json:"customer_id" gorm:"index"`
+	CustomerName   string        `json:"customer_name"`                // snapshot nama pelanggan saat transaksi
+	CashSessionID  string        `json:"cash_session_id" gorm:"index"` // shift kasir yang membukukan transaksi
+	PaymentMethod  string        `json:"payment_method"`               // single tender method, or "split" when paid with several tenders
+	PriceTier      string        `json:"price_tier"`                   // tier harga yang dipakai saat transaksi
+	Notes          string        `json:"notes"`
+	Subtotal       Money         `json:"subtotal"` // Jumlah subtotal item setelah diskon item
+	DiscountType   string        `json:"discount_type"`
+	DiscountValue  float64       `json:"discount_value"`
+	DiscountAmount Money         `json:"discount_amount"` // Diskon level nota (manual + promo), nominal
+	PromotionID    string        `json:"promotion_id"`
+	TaxAmount      Money         `json:"tax_amount"`  // Total pajak (inklusif + eksklusif)
+	Total          Money         `json:"total"`       // Subtotal - DiscountAmount + pajak eksklusif
+	ChangeDue      Money         `json:"change_due"`  // Kembalian tunai
+	PrintCount     int           `json:"print_count"` // berapa kali struk dicetak; >1 berarti cetak ulang
+	Synced         bool          `json:"synced"`
+	IsDeleted      bool          `json:"is_deleted" gorm:"default:false"`
+	DeletedAt      *time.Time    `json:"deleted_at"`
+	CreatedAt      time.Time     `json:"created_at" gorm:"index"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	Items          []SaleItem    `json:"items"`
+	Payments       []SalePayment `json:"payments"`
+	TaxSummary     []TaxSummary  `json:"tax_summary,omitempty" gorm:"-"`
+}
+
+// SaleItem links to Sale.
+type SaleItem struct {
+	ID              string     `json:"id" gorm:"primaryKey"`
+	SaleID          string     `json:"sale_id" gorm:"index"`
+	ProductID       string     `json:"product_id" gorm:"index"`
+	Qty             Qty        `json:"qty"`              // dalam satuan jual (Unit)
+	Unit            string     `json:"unit"`             // kosong = satuan dasar produk
+	UnitFactor      Qty        `json:"unit_factor"`      // satuan dasar per satuan jual
+	BaseQty         Qty        `json:"base_qty"`         // Qty x UnitFactor, yang mengurangi stok
+	Price           Money      `json:"price"`            // Harga satuan asli sebelum diskon
+	PriceOverridden bool       `json:"price_overridden"` // harga diubah dengan otorisasi manajer
+	DiscountType    string     `json:"discount_type"`    // "percent" or "amount", manual line discount
+	DiscountValue   float64    `json:"discount_value"`
+	DiscountAmount  Money      `json:"discount_amount"` // Total diskon baris (manual + promo)
+	PromotionID     string     `json:"promotion_id"`
+	Subtotal        Money      `json:"subtotal"`  // Qty * Price - DiscountAmount
+	UnitCost        Money      `json:"unit_cost"` // HPP per satuan dasar saat terjual
+	TaxRateID       string     `json:"tax_rate_id"`
+	TaxCode         string     `json:"tax_code"`
+	TaxRate         float64    `json:"tax_rate"` // persen, snapshot saat transaksi
+	TaxInclusive    bool       `json:"tax_inclusive"`
+	TaxBase         Money      `json:"tax_base"` // DPP setelah alokasi diskon nota
+	TaxAmount       Money      `json:"tax_amount"`
+	Synced          bool       `json:"synced"`
+	IsDeleted       bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt       *time.Time `json:"deleted_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// GrossAmount is the line value at the original price, before discounts.
+func (i SaleItem) GrossAmount() Money {
+	return i.Price.TimesQty(i.Qty)
+}
+
+// LineTotal is the discounted line value. Rows recorded before discounts
+// existed have no stored subtotal and fall back to the gross amount.
+func (i SaleItem) LineTotal() Money {
+	if i.Subtotal == 0 && i.DiscountAmount == 0 {
+		return i.GrossAmount()
+	}
+	return i.Subtotal
+}
+
+// CostAmount is the cost of goods sold for the line.
+func (i SaleItem) CostAmount() Money {
+	return i.UnitCost.TimesQty(i.BaseQty)
+}
+
+// Payment methods accepted as sale tenders.
+const (
+	PaymentCash     = "cash"
+	PaymentTransfer = "transfer"
+	PaymentQRIS     = "qris"
+	PaymentHutang   = "hutang"
+	PaymentSplit    = "split"
+)
+
+// SalePayment is one tender used to settle a Sale.
+type SalePayment struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	SaleID    string     `json:"sale_id" gorm:"index"`
+	Method    string     `json:"method"`    // cash, transfer, qris, hutang
+	Amount    Money      `json:"amount"`    // Nominal yang dialokasikan ke total
+	Tendered  Money      `json:"tendered"`  // Uang diterima (cash bisa lebih dari amount)
+	Reference string     `json:"reference"` // No. referensi transfer/QRIS
+	Synced    bool       `json:"synced"`
+	IsDeleted bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Cash session statuses and movement types.
+const (
+	CashSessionOpen   = "open"
+	CashSessionClosed = "closed"
+
+	CashPayIn  = "pay_in"  // kas masuk, mis. tambahan uang kembalian
+	CashPayOut = "pay_out" // kas keluar, mis. bayar kurir
+)
+
+// CashSession is one cashier's shift on a till, from opening float to the
+// closing cash count.
+type CashSession struct {
+	ID           string         `json:"id" gorm:"primaryKey"`
+	BranchID     string         `json:"branch_id"`
+	TillID       string         `json:"till_id" gorm:"index"`
+	Cashier      string         `json:"cashier"`
+	Status       string         `json:"status"`
+	OpeningFloat Money          `json:"opening_float"` // modal awal laci
+	OpenedAt     time.Time      `json:"opened_at"`
+	ClosedAt     *time.Time     `json:"closed_at"`
+	ExpectedCash Money          `json:"expected_cash"` // diisi saat tutup
+	CountedCash  Money          `json:"counted_cash"`
+	Difference   Money          `json:"difference"` // counted - expected
+	CloseNote    string         `json:"close_note"`
+	Synced       bool           `json:"synced"`
+	IsDeleted    bool           `json:"is_deleted" gorm:"default:false"`
+	DeletedAt    *time.Time     `json:"deleted_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	Movements    []CashMovement `json:"movements"`
+}
+
+// CashMovement is cash put into or taken out of the drawer outside a sale.
+type CashMovement struct {
 	ID            string     `json:"id" gorm:"primaryKey"`
-	ReceiptNo     string     `json:"receipt_no"`
-	BranchID      string     `json:"branch_id"`
-	BranchName    string     `json:"branch_name"`
-	PaymentMethod string     `json:"payment_method"` // "cash" or "hutang"
-	Notes         string     `json:"notes"`
-	Total         float64    `json:"total"`
+	CashSessionID string     `json:"cash_session_id"`
+	Type          string     `json:"type"` // pay_in atau pay_out
+	Amount        Money      `json:"amount"`
+	Reason        string     `json:"reason"`
 	Synced        bool       `json:"synced"`
 	IsDeleted     bool       `json:"is_deleted" gorm:"default:false"`
 	DeletedAt     *time.Time `json:"deleted_at"`
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
-	Items         []SaleItem `json:"items"`
 }
 
-// SaleItem links to Sale.
-type SaleItem struct {
+// Draft sale statuses.
+const (
+	DraftOpen   = "open"   // sedang dikerjakan di sebuah till
+	DraftParked = "parked" // ditahan, bisa dilanjutkan dari till mana pun
+	DraftPosted = "posted" // sudah menjadi Sale
+)
+
+// DraftSale is a cart kept on the server until it is posted as a Sale.
+// Drafts never touch stock and are not synced upstream.
+type DraftSale struct {
+	ID            string          `json:"id" gorm:"primaryKey"`
+	Label         string          `json:"label"` // nama/penanda saat diparkir, mis. "Bu Ani"
+	Status        string          `json:"status"`
+	BranchID      string          `json:"branch_id"`
+	TillID        string          `json:"till_id"` // till yang terakhir memegang draft
+	CustomerID    string          `json:"customer_id"`
+	Notes         string          `json:"notes"`
+	DiscountType  string          `json:"discount_type"`
+	DiscountValue float64         `json:"discount_value"`
+	SaleID        string          `json:"sale_id"` // diisi setelah diposting
+	PostedAt      *time.Time      `json:"posted_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	Items         []DraftSaleItem `json:"items"`
+}
+
+// DraftSaleItem is a cart line of a DraftSale.
+type DraftSaleItem struct {
+	ID            string    `json:"id" gorm:"primaryKey"`
+	DraftSaleID   string    `json:"draft_sale_id"`
+	ProductID     string    `json:"product_id"`
+	Qty           Qty       `json:"qty"`
+	Unit          string    `json:"unit"`  // kosong = satuan dasar
+	Price         Money     `json:"price"` // 0 = harga tier saat diposting
+	DiscountType  string    `json:"discount_type"`
+	DiscountValue float64   `json:"discount_value"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ReceiptTemplate configures the printed receipt of a branch; the row with
+// an empty BranchID is the store-wide default. Templates stay on the device.
+type ReceiptTemplate struct {
+	ID             string    `json:"id" gorm:"primaryKey"`
+	BranchID       string    `json:"branch_id" gorm:"uniqueIndex"`
+	StoreName      string    `json:"store_name"`
+	Address        string    `json:"address"`
+	Phone          string    `json:"phone"`
+	Header         string    `json:"header"`      // baris tambahan di atas, dipisah newline
+	Footer         string    `json:"footer"`      // mis. "Terima kasih"
+	PaperWidth     int       `json:"paper_width"` // 58 atau 80 (mm)
+	ShowTaxSummary bool      `json:"show_tax_summary"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TaxRate is a configurable tax such as PPN 11%.
+type TaxRate struct {
 	ID        string     `json:"id" gorm:"primaryKey"`
-	SaleID    string     `json:"sale_id"`
-	ProductID string     `json:"product_id"`
-	Qty       int        `json:"qty"`
-	Price     float64    `json:"price"`
+	Code      string     `json:"code"` // mis. "PPN"
+	Name      string     `json:"name"`
+	Rate      float64    `json:"rate"`      // persen
+	Inclusive bool       `json:"inclusive"` // harga jual sudah termasuk pajak
+	IsDefault bool       `json:"is_default"`
 	Synced    bool       `json:"synced"`
 	IsDeleted bool       `json:"is_deleted" gorm:"default:false"`
 	DeletedAt *time.Time `json:"deleted_at"`
@@ -64,12 +542,298 @@ type SaleItem struct {
 	UpdatedAt time.Time  `json:"updated_at"`
 }
 
-// StockOpname represents a stock take session.
+// TaxSummary is the per-rate tax breakdown printed on receipts and invoices.
+type TaxSummary struct {
+	TaxRateID string  `json:"tax_rate_id"`
+	Code      string  `json:"code"`
+	Rate      float64 `json:"rate"`
+	Inclusive bool    `json:"inclusive"`
+	Base      Money   `json:"base"` // DPP
+	Tax       Money   `json:"tax"`
+}
+
+// Promotion types evaluated at checkout.
+const (
+	PromoBuyXGetY   = "buy_x_get_y" // beli BuyQty gratis FreeQty
+	PromoBundle     = "bundle"      // BundleQty unit seharga BundlePrice
+	PromoTimeWindow = "time_window" // diskon per unit selama periode/jam tertentu
+	PromoMinSpend   = "min_spend"   // diskon nota jika belanja >= MinSpend
+)
+
+// Promotion is a discount rule applied automatically by CreateSale.
+type Promotion struct {
+	ID            string     `json:"id" gorm:"primaryKey"`
+	Name          string     `json:"name"`
+	Type          string     `json:"type"`
+	ProductID     string     `json:"product_id"` // kosong = semua produk (time_window)
+	BranchID      string     `json:"branch_id"`  // kosong = semua cabang
+	BuyQty        int        `json:"buy_qty"`
+	FreeQty       int        `json:"free_qty"`
+	BundleQty     int        `json:"bundle_qty"`
+	BundlePrice   Money      `json:"bundle_price"`
+	DiscountType  string     `json:"discount_type"` // "percent" or "amount"
+	DiscountValue float64    `json:"discount_value"`
+	MinSpend      Money      `json:"min_spend"`
+	StartsAt      *time.Time `json:"starts_at"`
+	EndsAt        *time.Time `json:"ends_at"`
+	DailyStart    string     `json:"daily_start"` // "HH:MM", optional happy hour
+	DailyEnd      string     `json:"daily_end"`
+	Active        bool       `json:"active"`
+	Synced        bool       `json:"synced"`
+	IsDeleted     bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt     *time.Time `json:"deleted_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Supplier is a vendor goods are bought from.
+type Supplier struct {
+	ID           string     `json:"id" gorm:"primaryKey"`
+	Code         string     `json:"code"`
+	Name         string     `json:"name"`
+	ContactName  string     `json:"contact_name"`
+	Phone        string     `json:"phone"`
+	Email        string     `json:"email"`
+	Address      string     `json:"address"`
+	NPWP         string     `json:"npwp"`
+	PaymentTerms int        `json:"payment_terms"` // tempo pembayaran dalam hari, 0 = tunai
+	Note         string     `json:"note"`
+	Synced       bool       `json:"synced"`
+	IsDeleted    bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt    *time.Time `json:"deleted_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Purchase order statuses. A draft can still be edited; once sent it waits
+// for goods, and it closes when everything arrived or it is closed by hand.
+const (
+	PurchaseOrderDraft   = "draft"
+	PurchaseOrderSent    = "sent"
+	PurchaseOrderPartial = "partially_received"
+	PurchaseOrderClosed  = "closed"
+)
+
+// PurchaseOrder is an order for goods from a supplier, delivered to a branch.
+type PurchaseOrder struct {
+	ID         string              `json:"id" gorm:"primaryKey"`
+	Number     string              `json:"number" gorm:"index"` // mis. PO-JKT-260118-001
+	SupplierID string              `json:"supplier_id" gorm:"index"`
+	BranchID   string              `json:"branch_id"`
+	Status     string              `json:"status"`
+	OrderDate  time.Time           `json:"order_date"`
+	ExpectedAt *time.Time          `json:"expected_at"` // perkiraan barang datang
+	Note       string              `json:"note"`
+	CreatedBy  string              `json:"created_by"`
+	Total      Money               `json:"total"`
+	SentAt     *time.Time          `json:"sent_at"`
+	ClosedAt   *time.Time          `json:"closed_at"`
+	Synced     bool                `json:"synced"`
+	IsDeleted  bool                `json:"is_deleted" gorm:"default:false"`
+	DeletedAt  *time.Time          `json:"deleted_at"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+	Items      []PurchaseOrderItem `json:"items"`
+}
+
+// PurchaseOrderItem is one ordered product. Qty and ReceivedQty count Unit
+// (empty = the product's base unit), which holds UnitFactor base units.
+type PurchaseOrderItem struct {
+	ID              string     `json:"id" gorm:"primaryKey"`
+	PurchaseOrderID string     `json:"purchase_order_id" gorm:"index"`
+	ProductID       string     `json:"product_id"`
+	Unit            string     `json:"unit"`
+	UnitFactor      Qty        `json:"unit_factor"`
+	Qty             Qty        `json:"qty"`
+	UnitCost        Money      `json:"unit_cost"` // harga beli per satuan Unit
+	Subtotal        Money      `json:"subtotal"`
+	ReceivedQty     Qty        `json:"received_qty"`
+	Synced          bool       `json:"synced"`
+	IsDeleted       bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt       *time.Time `json:"deleted_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// GoodsReceipt records goods arriving at a branch, against a purchase order
+// or ad hoc (PurchaseOrderID empty).
+type GoodsReceipt struct {
+	ID              string             `json:"id" gorm:"primaryKey"`
+	Number          string             `json:"number" gorm:"index"` // mis. GR-JKT-261018-001
+	PurchaseOrderID string             `json:"purchase_order_id" gorm:"index"`
+	SupplierID      string             `json:"supplier_id" gorm:"index"`
+	BranchID        string             `json:"branch_id"`
+	InvoiceNo       string             `json:"invoice_no"` // nomor faktur pemasok
+	ReceivedAt      time.Time          `json:"received_at"`
+	ReceivedBy      string             `json:"received_by"`
+	Note            string             `json:"note"`
+	Total           Money              `json:"total"`
+	Synced          bool               `json:"synced"`
+	IsDeleted       bool               `json:"is_deleted" gorm:"default:false"`
+	DeletedAt       *time.Time         `json:"deleted_at"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+	Items           []GoodsReceiptItem `json:"items"`
+}
+
+// GoodsReceiptItem is one received product. OrderedQty and Qty count Unit
+// like the purchase order line; BaseQty is what went into stock.
+type GoodsReceiptItem struct {
+	ID                  string     `json:"id" gorm:"primaryKey"`
+	GoodsReceiptID      string     `json:"goods_receipt_id" gorm:"index"`
+	PurchaseOrderItemID string     `json:"purchase_order_item_id"` // kosong = di luar PO
+	ProductID           string     `json:"product_id" gorm:"index"`
+	Unit                string     `json:"unit"`
+	UnitFactor          Qty        `json:"unit_factor"`
+	OrderedQty          Qty        `json:"ordered_qty"` // qty di baris PO, 0 = di luar PO
+	Qty                 Qty        `json:"qty"`
+	BaseQty             Qty        `json:"base_qty"`
+	UnitCost            Money      `json:"unit_cost"` // per satuan Unit
+	Subtotal            Money      `json:"subtotal"`
+	BatchNo             string     `json:"batch_no"`
+	ExpiryDate          *time.Time `json:"expiry_date"`
+	Synced              bool       `json:"synced"`
+	IsDeleted           bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt           *time.Time `json:"deleted_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// Stock transfer statuses. The destination requests, the source dispatches
+// (stock leaves the source) and the destination receives (stock arrives).
+const (
+	TransferRequested  = "requested"
+	TransferDispatched = "dispatched"
+	TransferReceived   = "received"
+	TransferCancelled  = "cancelled"
+)
+
+// StockTransfer moves goods from one branch to another.
+type StockTransfer struct {
+	ID             string              `json:"id" gorm:"primaryKey"`
+	Number         string              `json:"number" gorm:"index"` // mis. TR-JKT-261018-001
+	FromBranchID   string              `json:"from_branch_id" gorm:"index"`
+	ToBranchID     string              `json:"to_branch_id" gorm:"index"`
+	Status         string              `json:"status"`
+	Note           string              `json:"note"`
+	RequestedBy    string              `json:"requested_by"`
+	RequestedAt    time.Time           `json:"requested_at"`
+	DispatchedBy   string              `json:"dispatched_by"`
+	DispatchedAt   *time.Time          `json:"dispatched_at"`
+	ReceivedBy     string              `json:"received_by"`
+	ReceivedAt     *time.Time          `json:"received_at"`
+	HasDiscrepancy bool                `json:"has_discrepancy"` // ada barang yang tidak sesuai saat diterima
+	Synced         bool                `json:"synced"`
+	IsDeleted      bool                `json:"is_deleted" gorm:"default:false"`
+	DeletedAt      *time.Time          `json:"deleted_at"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+	Items          []StockTransferItem `json:"items"`
+}
+
+// StockTransferItem is one product of a transfer. Quantities count Unit,
+// which holds UnitFactor base units.
+type StockTransferItem struct {
+	ID              string     `json:"id" gorm:"primaryKey"`
+	StockTransferID string     `json:"stock_transfer_id" gorm:"index"`
+	ProductID       string     `json:"product_id"`
+	Unit            string     `json:"unit"`
+	UnitFactor      Qty        `json:"unit_factor"`
+	RequestedQty    Qty        `json:"requested_qty"`
+	DispatchedQty   Qty        `json:"dispatched_qty"`
+	ReceivedQty     Qty        `json:"received_qty"`
+	Discrepancy     Qty        `json:"discrepancy"` // dikirim - diterima
+	DiscrepancyNote string     `json:"discrepancy_note"`
+	UnitCost        Money      `json:"unit_cost"` // HPP per satuan dasar saat keluar dari cabang asal
+	Synced          bool       `json:"synced"`
+	IsDeleted       bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt       *time.Time `json:"deleted_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Stock adjustment reasons.
+const (
+	AdjustmentDamaged     = "damaged"
+	AdjustmentExpired     = "expired"
+	AdjustmentTheft       = "theft"
+	AdjustmentSample      = "sample"
+	AdjustmentInternalUse = "internal_use"
+	AdjustmentCorrection  = "correction" // salah hitung/input; boleh menambah stok
+)
+
+// Stock adjustment statuses.
+const (
+	AdjustmentPending  = "pending" // menunggu persetujuan manajer
+	AdjustmentPosted   = "posted"
+	AdjustmentRejected = "rejected"
+)
+
+// StockAdjustment corrects a branch's stock outside a stock count, e.g. to
+// write off damaged goods. Adjustments whose value (stock out plus stock in)
+// is above the approval limit wait for a manager before stock changes.
+type StockAdjustment struct {
+	ID         string                `json:"id" gorm:"primaryKey"`
+	Number     string                `json:"number" gorm:"index"`
+	BranchID   string                `json:"branch_id" gorm:"index"`
+	Reason     string                `json:"reason"`
+	Status     string                `json:"status"`
+	Note       string                `json:"note"`
+	CreatedBy  string                `json:"created_by"`
+	ApprovedBy string                `json:"approved_by"` // juga yang menolak
+	ApprovedAt *time.Time            `json:"approved_at"`
+	PostedAt   *time.Time            `json:"posted_at"`
+	LossValue  Money                 `json:"loss_value"` // nilai stok keluar dikurangi yang masuk, pada HPP
+	Synced     bool                  `json:"synced"`
+	IsDeleted  bool                  `json:"is_deleted" gorm:"default:false"`
+	DeletedAt  *time.Time            `json:"deleted_at"`
+	CreatedAt  time.Time             `json:"created_at"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+	Items      []StockAdjustmentItem `json:"items"`
+}
+
+// StockAdjustmentItem is one product adjusted. Qty is in Unit and signed:
+// negative takes stock out.
+type StockAdjustmentItem struct {
+	ID                string     `json:"id" gorm:"primaryKey"`
+	StockAdjustmentID string     `json:"stock_adjustment_id" gorm:"index"`
+	ProductID         string     `json:"product_id"`
+	Unit              string     `json:"unit"`
+	UnitFactor        Qty        `json:"unit_factor"`
+	Qty               Qty        `json:"qty"`
+	BaseQty           Qty        `json:"base_qty"`
+	UnitCost          Money      `json:"unit_cost"` // HPP per satuan dasar; perkiraan sampai diposting
+	Value             Money      `json:"value"`     // BaseQty x UnitCost, negatif = kerugian
+	Note              string     `json:"note"`
+	Synced            bool       `json:"synced"`
+	IsDeleted         bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt         *time.Time `json:"deleted_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// Stock opname statuses. A session is counting until it is finalized or
+// cancelled; the deprecated one-shot POST /api/stock-opname starts, counts and
+// finalizes a session in one request.
+const (
+	OpnameCounting  = "counting"
+	OpnameFinalized = "finalized"
+	OpnameCancelled = "cancelled"
+)
+
+// StockOpname represents a stock take session. A session started on the
+// server snapshots the system stock of the products in scope (CategoryID and
+// its subcategories, or everything) and only changes stock when finalized.
 type StockOpname struct {
 	ID          string            `json:"id" gorm:"primaryKey"`
+	Number      string            `json:"number" gorm:"index"`
 	BranchID    string            `json:"branch_id"`
+	Status      string            `json:"status" gorm:"default:finalized"`
+	CategoryID  string            `json:"category_id"` // kosong = semua produk
 	PerformedBy string            `json:"performed_by"`
 	Note        string            `json:"note"`
+	FinalizedBy string            `json:"finalized_by"`
+	FinalizedAt *time.Time        `json:"finalized_at"`
 	Synced      bool              `json:"synced"`
 	IsDeleted   bool              `json:"is_deleted" gorm:"default:false"`
 	DeletedAt   *time.Time        `json:"deleted_at"`
@@ -78,13 +842,41 @@ type StockOpname struct {
 	Items       []StockOpnameItem `json:"items"`
 }
 
-// StockOpnameItem holds per-product opname figures.
+// StockOpnameItem holds per-product opname figures. In a session SystemQty
+// is the snapshot taken at the start and PhysicalQty the sum of the live
+// counts; ExpectedQty and Variance are set when the session is finalized.
 type StockOpnameItem struct {
 	ID            string     `json:"id" gorm:"primaryKey"`
-	StockOpnameID string     `json:"stock_opname_id"`
+	StockOpnameID string     `json:"stock_opname_id" gorm:"index"`
+	ProductID     string     `json:"product_id"`
+	SystemQty     Qty        `json:"system_qty"` // satuan dasar
+	PhysicalQty   Qty        `json:"physical_qty"`
+	Counted       bool       `json:"counted"`
+	CountedAt     *time.Time `json:"counted_at"`   // hitungan terakhir
+	ExpectedQty   Qty        `json:"expected_qty"` // stok sistem saat dihitung
+	Variance      Qty        `json:"variance"`     // PhysicalQty - ExpectedQty
+	Synced        bool       `json:"synced"`
+	IsDeleted     bool       `json:"is_deleted" gorm:"default:false"`
+	DeletedAt     *time.Time `json:"deleted_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// StockOpnameCount is one count entered during an opname session, typed or
+// scanned. Counts of a product add up (several counters, several shelves)
+// until a recount replaces them; replaced counts are kept as Superseded.
+type StockOpnameCount struct {
+	ID            string     `json:"id" gorm:"primaryKey"`
+	StockOpnameID string     `json:"stock_opname_id" gorm:"index"`
 	ProductID     string     `json:"product_id"`
-	SystemQty     int        `json:"system_qty"`
-	PhysicalQty   int        `json:"physical_qty"`
+	Unit          string     `json:"unit"`
+	UnitFactor    Qty        `json:"unit_factor"`
+	Qty           Qty        `json:"qty"`
+	BaseQty       Qty        `json:"base_qty"`
+	Barcode       string     `json:"barcode"` // kode yang dipindai, jika ada
+	CountedBy     string     `json:"counted_by"`
+	Recount       bool       `json:"recount"`
+	Superseded    bool       `json:"superseded"`
 	Synced        bool       `json:"synced"`
 	IsDeleted     bool       `json:"is_deleted" gorm:"default:false"`
 	DeletedAt     *time.Time `json:"deleted_at"`
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Money is an amount in sen (1/100 rupiah). Amounts are stored and summed as
+// integers so totals always match their items; JSON keeps the rupiah value
+// (e.g. 12500.5) so API clients and sync payloads stay unchanged.
+type Money int64
+
+// FromRupiah converts a rupiah value to Money, rounding to the nearest sen.
+func FromRupiah(v float64) Money {
+	return Money(math.Round(v * 100))
+}
+
+// Rupiah returns m as a rupiah value, for display and Excel cells.
+func (m Money) Rupiah() float64 {
+	return float64(m) / 100
+}
+
+// Times multiplies m by a quantity.
+func (m Money) Times(qty int) Money {
+	return m * Money(qty)
+}
+
+// Percent returns pct percent of m, rounded half away from zero.
+func (m Money) Percent(pct float64) Money {
+	return Money(math.Round(float64(m) * pct / 100))
+}
+
+// String formats m as a rupiah decimal without trailing zeros.
+func (m Money) String() string {
+	neg := m < 0
+	if neg {
+		m = -m
+	}
+	s := strconv.FormatInt(int64(m/100), 10)
+	if sen := int64(m % 100); sen != 0 {
+		if sen%10 == 0 {
+			s += "." + strconv.FormatInt(sen/10, 10)
+		} else {
+			s += fmt.Sprintf(".%02d", sen)
+		}
+	}
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a rupiah number (or numeric string) and parses it
+// exactly, without going through float64.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(bytes.TrimSpace(data), `"`)
+	if len(data) == 0 || string(data) == "null" {
+		*m = 0
+		return nil
+	}
+	r, ok := new(big.Rat).SetString(string(data))
+	if !ok {
+		return fmt.Errorf("invalid money amount %q", data)
+	}
+	r.Mul(r, big.NewRat(100, 1))
+	// bulatkan ke sen terdekat (half away from zero)
+	num, den := r.Num(), r.Denom()
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	if !q.IsInt64() {
+		return fmt.Errorf("money amount %q out of range", data)
+	}
+	*m = Money(q.Int64())
+	return nil
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+	}{
+		{`12500`, 1250000},
+		{`12500.5`, 1250050},
+		{`"12500.50"`, 1250050},
+		{`0.005`, 1}, // setengah sen dibulatkan menjauhi nol
+		{`0.0049`, 0},
+		{`-0.005`, -1},
+		{`-1500.25`, -150025},
+		{`0.1`, 10}, // tanpa galat float64
+		{`null`, 0},
+		{`""`, 0},
+		{`1e3`, 100000},
+	}
+	for _, tt := range tests {
+		var got Money
+		if err := json.Unmarshal([]byte(tt.in), &got); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d sen, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyUnmarshalJSONInvalid(t *testing.T) {
+	for _, in := range []string{`"abc"`, `"12.5.0"`, `99999999999999999999`} {
+		var m Money
+		if err := json.Unmarshal([]byte(in), &m); err == nil {
+			t.Errorf("Unmarshal(%s) = %d, want an error", in, m)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{0, "0"},
+		{1250000, "12500"},
+		{1250050, "12500.5"},
+		{1250005, "12500.05"},
+		{-150025, "-1500.25"},
+		{-5, "-0.05"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", tt.m, got, tt.want)
+		}
+		b, err := json.Marshal(tt.m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back Money
+		if err := json.Unmarshal(b, &back); err != nil || back != tt.m {
+			t.Errorf("JSON round trip of %d gave %d (%s), err %v", tt.m, back, b, err)
+		}
+	}
+}
+
+func TestFromRupiah(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want Money
+	}{
+		{0, 0},
+		{12500, 1250000},
+		{0.1 + 0.2, 30},
+		{19.999, 2000},
+		{-2.5, -250},
+	}
+	for _, tt := range tests {
+		if got := FromRupiah(tt.in); got != tt.want {
+			t.Errorf("FromRupiah(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyPercent(t *testing.T) {
+	tests := []struct {
+		m    Money
+		pct  float64
+		want Money
+	}{
+		{1000000, 11, 110000},
+		{999, 10, 100},  // 99.9 sen
+		{1005, 50, 503}, // 502.5 sen, menjauhi nol
+		{-1005, 50, -503},
+		{1000, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Percent(tt.pct); got != tt.want {
+			t.Errorf("Money(%d).Percent(%v) = %d, want %d", tt.m, tt.pct, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// QtyScale is the number of stored steps per unit.
+const QtyScale = 1000
+
+// Qty is a quantity in thousandths of a unit, so stock can hold 2.5 kg of
+// rice or 0.05 of a pack while staying an integer in the database like Money.
+// JSON carries the plain decimal (e.g. 2.5).
+type Qty int64
+
+// Units returns a Qty of n whole units.
+func Units(n int) Qty {
+	return Qty(n) * QtyScale
+}
+
+// QtyFromFloat converts v units to Qty, rounding to the nearest thousandth.
+func QtyFromFloat(v float64) Qty {
+	return Qty(math.Round(v * QtyScale))
+}
+
+// Float returns q in units, for display and Excel cells.
+func (q Qty) Float() float64 {
+	return float64(q) / QtyScale
+}
+
+// Whole is the number of complete units in q; promotions count these.
+func (q Qty) Whole() int {
+	return int(q / QtyScale)
+}
+
+// Mul converts q counted in a unit of factor base units to base units.
+func (q Qty) Mul(factor Qty) Qty {
+	return Qty(math.Round(float64(q) * float64(factor) / QtyScale))
+}
+
+// Div converts base units q to a count of units of factor base units.
+func (q Qty) Div(factor Qty) Qty {
+	if factor == 0 {
+		return 0
+	}
+	return Qty(math.Round(float64(q) * QtyScale / float64(factor)))
+}
+
+// String formats q as a decimal without trailing zeros.
+func (q Qty) String() string {
+	neg := q < 0
+	if neg {
+		q = -q
+	}
+	s := strconv.FormatInt(int64(q/QtyScale), 10)
+	if frac := int64(q % QtyScale); frac != 0 {
+		s += "." + strings.TrimRight(fmt.Sprintf("%03d", frac), "0")
+	}
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
+
+func (q Qty) MarshalJSON() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+// UnmarshalJSON accepts a number (or numeric string) and parses it exactly,
+// rounding to the nearest thousandth.
+func (q *Qty) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(bytes.TrimSpace(data), `"`)
+	if len(data) == 0 || string(data) == "null" {
+		*q = 0
+		return nil
+	}
+	r, ok := new(big.Rat).SetString(string(data))
+	if !ok {
+		return fmt.Errorf("invalid quantity %q", data)
+	}
+	r.Mul(r, big.NewRat(QtyScale, 1))
+	num, den := r.Num(), r.Denom()
+	n, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			n.Sub(n, big.NewInt(1))
+		} else {
+			n.Add(n, big.NewInt(1))
+		}
+	}
+	if !n.IsInt64() {
+		return fmt.Errorf("quantity %q out of range", data)
+	}
+	*q = Qty(n.Int64())
+	return nil
+}
+
+// TimesQty multiplies a unit price by a (possibly fractional) quantity,
+// rounded to the nearest sen.
+func (m Money) TimesQty(q Qty) Money {
+	return Money(math.Round(float64(m) * float64(q) / QtyScale))
+}
+
+// PerQty is the price of one unit when q units cost m, e.g. the cost per kg
+// of a 25 kg sak; rounded to the nearest sen.
+func (m Money) PerQty(q Qty) Money {
+	if q == 0 {
+		return 0
+	}
+	return Money(math.Round(float64(m) * QtyScale / float64(q)))
+}
+
+// QtyLabel describes the quantity in the unit it was sold in and, for a
+// larger unit, in the base unit too: "2 sak (50 kg)" or "2.5 kg".
+func (i SaleItem) QtyLabel(baseUnit string) string {
+	if i.Unit == "" || i.UnitFactor == Units(1) {
+		return strings.TrimSpace(i.Qty.String() + " " + baseUnit)
+	}
+	return fmt.Sprintf("%s %s (%s)", i.Qty, i.Unit, strings.TrimSpace(i.BaseQty.String()+" "+baseUnit))
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestQtyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Qty
+	}{
+		{`3`, 3000},
+		{`2.5`, 2500},
+		{`"12.125"`, 12125},
+		{`0.0005`, 1}, // setengah langkah dibulatkan menjauhi nol
+		{`0.0004`, 0},
+		{`-1.0005`, -1001},
+		{`null`, 0},
+		{`""`, 0},
+	}
+	for _, tt := range tests {
+		var got Qty
+		if err := json.Unmarshal([]byte(tt.in), &got); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+	var q Qty
+	if err := json.Unmarshal([]byte(`"2 kg"`), &q); err == nil {
+		t.Errorf(`Unmarshal("2 kg") = %d, want an error`, q)
+	}
+}
+
+func TestQtyString(t *testing.T) {
+	tests := []struct {
+		q    Qty
+		want string
+	}{
+		{0, "0"},
+		{Units(3), "3"},
+		{2500, "2.5"},
+		{12125, "12.125"},
+		{50, "0.05"},
+		{-1500, "-1.5"},
+	}
+	for _, tt := range tests {
+		if got := tt.q.String(); got != tt.want {
+			t.Errorf("Qty(%d).String() = %q, want %q", tt.q, got, tt.want)
+		}
+		b, err := json.Marshal(tt.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back Qty
+		if err := json.Unmarshal(b, &back); err != nil || back != tt.q {
+			t.Errorf("JSON round trip of %d gave %d (%s), err %v", tt.q, back, b, err)
+		}
+	}
+}
+
+func TestQtyUnitConversion(t *testing.T) {
+	tests := []struct {
+		name          string
+		qty, factor   Qty
+		base, counted Qty
+	}{
+		{"2 sak of 25 kg", Units(2), Units(25), Units(50), Units(2)},
+		{"half a slop of 10", 500, Units(10), Units(5), 500},
+		{"base unit", 2500, Units(1), 2500, 2500},
+		{"a third of a pack of 3", 333, Units(3), 999, 333},
+	}
+	for _, tt := range tests {
+		if got := tt.qty.Mul(tt.factor); got != tt.base {
+			t.Errorf("%s: Mul = %d, want %d", tt.name, got, tt.base)
+		}
+		if got := tt.base.Div(tt.factor); got != tt.counted {
+			t.Errorf("%s: Div = %d, want %d", tt.name, got, tt.counted)
+		}
+	}
+	if got := Units(5).Div(0); got != 0 {
+		t.Errorf("Div(0) = %d, want 0", got)
+	}
+}
+
+func TestMoneyTimesQty(t *testing.T) {
+	tests := []struct {
+		price Money
+		qty   Qty
+		want  Money
+	}{
+		{1250000, Units(2), 2500000},
+		{1250000, 2500, 3125000},
+		{333, 500, 167}, // 166.5 sen
+		{1000, 1, 1},
+		{1000, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.price.TimesQty(tt.qty); got != tt.want {
+			t.Errorf("Money(%d).TimesQty(%d) = %d, want %d", tt.price, tt.qty, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyPerQty(t *testing.T) {
+	tests := []struct {
+		total Money
+		qty   Qty
+		want  Money
+	}{
+		{25000000, Units(25), 1000000},
+		{1000, Units(3), 333},
+		{2000, Units(3), 667},
+		{1000, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.total.PerQty(tt.qty); got != tt.want {
+			t.Errorf("Money(%d).PerQty(%d) = %d, want %d", tt.total, tt.qty, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package pricing
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"shosha_mart_backend/models"
+)
+
+// Discount kinds accepted on sale lines, sales and promotions.
+const (
+	DiscountPercent = "percent"
+	DiscountAmount  = "amount"
+)
+
+// ValidDiscountType reports whether kind is empty or a known discount kind.
+func ValidDiscountType(kind string) bool {
+	return kind == "" || kind == DiscountPercent || kind == DiscountAmount
+}
+
+// Discount returns the discount for base, capped so it never exceeds base.
+// value is a percentage or a rupiah amount depending on kind.
+func Discount(base models.Money, kind string, value float64) models.Money {
+	if base <= 0 || value <= 0 {
+		return 0
+	}
+	var d models.Money
+	switch kind {
+	case DiscountPercent:
+		d = base.Percent(math.Min(value, 100))
+	case DiscountAmount:
+		d = models.FromRupiah(value)
+	}
+	return minMoney(d, base)
+}
+
+func minMoney(a, b models.Money) models.Money {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Line is a sale line being priced.
+type Line struct {
+	ProductID     string
+	Qty           models.Qty   // in the unit Price is quoted for
+	Price         models.Money // original unit price
+	DiscountType  string       // manual line discount
+	DiscountValue float64
+
+	DiscountAmount models.Money // manual + promotion, filled by ApplyLine
+	PromotionID    string
+	Subtotal       models.Money
+}
+
+// ApplyLine prices a line: the manual discount first, then the single best
+// line promotion on what is left.
+func ApplyLine(l *Line, promos []models.Promotion) {
+	gross := l.Price.TimesQty(l.Qty)
+	manual := Discount(gross, l.DiscountType, l.DiscountValue)
+
+	var best models.Money
+	bestID := ""
+	for _, p := range promos {
+		if p.ProductID != "" && p.ProductID != l.ProductID {
+			continue
+		}
+		if d := lineDiscount(p, l.Qty, l.Price); d > best {
+			best, bestID = d, p.ID
+		}
+	}
+
+	total := minMoney(manual+best, gross)
+	l.DiscountAmount = total
+	l.PromotionID = bestID
+	l.Subtotal = gross - total
+}
+
+// lineDiscount returns what promotion p takes off qty units sold at price.
+// Buy-X and bundle promotions count whole units only.
+func lineDiscount(p models.Promotion, q models.Qty, price models.Money) models.Money {
+	qty := q.Whole()
+	switch p.Type {
+	case models.PromoBuyXGetY:
+		group := p.BuyQty + p.FreeQty
+		if p.BuyQty <= 0 || p.FreeQty <= 0 || qty < group {
+			return 0
+		}
+		return price.Times((qty / group) * p.FreeQty)
+	case models.PromoBundle:
+		if p.BundleQty <= 0 || qty < p.BundleQty {
+			return 0
+		}
+		saving := price.Times(p.BundleQty) - p.BundlePrice
+		if saving <= 0 {
+			return 0
+		}
+		return saving.Times(qty / p.BundleQty)
+	case models.PromoTimeWindow:
+		if p.ProductID == "" && p.DiscountType == DiscountAmount {
+			// nominal per unit hanya masuk akal untuk satu produk
+			return 0
+		}
+		return Discount(price, p.DiscountType, p.DiscountValue).TimesQty(q)
+	}
+	return 0
+}
+
+// OrderDiscount returns the order-level discount for a sale subtotal: the
+// manual discount plus the best qualifying minimum-spend promotion.
+func OrderDiscount(subtotal models.Money, kind string, value float64, promos []models.Promotion) (models.Money, string) {
+	manual := Discount(subtotal, kind, value)
+
+	var best models.Money
+	bestID := ""
+	for _, p := range promos {
+		if p.Type != models.PromoMinSpend || subtotal < p.MinSpend {
+			continue
+		}
+		if d := Discount(subtotal, p.DiscountType, p.DiscountValue); d > best {
+			best, bestID = d, p.ID
+		}
+	}
+	return minMoney(manual+best, subtotal), bestID
+}
+
+// ActivePromotions filters promos down to the ones valid for branchID at now.
+func ActivePromotions(promos []models.Promotion, branchID string, now time.Time) []models.Promotion {
+	active := make([]models.Promotion, 0, len(promos))
+	for _, p := range promos {
+		if promotionActive(p, branchID, now) {
+			active = append(active, p)
+		}
+	}
+	return active
+}
+
+func promotionActive(p models.Promotion, branchID string, now time.Time) bool {
+	if !p.Active || p.IsDeleted {
+		return false
+	}
+	if p.BranchID != "" && p.BranchID != branchID {
+		return false
+	}
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && now.After(*p.EndsAt) {
+		return false
+	}
+	if p.DailyStart != "" && p.DailyEnd != "" {
+		clock := now.Format("15:04")
+		start, end := strings.TrimSpace(p.DailyStart), strings.TrimSpace(p.DailyEnd)
+		if start <= end {
+			return clock >= start && clock < end
+		}
+		// jendela melewati tengah malam, mis. 22:00-02:00
+		return clock >= start || clock < end
+	}
+	return true
+}
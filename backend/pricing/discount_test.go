@@ -0,0 +1,112 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	"shosha_mart_backend/models"
+)
+
+func TestApplyLine(t *testing.T) {
+	const price = models.Money(1000000) // Rp10.000
+	b2g1 := models.Promotion{ID: "b2g1", Type: models.PromoBuyXGetY, ProductID: "p1", BuyQty: 2, FreeQty: 1}
+	bundle := models.Promotion{ID: "bundle", Type: models.PromoBundle, ProductID: "p1", BundleQty: 3, BundlePrice: 2500000}
+	happy := models.Promotion{ID: "happy", Type: models.PromoTimeWindow, ProductID: "p1", DiscountType: DiscountPercent, DiscountValue: 20}
+	storeCut := models.Promotion{ID: "cut", Type: models.PromoTimeWindow, DiscountType: DiscountAmount, DiscountValue: 1000}
+	other := models.Promotion{ID: "other", Type: models.PromoTimeWindow, ProductID: "p2", DiscountType: DiscountPercent, DiscountValue: 50}
+
+	tests := []struct {
+		name         string
+		qty          float64
+		discountType string
+		discount     float64
+		promos       []models.Promotion
+		wantDiscount models.Money
+		wantPromo    string
+	}{
+		{"no discount", 3, "", 0, nil, 0, ""},
+		{"manual percent", 2, DiscountPercent, 10, nil, 200000, ""},
+		{"manual amount", 2, DiscountAmount, 5000, nil, 500000, ""},
+		{"manual amount capped at the line", 1, DiscountAmount, 15000, nil, price, ""},
+		{"percent above 100 capped", 1, DiscountPercent, 150, nil, price, ""},
+		{"buy 2 get 1", 7, "", 0, []models.Promotion{b2g1}, 2 * price, "b2g1"},
+		{"buy 2 get 1 short of a group", 2, "", 0, []models.Promotion{b2g1}, 0, ""},
+		{"bundle", 7, "", 0, []models.Promotion{bundle}, 1000000, "bundle"},
+		{"time window on a fraction", 2.5, "", 0, []models.Promotion{happy}, 500000, "happy"},
+		{"best promotion wins", 6, "", 0, []models.Promotion{bundle, b2g1, happy}, 2 * price, "b2g1"},
+		{"promotion of another product ignored", 2, "", 0, []models.Promotion{other}, 0, ""},
+		{"store-wide amount per unit ignored", 2, "", 0, []models.Promotion{storeCut}, 0, ""},
+		{"manual then promotion", 3, DiscountAmount, 5000, []models.Promotion{b2g1}, 1500000, "b2g1"},
+		{"manual and promotion capped", 3, DiscountPercent, 80, []models.Promotion{b2g1}, 3 * price, "b2g1"},
+	}
+	for _, tt := range tests {
+		l := Line{ProductID: "p1", Qty: models.QtyFromFloat(tt.qty), Price: price, DiscountType: tt.discountType, DiscountValue: tt.discount}
+		ApplyLine(&l, tt.promos)
+		if l.DiscountAmount != tt.wantDiscount || l.PromotionID != tt.wantPromo {
+			t.Errorf("%s: discount %s via %q, want %s via %q", tt.name, l.DiscountAmount, l.PromotionID, tt.wantDiscount, tt.wantPromo)
+		}
+		if gross := price.TimesQty(l.Qty); l.Subtotal != gross-l.DiscountAmount {
+			t.Errorf("%s: subtotal %s, want %s", tt.name, l.Subtotal, gross-l.DiscountAmount)
+		}
+	}
+}
+
+func TestOrderDiscount(t *testing.T) {
+	spend100 := models.Promotion{ID: "s100", Type: models.PromoMinSpend, MinSpend: 10000000, DiscountType: DiscountPercent, DiscountValue: 5}
+	spend200 := models.Promotion{ID: "s200", Type: models.PromoMinSpend, MinSpend: 20000000, DiscountType: DiscountAmount, DiscountValue: 15000}
+	promos := []models.Promotion{spend100, spend200}
+	tests := []struct {
+		name      string
+		subtotal  models.Money
+		kind      string
+		value     float64
+		want      models.Money
+		wantPromo string
+	}{
+		{"below every minimum", 9999900, "", 0, 0, ""},
+		{"one minimum reached", 12000000, "", 0, 600000, "s100"},
+		{"best of two", 25000000, "", 0, 1500000, "s200"},
+		{"manual on top", 12000000, DiscountAmount, 1000, 700000, "s100"},
+		{"capped at the subtotal", 500000, DiscountAmount, 10000, 500000, ""},
+	}
+	for _, tt := range tests {
+		got, promo := OrderDiscount(tt.subtotal, tt.kind, tt.value, promos)
+		if got != tt.want || promo != tt.wantPromo {
+			t.Errorf("%s: %s via %q, want %s via %q", tt.name, got, promo, tt.want, tt.wantPromo)
+		}
+	}
+}
+
+func TestActivePromotions(t *testing.T) {
+	at := func(clock string) time.Time {
+		v, _ := time.Parse("2006-01-02 15:04", "2026-10-18 "+clock)
+		return v
+	}
+	past, future := at("00:00").AddDate(0, 0, -1), at("00:00").AddDate(0, 0, 1)
+	tests := []struct {
+		name  string
+		promo models.Promotion
+		now   time.Time
+		want  bool
+	}{
+		{"plain", models.Promotion{Active: true}, at("12:00"), true},
+		{"inactive", models.Promotion{}, at("12:00"), false},
+		{"deleted", models.Promotion{Active: true, IsDeleted: true}, at("12:00"), false},
+		{"other branch", models.Promotion{Active: true, BranchID: "b2"}, at("12:00"), false},
+		{"this branch", models.Promotion{Active: true, BranchID: "b1"}, at("12:00"), true},
+		{"not started", models.Promotion{Active: true, StartsAt: &future}, at("12:00"), false},
+		{"ended", models.Promotion{Active: true, EndsAt: &past}, at("12:00"), false},
+		{"within period", models.Promotion{Active: true, StartsAt: &past, EndsAt: &future}, at("12:00"), true},
+		{"happy hour", models.Promotion{Active: true, DailyStart: "15:00", DailyEnd: "17:00"}, at("16:59"), true},
+		{"happy hour over", models.Promotion{Active: true, DailyStart: "15:00", DailyEnd: "17:00"}, at("17:00"), false},
+		{"overnight before midnight", models.Promotion{Active: true, DailyStart: "22:00", DailyEnd: "02:00"}, at("23:30"), true},
+		{"overnight after midnight", models.Promotion{Active: true, DailyStart: "22:00", DailyEnd: "02:00"}, at("01:00"), true},
+		{"overnight daytime", models.Promotion{Active: true, DailyStart: "22:00", DailyEnd: "02:00"}, at("12:00"), false},
+	}
+	for _, tt := range tests {
+		got := len(ActivePromotions([]models.Promotion{tt.promo}, "b1", tt.now)) == 1
+		if got != tt.want {
+			t.Errorf("%s: active = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package pricing
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"shosha_mart_backend/models"
+)
+
+// Price change sources recorded in ProductPriceHistory.
+const (
+	SourceManual   = "manual"
+	SourceSchedule = "schedule"
+	SourceImport   = "import"
+)
+
+// Change describes who changed prices and why.
+type Change struct {
+	BranchID   string
+	By         string
+	Source     string
+	ScheduleID string
+	At         time.Time
+}
+
+// tierColumn is the products column holding the price of tier.
+func tierColumn(tier string) string {
+	switch tier {
+	case models.PriceTierInvestor:
+		return "price_investor"
+	case models.PriceTierShosha:
+		return "price_shosha"
+	}
+	return "price"
+}
+
+// ownPrice is the price stored for tier, without the fallback of TierPrice.
+func ownPrice(p models.Product, tier string) models.Money {
+	switch tier {
+	case models.PriceTierInvestor:
+		return p.PriceInvestor
+	case models.PriceTierShosha:
+		return p.PriceShosha
+	}
+	return p.Price
+}
+
+// setOwnPrice sets the price stored for tier on p.
+func setOwnPrice(p *models.Product, tier string, price models.Money) {
+	switch tier {
+	case models.PriceTierInvestor:
+		p.PriceInvestor = price
+	case models.PriceTierShosha:
+		p.PriceShosha = price
+	default:
+		p.Price = price
+	}
+}
+
+// BranchPriceID is the id of a branch's price for tier of a product. Every
+// sidecar derives the same id, so rows never duplicate across sync.
+func BranchPriceID(branchID, productID, tier string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(branchID+"/"+productID+"/"+tier)).String()
+}
+
+// ForBranch puts branchID's own prices of p (see models.BranchPrice) over
+// its tier prices, so p is priced as at that branch.
+func ForBranch(tx *gorm.DB, branchID string, p *models.Product) error {
+	var prices []models.BranchPrice
+	if err := tx.Where("branch_id = ? AND product_id = ? AND is_deleted = ?", branchID, p.ID, false).Find(&prices).Error; err != nil {
+		return err
+	}
+	for _, bp := range prices {
+		setOwnPrice(p, bp.Tier, bp.Price)
+	}
+	return nil
+}
+
+// SetPrices writes the given tier prices of product and records a history
+// row for every price that actually changes.
+func SetPrices(tx *gorm.DB, product models.Product, prices map[string]models.Money, ch Change) error {
+	if ch.At.IsZero() {
+		ch.At = time.Now()
+	}
+	updates := map[string]interface{}{}
+	for _, tier := range []string{models.PriceTierDefault, models.PriceTierInvestor, models.PriceTierShosha} {
+		price, ok := prices[tier]
+		if !ok || price == ownPrice(product, tier) {
+			continue
+		}
+		if err := tx.Create(&models.ProductPriceHistory{
+			ID:         uuid.NewString(),
+			ProductID:  product.ID,
+			BranchID:   ch.BranchID,
+			Tier:       tier,
+			OldPrice:   ownPrice(product, tier),
+			NewPrice:   price,
+			ChangedBy:  ch.By,
+			Source:     ch.Source,
+			ScheduleID: ch.ScheduleID,
+			ChangedAt:  ch.At,
+			Synced:     false,
+		}).Error; err != nil {
+			return err
+		}
+		updates[tierColumn(tier)] = price
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	updates["synced"] = false
+	return tx.Model(&models.Product{}).Where("id = ?", product.ID).Updates(updates).Error
+}
+
+// SetBranchPrice sets ch.BranchID's own price for tier of product, leaving
+// the product's price for other branches alone, and records the change in
+// the price history when the price at that branch actually changes.
+func SetBranchPrice(tx *gorm.DB, product models.Product, tier string, price models.Money, ch Change) error {
+	if ch.At.IsZero() {
+		ch.At = time.Now()
+	}
+	if err := ForBranch(tx, ch.BranchID, &product); err != nil {
+		return err
+	}
+	old := ownPrice(product, tier)
+	if price == old {
+		return nil
+	}
+	if err := tx.Create(&models.ProductPriceHistory{
+		ID:         uuid.NewString(),
+		ProductID:  product.ID,
+		BranchID:   ch.BranchID,
+		Tier:       tier,
+		OldPrice:   old,
+		NewPrice:   price,
+		ChangedBy:  ch.By,
+		Source:     ch.Source,
+		ScheduleID: ch.ScheduleID,
+		ChangedAt:  ch.At,
+		Synced:     false,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"price", "synced", "is_deleted", "deleted_at", "updated_at"}),
+	}).Create(&models.BranchPrice{
+		ID:        BranchPriceID(ch.BranchID, product.ID, tier),
+		ProductID: product.ID,
+		BranchID:  ch.BranchID,
+		Tier:      tier,
+		Price:     price,
+		Synced:    false,
+	}).Error
+}
+
+// ApplyDuePriceChanges applies the scheduled price changes for branchID that
+// are due at now, oldest first, and returns how many were applied. A branch's
+// changes become its BranchPrice; branchID "" applies the chain-wide changes
+// to the products' own prices, which only one place may do (the upstream
+// server, or a sidecar without one) so history is not written twice. Changes
+// for removed products are marked applied without touching anything.
+func ApplyDuePriceChanges(db *gorm.DB, branchID string, now time.Time) (int, error) {
+	var due []models.ScheduledPriceChange
+	if err := db.Where("applied_at IS NULL AND is_deleted = ? AND effective_at <= ? AND branch_id = ?", false, now, branchID).
+		Order("effective_at, created_at").Find(&due).Error; err != nil {
+		return 0, err
+	}
+	applied := 0
+	for _, s := range due {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var product models.Product
+			err := tx.First(&product, "id = ? AND is_deleted = ?", s.ProductID, false).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if err == nil {
+				ch := Change{
+					BranchID:   branchID,
+					By:         s.CreatedBy,
+					Source:     SourceSchedule,
+					ScheduleID: s.ID,
+					At:         now,
+				}
+				if branchID == "" {
+					err = SetPrices(tx, product, map[string]models.Money{s.Tier: s.NewPrice}, ch)
+				} else {
+					err = SetBranchPrice(tx, product, s.Tier, s.NewPrice, ch)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return tx.Model(&models.ScheduledPriceChange{}).Where("id = ?", s.ID).Updates(map[string]interface{}{
+				"applied_at": now,
+				"synced":     false,
+			}).Error
+		})
+		if err != nil {
+			return applied, err
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// StartScheduler applies the due price changes of each of branchIDs ("" for
+// the chain-wide ones) now and then every interval, for the life of the
+// process.
+func StartScheduler(db *gorm.DB, interval time.Duration, branchIDs ...string) {
+	run := func() {
+		for _, branchID := range branchIDs {
+			n, err := ApplyDuePriceChanges(db, branchID, time.Now())
+			if err != nil {
+				log.Printf("[PRICE] applying scheduled prices (branch %q) failed: %v", branchID, err)
+			} else if n > 0 {
+				log.Printf("[PRICE] applied %d scheduled price change(s) (branch %q)", n, branchID)
+			}
+		}
+	}
+	go func() {
+		run()
+		for range time.Tick(interval) {
+			run()
+		}
+	}()
+}
@@ -0,0 +1,99 @@
+package pricing
+
+import (
+	"math"
+	"sort"
+
+	"shosha_mart_backend/models"
+)
+
+// TaxableLine is a discounted sale line with the tax settings snapshotted on it.
+type TaxableLine struct {
+	Amount    models.Money // line subtotal after line discounts
+	Rate      float64      // percent
+	Inclusive bool
+}
+
+// TaxResult is the tax base (DPP) and tax of one line.
+type TaxResult struct {
+	Base models.Money
+	Tax  models.Money
+}
+
+// Tax splits amount into base and tax. Inclusive amounts already contain the
+// tax; exclusive amounts are the base and the tax comes on top.
+func Tax(amount models.Money, rate float64, inclusive bool) (base, tax models.Money) {
+	if rate <= 0 || amount <= 0 {
+		return amount, 0
+	}
+	if inclusive {
+		base = models.Money(math.Round(float64(amount) * 100 / (100 + rate)))
+		return base, amount - base
+	}
+	return amount, amount.Percent(rate)
+}
+
+// ApplyTax spreads the order discount over the lines pro rata to their
+// amounts and computes the tax of every line. It returns the per-line results,
+// the total tax and the part of it (exclusive taxes) to add to the sale total.
+// The shares are rounded cumulatively so they add up to the discount exactly.
+func ApplyTax(lines []TaxableLine, orderDiscount models.Money) (results []TaxResult, totalTax, addedTax models.Money) {
+	var subtotal models.Money
+	for _, l := range lines {
+		subtotal += l.Amount
+	}
+
+	results = make([]TaxResult, len(lines))
+	var running, allocated models.Money
+	for i, l := range lines {
+		amount := l.Amount
+		if subtotal > 0 && orderDiscount > 0 {
+			running += l.Amount
+			upTo := models.Money(math.Round(float64(orderDiscount) * float64(running) / float64(subtotal)))
+			amount -= upTo - allocated
+			allocated = upTo
+		}
+		base, tax := Tax(amount, l.Rate, l.Inclusive)
+		results[i] = TaxResult{Base: base, Tax: tax}
+		totalTax += tax
+		if !l.Inclusive {
+			addedTax += tax
+		}
+	}
+	return results, totalTax, addedTax
+}
+
+// SummarizeTax groups sale items per tax rate for receipts and invoices.
+func SummarizeTax(items []models.SaleItem) []models.TaxSummary {
+	byKey := make(map[string]*models.TaxSummary)
+	var keys []string
+	for _, item := range items {
+		if item.TaxRate <= 0 && item.TaxAmount == 0 {
+			continue
+		}
+		key := item.TaxRateID
+		if key == "" {
+			key = item.TaxCode
+		}
+		sum, ok := byKey[key]
+		if !ok {
+			sum = &models.TaxSummary{
+				TaxRateID: item.TaxRateID,
+				Code:      item.TaxCode,
+				Rate:      item.TaxRate,
+				Inclusive: item.TaxInclusive,
+			}
+			byKey[key] = sum
+			keys = append(keys, key)
+		}
+		sum.Base += item.TaxBase
+		sum.Tax += item.TaxAmount
+	}
+	sort.Strings(keys)
+
+	summary := make([]models.TaxSummary, 0, len(keys))
+	for _, k := range keys {
+		summary = append(summary, *byKey[k])
+	}
+	return summary
+}
@@ -0,0 +1,102 @@
+package pricing
+
+import (
+	"reflect"
+	"testing"
+
+	"shosha_mart_backend/models"
+)
+
+func TestTax(t *testing.T) {
+	tests := []struct {
+		name      string
+		amount    models.Money
+		rate      float64
+		inclusive bool
+		base, tax models.Money
+	}{
+		{"exclusive", 1000000, 11, false, 1000000, 110000},
+		{"inclusive", 1110000, 11, true, 1000000, 110000},
+		{"inclusive rounds the base", 1000000, 11, true, 900901, 99099},
+		{"no rate", 1000000, 0, true, 1000000, 0},
+		{"nothing to tax", 0, 11, false, 0, 0},
+	}
+	for _, tt := range tests {
+		base, tax := Tax(tt.amount, tt.rate, tt.inclusive)
+		if base != tt.base || tax != tt.tax {
+			t.Errorf("%s: base %s tax %s, want base %s tax %s", tt.name, base, tax, tt.base, tt.tax)
+		}
+	}
+}
+
+func TestApplyTax(t *testing.T) {
+	tests := []struct {
+		name         string
+		lines        []TaxableLine
+		discount     models.Money
+		want         []TaxResult
+		total, added models.Money
+	}{
+		{
+			name:  "exclusive lines add tax",
+			lines: []TaxableLine{{Amount: 1000000, Rate: 11}, {Amount: 500000, Rate: 11}},
+			want:  []TaxResult{{Base: 1000000, Tax: 110000}, {Base: 500000, Tax: 55000}},
+			total: 165000, added: 165000,
+		},
+		{
+			name:  "inclusive tax is not added",
+			lines: []TaxableLine{{Amount: 1110000, Rate: 11, Inclusive: true}, {Amount: 500000}},
+			want:  []TaxResult{{Base: 1000000, Tax: 110000}, {Base: 500000, Tax: 0}},
+			total: 110000, added: 0,
+		},
+		{
+			name:     "order discount spread pro rata",
+			lines:    []TaxableLine{{Amount: 3000000, Rate: 11}, {Amount: 1000000, Rate: 11}},
+			discount: 400000,
+			want:     []TaxResult{{Base: 2700000, Tax: 297000}, {Base: 900000, Tax: 99000}},
+			total:    396000, added: 396000,
+		},
+		{
+			name:     "discount shares add up exactly",
+			lines:    []TaxableLine{{Amount: 100}, {Amount: 100}, {Amount: 100}},
+			discount: 100,
+			want:     []TaxResult{{Base: 67}, {Base: 66}, {Base: 67}},
+		},
+	}
+	for _, tt := range tests {
+		got, total, added := ApplyTax(tt.lines, tt.discount)
+		if !reflect.DeepEqual(got, tt.want) || total != tt.total || added != tt.added {
+			t.Errorf("%s: %v total %s added %s, want %v total %s added %s", tt.name, got, total, added, tt.want, tt.total, tt.added)
+		}
+		var bases, amounts models.Money
+		for i := range got {
+			bases += got[i].Base
+			if !tt.lines[i].Inclusive {
+				continue
+			}
+			bases += got[i].Tax
+		}
+		for _, l := range tt.lines {
+			amounts += l.Amount
+		}
+		if bases != amounts-tt.discount {
+			t.Errorf("%s: lines after discount sum to %s, want %s", tt.name, bases, amounts-tt.discount)
+		}
+	}
+}
+
+func TestSummarizeTax(t *testing.T) {
+	items := []models.SaleItem{
+		{TaxRateID: "ppn", TaxCode: "PPN", TaxRate: 11, TaxBase: 1000000, TaxAmount: 110000},
+		{TaxRateID: "ppn", TaxCode: "PPN", TaxRate: 11, TaxBase: 500000, TaxAmount: 55000},
+		{TaxCode: "PB1", TaxRate: 10, TaxInclusive: true, TaxBase: 200000, TaxAmount: 20000},
+		{TaxBase: 300000}, // tanpa pajak
+	}
+	want := []models.TaxSummary{
+		{Code: "PB1", Rate: 10, Inclusive: true, Base: 200000, Tax: 20000},
+		{TaxRateID: "ppn", Code: "PPN", Rate: 11, Base: 1500000, Tax: 165000},
+	}
+	if got := SummarizeTax(items); !reflect.DeepEqual(got, want) {
+		t.Errorf("SummarizeTax = %+v, want %+v", got, want)
+	}
+}
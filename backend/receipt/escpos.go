@@ -0,0 +1,59 @@
+package receipt
+
+import "bytes"
+
+// ESC/POS command bytes.
+var (
+	escInit        = []byte{0x1b, '@'}
+	escAlignLeft   = []byte{0x1b, 'a', 0}
+	escAlignCenter = []byte{0x1b, 'a', 1}
+	escBoldOn      = []byte{0x1b, 'E', 1}
+	escBoldOff     = []byte{0x1b, 'E', 0}
+	escSizeTall    = []byte{0x1d, '!', 0x01}
+	escSizeNormal  = []byte{0x1d, '!', 0x00}
+	escFeed        = []byte{0x1b, 'd', 4}
+	escCut         = []byte{0x1d, 'V', 0x42, 0} // partial cut
+)
+
+// ESCPOS renders the receipt for a thermal printer. The printer does the
+// centring; characters outside ASCII print as '?'.
+func ESCPOS(lines []Line) []byte {
+	var b bytes.Buffer
+	b.Write(escInit)
+	for _, l := range lines {
+		if l.Align == Center {
+			b.Write(escAlignCenter)
+		} else {
+			b.Write(escAlignLeft)
+		}
+		if l.Bold {
+			b.Write(escBoldOn)
+		}
+		if l.Tall {
+			b.Write(escSizeTall)
+		}
+		b.Write(asciiBytes(l.Text))
+		b.WriteByte('\n')
+		if l.Tall {
+			b.Write(escSizeNormal)
+		}
+		if l.Bold {
+			b.Write(escBoldOff)
+		}
+	}
+	b.Write(escAlignLeft)
+	b.Write(escFeed)
+	b.Write(escCut)
+	return b.Bytes()
+}
+
+func asciiBytes(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r < 0x20 || r > 0x7e {
+			r = '?'
+		}
+		out = append(out, byte(r))
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// PDF page metrics, in points. Courier glyphs are 0.6em wide.
+const (
+	pdfFontSize   = 8.0
+	pdfLineHeight = 10.0
+	pdfMargin     = 12.0
+	mmToPt        = 72 / 25.4
+)
+
+// PDF renders the receipt as a single-page PDF as wide as the paper roll and
+// as tall as its content, using the built-in Courier fonts.
+func PDF(lines []Line, cols, paperWidth int) []byte {
+	width := float64(paperWidth) * mmToPt
+	if textWidth := float64(cols)*pdfFontSize*0.6 + 2*pdfMargin; textWidth > width {
+		width = textWidth
+	}
+	height := float64(len(lines))*pdfLineHeight + 2*pdfMargin
+
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "BT\n%.2f TL\n%.2f %.2f Td\n", pdfLineHeight, pdfMargin, height-pdfMargin-pdfFontSize)
+	for _, l := range lines {
+		font := "F1"
+		if l.Bold || l.Tall {
+			font = "F2"
+		}
+		fmt.Fprintf(&content, "/%s %.1f Tf (%s) Tj T*\n", font, pdfFontSize, pdfEscape(padded(l, cols)))
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 4 0 R /F2 5 0 R >> >> /Contents 6 0 R >>", width, height),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+// pdfEscape encodes s as a PDF literal string body in Latin-1.
+func pdfEscape(s string) string {
+	var b bytes.Buffer
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r < 0x20:
+			b.WriteByte(' ')
+		case r < 0x100:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
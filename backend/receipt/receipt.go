@@ -0,0 +1,282 @@
+// Package receipt lays out sale receipts for thermal printers and renders
+// them as plain text, ESC/POS byte streams or PDF.
+package receipt
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"shosha_mart_backend/models"
+)
+
+// Paper widths in millimetres and the characters per line they hold in the
+// printer's default font.
+const (
+	Paper58 = 58
+	Paper80 = 80
+)
+
+// Columns returns the characters per line for a paper width.
+func Columns(paperWidth int) int {
+	if paperWidth == Paper58 {
+		return 32
+	}
+	return 48
+}
+
+// DefaultTemplate is used when no template has been saved.
+func DefaultTemplate() models.ReceiptTemplate {
+	return models.ReceiptTemplate{
+		StoreName:      "SHO SHA MART",
+		Address:        "Jl. Pahlawan No.33, Sukabumi Sel., Kb. Jeruk, Jakarta Barat 11560",
+		Footer:         "Terima kasih atas kunjungan Anda",
+		PaperWidth:     Paper80,
+		ShowTaxSummary: true,
+	}
+}
+
+// Item names a sale line for printing.
+type Item struct {
+	Name string
+	Unit string
+}
+
+// Receipt is everything printed for one sale.
+type Receipt struct {
+	Sale  models.Sale     // with Items, Payments and TaxSummary loaded
+	Items map[string]Item // by product id
+	Copy  int             // 1 = first print, 2 = first reprint, ...
+}
+
+// Align is the horizontal alignment of a line.
+type Align int
+
+const (
+	Left Align = iota
+	Center
+)
+
+// Line is one printed row. Text never exceeds the column width.
+type Line struct {
+	Text  string
+	Align Align
+	Bold  bool
+	Tall  bool // double height, same width
+}
+
+// Layout arranges a receipt into lines of at most cols characters.
+func Layout(r Receipt, t models.ReceiptTemplate, cols int) []Line {
+	var out []Line
+	add := func(text string, align Align, bold bool) {
+		for _, l := range wrap(text, cols) {
+			out = append(out, Line{Text: l, Align: align, Bold: bold})
+		}
+	}
+	rule := func(ch string) {
+		out = append(out, Line{Text: strings.Repeat(ch, cols)})
+	}
+	pair := func(left, right string, bold bool) {
+		out = append(out, Line{Text: twoColumns(left, right, cols), Bold: bold})
+	}
+	sale := r.Sale
+
+	if t.StoreName != "" {
+		out = append(out, Line{Text: truncate(t.StoreName, cols), Align: Center, Bold: true, Tall: true})
+	}
+	add(t.Address, Center, false)
+	if t.Phone != "" {
+		add("Telp. "+t.Phone, Center, false)
+	}
+	for _, h := range splitLines(t.Header) {
+		add(h, Center, false)
+	}
+	if r.Copy > 1 {
+		add(fmt.Sprintf("*** CETAK ULANG #%d ***", r.Copy-1), Center, true)
+	}
+	rule("=")
+
+	pair("No", sale.ReceiptNo, false)
+	pair("Tanggal", sale.CreatedAt.Format("02/01/2006 15:04"), false)
+	if sale.BranchName != "" {
+		pair("Cabang", sale.BranchName, false)
+	}
+	if sale.CustomerName != "" {
+		pair("Pelanggan", sale.CustomerName, false)
+	}
+	rule("-")
+
+	for _, item := range sale.Items {
+		info := r.Items[item.ProductID]
+		name := info.Name
+		if name == "" {
+			name = item.ProductID
+		}
+		add(name, Left, false)
+		qty := "  " + item.QtyLabel(info.Unit) + " x " + Rupiah(item.Price)
+		pair(qty, Rupiah(item.GrossAmount()), false)
+		if item.DiscountAmount > 0 {
+			pair("  Diskon", "-"+Rupiah(item.DiscountAmount), false)
+		}
+	}
+	rule("-")
+
+	pair("Subtotal", Rupiah(sale.Subtotal), false)
+	if sale.DiscountAmount > 0 {
+		pair("Diskon", "-"+Rupiah(sale.DiscountAmount), false)
+	}
+	if t.ShowTaxSummary {
+		for _, s := range sale.TaxSummary {
+			label := fmt.Sprintf("%s %g%%", s.Code, s.Rate)
+			if s.Inclusive {
+				label += " (termasuk)"
+			}
+			pair(label, Rupiah(s.Tax), false)
+		}
+	}
+	pair("TOTAL", Rupiah(sale.Total), true)
+	rule("-")
+
+	for _, p := range sale.Payments {
+		amount := p.Amount
+		if p.Method == models.PaymentCash && p.Tendered > amount {
+			amount = p.Tendered
+		}
+		pair(paymentLabel(p.Method), Rupiah(amount), false)
+	}
+	if sale.ChangeDue > 0 {
+		pair("Kembali", Rupiah(sale.ChangeDue), false)
+	}
+	if sale.Notes != "" {
+		rule("-")
+		add(sale.Notes, Left, false)
+	}
+
+	if footer := splitLines(t.Footer); len(footer) > 0 {
+		rule("=")
+		for _, f := range footer {
+			add(f, Center, false)
+		}
+	}
+	return out
+}
+
+func paymentLabel(method string) string {
+	switch method {
+	case models.PaymentCash:
+		return "Tunai"
+	case models.PaymentTransfer:
+		return "Transfer"
+	case models.PaymentQRIS:
+		return "QRIS"
+	case models.PaymentHutang:
+		return "Hutang"
+	}
+	return method
+}
+
+// Rupiah formats m the Indonesian way: "Rp12.500" or "Rp12.500,50".
+func Rupiah(m models.Money) string {
+	sign := ""
+	if m < 0 {
+		sign = "-"
+		m = -m
+	}
+	whole := fmt.Sprintf("%d", int64(m)/100)
+	var b strings.Builder
+	for i, ch := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(ch)
+	}
+	if sen := int64(m) % 100; sen != 0 {
+		fmt.Fprintf(&b, ",%02d", sen)
+	}
+	return sign + "Rp" + b.String()
+}
+
+// twoColumns puts left and right on one line, shortening left when needed.
+func twoColumns(left, right string, cols int) string {
+	gap := cols - utf8.RuneCountInString(right)
+	if gap < 1 {
+		return truncate(right, cols)
+	}
+	left = truncate(left, gap-1)
+	return left + strings.Repeat(" ", cols-utf8.RuneCountInString(left)-utf8.RuneCountInString(right)) + right
+}
+
+func truncate(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+// wrap breaks text into lines of at most cols runes, on spaces where possible.
+func wrap(text string, cols int) []string {
+	var lines []string
+	for _, para := range splitLines(text) {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			for utf8.RuneCountInString(word) > cols {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				r := []rune(word)
+				lines = append(lines, string(r[:cols]))
+				word = string(r[cols:])
+			}
+			switch {
+			case line == "":
+				line = word
+			case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= cols:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func splitLines(s string) []string {
+	var out []string
+	for _, l := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// padded returns the line text positioned within cols, for monospace output.
+func padded(l Line, cols int) string {
+	if l.Align != Center {
+		return l.Text
+	}
+	pad := (cols - utf8.RuneCountInString(l.Text)) / 2
+	if pad <= 0 {
+		return l.Text
+	}
+	return strings.Repeat(" ", pad) + l.Text
+}
+
+// Text renders the receipt as plain monospace text.
+func Text(lines []Line, cols int) []byte {
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString(padded(l, cols))
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,175 @@
+package reports
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/models"
+)
+
+// AdjustmentLine is one posted stock adjustment line.
+type AdjustmentLine struct {
+	AdjustmentID string       `json:"adjustment_id"`
+	Number       string       `json:"number"`
+	BranchID     string       `json:"branch_id"`
+	PostedAt     time.Time    `json:"posted_at"`
+	Reason       string       `json:"reason"`
+	ProductID    string       `json:"product_id"`
+	SKU          string       `json:"sku"`
+	Name         string       `json:"name"`
+	Unit         string       `json:"unit"`
+	Qty          models.Qty   `json:"qty"` // dalam Unit, negatif = keluar
+	BaseQty      models.Qty   `json:"base_qty"`
+	UnitCost     models.Money `json:"unit_cost"`
+	Value        models.Money `json:"value"`
+	Note         string       `json:"note"`
+	CreatedBy    string       `json:"created_by"`
+	ApprovedBy   string       `json:"approved_by"`
+}
+
+// AdjustmentReasonTotal sums the posted adjustments of one reason.
+type AdjustmentReasonTotal struct {
+	Reason      string       `json:"reason"`
+	Adjustments int          `json:"adjustments"`
+	Lines       int          `json:"lines"`
+	LossValue   models.Money `json:"loss_value"` // kerugian bersih, positif = rugi
+}
+
+// AdjustmentReport is the stock adjustments posted over a period.
+type AdjustmentReport struct {
+	Start     time.Time               `json:"start"`
+	End       time.Time               `json:"end"`
+	Reasons   []AdjustmentReasonTotal `json:"reasons"`
+	Lines     []AdjustmentLine        `json:"lines"`
+	LossValue models.Money            `json:"loss_value"`
+}
+
+// StockAdjustments loads the adjustments posted between start and end,
+// optionally for one branch, with their totals per reason.
+func StockAdjustments(db *gorm.DB, branchID string, start, end time.Time) (AdjustmentReport, error) {
+	rep := AdjustmentReport{Start: start, End: end, Reasons: []AdjustmentReasonTotal{}, Lines: []AdjustmentLine{}}
+	q := db.Table("stock_adjustment_items").
+		Select("stock_adjustments.id AS adjustment_id, stock_adjustments.number, stock_adjustments.branch_id, stock_adjustments.posted_at, "+
+			"stock_adjustments.reason, stock_adjustment_items.product_id, products.sku, COALESCE(products.name, stock_adjustment_items.product_id) AS name, "+
+			"stock_adjustment_items.unit, stock_adjustment_items.qty, stock_adjustment_items.base_qty, stock_adjustment_items.unit_cost, "+
+			"stock_adjustment_items.value, stock_adjustment_items.note, stock_adjustments.created_by, stock_adjustments.approved_by").
+		Joins("JOIN stock_adjustments ON stock_adjustments.id = stock_adjustment_items.stock_adjustment_id").
+		Joins("LEFT JOIN products ON products.id = stock_adjustment_items.product_id").
+		Where("stock_adjustments.status = ? AND stock_adjustments.posted_at BETWEEN ? AND ?", models.AdjustmentPosted, start, end).
+		Where("stock_adjustments.is_deleted = ? AND stock_adjustment_items.is_deleted = ?", false, false)
+	if branchID != "" {
+		q = q.Where("stock_adjustments.branch_id = ?", branchID)
+	}
+	if err := q.Order("stock_adjustments.posted_at, stock_adjustments.number, stock_adjustment_items.created_at").Scan(&rep.Lines).Error; err != nil {
+		return rep, err
+	}
+
+	byReason := map[string]*AdjustmentReasonTotal{}
+	docs := map[string]bool{}
+	for _, l := range rep.Lines {
+		t := byReason[l.Reason]
+		if t == nil {
+			t = &AdjustmentReasonTotal{Reason: l.Reason}
+			byReason[l.Reason] = t
+		}
+		if !docs[l.AdjustmentID] {
+			docs[l.AdjustmentID] = true
+			t.Adjustments++
+		}
+		t.Lines++
+		t.LossValue -= l.Value
+		rep.LossValue -= l.Value
+	}
+	for _, t := range byReason {
+		rep.Reasons = append(rep.Reasons, *t)
+	}
+	sort.Slice(rep.Reasons, func(i, j int) bool {
+		return rep.Reasons[i].LossValue > rep.Reasons[j].LossValue
+	})
+	return rep, nil
+}
+
+// adjustmentReasonLabels are the report names of adjustment reasons.
+var adjustmentReasonLabels = map[string]string{
+	models.AdjustmentDamaged:     "Rusak",
+	models.AdjustmentExpired:     "Kedaluwarsa",
+	models.AdjustmentTheft:       "Hilang/dicuri",
+	models.AdjustmentSample:      "Sampel",
+	models.AdjustmentInternalUse: "Pemakaian internal",
+	models.AdjustmentCorrection:  "Koreksi",
+}
+
+// GenerateAdjustmentReport writes rep as Excel with a sheet of totals per
+// reason and one of lines, and returns its path.
+func GenerateAdjustmentReport(cfg config.AppConfig, rep AdjustmentReport) (string, error) {
+	if err := os.MkdirAll(cfg.ExportDir, 0o755); err != nil {
+		return "", err
+	}
+	f := excelize.NewFile()
+	defer f.Close()
+	summary, detail := "Ringkasan", "Detail"
+	f.SetSheetName(f.GetSheetName(0), summary)
+	f.NewSheet(detail)
+
+	label := func(reason string) string {
+		if l, ok := adjustmentReasonLabels[reason]; ok {
+			return l
+		}
+		return reason
+	}
+	rupiah, err := f.NewStyle(&excelize.Style{NumFmt: 3}) // #,##0
+	if err != nil {
+		return "", err
+	}
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return "", err
+	}
+
+	f.SetCellValue(summary, "A1", "LAPORAN PENYESUAIAN STOK")
+	f.SetCellValue(summary, "A2", fmt.Sprintf("Periode %s s/d %s", rep.Start.Format("02-01-2006"), rep.End.Format("02-01-2006")))
+	f.SetSheetRow(summary, "A4", &[]interface{}{"Alasan", "Dokumen", "Baris", "Kerugian"})
+	row := 5
+	for _, t := range rep.Reasons {
+		f.SetSheetRow(summary, fmt.Sprintf("A%d", row), &[]interface{}{label(t.Reason), t.Adjustments, t.Lines, t.LossValue.Rupiah()})
+		row++
+	}
+	f.SetSheetRow(summary, fmt.Sprintf("A%d", row), &[]interface{}{"Total", "", "", rep.LossValue.Rupiah()})
+	f.SetCellStyle(summary, "A1", "A1", bold)
+	f.SetCellStyle(summary, "A4", "D4", bold)
+	f.SetCellStyle(summary, "D5", fmt.Sprintf("D%d", row), rupiah)
+	f.SetCellStyle(summary, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), bold)
+	f.SetColWidth(summary, "A", "A", 22)
+	f.SetColWidth(summary, "D", "D", 16)
+
+	f.SetSheetRow(detail, "A1", &[]interface{}{"Tanggal", "No. Dokumen", "Alasan", "SKU", "Nama Barang", "Qty", "Satuan", "HPP", "Nilai", "Keterangan", "Dibuat", "Disetujui"})
+	row = 2
+	for _, l := range rep.Lines {
+		f.SetSheetRow(detail, fmt.Sprintf("A%d", row), &[]interface{}{
+			l.PostedAt.Local().Format("02-01-2006 15:04"), l.Number, label(l.Reason), l.SKU, l.Name,
+			l.Qty.Float(), l.Unit, l.UnitCost.Rupiah(), l.Value.Rupiah(), l.Note, l.CreatedBy, l.ApprovedBy,
+		})
+		row++
+	}
+	f.SetCellStyle(detail, "A1", "L1", bold)
+	if row > 2 {
+		f.SetCellStyle(detail, "H2", fmt.Sprintf("I%d", row-1), rupiah)
+	}
+	f.SetColWidth(detail, "A", "C", 17)
+	f.SetColWidth(detail, "E", "E", 32)
+	f.SetColWidth(detail, "J", "J", 24)
+
+	filename := fmt.Sprintf("penyesuaian_stok_%s_%s.xlsx", rep.Start.Format("20060102"), rep.End.Format("20060102"))
+	path := filepath.Join(cfg.ExportDir, filename)
+	if err := f.SaveAs(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
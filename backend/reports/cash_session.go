@@ -0,0 +1,195 @@
+package reports
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/models"
+)
+
+// CashMethodTotal is what one payment method collected during a session.
+type CashMethodTotal struct {
+	Method string       `json:"method"`
+	Count  int          `json:"count"`
+	Amount models.Money `json:"amount"`
+}
+
+// CashSessionReport is the X report of an open session or the Z report of
+// a closed one.
+type CashSessionReport struct {
+	Kind         string             `json:"kind"` // "X" or "Z"
+	GeneratedAt  time.Time          `json:"generated_at"`
+	Session      models.CashSession `json:"session"`
+	Sales        int                `json:"sales"`
+	Subtotal     models.Money       `json:"subtotal"`
+	Discount     models.Money       `json:"discount"`
+	Tax          models.Money       `json:"tax"`
+	Total        models.Money       `json:"total"`
+	ByMethod     []CashMethodTotal  `json:"by_method"`
+	CashSales    models.Money       `json:"cash_sales"`   // tunai bersih (setelah kembalian)
+	ChangeGiven  models.Money       `json:"change_given"` // total kembalian
+	PayIns       models.Money       `json:"pay_ins"`
+	PayOuts      models.Money       `json:"pay_outs"`
+	ExpectedCash models.Money       `json:"expected_cash"` // float + cash sales + pay-ins - pay-outs
+	CountedCash  *models.Money      `json:"counted_cash,omitempty"`
+	Difference   *models.Money      `json:"difference,omitempty"` // counted - expected
+}
+
+// BuildCashSessionReport totals the live sales and cash movements of a
+// session. Closed sessions report the figures recorded at closing.
+func BuildCashSessionReport(db *gorm.DB, session models.CashSession) (CashSessionReport, error) {
+	rep := CashSessionReport{Kind: "X", GeneratedAt: time.Now(), Session: session}
+
+	var movements []models.CashMovement
+	if err := db.Where("cash_session_id = ? AND is_deleted = ?", session.ID, false).Order("created_at").Find(&movements).Error; err != nil {
+		return rep, err
+	}
+	rep.Session.Movements = movements
+	for _, m := range movements {
+		if m.Type == models.CashPayIn {
+			rep.PayIns += m.Amount
+		} else {
+			rep.PayOuts += m.Amount
+		}
+	}
+
+	var sales []models.Sale
+	if err := db.Where("cash_session_id = ? AND is_deleted = ?", session.ID, false).
+		Preload("Payments", "is_deleted = ?", false).
+		Find(&sales).Error; err != nil {
+		return rep, err
+	}
+	methods := map[string]*CashMethodTotal{}
+	for _, s := range sales {
+		rep.Sales++
+		rep.Subtotal += s.Subtotal
+		rep.Discount += s.DiscountAmount
+		rep.Tax += s.TaxAmount
+		rep.Total += s.Total
+		rep.ChangeGiven += s.ChangeDue
+		for _, p := range s.Payments {
+			mt, ok := methods[p.Method]
+			if !ok {
+				mt = &CashMethodTotal{Method: p.Method}
+				methods[p.Method] = mt
+			}
+			mt.Count++
+			mt.Amount += p.Amount
+			if p.Method == models.PaymentCash {
+				rep.CashSales += p.Amount
+			}
+		}
+	}
+	for _, mt := range methods {
+		rep.ByMethod = append(rep.ByMethod, *mt)
+	}
+	sort.Slice(rep.ByMethod, func(i, j int) bool { return rep.ByMethod[i].Method < rep.ByMethod[j].Method })
+
+	rep.ExpectedCash = session.OpeningFloat + rep.CashSales + rep.PayIns - rep.PayOuts
+	if session.Status == models.CashSessionClosed {
+		rep.Kind = "Z"
+		rep.ExpectedCash = session.ExpectedCash
+		counted, diff := session.CountedCash, session.Difference
+		rep.CountedCash = &counted
+		rep.Difference = &diff
+	}
+	return rep, nil
+}
+
+// GenerateCashSessionReport writes an X/Z report to an Excel file.
+func GenerateCashSessionReport(cfg config.AppConfig, rep CashSessionReport) (string, error) {
+	if err := os.MkdirAll(cfg.ExportDir, 0o755); err != nil {
+		return "", err
+	}
+
+	f := excelize.NewFile()
+	summarySheet := "Ringkasan"
+	methodSheet := "Pembayaran"
+	movementSheet := "Kas Masuk-Keluar"
+	f.SetSheetName(f.GetSheetName(0), summarySheet)
+	if _, err := f.NewSheet(methodSheet); err != nil {
+		return "", err
+	}
+	if _, err := f.NewSheet(movementSheet); err != nil {
+		return "", err
+	}
+
+	s := rep.Session
+	closedAt := "-"
+	if s.ClosedAt != nil {
+		closedAt = s.ClosedAt.Format("02-01-2006 15:04")
+	}
+	rows := [][]interface{}{
+		{fmt.Sprintf("Laporan %s Kasir", rep.Kind)},
+		{"Cabang", s.BranchID},
+		{"Till", s.TillID},
+		{"Kasir", s.Cashier},
+		{"Dibuka", s.OpenedAt.Format("02-01-2006 15:04")},
+		{"Ditutup", closedAt},
+		{},
+		{"Jumlah Transaksi", rep.Sales},
+		{"Subtotal", rep.Subtotal.Rupiah()},
+		{"Diskon", rep.Discount.Rupiah()},
+		{"Pajak", rep.Tax.Rupiah()},
+		{"Total Penjualan", rep.Total.Rupiah()},
+		{},
+		{"Modal Awal", s.OpeningFloat.Rupiah()},
+		{"Penjualan Tunai", rep.CashSales.Rupiah()},
+		{"Kas Masuk", rep.PayIns.Rupiah()},
+		{"Kas Keluar", rep.PayOuts.Rupiah()},
+		{"Kas Seharusnya", rep.ExpectedCash.Rupiah()},
+	}
+	if rep.CountedCash != nil {
+		rows = append(rows,
+			[]interface{}{"Kas Dihitung", rep.CountedCash.Rupiah()},
+			[]interface{}{"Selisih", rep.Difference.Rupiah()},
+		)
+	}
+	for i, r := range rows {
+		for j, v := range r {
+			cell, _ := excelize.CoordinatesToCellName(j+1, i+1)
+			f.SetCellValue(summarySheet, cell, v)
+		}
+	}
+
+	for i, h := range []string{"Metode", "Jumlah Transaksi", "Nominal"} {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(methodSheet, cell, h)
+	}
+	for i, m := range rep.ByMethod {
+		row := i + 2
+		f.SetCellValue(methodSheet, fmt.Sprintf("A%d", row), m.Method)
+		f.SetCellValue(methodSheet, fmt.Sprintf("B%d", row), m.Count)
+		f.SetCellValue(methodSheet, fmt.Sprintf("C%d", row), m.Amount.Rupiah())
+	}
+
+	for i, h := range []string{"Waktu", "Jenis", "Nominal", "Keterangan"} {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(movementSheet, cell, h)
+	}
+	for i, m := range s.Movements {
+		row := i + 2
+		kind := "Kas Masuk"
+		if m.Type == models.CashPayOut {
+			kind = "Kas Keluar"
+		}
+		f.SetCellValue(movementSheet, fmt.Sprintf("A%d", row), m.CreatedAt.Format("02-01-2006 15:04"))
+		f.SetCellValue(movementSheet, fmt.Sprintf("B%d", row), kind)
+		f.SetCellValue(movementSheet, fmt.Sprintf("C%d", row), m.Amount.Rupiah())
+		f.SetCellValue(movementSheet, fmt.Sprintf("D%d", row), m.Reason)
+	}
+
+	filename := fmt.Sprintf("cash_%s_%s_%s.xlsx", rep.Kind, s.TillID, s.OpenedAt.Format("20060102_1504"))
+	path := filepath.Join(cfg.ExportDir, filename)
+	if err := f.SaveAs(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,143 @@
+package reports
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/catalog"
+	"shosha_mart_backend/models"
+)
+
+// uncategorized labels sales of products without a (live) category.
+const uncategorized = "Tanpa Kategori"
+
+// CategoryTotal is what the products of a category sold and what it cost.
+// Qty, Revenue and Cost include the sub-categories; Own* count only products
+// placed directly in it.
+type CategoryTotal struct {
+	CategoryID string       `json:"category_id"` // kosong = tanpa kategori
+	ParentID   string       `json:"parent_id"`
+	Name       string       `json:"name"`
+	Path       string       `json:"path"`
+	Depth      int          `json:"depth"`
+	Qty        models.Qty   `json:"qty"` // satuan dasar
+	Revenue    models.Money `json:"revenue"`
+	Cost       models.Money `json:"cost"` // HPP
+	OwnQty     models.Qty   `json:"own_qty"`
+	OwnRevenue models.Money `json:"own_revenue"`
+	OwnCost    models.Money `json:"own_cost"`
+}
+
+// CategorySales rolls item sales between start and end up the category tree,
+// optionally for one branch. Revenue is the line total after line discounts;
+// order-level discounts are not spread over categories. Categories with no
+// sales are left out.
+func CategorySales(db *gorm.DB, branchID string, start, end time.Time) ([]CategoryTotal, error) {
+	var rows []struct {
+		CategoryID string
+		Qty        models.Qty
+		Revenue    models.Money
+		Cost       models.Money
+	}
+	q := db.Table("sale_items").
+		Select("COALESCE(products.category_id, '') AS category_id, COALESCE(SUM(sale_items.base_qty), 0) AS qty, "+
+			"COALESCE(SUM("+lineRevenueSQL+"), 0) AS revenue, COALESCE(SUM("+lineCostSQL+"), 0) AS cost").
+		Joins("JOIN sales ON sales.id = sale_items.sale_id").
+		Joins("LEFT JOIN products ON products.id = sale_items.product_id").
+		Where("sales.created_at BETWEEN ? AND ? AND sales.is_deleted = ? AND sale_items.is_deleted = ?", start, end, false, false)
+	if branchID != "" {
+		q = q.Where("sales.branch_id = ?", branchID)
+	}
+	if err := q.Group("COALESCE(products.category_id, '')").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	cats, err := catalog.LoadCategories(db)
+	if err != nil {
+		return nil, err
+	}
+	totals := map[string]*CategoryTotal{}
+	get := func(id string) *CategoryTotal {
+		t, ok := totals[id]
+		if !ok {
+			t = &CategoryTotal{CategoryID: id, Name: uncategorized, Path: uncategorized}
+			if c, ok := cats[id]; ok {
+				t.ParentID = c.ParentID
+				t.Name = c.Name
+				t.Path = cats.Path(id)
+				t.Depth = len(cats.Ancestors(id)) - 1
+			}
+			totals[id] = t
+		}
+		return t
+	}
+	for _, r := range rows {
+		id := r.CategoryID
+		if _, ok := cats[id]; !ok {
+			id = "" // kategori sudah dihapus
+		}
+		own := get(id)
+		own.OwnQty += r.Qty
+		own.OwnRevenue += r.Revenue
+		own.OwnCost += r.Cost
+		chain := cats.Ancestors(id)
+		if len(chain) == 0 {
+			chain = []string{""}
+		}
+		for _, a := range chain {
+			t := get(a)
+			t.Qty += r.Qty
+			t.Revenue += r.Revenue
+			t.Cost += r.Cost
+		}
+	}
+
+	out := make([]CategoryTotal, 0, len(totals))
+	for _, t := range totals {
+		out = append(out, *t)
+	}
+	// tanpa kategori di akhir, sisanya urut path agar induk diikuti anaknya
+	sort.Slice(out, func(i, j int) bool {
+		if (out[i].CategoryID == "") != (out[j].CategoryID == "") {
+			return out[j].CategoryID == ""
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out, nil
+}
+
+// writeCategorySheet adds a "Kategori" sheet with the category roll-up.
+func writeCategorySheet(f *excelize.File, db *gorm.DB, branchID string, start, end time.Time) error {
+	totals, err := CategorySales(db, branchID, start, end)
+	if err != nil {
+		return err
+	}
+	sheet := "Kategori"
+	if _, err := f.NewSheet(sheet); err != nil {
+		return err
+	}
+	f.SetCellValue(sheet, "A1", fmt.Sprintf("Penjualan per Kategori %s - %s", start.Format("02 Jan 2006"), end.Format("02 Jan 2006")))
+	for i, h := range []string{"Kategori", "Qty", "Penjualan", "Qty Langsung", "Penjualan Langsung", "HPP", "Laba Kotor"} {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 3)
+		f.SetCellValue(sheet, cell, h)
+	}
+	row := 4
+	for _, t := range totals {
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), strings.Repeat("  ", t.Depth)+t.Name)
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), t.Qty.Float())
+		f.SetCellValue(sheet, fmt.Sprintf("C%d", row), t.Revenue.Rupiah())
+		f.SetCellValue(sheet, fmt.Sprintf("D%d", row), t.OwnQty.Float())
+		f.SetCellValue(sheet, fmt.Sprintf("E%d", row), t.OwnRevenue.Rupiah())
+		f.SetCellValue(sheet, fmt.Sprintf("F%d", row), t.Cost.Rupiah())
+		f.SetCellValue(sheet, fmt.Sprintf("G%d", row), (t.Revenue - t.Cost).Rupiah())
+		row++
+	}
+	f.SetColWidth(sheet, "A", "A", 32)
+	f.SetColWidth(sheet, "B", "G", 16)
+	return nil
+}
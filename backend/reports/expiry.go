@@ -0,0 +1,81 @@
+package reports
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/inventory"
+)
+
+// GenerateExpiryReport writes the expired and near-expiry batches as Excel
+// and returns its path.
+func GenerateExpiryReport(cfg config.AppConfig, rows []inventory.ExpiryRow, days int) (string, error) {
+	if err := os.MkdirAll(cfg.ExportDir, 0o755); err != nil {
+		return "", err
+	}
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := "Kedaluwarsa"
+	f.SetSheetName(f.GetSheetName(0), sheet)
+
+	f.SetCellValue(sheet, "A1", "LAPORAN BATCH KEDALUWARSA")
+	f.SetCellValue(sheet, "A2", fmt.Sprintf("Per %s, termasuk yang kedaluwarsa dalam %d hari", time.Now().Format("02-01-2006"), days))
+	headers := []string{"SKU", "Nama Barang", "Batch", "Kedaluwarsa", "Sisa Hari", "Sisa Stok", "Satuan", "HPP", "Nilai", "Status"}
+	for i, h := range headers {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 4)
+		f.SetCellValue(sheet, cell, h)
+	}
+	row := 5
+	for _, r := range rows {
+		status := "Segera kedaluwarsa"
+		if r.Status == inventory.Expired {
+			status = "Kedaluwarsa"
+		}
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), r.SKU)
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), r.Name)
+		f.SetCellValue(sheet, fmt.Sprintf("C%d", row), r.BatchNo)
+		f.SetCellValue(sheet, fmt.Sprintf("D%d", row), r.ExpiryDate.Format("02-01-2006"))
+		f.SetCellValue(sheet, fmt.Sprintf("E%d", row), r.DaysLeft)
+		f.SetCellValue(sheet, fmt.Sprintf("F%d", row), r.Remaining.Float())
+		f.SetCellValue(sheet, fmt.Sprintf("G%d", row), r.Unit)
+		f.SetCellValue(sheet, fmt.Sprintf("H%d", row), r.UnitCost.Rupiah())
+		f.SetCellValue(sheet, fmt.Sprintf("I%d", row), r.Value.Rupiah())
+		f.SetCellValue(sheet, fmt.Sprintf("J%d", row), status)
+		row++
+	}
+
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return "", err
+	}
+	title, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}})
+	if err != nil {
+		return "", err
+	}
+	rupiah, err := f.NewStyle(&excelize.Style{NumFmt: 3}) // #,##0
+	if err != nil {
+		return "", err
+	}
+	f.SetCellStyle(sheet, "A1", "A1", title)
+	f.SetCellStyle(sheet, "A4", "J4", bold)
+	if row > 5 {
+		f.SetCellStyle(sheet, "H5", fmt.Sprintf("I%d", row-1), rupiah)
+	}
+	f.SetColWidth(sheet, "A", "A", 16)
+	f.SetColWidth(sheet, "B", "B", 32)
+	f.SetColWidth(sheet, "C", "D", 14)
+	f.SetColWidth(sheet, "E", "I", 11)
+	f.SetColWidth(sheet, "J", "J", 20)
+
+	filename := fmt.Sprintf("kedaluwarsa_%s.xlsx", time.Now().Format("20060102_150405"))
+	path := filepath.Join(cfg.ExportDir, filename)
+	if err := f.SaveAs(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,137 @@
+package reports
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/models"
+)
+
+// SQL for a sale line's revenue (after line discounts; rows from before
+// discounts existed fall back to qty x price) and its cost of goods sold.
+const (
+	lineRevenueSQL = "CASE WHEN sale_items.subtotal = 0 AND sale_items.discount_amount = 0 THEN CAST(ROUND(sale_items.qty * sale_items.price / 1000.0) AS BIGINT) ELSE sale_items.subtotal END"
+	lineCostSQL    = "CAST(ROUND(sale_items.base_qty * sale_items.unit_cost / 1000.0) AS BIGINT)"
+)
+
+// Groupings accepted by Margins.
+const (
+	MarginByProduct  = "product"
+	MarginByCategory = "category"
+	MarginByBranch   = "branch"
+	MarginByDay      = "day"
+)
+
+// MarginRow is revenue, cost of goods sold and gross margin of one product,
+// category, branch or day. Order-level discounts are not spread over lines.
+type MarginRow struct {
+	Key       string       `json:"key"` // product id, category id, branch id atau tanggal
+	Name      string       `json:"name"`
+	Qty       models.Qty   `json:"qty"` // satuan dasar
+	Revenue   models.Money `json:"revenue"`
+	Cost      models.Money `json:"cost"`
+	Margin    models.Money `json:"margin"`
+	MarginPct float64      `json:"margin_pct"` // Margin / Revenue x 100
+}
+
+func (r *MarginRow) finish() {
+	r.Margin = r.Revenue - r.Cost
+	if r.Revenue != 0 {
+		r.MarginPct = float64(r.Margin) * 100 / float64(r.Revenue)
+	}
+}
+
+// Margins groups gross margin between start and end by product, category
+// (rolled up the tree like CategorySales), branch or day, optionally for one
+// branch. Lines sold before costing existed carry no cost.
+func Margins(db *gorm.DB, by, branchID string, start, end time.Time) ([]MarginRow, error) {
+	if by == MarginByCategory {
+		totals, err := CategorySales(db, branchID, start, end)
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]MarginRow, len(totals))
+		for i, t := range totals {
+			rows[i] = MarginRow{Key: t.CategoryID, Name: t.Path, Qty: t.Qty, Revenue: t.Revenue, Cost: t.Cost}
+			rows[i].finish()
+		}
+		return rows, nil
+	}
+
+	var key, name, order string
+	q := db.Table("sale_items").
+		Joins("JOIN sales ON sales.id = sale_items.sale_id").
+		Where("sales.created_at BETWEEN ? AND ? AND sales.is_deleted = ? AND sale_items.is_deleted = ?", start, end, false, false)
+	switch by {
+	case MarginByProduct:
+		key, name = "sale_items.product_id", "COALESCE(MAX(products.name), sale_items.product_id)"
+		order = "revenue - cost DESC"
+		q = q.Joins("LEFT JOIN products ON products.id = sale_items.product_id")
+	case MarginByBranch:
+		key, name = "sales.branch_id", "COALESCE(MAX(branches.name), sales.branch_id)"
+		order = "name"
+		q = q.Joins("LEFT JOIN branches ON branches.id = sales.branch_id")
+	case MarginByDay:
+		key, name = "strftime('%Y-%m-%d', sales.created_at)", "strftime('%Y-%m-%d', sales.created_at)"
+		order = "name"
+	default:
+		return nil, fmt.Errorf("unknown margin grouping %q", by)
+	}
+	if branchID != "" {
+		q = q.Where("sales.branch_id = ?", branchID)
+	}
+	var rows []MarginRow
+	err := q.Select(fmt.Sprintf("%s AS key, %s AS name, COALESCE(SUM(sale_items.base_qty), 0) AS qty, "+
+		"COALESCE(SUM(%s), 0) AS revenue, COALESCE(SUM(%s), 0) AS cost",
+		key, name, lineRevenueSQL, lineCostSQL)).
+		Group(key).Order(order).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].finish()
+	}
+	return rows, nil
+}
+
+// writeMarginSheet adds a "Margin" sheet with gross margin per product.
+func writeMarginSheet(f *excelize.File, db *gorm.DB, branchID string, start, end time.Time) error {
+	rows, err := Margins(db, MarginByProduct, branchID, start, end)
+	if err != nil {
+		return err
+	}
+	sheet := "Margin"
+	if _, err := f.NewSheet(sheet); err != nil {
+		return err
+	}
+	f.SetCellValue(sheet, "A1", fmt.Sprintf("Laba Kotor per Produk %s - %s", start.Format("02 Jan 2006"), end.Format("02 Jan 2006")))
+	for i, h := range []string{"Produk", "Qty", "Penjualan", "HPP", "Laba Kotor", "Margin %"} {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 3)
+		f.SetCellValue(sheet, cell, h)
+	}
+	row := 4
+	var total MarginRow
+	for _, r := range rows {
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), r.Name)
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), r.Qty.Float())
+		f.SetCellValue(sheet, fmt.Sprintf("C%d", row), r.Revenue.Rupiah())
+		f.SetCellValue(sheet, fmt.Sprintf("D%d", row), r.Cost.Rupiah())
+		f.SetCellValue(sheet, fmt.Sprintf("E%d", row), r.Margin.Rupiah())
+		f.SetCellValue(sheet, fmt.Sprintf("F%d", row), r.MarginPct)
+		total.Revenue += r.Revenue
+		total.Cost += r.Cost
+		row++
+	}
+	total.finish()
+	f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "TOTAL")
+	f.SetCellValue(sheet, fmt.Sprintf("C%d", row), total.Revenue.Rupiah())
+	f.SetCellValue(sheet, fmt.Sprintf("D%d", row), total.Cost.Rupiah())
+	f.SetCellValue(sheet, fmt.Sprintf("E%d", row), total.Margin.Rupiah())
+	f.SetCellValue(sheet, fmt.Sprintf("F%d", row), total.MarginPct)
+	f.SetColWidth(sheet, "A", "A", 32)
+	f.SetColWidth(sheet, "B", "F", 16)
+	return nil
+}
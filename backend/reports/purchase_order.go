@@ -0,0 +1,184 @@
+package reports
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/models"
+)
+
+// PurchaseOrderLine is an ordered product as printed on the document.
+type PurchaseOrderLine struct {
+	SKU      string
+	Name     string
+	Unit     string
+	Qty      models.Qty
+	UnitCost models.Money
+	Subtotal models.Money
+}
+
+// PurchaseOrderDocument is what a printed purchase order shows.
+type PurchaseOrderDocument struct {
+	Order    models.PurchaseOrder
+	Supplier models.Supplier
+	Branch   models.Branch
+	Lines    []PurchaseOrderLine
+}
+
+// BuildPurchaseOrderDocument loads the supplier, branch and product names of
+// po (whose Items must be loaded).
+func BuildPurchaseOrderDocument(db *gorm.DB, po models.PurchaseOrder) (PurchaseOrderDocument, error) {
+	doc := PurchaseOrderDocument{Order: po}
+	if err := db.First(&doc.Supplier, "id = ?", po.SupplierID).Error; err != nil {
+		return doc, err
+	}
+	// cabang lokal bisa belum ada di tabel branches
+	db.Limit(1).Find(&doc.Branch, "id = ?", po.BranchID)
+
+	ids := make([]string, len(po.Items))
+	for i, it := range po.Items {
+		ids[i] = it.ProductID
+	}
+	var products []models.Product
+	if err := db.Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return doc, err
+	}
+	byID := map[string]models.Product{}
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+	for _, it := range po.Items {
+		p := byID[it.ProductID]
+		unit := it.Unit
+		if unit == "" {
+			unit = p.Unit
+		}
+		doc.Lines = append(doc.Lines, PurchaseOrderLine{
+			SKU:      p.SKU,
+			Name:     p.Name,
+			Unit:     unit,
+			Qty:      it.Qty,
+			UnitCost: it.UnitCost,
+			Subtotal: it.Subtotal,
+		})
+	}
+	return doc, nil
+}
+
+// GeneratePurchaseOrderDocument writes doc as an A4 Excel sheet ready to be
+// printed or sent to the supplier, and returns its path.
+func GeneratePurchaseOrderDocument(cfg config.AppConfig, doc PurchaseOrderDocument) (string, error) {
+	if err := os.MkdirAll(cfg.ExportDir, 0o755); err != nil {
+		return "", err
+	}
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := "PO"
+	f.SetSheetName(f.GetSheetName(0), sheet)
+
+	po, s := doc.Order, doc.Supplier
+	expected := "-"
+	if po.ExpectedAt != nil {
+		expected = po.ExpectedAt.Format("02-01-2006")
+	}
+	branch := doc.Branch.Name
+	if branch == "" {
+		branch = po.BranchID
+	}
+	terms := "Tunai"
+	if s.PaymentTerms > 0 {
+		terms = fmt.Sprintf("%d hari", s.PaymentTerms)
+	}
+	header := [][]interface{}{
+		{"PURCHASE ORDER"},
+		{},
+		{"No. PO", po.Number, "", "Pemasok", s.Name},
+		{"Tanggal", po.OrderDate.Format("02-01-2006"), "", "Kontak", strings.TrimSpace(s.ContactName + " " + s.Phone)},
+		{"Dikirim ke", branch, "", "Alamat", s.Address},
+		{"Alamat", doc.Branch.Address, "", "NPWP", s.NPWP},
+		{"Perkiraan Tiba", expected, "", "Tempo", terms},
+		{"Status", po.Status},
+		{},
+		{"No", "SKU", "Nama Barang", "Qty", "Satuan", "Harga Satuan", "Jumlah"},
+	}
+	for i, r := range header {
+		for j, v := range r {
+			cell, _ := excelize.CoordinatesToCellName(j+1, i+1)
+			f.SetCellValue(sheet, cell, v)
+		}
+	}
+	headerRow := len(header)
+	row := headerRow + 1
+	for i, l := range doc.Lines {
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), i+1)
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), l.SKU)
+		f.SetCellValue(sheet, fmt.Sprintf("C%d", row), l.Name)
+		f.SetCellValue(sheet, fmt.Sprintf("D%d", row), l.Qty.Float())
+		f.SetCellValue(sheet, fmt.Sprintf("E%d", row), l.Unit)
+		f.SetCellValue(sheet, fmt.Sprintf("F%d", row), l.UnitCost.Rupiah())
+		f.SetCellValue(sheet, fmt.Sprintf("G%d", row), l.Subtotal.Rupiah())
+		row++
+	}
+	f.SetCellValue(sheet, fmt.Sprintf("F%d", row), "Total")
+	f.SetCellValue(sheet, fmt.Sprintf("G%d", row), po.Total.Rupiah())
+	totalRow := row
+	if po.Note != "" {
+		row += 2
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "Catatan")
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), po.Note)
+	}
+	row += 3
+	f.SetCellValue(sheet, fmt.Sprintf("B%d", row), "Dibuat oleh")
+	f.SetCellValue(sheet, fmt.Sprintf("F%d", row), "Disetujui")
+	f.SetCellValue(sheet, fmt.Sprintf("B%d", row+4), po.CreatedBy)
+
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return "", err
+	}
+	title, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 16}})
+	if err != nil {
+		return "", err
+	}
+	rupiah, err := f.NewStyle(&excelize.Style{NumFmt: 3}) // #,##0
+	if err != nil {
+		return "", err
+	}
+	f.SetCellStyle(sheet, "A1", "A1", title)
+	f.SetCellStyle(sheet, fmt.Sprintf("A%d", headerRow), fmt.Sprintf("G%d", headerRow), bold)
+	f.SetCellStyle(sheet, fmt.Sprintf("F%d", headerRow+1), fmt.Sprintf("G%d", totalRow), rupiah)
+	f.SetCellStyle(sheet, fmt.Sprintf("F%d", totalRow), fmt.Sprintf("F%d", totalRow), bold)
+	f.SetColWidth(sheet, "A", "A", 14)
+	f.SetColWidth(sheet, "B", "B", 16)
+	f.SetColWidth(sheet, "C", "C", 36)
+	f.SetColWidth(sheet, "D", "E", 9)
+	f.SetColWidth(sheet, "F", "G", 15)
+
+	size, portrait := 9, "portrait" // A4
+	fitWidth, fitHeight := 1, 0
+	if err := f.SetPageLayout(sheet, &excelize.PageLayoutOptions{
+		Size:        &size,
+		Orientation: &portrait,
+		FitToWidth:  &fitWidth,
+		FitToHeight: &fitHeight,
+	}); err != nil {
+		return "", err
+	}
+	fit := true
+	if err := f.SetSheetProps(sheet, &excelize.SheetPropsOptions{FitToPage: &fit}); err != nil {
+		return "", err
+	}
+
+	filename := fmt.Sprintf("%s.xlsx", strings.ReplaceAll(po.Number, "/", "-"))
+	path := filepath.Join(cfg.ExportDir, filename)
+	if err := f.SaveAs(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -17,7 +17,7 @@ import (
 // GenerateSalesReport builds an Excel export for sales between start/end.
 func GenerateSalesReport(db *gorm.DB, cfg config.AppConfig, start, end time.Time) (string, error) {
 	var sales []models.Sale
-	if err := db.Where("created_at BETWEEN ? AND ?", start, end).Preload("Items").Find(&sales).Error; err != nil {
+	if err := db.Where("created_at BETWEEN ? AND ?", start, end).Preload("Items", "is_deleted = ?", false).Preload("Payments", "is_deleted = ?", false).Find(&sales).Error; err != nil {
 		return "", err
 	}
 
@@ -33,7 +33,7 @@ func GenerateSalesReport(db *gorm.DB, cfg config.AppConfig, start, end time.Time
 	f.SetCellValue(sheet, "A2", title)
 	f.SetCellValue(sheet, "A3", fmt.Sprintf("Cabang: %s", cfg.BranchID))
 
-	headers := []string{"No", "Tanggal", "No Nota", "Total", "Items"}
+	headers := []string{"No", "Tanggal", "No Nota", "Bruto", "Diskon", "Total", "Items"}
 	for i, h := range headers {
 		cell, _ := excelize.CoordinatesToCellName(i+1, 5)
 		f.SetCellValue(sheet, cell, h)
@@ -44,17 +44,31 @@ func GenerateSalesReport(db *gorm.DB, cfg config.AppConfig, start, end time.Time
 		dateCell, _ := excelize.CoordinatesToCellName(2, row)
 		noCell, _ := excelize.CoordinatesToCellName(1, row)
 		receiptCell, _ := excelize.CoordinatesToCellName(3, row)
-		totalCell, _ := excelize.CoordinatesToCellName(4, row)
-		itemsCell, _ := excelize.CoordinatesToCellName(5, row)
+		grossCell, _ := excelize.CoordinatesToCellName(4, row)
+		discountCell, _ := excelize.CoordinatesToCellName(5, row)
+		totalCell, _ := excelize.CoordinatesToCellName(6, row)
+		itemsCell, _ := excelize.CoordinatesToCellName(7, row)
 
+		gross, discount := saleBreakdown(sale)
 		f.SetCellValue(sheet, noCell, idx+1)
 		f.SetCellValue(sheet, dateCell, sale.CreatedAt.Format("02-01-2006 15:04"))
 		f.SetCellValue(sheet, receiptCell, sale.ReceiptNo)
-		f.SetCellValue(sheet, totalCell, sale.Total)
+		f.SetCellValue(sheet, grossCell, gross.Rupiah())
+		f.SetCellValue(sheet, discountCell, discount.Rupiah())
+		f.SetCellValue(sheet, totalCell, sale.Total.Rupiah())
 		f.SetCellValue(sheet, itemsCell, len(sale.Items))
 		row++
 	}
 
+	row = writeSalesSummary(f, sheet, row+1, 6, sales)
+	writeTenderSummary(f, sheet, row+1, 6, tenderTotals(sales))
+	if err := writeCategorySheet(f, db, "", start, end); err != nil {
+		return "", err
+	}
+	if err := writeMarginSheet(f, db, "", start, end); err != nil {
+		return "", err
+	}
+
 	filename := fmt.Sprintf("sales_%s_%s.xlsx", start.Format("20060102"), end.Format("20060102"))
 	path := filepath.Join(cfg.ExportDir, filename)
 	if err := f.SaveAs(path); err != nil {
@@ -70,7 +84,7 @@ func GenerateSalesReportByBranch(db *gorm.DB, cfg config.AppConfig, branchID str
 	if branchID != "" {
 		query = query.Where("branch_id = ?", branchID)
 	}
-	if err := query.Preload("Items").Order("created_at ASC").Find(&sales).Error; err != nil {
+	if err := query.Preload("Items", "is_deleted = ?", false).Preload("Payments", "is_deleted = ?", false).Order("created_at ASC").Find(&sales).Error; err != nil {
 		return "", err
 	}
 
@@ -96,6 +110,12 @@ func GenerateSalesReportByBranch(db *gorm.DB, cfg config.AppConfig, branchID str
 	if err := writeSalesSheet(f, "Penjualan", branchName, start, end, grouped); err != nil {
 		return "", err
 	}
+	if err := writeCategorySheet(f, db, branchID, start, end); err != nil {
+		return "", err
+	}
+	if err := writeMarginSheet(f, db, branchID, start, end); err != nil {
+		return "", err
+	}
 
 	filename := fmt.Sprintf("sales_branch_%s_%s_%s.xlsx", branchID, start.Format("20060102"), end.Format("20060102"))
 	path := filepath.Join(cfg.ExportDir, filename)
@@ -109,7 +129,8 @@ func GenerateSalesReportByBranch(db *gorm.DB, cfg config.AppConfig, branchID str
 func GenerateSalesReportGlobal(db *gorm.DB, cfg config.AppConfig, start, end time.Time) (string, error) {
 	var sales []models.Sale
 	if err := db.Where("created_at BETWEEN ? AND ? AND is_deleted = false", start, end).
-		Preload("Items").
+		Preload("Items", "is_deleted = ?", false).
+		Preload("Payments", "is_deleted = ?", false).
 		Order("branch_id ASC, created_at ASC").
 		Find(&sales).Error; err != nil {
 		return "", err
@@ -160,6 +181,12 @@ func GenerateSalesReportGlobal(db *gorm.DB, cfg config.AppConfig, start, end tim
 			return "", err
 		}
 	}
+	if err := writeCategorySheet(f, db, "", start, end); err != nil {
+		return "", err
+	}
+	if err := writeMarginSheet(f, db, "", start, end); err != nil {
+		return "", err
+	}
 
 	filename := fmt.Sprintf("sales_global_%s_%s.xlsx", start.Format("20060102"), end.Format("20060102"))
 	path := filepath.Join(cfg.ExportDir, filename)
@@ -188,7 +215,7 @@ func writeSalesSheet(f *excelize.File, sheetName, branchName string, start, end
 	f.SetCellValue(sheetName, "A3", fmt.Sprintf("Cabang: %s", branchName))
 
 	// Table headers
-	headers := []string{"Tanggal", "No", "No Nota", "Metode Bayar", "Total", "Jumlah Item"}
+	headers := []string{"Tanggal", "No", "No Nota", "Metode Bayar", "Bruto", "Diskon", "Total", "Jumlah Item"}
 	headerRow := 5
 	for i, h := range headers {
 		cell, _ := excelize.CoordinatesToCellName(i+1, headerRow)
@@ -203,8 +230,10 @@ func writeSalesSheet(f *excelize.File, sheetName, branchName string, start, end
 	sort.Strings(dates)
 
 	row := headerRow + 1
+	var allSales []models.Sale
 	for _, date := range dates {
 		salesForDate := grouped[date]
+		allSales = append(allSales, salesForDate...)
 
 		for idx, sale := range salesForDate {
 			// Date (only on first row of each date group)
@@ -216,13 +245,18 @@ func writeSalesSheet(f *excelize.File, sheetName, branchName string, start, end
 			noCell, _ := excelize.CoordinatesToCellName(2, row)
 			receiptCell, _ := excelize.CoordinatesToCellName(3, row)
 			paymentCell, _ := excelize.CoordinatesToCellName(4, row)
-			totalCell, _ := excelize.CoordinatesToCellName(5, row)
-			itemsCell, _ := excelize.CoordinatesToCellName(6, row)
+			grossCell, _ := excelize.CoordinatesToCellName(5, row)
+			discountCell, _ := excelize.CoordinatesToCellName(6, row)
+			totalCell, _ := excelize.CoordinatesToCellName(7, row)
+			itemsCell, _ := excelize.CoordinatesToCellName(8, row)
 
+			gross, discount := saleBreakdown(sale)
 			f.SetCellValue(sheetName, noCell, idx+1)
 			f.SetCellValue(sheetName, receiptCell, sale.ReceiptNo)
 			f.SetCellValue(sheetName, paymentCell, sale.PaymentMethod)
-			f.SetCellValue(sheetName, totalCell, sale.Total)
+			f.SetCellValue(sheetName, grossCell, gross.Rupiah())
+			f.SetCellValue(sheetName, discountCell, discount.Rupiah())
+			f.SetCellValue(sheetName, totalCell, sale.Total.Rupiah())
 			f.SetCellValue(sheetName, itemsCell, len(sale.Items))
 			row++
 		}
@@ -231,30 +265,122 @@ func writeSalesSheet(f *excelize.File, sheetName, branchName string, start, end
 		subtotalRow := row
 		dateCell, _ := excelize.CoordinatesToCellName(1, subtotalRow)
 		labelCell, _ := excelize.CoordinatesToCellName(3, subtotalRow)
-		subtotalCell, _ := excelize.CoordinatesToCellName(5, subtotalRow)
+		grossCell, _ := excelize.CoordinatesToCellName(5, subtotalRow)
+		discountCell, _ := excelize.CoordinatesToCellName(6, subtotalRow)
+		subtotalCell, _ := excelize.CoordinatesToCellName(7, subtotalRow)
 
 		f.SetCellValue(sheetName, dateCell, "")
 		f.SetCellValue(sheetName, labelCell, "Subtotal")
 
-		subtotal := 0.0
+		var subtotal, grossTotal, discountTotal models.Money
 		for _, s := range salesForDate {
+			gross, discount := saleBreakdown(s)
+			grossTotal += gross
+			discountTotal += discount
 			subtotal += s.Total
 		}
-		f.SetCellValue(sheetName, subtotalCell, subtotal)
+		f.SetCellValue(sheetName, grossCell, grossTotal.Rupiah())
+		f.SetCellValue(sheetName, discountCell, discountTotal.Rupiah())
+		f.SetCellValue(sheetName, subtotalCell, subtotal.Rupiah())
 		row++
 	}
 
+	row = writeSalesSummary(f, sheetName, row+1, 7, allSales)
+	writeTenderSummary(f, sheetName, row+1, 7, tenderTotals(allSales))
+
 	// Set column widths
 	f.SetColWidth(sheetName, "A", "A", 12) // Tanggal
 	f.SetColWidth(sheetName, "B", "B", 6)  // No
 	f.SetColWidth(sheetName, "C", "C", 15) // No Nota
 	f.SetColWidth(sheetName, "D", "D", 14) // Metode Bayar
-	f.SetColWidth(sheetName, "E", "E", 12) // Total
-	f.SetColWidth(sheetName, "F", "F", 12) // Jumlah Item
+	f.SetColWidth(sheetName, "E", "E", 12) // Bruto
+	f.SetColWidth(sheetName, "F", "F", 12) // Diskon
+	f.SetColWidth(sheetName, "G", "G", 12) // Total
+	f.SetColWidth(sheetName, "H", "H", 12) // Jumlah Item
 
 	return nil
 }
 
+// saleBreakdown returns the gross value of a sale (items at their original
+// price) and the total discount given on its lines and on the order.
+func saleBreakdown(s models.Sale) (gross, discount models.Money) {
+	if len(s.Items) == 0 {
+		return s.Total + s.DiscountAmount, s.DiscountAmount
+	}
+	for _, item := range s.Items {
+		gross += item.GrossAmount()
+		discount += item.GrossAmount() - item.LineTotal()
+	}
+	return gross, discount + s.DiscountAmount
+}
+
+// writeSalesSummary writes gross sales, discounts and net sales for the
+// period, with amounts in amountCol. It returns the next free row.
+func writeSalesSummary(f *excelize.File, sheetName string, row, amountCol int, sales []models.Sale) int {
+	var gross, discount, net models.Money
+	for _, s := range sales {
+		g, d := saleBreakdown(s)
+		gross += g
+		discount += d
+		net += s.Total
+	}
+	lines := []struct {
+		label  string
+		amount models.Money
+	}{
+		{"Penjualan Kotor", gross},
+		{"Diskon", discount},
+		{"Penjualan Bersih", net},
+	}
+	for _, l := range lines {
+		labelCell, _ := excelize.CoordinatesToCellName(amountCol-1, row)
+		amountCell, _ := excelize.CoordinatesToCellName(amountCol, row)
+		f.SetCellValue(sheetName, labelCell, l.label)
+		f.SetCellValue(sheetName, amountCell, l.amount.Rupiah())
+		row++
+	}
+	return row
+}
+
+// tenderTotals sums payment amounts per tender method. Sales without payment
+// rows (recorded before split tenders) count fully towards their payment_method.
+func tenderTotals(sales []models.Sale) map[string]models.Money {
+	totals := make(map[string]models.Money)
+	for _, s := range sales {
+		if len(s.Payments) == 0 {
+			totals[s.PaymentMethod] += s.Total
+			continue
+		}
+		for _, p := range s.Payments {
+			totals[p.Method] += p.Amount
+		}
+	}
+	return totals
+}
+
+// writeTenderSummary writes a per-method payment summary starting at row, with
+// amounts in amountCol and method names just left of it. It returns the next free row.
+func writeTenderSummary(f *excelize.File, sheetName string, row, amountCol int, totals map[string]models.Money) int {
+	labelCell, _ := excelize.CoordinatesToCellName(amountCol-1, row)
+	f.SetCellValue(sheetName, labelCell, "Ringkasan Pembayaran")
+	row++
+
+	methods := make([]string, 0, len(totals))
+	for m := range totals {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+
+	for _, m := range methods {
+		methodCell, _ := excelize.CoordinatesToCellName(amountCol-1, row)
+		amountCell, _ := excelize.CoordinatesToCellName(amountCol, row)
+		f.SetCellValue(sheetName, methodCell, m)
+		f.SetCellValue(sheetName, amountCell, totals[m].Rupiah())
+		row++
+	}
+	return row
+}
+
 // sanitizeSheetName ensures sheet name is valid for Excel (max 31 chars, no special chars)
 func sanitizeSheetName(name string) string {
 	// Replace invalid characters
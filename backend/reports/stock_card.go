@@ -0,0 +1,190 @@
+package reports
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/models"
+)
+
+// StockCard is a product's stock movements at one branch over a period,
+// between the stock before the first and after the last of them.
+type StockCard struct {
+	Product   models.Product
+	Branch    models.Branch
+	BranchID  string
+	Start     time.Time
+	End       time.Time
+	Opening   models.Qty
+	Closing   models.Qty
+	Movements []models.StockMovement
+}
+
+// BuildStockCard loads the movements of product at branchID between start and
+// end. The opening balance comes from the nearest recorded movement; onHand
+// is the branch's current stock, used when no movement was ever recorded.
+func BuildStockCard(db *gorm.DB, product models.Product, branchID string, onHand models.Qty, start, end time.Time) (StockCard, error) {
+	card := StockCard{Product: product, BranchID: branchID, Start: start, End: end}
+	db.Limit(1).Find(&card.Branch, "id = ?", branchID)
+
+	base := func() *gorm.DB {
+		return db.Where("product_id = ? AND branch_id = ? AND is_deleted = ?", product.ID, branchID, false)
+	}
+	if err := base().Where("created_at BETWEEN ? AND ?", start, end).
+		Order("created_at, id").Find(&card.Movements).Error; err != nil {
+		return card, err
+	}
+
+	// saldo awal: saldo mutasi terakhir sebelum periode, atau saldo sebelum
+	// mutasi pertama sesudahnya (stok lama sebelum kartu stok dicatat)
+	var prev, next []models.StockMovement
+	if err := base().Where("created_at < ?", start).Order("created_at DESC, id DESC").Limit(1).Find(&prev).Error; err != nil {
+		return card, err
+	}
+	switch {
+	case len(prev) > 0:
+		card.Opening = prev[0].Balance
+	case len(card.Movements) > 0:
+		card.Opening = card.Movements[0].Balance - card.Movements[0].Delta
+	default:
+		if err := base().Where("created_at > ?", end).Order("created_at, id").Limit(1).Find(&next).Error; err != nil {
+			return card, err
+		}
+		card.Opening = onHand
+		if len(next) > 0 {
+			card.Opening = next[0].Balance - next[0].Delta
+		}
+	}
+	card.Closing = card.Opening
+	if n := len(card.Movements); n > 0 {
+		card.Closing = card.Movements[n-1].Balance
+	}
+	return card, nil
+}
+
+// movementLabels are the stock card names of movement sources.
+var movementLabels = map[string]string{
+	models.MovementOpening:     "Stok awal",
+	models.MovementAdjustment:  "Penyesuaian",
+	models.MovementImport:      "Impor",
+	models.MovementSale:        "Penjualan",
+	models.MovementSaleEdit:    "Ubah penjualan",
+	models.MovementSaleVoid:    "Batal penjualan",
+	models.MovementReceipt:     "Penerimaan barang",
+	models.MovementTransferOut: "Mutasi keluar",
+	models.MovementTransferIn:  "Mutasi masuk",
+	models.MovementOpname:      "Stock opname",
+	models.MovementWriteOff:    "Hapus buku kedaluwarsa",
+}
+
+// GenerateStockCard writes card as an Excel sheet and returns its path.
+// Quantities are in the product's base unit.
+func GenerateStockCard(cfg config.AppConfig, card StockCard) (string, error) {
+	if err := os.MkdirAll(cfg.ExportDir, 0o755); err != nil {
+		return "", err
+	}
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := "Kartu Stok"
+	f.SetSheetName(f.GetSheetName(0), sheet)
+
+	p := card.Product
+	branch := card.Branch.Name
+	if branch == "" {
+		branch = card.BranchID
+	}
+	// rentang default dateRangeQuery tidak dibatasi
+	from, to := "-", "-"
+	if !card.Start.IsZero() {
+		from = card.Start.Format("02-01-2006")
+	}
+	if card.End.Before(time.Now().AddDate(50, 0, 0)) {
+		to = card.End.Format("02-01-2006")
+	}
+	header := [][]interface{}{
+		{"KARTU STOK"},
+		{},
+		{"Produk", p.Name},
+		{"SKU", p.SKU},
+		{"Satuan", p.Unit},
+		{"Cabang", branch},
+		{"Periode", from + " s/d " + to},
+		{},
+		{"Tanggal", "Jenis", "No. Dokumen", "Masuk", "Keluar", "Saldo", "HPP", "User", "Keterangan"},
+	}
+	for i, r := range header {
+		for j, v := range r {
+			cell, _ := excelize.CoordinatesToCellName(j+1, i+1)
+			f.SetCellValue(sheet, cell, v)
+		}
+	}
+	headerRow := len(header)
+	row := headerRow + 1
+	f.SetCellValue(sheet, fmt.Sprintf("B%d", row), "Saldo awal")
+	f.SetCellValue(sheet, fmt.Sprintf("F%d", row), card.Opening.Float())
+	openingRow := row
+	row++
+	for _, m := range card.Movements {
+		label := movementLabels[m.Source]
+		if label == "" {
+			label = m.Source
+		}
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), m.CreatedAt.Local().Format("02-01-2006 15:04"))
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), label)
+		f.SetCellValue(sheet, fmt.Sprintf("C%d", row), m.DocNo)
+		if m.Delta >= 0 {
+			f.SetCellValue(sheet, fmt.Sprintf("D%d", row), m.Delta.Float())
+		} else {
+			f.SetCellValue(sheet, fmt.Sprintf("E%d", row), (-m.Delta).Float())
+		}
+		f.SetCellValue(sheet, fmt.Sprintf("F%d", row), m.Balance.Float())
+		f.SetCellValue(sheet, fmt.Sprintf("G%d", row), m.UnitCost.Rupiah())
+		f.SetCellValue(sheet, fmt.Sprintf("H%d", row), m.CreatedBy)
+		f.SetCellValue(sheet, fmt.Sprintf("I%d", row), m.Note)
+		row++
+	}
+	f.SetCellValue(sheet, fmt.Sprintf("B%d", row), "Saldo akhir")
+	f.SetCellValue(sheet, fmt.Sprintf("F%d", row), card.Closing.Float())
+
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return "", err
+	}
+	title, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 16}})
+	if err != nil {
+		return "", err
+	}
+	rupiah, err := f.NewStyle(&excelize.Style{NumFmt: 3}) // #,##0
+	if err != nil {
+		return "", err
+	}
+	f.SetCellStyle(sheet, "A1", "A1", title)
+	f.SetCellStyle(sheet, fmt.Sprintf("A%d", headerRow), fmt.Sprintf("I%d", headerRow), bold)
+	f.SetCellStyle(sheet, fmt.Sprintf("A%d", openingRow), fmt.Sprintf("F%d", openingRow), bold)
+	f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row), bold)
+	f.SetCellStyle(sheet, fmt.Sprintf("G%d", openingRow+1), fmt.Sprintf("G%d", row), rupiah)
+	f.SetColWidth(sheet, "A", "A", 17)
+	f.SetColWidth(sheet, "B", "C", 20)
+	f.SetColWidth(sheet, "D", "G", 11)
+	f.SetColWidth(sheet, "H", "H", 14)
+	f.SetColWidth(sheet, "I", "I", 30)
+	f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: headerRow, TopLeftCell: fmt.Sprintf("A%d", headerRow+1), ActivePane: "bottomLeft"})
+
+	code := p.SKU
+	if code == "" {
+		code = p.ID
+	}
+	filename := fmt.Sprintf("kartu_stok_%s_%s.xlsx", strings.ReplaceAll(code, "/", "-"), time.Now().Format("20060102_150405"))
+	path := filepath.Join(cfg.ExportDir, filename)
+	if err := f.SaveAs(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
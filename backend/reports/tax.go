@@ -0,0 +1,107 @@
+package reports
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"shosha_mart_backend/config"
+	"shosha_mart_backend/models"
+	"shosha_mart_backend/pricing"
+)
+
+// GenerateTaxReport builds the PPN report: a summary per tax rate and one
+// invoice row per sale with its DPP and tax.
+func GenerateTaxReport(db *gorm.DB, cfg config.AppConfig, branchID string, start, end time.Time) (string, error) {
+	var sales []models.Sale
+	query := db.Where("created_at BETWEEN ? AND ? AND is_deleted = false", start, end)
+	if branchID != "" {
+		query = query.Where("branch_id = ?", branchID)
+	}
+	if err := query.Preload("Items", "is_deleted = ?", false).Order("created_at ASC").Find(&sales).Error; err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(cfg.ExportDir, 0o755); err != nil {
+		return "", err
+	}
+
+	f := excelize.NewFile()
+	summarySheet := "Ringkasan Pajak"
+	detailSheet := "Detail Faktur"
+	f.SetSheetName(f.GetSheetName(0), summarySheet)
+	if _, err := f.NewSheet(detailSheet); err != nil {
+		return "", err
+	}
+
+	period := fmt.Sprintf("Periode: %s - %s", start.Format("02 Jan 2006"), end.Format("02 Jan 2006"))
+	f.SetCellValue(summarySheet, "A1", "Laporan Pajak (PPN)")
+	f.SetCellValue(summarySheet, "A2", period)
+	if branchID != "" {
+		f.SetCellValue(summarySheet, "A3", fmt.Sprintf("Cabang: %s", branchID))
+	}
+
+	var allItems []models.SaleItem
+	for _, sale := range sales {
+		allItems = append(allItems, sale.Items...)
+	}
+
+	headers := []string{"Kode", "Tarif (%)", "Jenis", "DPP", "Pajak"}
+	for i, h := range headers {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 5)
+		f.SetCellValue(summarySheet, cell, h)
+	}
+	row := 6
+	var totalBase, totalTax models.Money
+	for _, s := range pricing.SummarizeTax(allItems) {
+		kind := "Eksklusif"
+		if s.Inclusive {
+			kind = "Inklusif"
+		}
+		f.SetCellValue(summarySheet, fmt.Sprintf("A%d", row), s.Code)
+		f.SetCellValue(summarySheet, fmt.Sprintf("B%d", row), s.Rate)
+		f.SetCellValue(summarySheet, fmt.Sprintf("C%d", row), kind)
+		f.SetCellValue(summarySheet, fmt.Sprintf("D%d", row), s.Base.Rupiah())
+		f.SetCellValue(summarySheet, fmt.Sprintf("E%d", row), s.Tax.Rupiah())
+		totalBase += s.Base
+		totalTax += s.Tax
+		row++
+	}
+	f.SetCellValue(summarySheet, fmt.Sprintf("A%d", row), "TOTAL")
+	f.SetCellValue(summarySheet, fmt.Sprintf("D%d", row), totalBase.Rupiah())
+	f.SetCellValue(summarySheet, fmt.Sprintf("E%d", row), totalTax.Rupiah())
+
+	detailHeaders := []string{"Tanggal", "No Nota", "Cabang", "DPP", "PPN", "Total"}
+	for i, h := range detailHeaders {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(detailSheet, cell, h)
+	}
+	row = 2
+	for _, sale := range sales {
+		var base models.Money
+		for _, item := range sale.Items {
+			base += item.TaxBase
+		}
+		f.SetCellValue(detailSheet, fmt.Sprintf("A%d", row), sale.CreatedAt.Format("02-01-2006 15:04"))
+		f.SetCellValue(detailSheet, fmt.Sprintf("B%d", row), sale.ReceiptNo)
+		f.SetCellValue(detailSheet, fmt.Sprintf("C%d", row), sale.BranchName)
+		f.SetCellValue(detailSheet, fmt.Sprintf("D%d", row), base.Rupiah())
+		f.SetCellValue(detailSheet, fmt.Sprintf("E%d", row), sale.TaxAmount.Rupiah())
+		f.SetCellValue(detailSheet, fmt.Sprintf("F%d", row), sale.Total.Rupiah())
+		row++
+	}
+
+	filename := fmt.Sprintf("tax_%s_%s.xlsx", start.Format("20060102"), end.Format("20060102"))
+	if branchID != "" {
+		filename = fmt.Sprintf("tax_branch_%s_%s_%s.xlsx", branchID, start.Format("20060102"), end.Format("20060102"))
+	}
+	path := filepath.Join(cfg.ExportDir, filename)
+	if err := f.SaveAs(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
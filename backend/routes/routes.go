@@ -14,7 +14,8 @@ func Register(r *gin.Engine, db *gorm.DB, cfg config.AppConfig, worker *syncsvc.
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173", "http://127.0.0.1:5173", "http://localhost:8080", "http://127.0.0.1:8080"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "X-POS-User"},
+		ExposeHeaders:    []string{"Content-Disposition", "X-Receipt-Copy"},
 		AllowCredentials: true,
 		AllowOriginFunc: func(origin string) bool {
 			// Allow Electron file:// protocol dan localhost
@@ -32,25 +33,139 @@ func Register(r *gin.Engine, db *gorm.DB, cfg config.AppConfig, worker *syncsvc.
 	r.GET("/api/products", controllers.ListProducts(db))
 	r.POST("/api/products", controllers.CreateProduct(db, cfg))
 	r.POST("/api/products/bulk", controllers.BulkCreateProducts(db, cfg))
+	r.GET("/api/products/export", controllers.ExportProducts(db))
+	r.POST("/api/products/import", controllers.ImportProducts(db, cfg))
+	r.GET("/api/products/import/reports/:name", controllers.ImportReport(cfg))
+	r.GET("/api/products/lookup", controllers.LookupProduct(db, cfg))
 	r.PUT("/api/products/:id", controllers.UpdateProduct(db, cfg))
 	r.DELETE("/api/products/:id", controllers.DeleteProduct(db, cfg))
+	r.GET("/api/products/:id/prices", controllers.ProductPrices(db))
+	r.GET("/api/products/:id/movements", controllers.ListProductMovements(db, cfg))
+	r.GET("/api/products/:id/stock-card", controllers.StockCardReport(db, cfg))
+	r.POST("/api/products/:id/prices/schedule", controllers.ScheduleProductPrice(db, cfg))
+	r.DELETE("/api/products/:id/prices/schedule/:scheduleId", controllers.CancelScheduledPrice(db))
+
+	r.GET("/api/categories", controllers.ListCategories(db))
+	r.POST("/api/categories", controllers.CreateCategory(db))
+	r.PUT("/api/categories/:id", controllers.UpdateCategory(db))
+	r.DELETE("/api/categories/:id", controllers.DeleteCategory(db))
+
+	r.GET("/api/brands", controllers.ListBrands(db))
+	r.POST("/api/brands", controllers.CreateBrand(db))
+	r.PUT("/api/brands/:id", controllers.UpdateBrand(db))
+	r.DELETE("/api/brands/:id", controllers.DeleteBrand(db))
 
 	r.GET("/api/branches", controllers.ListBranches(db))
 	r.POST("/api/branches", controllers.CreateBranch(db, cfg))
 	r.PUT("/api/branches/:id", controllers.UpdateBranch(db, cfg))
 	r.DELETE("/api/branches/:id", controllers.DeleteBranch(db, cfg))
 
+	r.GET("/api/customers", controllers.ListCustomers(db))
+	r.POST("/api/customers", controllers.CreateCustomer(db))
+	r.GET("/api/customers/:id", controllers.GetCustomer(db))
+	r.PUT("/api/customers/:id", controllers.UpdateCustomer(db))
+	r.DELETE("/api/customers/:id", controllers.DeleteCustomer(db))
+	r.GET("/api/customers/:id/sales", controllers.CustomerSales(db))
+
+	r.GET("/api/suppliers", controllers.ListSuppliers(db))
+	r.POST("/api/suppliers", controllers.CreateSupplier(db))
+	r.GET("/api/suppliers/:id", controllers.GetSupplier(db))
+	r.PUT("/api/suppliers/:id", controllers.UpdateSupplier(db))
+	r.DELETE("/api/suppliers/:id", controllers.DeleteSupplier(db))
+
+	r.GET("/api/purchase-orders", controllers.ListPurchaseOrders(db))
+	r.POST("/api/purchase-orders", controllers.CreatePurchaseOrder(db, cfg))
+	r.GET("/api/purchase-orders/:id", controllers.GetPurchaseOrder(db))
+	r.PUT("/api/purchase-orders/:id", controllers.UpdatePurchaseOrder(db, cfg))
+	r.DELETE("/api/purchase-orders/:id", controllers.DeletePurchaseOrder(db))
+	r.POST("/api/purchase-orders/:id/send", controllers.SendPurchaseOrder(db))
+	r.POST("/api/purchase-orders/:id/close", controllers.ClosePurchaseOrder(db))
+	r.GET("/api/purchase-orders/:id/document", controllers.PurchaseOrderDocument(db, cfg))
+
+	r.GET("/api/goods-receipts", controllers.ListGoodsReceipts(db))
+	r.POST("/api/goods-receipts", controllers.CreateGoodsReceipt(db, cfg))
+	r.GET("/api/goods-receipts/:id", controllers.GetGoodsReceipt(db))
+
+	r.GET("/api/transfers", controllers.ListTransfers(db, cfg))
+	r.POST("/api/transfers", controllers.CreateTransfer(db, cfg))
+	r.GET("/api/transfers/:id", controllers.GetTransfer(db))
+	r.POST("/api/transfers/:id/dispatch", controllers.DispatchTransfer(db, cfg))
+	r.POST("/api/transfers/:id/receive", controllers.ReceiveTransfer(db, cfg))
+	r.POST("/api/transfers/:id/cancel", controllers.CancelTransfer(db))
+
+	r.GET("/api/inventory/reorder-levels", controllers.ListReorderLevels(db, cfg))
+	r.PUT("/api/products/:id/reorder-level", controllers.SetReorderLevel(db, cfg))
+	r.DELETE("/api/products/:id/reorder-level", controllers.DeleteReorderLevel(db, cfg))
+	r.GET("/api/inventory/low-stock", controllers.LowStock(db, cfg))
+	r.GET("/api/inventory/reorder-suggestions", controllers.ReorderSuggestions(db, cfg))
+	r.POST("/api/inventory/reorder-suggestions/purchase-order", controllers.CreateSuggestedPurchaseOrder(db, cfg))
+	r.GET("/api/inventory/batches", controllers.ListBatches(db, cfg))
+	r.POST("/api/inventory/batches/:id/write-off", controllers.WriteOffBatch(db, cfg))
+	r.POST("/api/inventory/batches/write-off-expired", controllers.WriteOffExpired(db, cfg))
+	r.GET("/api/inventory/expiry", controllers.ExpiryReport(db, cfg))
+
+	r.GET("/api/stock-adjustments", controllers.ListStockAdjustments(db))
+	r.POST("/api/stock-adjustments", controllers.CreateStockAdjustment(db, cfg))
+	r.GET("/api/stock-adjustments/:id", controllers.GetStockAdjustment(db))
+	r.POST("/api/stock-adjustments/:id/approve", controllers.ApproveStockAdjustment(db, cfg))
+	r.POST("/api/stock-adjustments/:id/reject", controllers.RejectStockAdjustment(db, cfg))
+
 	r.POST("/api/sales", controllers.CreateSale(db, cfg))
 	r.GET("/api/sales", controllers.ListSales(db))
 	r.GET("/api/sales/:id", controllers.GetSale(db))
+	r.GET("/api/sales/:id/receipt", controllers.SaleReceipt(db))
 	r.PUT("/api/sales/:id", controllers.UpdateSale(db))
-	r.DELETE("/api/sales/:id", controllers.DeleteSale(db))
-	r.PUT("/api/sales/:id/items/:itemId", controllers.UpdateSaleItem(db))
-	r.POST("/api/sales/:id/items", controllers.AddSaleItem(db))
-	r.DELETE("/api/sales/:id/items/:itemId", controllers.DeleteSaleItem(db))
+	r.DELETE("/api/sales/:id", controllers.DeleteSale(db, cfg))
+	r.PUT("/api/sales/:id/items/:itemId", controllers.UpdateSaleItem(db, cfg))
+	r.POST("/api/sales/:id/items", controllers.AddSaleItem(db, cfg))
+	r.DELETE("/api/sales/:id/items/:itemId", controllers.DeleteSaleItem(db, cfg))
 	r.GET("/api/sales/export", controllers.ExportSalesReport(db))
+	r.GET("/api/sales/reconcile", controllers.ReconcileSales(db))
+	r.POST("/api/sales/reconcile", controllers.FixSaleTotals(db))
+
+	r.GET("/api/drafts", controllers.ListDrafts(db))
+	r.POST("/api/drafts", controllers.CreateDraft(db, cfg))
+	r.GET("/api/drafts/:id", controllers.GetDraft(db, cfg))
+	r.PUT("/api/drafts/:id", controllers.UpdateDraft(db, cfg))
+	r.DELETE("/api/drafts/:id", controllers.DeleteDraft(db))
+	r.POST("/api/drafts/:id/items", controllers.AddDraftItem(db, cfg))
+	r.PUT("/api/drafts/:id/items/:itemId", controllers.UpdateDraftItem(db, cfg))
+	r.DELETE("/api/drafts/:id/items/:itemId", controllers.DeleteDraftItem(db, cfg))
+	r.POST("/api/drafts/:id/park", controllers.ParkDraft(db, cfg))
+	r.POST("/api/drafts/:id/resume", controllers.ResumeDraft(db, cfg))
+	r.POST("/api/drafts/:id/post", controllers.PostDraft(db, cfg))
+
+	r.GET("/api/cash-sessions", controllers.ListCashSessions(db))
+	r.POST("/api/cash-sessions", controllers.OpenCashSession(db, cfg))
+	r.GET("/api/cash-sessions/current", controllers.CurrentCashSession(db, cfg))
+	r.GET("/api/cash-sessions/:id", controllers.GetCashSession(db))
+	r.POST("/api/cash-sessions/:id/movements", controllers.AddCashMovement(db))
+	r.POST("/api/cash-sessions/:id/close", controllers.CloseCashSession(db))
+	r.GET("/api/cash-sessions/:id/report", controllers.CashSessionReport(db, cfg))
+
+	r.GET("/api/promotions", controllers.ListPromotions(db))
+	r.POST("/api/promotions", controllers.CreatePromotion(db))
+	r.PUT("/api/promotions/:id", controllers.UpdatePromotion(db))
+	r.DELETE("/api/promotions/:id", controllers.DeletePromotion(db))
+
+	r.GET("/api/receipt-template", controllers.GetReceiptTemplate(db))
+	r.PUT("/api/receipt-template", controllers.SaveReceiptTemplate(db))
+
+	r.GET("/api/tax-rates", controllers.ListTaxRates(db))
+	r.POST("/api/tax-rates", controllers.CreateTaxRate(db))
+	r.PUT("/api/tax-rates/:id", controllers.UpdateTaxRate(db))
+	r.DELETE("/api/tax-rates/:id", controllers.DeleteTaxRate(db))
 
-	r.POST("/api/stock-opname", controllers.CreateStockOpname(db, cfg))
+	r.POST("/api/stock-opname", controllers.CreateStockOpname(db, cfg)) // deprecated; satu kali jalan lewat sesi
+	r.GET("/api/stock-opname", controllers.ListStockOpnames(db))
+	r.POST("/api/stock-opname/sessions", controllers.StartStockOpname(db, cfg))
+	r.GET("/api/stock-opname/:id", controllers.GetStockOpname(db))
+	r.GET("/api/stock-opname/:id/uncounted", controllers.UncountedOpnameProducts(db))
+	r.GET("/api/stock-opname/:id/counts", controllers.ListOpnameCounts(db))
+	r.POST("/api/stock-opname/:id/counts", controllers.AddOpnameCount(db, cfg))
+	r.DELETE("/api/stock-opname/:id/counts/:countId", controllers.DeleteOpnameCount(db, cfg))
+	r.POST("/api/stock-opname/:id/finalize", controllers.FinalizeStockOpname(db, cfg))
+	r.POST("/api/stock-opname/:id/cancel", controllers.CancelStockOpname(db, cfg))
 
 	r.GET("/api/sync/summary", controllers.SyncSummary(db, cfg, worker))
 	r.POST("/api/sync/run", controllers.SyncRun(worker))
@@ -63,4 +178,7 @@ func Register(r *gin.Engine, db *gorm.DB, cfg config.AppConfig, worker *syncsvc.
 	r.GET("/api/reports/sales", controllers.SalesReport(db, cfg))
 	r.GET("/api/reports/sales/branch/:branch_id", controllers.SalesReportByBranch(db, cfg))
 	r.GET("/api/reports/sales/global", controllers.SalesReportGlobal(db, cfg))
+	r.GET("/api/reports/tax", controllers.TaxReport(db, cfg))
+	r.GET("/api/reports/margins", controllers.MarginReport(db))
+	r.GET("/api/reports/stock-adjustments", controllers.StockAdjustmentReport(db, cfg))
 }
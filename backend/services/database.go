@@ -8,7 +8,10 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
+	"shosha_mart_backend/catalog"
 	"shosha_mart_backend/config"
+	"shosha_mart_backend/costing"
+	"shosha_mart_backend/migrations"
 	"shosha_mart_backend/models"
 )
 
@@ -26,11 +29,47 @@ func Connect(cfg config.AppConfig) (*gorm.DB, error) {
 		log.Printf("warn: failed enabling foreign keys: %v", err)
 	}
 
+	if err := migrations.Run(db); err != nil {
+		return nil, fmt.Errorf("data migrations: %w", err)
+	}
+
 	if err := db.AutoMigrate(
 		&models.Product{},
+		&models.ProductBarcode{},
+		&models.ProductUnit{},
+		&models.CostLayer{},
+		&models.ProductPriceHistory{},
+		&models.ScheduledPriceChange{},
+		&models.Supplier{},
+		&models.PurchaseOrder{},
+		&models.PurchaseOrderItem{},
+		&models.GoodsReceipt{},
+		&models.GoodsReceiptItem{},
+		&models.StockTransfer{},
+		&models.StockTransferItem{},
+		&models.StockMovement{},
+		&models.ReorderLevel{},
+		&models.StockBatch{},
+		&models.StockBatchAllocation{},
+		&models.StockAdjustment{},
+		&models.StockAdjustmentItem{},
+		&models.StockOpnameCount{},
+		&models.BranchStock{},
+		&models.BranchPrice{},
+		&models.Category{},
+		&models.Brand{},
 		&models.Branch{},
+		&models.Customer{},
+		&models.CashSession{},
+		&models.CashMovement{},
 		&models.Sale{},
 		&models.SaleItem{},
+		&models.SalePayment{},
+		&models.DraftSale{},
+		&models.DraftSaleItem{},
+		&models.ReceiptTemplate{},
+		&models.Promotion{},
+		&models.TaxRate{},
 		&models.StockOpname{},
 		&models.StockOpnameItem{},
 		&models.SyncState{},
@@ -38,5 +77,19 @@ func Connect(cfg config.AppConfig) (*gorm.DB, error) {
 		return nil, fmt.Errorf("auto migrate: %w", err)
 	}
 
+	// SKU dan barcode unik hanya di antara baris yang belum dihapus
+	for _, stmt := range []string{
+		`CREATE UNIQUE INDEX IF NOT EXISTS ux_products_sku ON products(sku) WHERE sku <> '' AND is_deleted = false`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS ux_product_barcodes_code ON product_barcodes(code) WHERE is_deleted = false`,
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			log.Printf("warn: failed creating unique index: %v", err)
+		}
+	}
+	catalog.Setup(db)
+	if err := costing.SyncProductStock(db, cfg.BranchID); err != nil {
+		return nil, fmt.Errorf("branch stock: %w", err)
+	}
+
 	return db, nil
 }
@@ -5,6 +5,7 @@ import (
 
 	"gorm.io/gorm"
 
+	"shosha_mart_backend/inventory"
 	"shosha_mart_backend/models"
 )
 
@@ -15,17 +16,47 @@ type Summary struct {
 	DbPath        string     `json:"dbPath"`
 	Status        string     `json:"status"`
 	LastError     string     `json:"lastError,omitempty"`
+	LowStock      int64      `json:"lowStock"` // produk di bawah stok minimum cabang ini
 }
 
-func Build(db *gorm.DB, dbPath, status, lastErr string) (Summary, error) {
+func Build(db *gorm.DB, dbPath, branchID, status, lastErr string) (Summary, error) {
 	var (
-		unsyncedProducts int64
-		unsyncedBranches int64
-		unsyncedSales    int64
-		unsyncedItems    int64
-		unsyncedOpname   int64
-		unsyncedOpItems  int64
-		syncState        models.SyncState
+		unsyncedProducts              int64
+		unsyncedBranches              int64
+		unsyncedSales                 int64
+		unsyncedItems                 int64
+		unsyncedPayments              int64
+		unsyncedPromos                int64
+		unsyncedTaxRates              int64
+		unsyncedCustomers             int64
+		unsyncedCashSessions          int64
+		unsyncedCashMovements         int64
+		unsyncedProductBarcodes       int64
+		unsyncedCategories            int64
+		unsyncedBrands                int64
+		unsyncedProductUnits          int64
+		unsyncedCostLayers            int64
+		unsyncedProductPriceHistories int64
+		unsyncedScheduledPriceChanges int64
+		unsyncedSuppliers             int64
+		unsyncedPurchaseOrders        int64
+		unsyncedPurchaseOrderItems    int64
+		unsyncedGoodsReceipts         int64
+		unsyncedGoodsReceiptItems     int64
+		unsyncedStockTransfers        int64
+		unsyncedStockTransferItems    int64
+		unsyncedStockMovements        int64
+		unsyncedReorderLevels         int64
+		unsyncedStockBatches          int64
+		unsyncedStockBatchAllocations int64
+		unsyncedStockAdjustments      int64
+		unsyncedStockAdjustmentItems  int64
+		unsyncedStockOpnameCounts     int64
+		unsyncedBranchStocks          int64
+		unsyncedBranchPrices          int64
+		unsyncedOpname                int64
+		unsyncedOpItems               int64
+		syncState                     models.SyncState
 	)
 
 	_ = db.First(&syncState, "id = ?", "singleton").Error
@@ -34,10 +65,44 @@ func Build(db *gorm.DB, dbPath, status, lastErr string) (Summary, error) {
 	db.Model(&models.Branch{}).Where("synced = ?", false).Count(&unsyncedBranches)
 	db.Model(&models.Sale{}).Where("synced = ?", false).Count(&unsyncedSales)
 	db.Model(&models.SaleItem{}).Where("synced = ?", false).Count(&unsyncedItems)
+	db.Model(&models.SalePayment{}).Where("synced = ?", false).Count(&unsyncedPayments)
+	db.Model(&models.Promotion{}).Where("synced = ?", false).Count(&unsyncedPromos)
+	db.Model(&models.TaxRate{}).Where("synced = ?", false).Count(&unsyncedTaxRates)
+	db.Model(&models.Customer{}).Where("synced = ?", false).Count(&unsyncedCustomers)
+	db.Model(&models.CashSession{}).Where("synced = ?", false).Count(&unsyncedCashSessions)
+	db.Model(&models.CashMovement{}).Where("synced = ?", false).Count(&unsyncedCashMovements)
+	db.Model(&models.ProductBarcode{}).Where("synced = ?", false).Count(&unsyncedProductBarcodes)
+	db.Model(&models.Category{}).Where("synced = ?", false).Count(&unsyncedCategories)
+	db.Model(&models.Brand{}).Where("synced = ?", false).Count(&unsyncedBrands)
+	db.Model(&models.ProductUnit{}).Where("synced = ?", false).Count(&unsyncedProductUnits)
+	db.Model(&models.CostLayer{}).Where("synced = ?", false).Count(&unsyncedCostLayers)
+	db.Model(&models.ProductPriceHistory{}).Where("synced = ?", false).Count(&unsyncedProductPriceHistories)
+	db.Model(&models.ScheduledPriceChange{}).Where("synced = ?", false).Count(&unsyncedScheduledPriceChanges)
+	db.Model(&models.Supplier{}).Where("synced = ?", false).Count(&unsyncedSuppliers)
+	db.Model(&models.PurchaseOrder{}).Where("synced = ?", false).Count(&unsyncedPurchaseOrders)
+	db.Model(&models.PurchaseOrderItem{}).Where("synced = ?", false).Count(&unsyncedPurchaseOrderItems)
+	db.Model(&models.GoodsReceipt{}).Where("synced = ?", false).Count(&unsyncedGoodsReceipts)
+	db.Model(&models.GoodsReceiptItem{}).Where("synced = ?", false).Count(&unsyncedGoodsReceiptItems)
+	db.Model(&models.StockTransfer{}).Where("synced = ?", false).Count(&unsyncedStockTransfers)
+	db.Model(&models.StockTransferItem{}).Where("synced = ?", false).Count(&unsyncedStockTransferItems)
+	db.Model(&models.StockMovement{}).Where("synced = ?", false).Count(&unsyncedStockMovements)
+	db.Model(&models.ReorderLevel{}).Where("synced = ?", false).Count(&unsyncedReorderLevels)
+	db.Model(&models.StockBatch{}).Where("synced = ?", false).Count(&unsyncedStockBatches)
+	db.Model(&models.StockBatchAllocation{}).Where("synced = ?", false).Count(&unsyncedStockBatchAllocations)
+	db.Model(&models.StockAdjustment{}).Where("synced = ?", false).Count(&unsyncedStockAdjustments)
+	db.Model(&models.StockAdjustmentItem{}).Where("synced = ?", false).Count(&unsyncedStockAdjustmentItems)
+	db.Model(&models.StockOpnameCount{}).Where("synced = ?", false).Count(&unsyncedStockOpnameCounts)
+	db.Model(&models.BranchStock{}).Where("synced = ?", false).Count(&unsyncedBranchStocks)
+	db.Model(&models.BranchPrice{}).Where("synced = ?", false).Count(&unsyncedBranchPrices)
 	db.Model(&models.StockOpname{}).Where("synced = ?", false).Count(&unsyncedOpname)
 	db.Model(&models.StockOpnameItem{}).Where("synced = ?", false).Count(&unsyncedOpItems)
 
-	total := int(unsyncedProducts + unsyncedBranches + unsyncedSales + unsyncedItems + unsyncedOpname + unsyncedOpItems)
+	total := int(unsyncedProducts + unsyncedBranches + unsyncedSales + unsyncedItems + unsyncedPayments + unsyncedPromos + unsyncedTaxRates + unsyncedCustomers + unsyncedCashSessions + unsyncedCashMovements + unsyncedProductBarcodes + unsyncedCategories + unsyncedBrands + unsyncedProductUnits + unsyncedCostLayers + unsyncedProductPriceHistories + unsyncedScheduledPriceChanges + unsyncedSuppliers + unsyncedPurchaseOrders + unsyncedPurchaseOrderItems + unsyncedGoodsReceipts + unsyncedGoodsReceiptItems + unsyncedStockTransfers + unsyncedStockTransferItems + unsyncedStockMovements + unsyncedReorderLevels + unsyncedStockBatches + unsyncedStockBatchAllocations + unsyncedStockAdjustments + unsyncedStockAdjustmentItems + unsyncedStockOpnameCounts + unsyncedBranchStocks + unsyncedBranchPrices + unsyncedOpname + unsyncedOpItems)
+
+	lowStock, err := inventory.CountLowStock(db, branchID)
+	if err != nil {
+		return Summary{}, err
+	}
 
 	return Summary{
 		QueuedChanges: total,
@@ -45,6 +110,7 @@ func Build(db *gorm.DB, dbPath, status, lastErr string) (Summary, error) {
 		DbPath:        dbPath,
 		Status:        status,
 		LastError:     lastErr,
+		LowStock:      lowStock,
 	}, nil
 }
 
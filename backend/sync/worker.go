@@ -16,6 +16,7 @@ import (
 	"gorm.io/gorm/clause"
 
 	"shosha_mart_backend/config"
+	"shosha_mart_backend/costing"
 	"shosha_mart_backend/models"
 )
 
@@ -113,28 +114,116 @@ func (w *Worker) RunOnce(ctx context.Context) error {
 
 func (w *Worker) upload(ctx context.Context) error {
 	var (
-		products []models.Product
-		branches []models.Branch
-		sales    []models.Sale
-		items    []models.SaleItem
-		opnames  []models.StockOpname
-		opItems  []models.StockOpnameItem
+		products       []models.Product
+		branches       []models.Branch
+		sales          []models.Sale
+		items          []models.SaleItem
+		payments       []models.SalePayment
+		promos         []models.Promotion
+		taxRates       []models.TaxRate
+		customers      []models.Customer
+		cashSessions   []models.CashSession
+		cashMovements  []models.CashMovement
+		barcodes       []models.ProductBarcode
+		categories     []models.Category
+		brands         []models.Brand
+		productUnits   []models.ProductUnit
+		costLayers     []models.CostLayer
+		priceHistory   []models.ProductPriceHistory
+		priceSchedules []models.ScheduledPriceChange
+		suppliers      []models.Supplier
+		purchaseOrders []models.PurchaseOrder
+		poItems        []models.PurchaseOrderItem
+		goodsReceipts  []models.GoodsReceipt
+		grItems        []models.GoodsReceiptItem
+		transfers      []models.StockTransfer
+		transferItems  []models.StockTransferItem
+		movements      []models.StockMovement
+		reorderLevels  []models.ReorderLevel
+		batches        []models.StockBatch
+		allocations    []models.StockBatchAllocation
+		adjustments    []models.StockAdjustment
+		adjItems       []models.StockAdjustmentItem
+		opCounts       []models.StockOpnameCount
+		brStock        []models.BranchStock
+		brPrices       []models.BranchPrice
+		opnames        []models.StockOpname
+		opItems        []models.StockOpnameItem
 	)
 	w.db.Where("synced = ?", false).Find(&products)
 	w.db.Where("synced = ?", false).Find(&branches)
 	w.db.Where("synced = ?", false).Find(&sales)
 	w.db.Where("synced = ?", false).Find(&items)
+	w.db.Where("synced = ?", false).Find(&payments)
+	w.db.Where("synced = ?", false).Find(&promos)
+	w.db.Where("synced = ?", false).Find(&taxRates)
+	w.db.Where("synced = ?", false).Find(&customers)
+	w.db.Where("synced = ?", false).Find(&cashSessions)
+	w.db.Where("synced = ?", false).Find(&cashMovements)
+	w.db.Where("synced = ?", false).Find(&barcodes)
+	w.db.Where("synced = ?", false).Find(&categories)
+	w.db.Where("synced = ?", false).Find(&brands)
+	w.db.Where("synced = ?", false).Find(&productUnits)
+	w.db.Where("synced = ?", false).Find(&costLayers)
+	w.db.Where("synced = ?", false).Find(&priceHistory)
+	w.db.Where("synced = ?", false).Find(&priceSchedules)
+	w.db.Where("synced = ?", false).Find(&suppliers)
+	w.db.Where("synced = ?", false).Find(&purchaseOrders)
+	w.db.Where("synced = ?", false).Find(&poItems)
+	w.db.Where("synced = ?", false).Find(&goodsReceipts)
+	w.db.Where("synced = ?", false).Find(&grItems)
+	w.db.Where("synced = ?", false).Find(&transfers)
+	w.db.Where("synced = ?", false).Find(&transferItems)
+	w.db.Where("synced = ?", false).Find(&movements)
+	w.db.Where("synced = ?", false).Find(&reorderLevels)
+	w.db.Where("synced = ?", false).Find(&batches)
+	w.db.Where("synced = ?", false).Find(&allocations)
+	w.db.Where("synced = ?", false).Find(&adjustments)
+	w.db.Where("synced = ?", false).Find(&adjItems)
+	w.db.Where("synced = ?", false).Find(&opCounts)
+	// stok cabang lain hanya salinan dari upstream; yang diunggah hanya milik sendiri
+	w.db.Where("synced = ? AND branch_id = ?", false, w.cfg.BranchID).Find(&brStock)
+	w.db.Where("synced = ?", false).Find(&brPrices)
 	w.db.Where("synced = ?", false).Find(&opnames)
 	w.db.Where("synced = ?", false).Find(&opItems)
 
 	payload := map[string]any{
-		"branch_id":          w.cfg.BranchID,
-		"products":           products,
-		"branches":           branches,
-		"sales":              sales,
-		"sale_items":         items,
-		"stock_opnames":      opnames,
-		"stock_opname_items": opItems,
+		"branch_id":               w.cfg.BranchID,
+		"products":                products,
+		"branches":                branches,
+		"sales":                   sales,
+		"sale_items":              items,
+		"sale_payments":           payments,
+		"promotions":              promos,
+		"tax_rates":               taxRates,
+		"customers":               customers,
+		"cash_sessions":           cashSessions,
+		"cash_movements":          cashMovements,
+		"product_barcodes":        barcodes,
+		"categories":              categories,
+		"brands":                  brands,
+		"product_units":           productUnits,
+		"cost_layers":             costLayers,
+		"product_price_histories": priceHistory,
+		"scheduled_price_changes": priceSchedules,
+		"suppliers":               suppliers,
+		"purchase_orders":         purchaseOrders,
+		"purchase_order_items":    poItems,
+		"goods_receipts":          goodsReceipts,
+		"goods_receipt_items":     grItems,
+		"stock_transfers":         transfers,
+		"stock_transfer_items":    transferItems,
+		"stock_movements":         movements,
+		"reorder_levels":          reorderLevels,
+		"stock_batches":           batches,
+		"stock_batch_allocations": allocations,
+		"stock_adjustments":       adjustments,
+		"stock_adjustment_items":  adjItems,
+		"stock_opname_counts":     opCounts,
+		"branch_stocks":           brStock,
+		"branch_prices":           brPrices,
+		"stock_opnames":           opnames,
+		"stock_opname_items":      opItems,
 	}
 	body, _ := json.Marshal(payload)
 
@@ -184,6 +273,238 @@ func (w *Worker) upload(ctx context.Context) error {
 		res := w.db.Model(&models.SaleItem{}).Where("id IN ?", ids).Update("synced", true)
 		log.Printf("[SYNC] marked sale_items synced: rows=%d, error=%v", res.RowsAffected, res.Error)
 	}
+	if len(payments) > 0 {
+		ids := make([]string, len(payments))
+		for i, p := range payments {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.SalePayment{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked sale_payments synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(promos) > 0 {
+		ids := make([]string, len(promos))
+		for i, p := range promos {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.Promotion{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked promotions synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(taxRates) > 0 {
+		ids := make([]string, len(taxRates))
+		for i, p := range taxRates {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.TaxRate{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked tax_rates synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(customers) > 0 {
+		ids := make([]string, len(customers))
+		for i, p := range customers {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.Customer{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked customers synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(cashSessions) > 0 {
+		ids := make([]string, len(cashSessions))
+		for i, p := range cashSessions {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.CashSession{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked cash_sessions synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(cashMovements) > 0 {
+		ids := make([]string, len(cashMovements))
+		for i, p := range cashMovements {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.CashMovement{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked cash_movements synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(barcodes) > 0 {
+		ids := make([]string, len(barcodes))
+		for i, p := range barcodes {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.ProductBarcode{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked product_barcodes synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(categories) > 0 {
+		ids := make([]string, len(categories))
+		for i, p := range categories {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.Category{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked categories synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(brands) > 0 {
+		ids := make([]string, len(brands))
+		for i, p := range brands {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.Brand{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked brands synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(productUnits) > 0 {
+		ids := make([]string, len(productUnits))
+		for i, p := range productUnits {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.ProductUnit{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked product_units synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(costLayers) > 0 {
+		ids := make([]string, len(costLayers))
+		for i, p := range costLayers {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.CostLayer{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked cost_layers synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(priceHistory) > 0 {
+		ids := make([]string, len(priceHistory))
+		for i, p := range priceHistory {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.ProductPriceHistory{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked product_price_histories synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(priceSchedules) > 0 {
+		ids := make([]string, len(priceSchedules))
+		for i, p := range priceSchedules {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.ScheduledPriceChange{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked scheduled_price_changes synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(suppliers) > 0 {
+		ids := make([]string, len(suppliers))
+		for i, p := range suppliers {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.Supplier{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked suppliers synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(purchaseOrders) > 0 {
+		ids := make([]string, len(purchaseOrders))
+		for i, p := range purchaseOrders {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.PurchaseOrder{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked purchase_orders synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(poItems) > 0 {
+		ids := make([]string, len(poItems))
+		for i, p := range poItems {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.PurchaseOrderItem{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked purchase_order_items synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(goodsReceipts) > 0 {
+		ids := make([]string, len(goodsReceipts))
+		for i, p := range goodsReceipts {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.GoodsReceipt{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked goods_receipts synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(grItems) > 0 {
+		ids := make([]string, len(grItems))
+		for i, p := range grItems {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.GoodsReceiptItem{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked goods_receipt_items synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(transfers) > 0 {
+		ids := make([]string, len(transfers))
+		for i, p := range transfers {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.StockTransfer{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked stock_transfers synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(transferItems) > 0 {
+		ids := make([]string, len(transferItems))
+		for i, p := range transferItems {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.StockTransferItem{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked stock_transfer_items synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(movements) > 0 {
+		ids := make([]string, len(movements))
+		for i, p := range movements {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.StockMovement{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked stock_movements synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(reorderLevels) > 0 {
+		ids := make([]string, len(reorderLevels))
+		for i, p := range reorderLevels {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.ReorderLevel{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked reorder_levels synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(batches) > 0 {
+		ids := make([]string, len(batches))
+		for i, p := range batches {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.StockBatch{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked stock_batches synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(allocations) > 0 {
+		ids := make([]string, len(allocations))
+		for i, p := range allocations {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.StockBatchAllocation{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked stock_batch_allocations synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(adjustments) > 0 {
+		ids := make([]string, len(adjustments))
+		for i, p := range adjustments {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.StockAdjustment{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked stock_adjustments synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(adjItems) > 0 {
+		ids := make([]string, len(adjItems))
+		for i, p := range adjItems {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.StockAdjustmentItem{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked stock_adjustment_items synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(opCounts) > 0 {
+		ids := make([]string, len(opCounts))
+		for i, p := range opCounts {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.StockOpnameCount{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked stock_opname_counts synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(brStock) > 0 {
+		ids := make([]string, len(brStock))
+		for i, p := range brStock {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.BranchStock{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked branch_stocks synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
+	if len(brPrices) > 0 {
+		ids := make([]string, len(brPrices))
+		for i, p := range brPrices {
+			ids[i] = p.ID
+		}
+		res := w.db.Model(&models.BranchPrice{}).Where("id IN ?", ids).Update("synced", true)
+		log.Printf("[SYNC] marked branch_prices synced: rows=%d, error=%v", res.RowsAffected, res.Error)
+	}
 	if len(opnames) > 0 {
 		ids := make([]string, len(opnames))
 		for i, p := range opnames {
@@ -203,9 +524,38 @@ func (w *Worker) upload(ctx context.Context) error {
 
 	// Prune locally: hard delete rows that are tombstoned and synced
 	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.SaleItem{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.SalePayment{})
 	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.Sale{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.ProductBarcode{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.ProductUnit{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.CostLayer{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.ProductPriceHistory{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.ScheduledPriceChange{})
 	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.Product{})
 	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.Branch{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.Promotion{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.TaxRate{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.Customer{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.CashMovement{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.CashSession{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.Category{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.Brand{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.Supplier{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.PurchaseOrder{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.PurchaseOrderItem{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.GoodsReceipt{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.GoodsReceiptItem{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockTransfer{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockTransferItem{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockMovement{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.ReorderLevel{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockBatch{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockBatchAllocation{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockAdjustment{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockAdjustmentItem{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockOpnameCount{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.BranchStock{})
+	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.BranchPrice{})
 	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockOpnameItem{})
 	w.db.Where("is_deleted = ? AND synced = ?", true, true).Delete(&models.StockOpname{})
 	return nil
@@ -261,24 +611,82 @@ func (w *Worker) download(ctx context.Context) error {
 		return fmt.Errorf("download failed: %s", resp.Status)
 	}
 	var data struct {
-		Products         []models.Product         `json:"products"`
-		Branches         []models.Branch          `json:"branches"`
-		Sales            []models.Sale            `json:"sales"`
-		SaleItems        []models.SaleItem        `json:"sale_items"`
-		StockOpnames     []models.StockOpname     `json:"stock_opnames"`
-		StockOpnameItems []models.StockOpnameItem `json:"stock_opname_items"`
-		LastSyncAt       *time.Time               `json:"last_sync_at"`
+		Products              []models.Product              `json:"products"`
+		Branches              []models.Branch               `json:"branches"`
+		Sales                 []models.Sale                 `json:"sales"`
+		SaleItems             []models.SaleItem             `json:"sale_items"`
+		SalePayments          []models.SalePayment          `json:"sale_payments"`
+		Promotions            []models.Promotion            `json:"promotions"`
+		TaxRates              []models.TaxRate              `json:"tax_rates"`
+		Customers             []models.Customer             `json:"customers"`
+		CashSessions          []models.CashSession          `json:"cash_sessions"`
+		CashMovements         []models.CashMovement         `json:"cash_movements"`
+		ProductBarcodes       []models.ProductBarcode       `json:"product_barcodes"`
+		Categories            []models.Category             `json:"categories"`
+		Brands                []models.Brand                `json:"brands"`
+		ProductUnits          []models.ProductUnit          `json:"product_units"`
+		CostLayers            []models.CostLayer            `json:"cost_layers"`
+		ProductPriceHistories []models.ProductPriceHistory  `json:"product_price_histories"`
+		ScheduledPriceChanges []models.ScheduledPriceChange `json:"scheduled_price_changes"`
+		Suppliers             []models.Supplier             `json:"suppliers"`
+		PurchaseOrders        []models.PurchaseOrder        `json:"purchase_orders"`
+		PurchaseOrderItems    []models.PurchaseOrderItem    `json:"purchase_order_items"`
+		GoodsReceipts         []models.GoodsReceipt         `json:"goods_receipts"`
+		GoodsReceiptItems     []models.GoodsReceiptItem     `json:"goods_receipt_items"`
+		StockTransfers        []models.StockTransfer        `json:"stock_transfers"`
+		StockTransferItems    []models.StockTransferItem    `json:"stock_transfer_items"`
+		StockMovements        []models.StockMovement        `json:"stock_movements"`
+		ReorderLevels         []models.ReorderLevel         `json:"reorder_levels"`
+		StockBatches          []models.StockBatch           `json:"stock_batches"`
+		StockBatchAllocations []models.StockBatchAllocation `json:"stock_batch_allocations"`
+		StockAdjustments      []models.StockAdjustment      `json:"stock_adjustments"`
+		StockAdjustmentItems  []models.StockAdjustmentItem  `json:"stock_adjustment_items"`
+		StockOpnameCounts     []models.StockOpnameCount     `json:"stock_opname_counts"`
+		BranchStocks          []models.BranchStock          `json:"branch_stocks"`
+		BranchPrices          []models.BranchPrice          `json:"branch_prices"`
+		StockOpnames          []models.StockOpname          `json:"stock_opnames"`
+		StockOpnameItems      []models.StockOpnameItem      `json:"stock_opname_items"`
+		LastSyncAt            *time.Time                    `json:"last_sync_at"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
 		return fmt.Errorf("decode changes: %w", err)
 	}
 	// Upsert: gunakan opsi berbeda per model agar tidak merujuk kolom yang tidak ada
-	saveOptsBranches := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"code", "name", "address", "phone", "synced", "is_deleted", "updated_at", "created_at"})}
-	saveOptsProducts := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"name", "unit", "stock", "price", "price_investor", "price_shosha", "branch_id", "synced", "is_deleted", "updated_at", "created_at"})}
-	saveOptsSales := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"receipt_no", "branch_id", "branch_name", "payment_method", "notes", "total", "synced", "is_deleted", "updated_at", "created_at"})}
-	saveOptsSaleItems := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"sale_id", "product_id", "qty", "price", "synced", "is_deleted", "updated_at", "created_at"})}
-	saveOptsOpnames := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"branch_id", "performed_by", "note", "synced", "is_deleted", "updated_at", "created_at"})}
-	saveOptsOpItems := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"stock_opname_id", "product_id", "system_qty", "physical_qty", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsBranches := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"code", "name", "address", "phone", "price_tier", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsProducts := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"sku", "name", "unit", "category_id", "brand_id", "attributes", "price", "price_investor", "price_shosha", "cost", "tax_rate_id", "branch_id", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsSales := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"receipt_no", "branch_id", "branch_name", "customer_id", "customer_name", "cash_session_id", "payment_method", "price_tier", "notes", "subtotal", "discount_type", "discount_value", "discount_amount", "promotion_id", "tax_amount", "total", "change_due", "print_count", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsSaleItems := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"sale_id", "product_id", "qty", "unit", "unit_factor", "base_qty", "price", "price_overridden", "discount_type", "discount_value", "discount_amount", "promotion_id", "subtotal", "unit_cost", "tax_rate_id", "tax_code", "tax_rate", "tax_inclusive", "tax_base", "tax_amount", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsSalePayments := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"sale_id", "method", "amount", "tendered", "reference", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsPromotions := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"name", "type", "product_id", "branch_id", "buy_qty", "free_qty", "bundle_qty", "bundle_price", "discount_type", "discount_value", "min_spend", "starts_at", "ends_at", "daily_start", "daily_end", "active", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsTaxRates := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"code", "name", "rate", "inclusive", "is_default", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsCustomers := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"name", "phone", "address", "npwp", "price_tier", "credit_limit", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsCashSessions := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"branch_id", "till_id", "cashier", "status", "opening_float", "opened_at", "closed_at", "expected_cash", "counted_cash", "difference", "close_note", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsCashMovements := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"cash_session_id", "type", "amount", "reason", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsProductBarcodes := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"product_id", "code", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsCategories := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"parent_id", "name", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsBrands := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"name", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsProductUnits := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"product_id", "name", "factor", "price", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsCostLayers := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"product_id", "branch_id", "source", "qty", "remaining", "unit_cost", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsProductPriceHistories := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"product_id", "branch_id", "tier", "old_price", "new_price", "changed_by", "source", "schedule_id", "changed_at", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsScheduledPriceChanges := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"product_id", "branch_id", "tier", "new_price", "effective_at", "created_by", "applied_at", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsSuppliers := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"code", "name", "contact_name", "phone", "email", "address", "npwp", "payment_terms", "note", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsPurchaseOrders := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"number", "supplier_id", "branch_id", "status", "order_date", "expected_at", "note", "created_by", "total", "sent_at", "closed_at", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsPurchaseOrderItems := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"purchase_order_id", "product_id", "unit", "unit_factor", "qty", "unit_cost", "subtotal", "received_qty", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsGoodsReceipts := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"number", "purchase_order_id", "supplier_id", "branch_id", "invoice_no", "received_at", "received_by", "note", "total", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsGoodsReceiptItems := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"goods_receipt_id", "purchase_order_item_id", "product_id", "unit", "unit_factor", "ordered_qty", "qty", "base_qty", "unit_cost", "subtotal", "batch_no", "expiry_date", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsStockTransfers := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"number", "from_branch_id", "to_branch_id", "status", "note", "requested_by", "requested_at", "dispatched_by", "dispatched_at", "received_by", "received_at", "has_discrepancy", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsStockTransferItems := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"stock_transfer_id", "product_id", "unit", "unit_factor", "requested_qty", "dispatched_qty", "received_qty", "discrepancy", "discrepancy_note", "unit_cost", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsStockMovements := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"product_id", "branch_id", "source", "doc_id", "doc_no", "delta", "balance", "unit_cost", "created_by", "note", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsReorderLevels := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"product_id", "branch_id", "min_stock", "reorder_qty", "supplier_id", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsStockBatches := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"product_id", "branch_id", "batch_no", "expiry_date", "qty", "remaining", "unit_cost", "source", "doc_id", "doc_no", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsStockBatchAllocations := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"batch_id", "product_id", "doc_id", "qty", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsStockAdjustments := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"number", "branch_id", "reason", "status", "note", "created_by", "approved_by", "approved_at", "posted_at", "loss_value", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsStockAdjustmentItems := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"stock_adjustment_id", "product_id", "unit", "unit_factor", "qty", "base_qty", "unit_cost", "value", "note", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsStockOpnameCounts := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"stock_opname_id", "product_id", "unit", "unit_factor", "qty", "base_qty", "barcode", "counted_by", "recount", "superseded", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsBranchStocks := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"product_id", "branch_id", "qty", "cost", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsBranchPrices := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"product_id", "branch_id", "tier", "price", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsOpnames := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"number", "branch_id", "status", "category_id", "performed_by", "note", "finalized_by", "finalized_at", "synced", "is_deleted", "updated_at", "created_at"})}
+	saveOptsOpItems := clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoUpdates: clause.AssignmentColumns([]string{"stock_opname_id", "product_id", "system_qty", "physical_qty", "counted", "counted_at", "expected_qty", "variance", "synced", "is_deleted", "updated_at", "created_at"})}
 	// Set synced=true untuk semua data hasil download
 	for i := range data.Branches {
 		data.Branches[i].Synced = true
@@ -288,12 +696,100 @@ func (w *Worker) download(ctx context.Context) error {
 	}
 	for i := range data.Sales {
 		data.Sales[i].Synced = true
-		// Clear Items relation to avoid conflict during upsert
+		// Clear Items/Payments relations to avoid conflict during upsert
 		data.Sales[i].Items = nil
+		data.Sales[i].Payments = nil
 	}
 	for i := range data.SaleItems {
 		data.SaleItems[i].Synced = true
 	}
+	for i := range data.SalePayments {
+		data.SalePayments[i].Synced = true
+	}
+	for i := range data.Promotions {
+		data.Promotions[i].Synced = true
+	}
+	for i := range data.TaxRates {
+		data.TaxRates[i].Synced = true
+	}
+	for i := range data.Customers {
+		data.Customers[i].Synced = true
+	}
+	for i := range data.CashSessions {
+		data.CashSessions[i].Synced = true
+	}
+	for i := range data.CashMovements {
+		data.CashMovements[i].Synced = true
+	}
+	for i := range data.ProductBarcodes {
+		data.ProductBarcodes[i].Synced = true
+	}
+	for i := range data.Categories {
+		data.Categories[i].Synced = true
+	}
+	for i := range data.Brands {
+		data.Brands[i].Synced = true
+	}
+	for i := range data.ProductUnits {
+		data.ProductUnits[i].Synced = true
+	}
+	for i := range data.CostLayers {
+		data.CostLayers[i].Synced = true
+	}
+	for i := range data.ProductPriceHistories {
+		data.ProductPriceHistories[i].Synced = true
+	}
+	for i := range data.ScheduledPriceChanges {
+		data.ScheduledPriceChanges[i].Synced = true
+	}
+	for i := range data.Suppliers {
+		data.Suppliers[i].Synced = true
+	}
+	for i := range data.PurchaseOrders {
+		data.PurchaseOrders[i].Synced = true
+	}
+	for i := range data.PurchaseOrderItems {
+		data.PurchaseOrderItems[i].Synced = true
+	}
+	for i := range data.GoodsReceipts {
+		data.GoodsReceipts[i].Synced = true
+	}
+	for i := range data.GoodsReceiptItems {
+		data.GoodsReceiptItems[i].Synced = true
+	}
+	for i := range data.StockTransfers {
+		data.StockTransfers[i].Synced = true
+	}
+	for i := range data.StockTransferItems {
+		data.StockTransferItems[i].Synced = true
+	}
+	for i := range data.StockMovements {
+		data.StockMovements[i].Synced = true
+	}
+	for i := range data.ReorderLevels {
+		data.ReorderLevels[i].Synced = true
+	}
+	for i := range data.StockBatches {
+		data.StockBatches[i].Synced = true
+	}
+	for i := range data.StockBatchAllocations {
+		data.StockBatchAllocations[i].Synced = true
+	}
+	for i := range data.StockAdjustments {
+		data.StockAdjustments[i].Synced = true
+	}
+	for i := range data.StockAdjustmentItems {
+		data.StockAdjustmentItems[i].Synced = true
+	}
+	for i := range data.StockOpnameCounts {
+		data.StockOpnameCounts[i].Synced = true
+	}
+	for i := range data.BranchStocks {
+		data.BranchStocks[i].Synced = true
+	}
+	for i := range data.BranchPrices {
+		data.BranchPrices[i].Synced = true
+	}
 	for i := range data.StockOpnames {
 		data.StockOpnames[i].Synced = true
 		// Clear Items relation to avoid conflict during upsert
@@ -307,14 +803,15 @@ func (w *Worker) download(ctx context.Context) error {
 		log.Printf("[SYNC] downloaded branches: %d, error: %v", len(data.Branches), res.Error)
 	}
 	if len(data.Products) > 0 {
-		res := w.db.Clauses(saveOptsProducts).Create(&data.Products)
+		// stok produk milik cabang ini, tidak ikut diunduh
+		res := w.db.Clauses(saveOptsProducts).Omit("stock").Create(&data.Products)
 		log.Printf("[SYNC] downloaded products: %d, error: %v", len(data.Products), res.Error)
 	}
 	if len(data.Sales) > 0 {
 		log.Printf("[SYNC] Attempting to save %d sales records", len(data.Sales))
 		successCount := 0
 		for idx, s := range data.Sales {
-			log.Printf("[SYNC] Sale[%d]: ID=%s, ReceiptNo=%s, BranchID=%s, Total=%.2f",
+			log.Printf("[SYNC] Sale[%d]: ID=%s, ReceiptNo=%s, BranchID=%s, Total=%s",
 				idx, s.ID, s.ReceiptNo, s.BranchID, s.Total)
 			res := w.db.Clauses(saveOptsSales).Create(&s)
 			if res.Error != nil {
@@ -337,6 +834,144 @@ func (w *Worker) download(ctx context.Context) error {
 	} else {
 		log.Printf("[SYNC] No sale_items data to download")
 	}
+	if len(data.SalePayments) > 0 {
+		res := w.db.Clauses(saveOptsSalePayments).Create(&data.SalePayments)
+		log.Printf("[SYNC] downloaded sale_payments: %d, error: %v", len(data.SalePayments), res.Error)
+	}
+	if len(data.Promotions) > 0 {
+		res := w.db.Clauses(saveOptsPromotions).Create(&data.Promotions)
+		log.Printf("[SYNC] downloaded promotions: %d, error: %v", len(data.Promotions), res.Error)
+	}
+	if len(data.TaxRates) > 0 {
+		res := w.db.Clauses(saveOptsTaxRates).Create(&data.TaxRates)
+		log.Printf("[SYNC] downloaded tax_rates: %d, error: %v", len(data.TaxRates), res.Error)
+	}
+	if len(data.Customers) > 0 {
+		res := w.db.Clauses(saveOptsCustomers).Create(&data.Customers)
+		log.Printf("[SYNC] downloaded customers: %d, error: %v", len(data.Customers), res.Error)
+	}
+	if len(data.CashSessions) > 0 {
+		res := w.db.Clauses(saveOptsCashSessions).Create(&data.CashSessions)
+		log.Printf("[SYNC] downloaded cash_sessions: %d, error: %v", len(data.CashSessions), res.Error)
+	}
+	if len(data.CashMovements) > 0 {
+		res := w.db.Clauses(saveOptsCashMovements).Create(&data.CashMovements)
+		log.Printf("[SYNC] downloaded cash_movements: %d, error: %v", len(data.CashMovements), res.Error)
+	}
+	if len(data.ProductBarcodes) > 0 {
+		res := w.db.Clauses(saveOptsProductBarcodes).Create(&data.ProductBarcodes)
+		log.Printf("[SYNC] downloaded product_barcodes: %d, error: %v", len(data.ProductBarcodes), res.Error)
+	}
+	if len(data.Categories) > 0 {
+		res := w.db.Clauses(saveOptsCategories).Create(&data.Categories)
+		log.Printf("[SYNC] downloaded categories: %d, error: %v", len(data.Categories), res.Error)
+	}
+	if len(data.Brands) > 0 {
+		res := w.db.Clauses(saveOptsBrands).Create(&data.Brands)
+		log.Printf("[SYNC] downloaded brands: %d, error: %v", len(data.Brands), res.Error)
+	}
+	if len(data.ProductUnits) > 0 {
+		res := w.db.Clauses(saveOptsProductUnits).Create(&data.ProductUnits)
+		log.Printf("[SYNC] downloaded product_units: %d, error: %v", len(data.ProductUnits), res.Error)
+	}
+	if len(data.CostLayers) > 0 {
+		res := w.db.Clauses(saveOptsCostLayers).Create(&data.CostLayers)
+		log.Printf("[SYNC] downloaded cost_layers: %d, error: %v", len(data.CostLayers), res.Error)
+	}
+	if len(data.ProductPriceHistories) > 0 {
+		res := w.db.Clauses(saveOptsProductPriceHistories).Create(&data.ProductPriceHistories)
+		log.Printf("[SYNC] downloaded product_price_histories: %d, error: %v", len(data.ProductPriceHistories), res.Error)
+	}
+	if len(data.ScheduledPriceChanges) > 0 {
+		res := w.db.Clauses(saveOptsScheduledPriceChanges).Create(&data.ScheduledPriceChanges)
+		log.Printf("[SYNC] downloaded scheduled_price_changes: %d, error: %v", len(data.ScheduledPriceChanges), res.Error)
+	}
+	if len(data.Suppliers) > 0 {
+		res := w.db.Clauses(saveOptsSuppliers).Create(&data.Suppliers)
+		log.Printf("[SYNC] downloaded suppliers: %d, error: %v", len(data.Suppliers), res.Error)
+	}
+	if len(data.PurchaseOrders) > 0 {
+		res := w.db.Clauses(saveOptsPurchaseOrders).Create(&data.PurchaseOrders)
+		log.Printf("[SYNC] downloaded purchase_orders: %d, error: %v", len(data.PurchaseOrders), res.Error)
+	}
+	if len(data.PurchaseOrderItems) > 0 {
+		res := w.db.Clauses(saveOptsPurchaseOrderItems).Create(&data.PurchaseOrderItems)
+		log.Printf("[SYNC] downloaded purchase_order_items: %d, error: %v", len(data.PurchaseOrderItems), res.Error)
+	}
+	if len(data.GoodsReceipts) > 0 {
+		res := w.db.Clauses(saveOptsGoodsReceipts).Create(&data.GoodsReceipts)
+		log.Printf("[SYNC] downloaded goods_receipts: %d, error: %v", len(data.GoodsReceipts), res.Error)
+	}
+	if len(data.GoodsReceiptItems) > 0 {
+		res := w.db.Clauses(saveOptsGoodsReceiptItems).Create(&data.GoodsReceiptItems)
+		log.Printf("[SYNC] downloaded goods_receipt_items: %d, error: %v", len(data.GoodsReceiptItems), res.Error)
+	}
+	if len(data.StockTransfers) > 0 {
+		res := w.db.Clauses(saveOptsStockTransfers).Create(&data.StockTransfers)
+		log.Printf("[SYNC] downloaded stock_transfers: %d, error: %v", len(data.StockTransfers), res.Error)
+	}
+	if len(data.StockTransferItems) > 0 {
+		res := w.db.Clauses(saveOptsStockTransferItems).Create(&data.StockTransferItems)
+		log.Printf("[SYNC] downloaded stock_transfer_items: %d, error: %v", len(data.StockTransferItems), res.Error)
+	}
+	if len(data.StockMovements) > 0 {
+		res := w.db.Clauses(saveOptsStockMovements).Create(&data.StockMovements)
+		log.Printf("[SYNC] downloaded stock_movements: %d, error: %v", len(data.StockMovements), res.Error)
+	}
+	if len(data.ReorderLevels) > 0 {
+		res := w.db.Clauses(saveOptsReorderLevels).Create(&data.ReorderLevels)
+		log.Printf("[SYNC] downloaded reorder_levels: %d, error: %v", len(data.ReorderLevels), res.Error)
+	}
+	if len(data.StockBatches) > 0 {
+		res := w.db.Clauses(saveOptsStockBatches).Create(&data.StockBatches)
+		log.Printf("[SYNC] downloaded stock_batches: %d, error: %v", len(data.StockBatches), res.Error)
+	}
+	if len(data.StockBatchAllocations) > 0 {
+		res := w.db.Clauses(saveOptsStockBatchAllocations).Create(&data.StockBatchAllocations)
+		log.Printf("[SYNC] downloaded stock_batch_allocations: %d, error: %v", len(data.StockBatchAllocations), res.Error)
+	}
+	if len(data.StockAdjustments) > 0 {
+		res := w.db.Clauses(saveOptsStockAdjustments).Create(&data.StockAdjustments)
+		log.Printf("[SYNC] downloaded stock_adjustments: %d, error: %v", len(data.StockAdjustments), res.Error)
+	}
+	if len(data.StockAdjustmentItems) > 0 {
+		res := w.db.Clauses(saveOptsStockAdjustmentItems).Create(&data.StockAdjustmentItems)
+		log.Printf("[SYNC] downloaded stock_adjustment_items: %d, error: %v", len(data.StockAdjustmentItems), res.Error)
+	}
+	if len(data.StockOpnameCounts) > 0 {
+		res := w.db.Clauses(saveOptsStockOpnameCounts).Create(&data.StockOpnameCounts)
+		log.Printf("[SYNC] downloaded stock_opname_counts: %d, error: %v", len(data.StockOpnameCounts), res.Error)
+	}
+	if len(data.BranchStocks) > 0 {
+		// stok cabang sendiri hanya diubah di sini; baris dari upstream
+		// dipakai bila belum ada (mis. sidecar baru dipasang)
+		var own, others []models.BranchStock
+		for _, s := range data.BranchStocks {
+			if s.BranchID == w.cfg.BranchID {
+				own = append(own, s)
+			} else {
+				others = append(others, s)
+			}
+		}
+		if len(own) > 0 {
+			res := w.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&own)
+			log.Printf("[SYNC] downloaded own branch_stocks: %d, error: %v", len(own), res.Error)
+			w.reportStockDrift(own)
+		}
+		if len(others) > 0 {
+			res := w.db.Clauses(saveOptsBranchStocks).Create(&others)
+			log.Printf("[SYNC] downloaded branch_stocks: %d, error: %v", len(others), res.Error)
+		}
+	}
+	if len(data.BranchStocks) > 0 || len(data.Products) > 0 {
+		if err := costing.SyncProductStock(w.db, w.cfg.BranchID); err != nil {
+			log.Printf("[SYNC] refresh product stock: %v", err)
+		}
+	}
+	if len(data.BranchPrices) > 0 {
+		res := w.db.Clauses(saveOptsBranchPrices).Create(&data.BranchPrices)
+		log.Printf("[SYNC] downloaded branch_prices: %d, error: %v", len(data.BranchPrices), res.Error)
+	}
 	if len(data.StockOpnames) > 0 {
 		res := w.db.Clauses(saveOptsOpnames).Create(&data.StockOpnames)
 		log.Printf("[SYNC] downloaded stock_opnames: %d, error: %v", len(data.StockOpnames), res.Error)
@@ -352,6 +987,22 @@ func (w *Worker) download(ctx context.Context) error {
 	return nil
 }
 
+// reportStockDrift compares the branch's own stock rows downloaded from
+// upstream with the local ones, which are the truth. A synced local row
+// that upstream holds differently is logged and queued for upload again.
+func (w *Worker) reportStockDrift(upstream []models.BranchStock) {
+	for _, u := range upstream {
+		var local models.BranchStock
+		if err := w.db.Limit(1).Find(&local, "id = ?", u.ID).Error; err != nil || local.ID == "" || !local.Synced {
+			continue
+		}
+		if local.Qty != u.Qty || local.IsDeleted != u.IsDeleted {
+			log.Printf("[SYNC] branch_stocks drift: product=%s local=%s upstream=%s", u.ProductID, local.Qty, u.Qty)
+			w.db.Model(&models.BranchStock{}).Where("id = ?", u.ID).Update("synced", false)
+		}
+	}
+}
+
 func (w *Worker) setStatus(status, errMsg string, ts *time.Time) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -44,7 +44,7 @@ GOARCH=amd64 \
 CGO_ENABLED=1 \
 CC=x86_64-w64-mingw32-gcc \
 CXX=x86_64-w64-mingw32-g++ \
-go build -o server.exe main.go
+go build -tags sqlite_fts5 -o server.exe main.go
 
 if [ $? -eq 0 ]; then
     print_success "Windows binary compiled: server.exe"
@@ -38,7 +38,7 @@ if ! command -v go &> /dev/null; then
 fi
 
 go mod download
-CGO_ENABLED=1 go build -o server main.go
+CGO_ENABLED=1 go build -tags sqlite_fts5 -o server main.go
 print_success "Backend binary compiled: $BACKEND_DIR/server"
 
 # 2. Build renderer
@@ -1,16 +1,174 @@
+export interface ProductBarcode {
+  id: string
+  product_id: string
+  code: string
+}
+
+// Satuan tambahan produk; factor = jumlah satuan dasar per satuan ini
+export interface ProductUnit {
+  id?: string
+  product_id?: string
+  name: string
+  factor: number
+  price: number // 0 = harga dasar x factor
+}
+
 export interface Product {
   id: string
+  sku?: string
+  barcodes?: ProductBarcode[]
+  units?: ProductUnit[]
   name: string
-  unit: string
-  stock: number
+  unit: string // satuan dasar
+  stock: number // dalam satuan dasar, boleh pecahan
   price: number
   price_investor?: number
   price_shosha?: number
+  cost?: number // HPP per satuan dasar
+  tax_rate_id?: string
+  category_id?: string
+  brand_id?: string
+  attributes?: Record<string, string>
   synced: boolean
   created_at: string
   updated_at: string
 }
-export type ProductInput = Pick<Product, 'name' | 'unit' | 'stock' | 'price' | 'price_investor' | 'price_shosha'>
+export type ProductInput = Pick<Product, 'name' | 'unit' | 'stock' | 'price' | 'price_investor' | 'price_shosha' | 'sku'> & { barcodes?: string[] }
+
+export interface ImportRowResult {
+  line: number
+  action: 'create' | 'update' | 'unchanged' | 'error'
+  product_id?: string
+  sku: string
+  name: string
+  changes?: { field: string; old: string; new: string }[]
+  errors?: string[]
+}
+
+export interface ProductImportResult {
+  dry_run: boolean
+  mode: 'all_or_nothing' | 'skip_invalid'
+  match: 'sku' | 'name'
+  committed: boolean
+  total: number
+  created: number
+  updated: number
+  unchanged: number
+  failed: number
+  rows: ImportRowResult[]
+  report: string
+  stock_adjustment: StockAdjustment | null // hanya dengan adjust_stock
+}
+
+export interface ProductImportOptions {
+  mapping?: Record<string, string>
+  match?: 'sku' | 'name'
+  mode?: 'all_or_nothing' | 'skip_invalid'
+  ignore?: string[]
+  dry_run?: boolean
+  // selisih kolom stok produk lama dibukukan sebagai penyesuaian koreksi
+  adjust_stock?: boolean
+  manager_pin?: string
+}
+// barcodes dikirim sebagai daftar kode; di respons berupa objek ProductBarcode
+export type ProductPayload = Partial<Omit<Product, 'barcodes'>> & { barcodes?: string[] }
+
+export interface ProductPriceHistory {
+  id: string
+  product_id: string
+  branch_id: string
+  tier: string // kosong = harga umum
+  old_price: number
+  new_price: number
+  changed_by: string
+  source: 'manual' | 'schedule'
+  schedule_id: string
+  changed_at: string
+}
+
+export interface ScheduledPriceChange {
+  id: string
+  product_id: string
+  branch_id: string // kosong = semua cabang
+  tier: string
+  new_price: number
+  effective_at: string
+  created_by: string
+  applied_at: string | null
+}
+
+export interface BranchPrice {
+  id: string
+  product_id: string
+  branch_id: string
+  tier: string
+  price: number
+}
+
+export interface ProductPrices {
+  current: { price: number; price_investor: number; price_shosha: number }
+  branch_prices: BranchPrice[]
+  history: ProductPriceHistory[]
+  scheduled: ScheduledPriceChange[]
+}
+
+export type StockMovementSource =
+  | 'opening' | 'adjustment' | 'import' | 'sale' | 'sale_edit' | 'sale_void'
+  | 'receipt' | 'transfer_out' | 'transfer_in' | 'opname' | 'write_off'
+
+export interface StockMovement {
+  id: string
+  product_id: string
+  branch_id: string
+  source: StockMovementSource
+  doc_id: string
+  doc_no: string
+  delta: number // satuan dasar; negatif = keluar
+  balance: number // stok cabang setelah perubahan
+  unit_cost: number
+  created_by: string
+  note: string
+  created_at: string
+}
+
+export interface Category {
+  id: string
+  parent_id: string
+  name: string
+  path: string // "Induk > Anak"
+  synced?: boolean
+}
+
+export interface Brand {
+  id: string
+  name: string
+  synced?: boolean
+}
+
+export interface CategoryTotal {
+  category_id: string // kosong = tanpa kategori
+  parent_id: string
+  name: string
+  path: string
+  depth: number
+  qty: number
+  revenue: number // termasuk sub-kategori
+  own_qty: number
+  own_revenue: number
+  cost?: number // HPP, termasuk sub-kategori
+  own_cost?: number
+}
+
+// Laba kotor per produk, kategori, cabang atau hari
+export interface MarginRow {
+  key: string
+  name: string
+  qty: number
+  revenue: number
+  cost: number
+  margin: number
+  margin_pct: number
+}
 
 export interface Branch {
   id: string
@@ -18,18 +176,190 @@ export interface Branch {
   name: string
   address?: string
   phone?: string
+  price_tier?: '' | 'investor' | 'shosha'
   synced?: boolean
   created_at?: string
   updated_at?: string
 }
 
-export interface SaleItem {
+export interface Customer {
   id: string
-  sale_id: string
+  name: string
+  phone?: string
+  address?: string
+  npwp?: string
+  price_tier?: '' | 'investor' | 'shosha' // kosong = ikut tier cabang
+  credit_limit?: number // 0 = tanpa batas
+  outstanding?: number // only returned by getCustomer
+  synced?: boolean
+  created_at?: string
+  updated_at?: string
+}
+
+export interface Supplier {
+  id: string
+  code?: string
+  name: string
+  contact_name?: string
+  phone?: string
+  email?: string
+  address?: string
+  npwp?: string
+  payment_terms?: number // hari, 0 = tunai
+  note?: string
+  synced?: boolean
+  created_at?: string
+  updated_at?: string
+}
+
+export type PurchaseOrderStatus = 'draft' | 'sent' | 'partially_received' | 'closed'
+
+export interface PurchaseOrderItem {
+  id: string
+  purchase_order_id: string
   product_id: string
+  unit: string // kosong = satuan dasar
+  unit_factor: number
   qty: number
-  price: number
+  unit_cost: number
+  subtotal: number
+  received_qty: number
+}
+
+export interface PurchaseOrder {
+  id: string
+  number: string
+  supplier_id: string
+  branch_id: string
+  status: PurchaseOrderStatus
+  order_date: string
+  expected_at?: string | null
+  note?: string
+  created_by?: string
+  total: number
+  sent_at?: string | null
+  closed_at?: string | null
+  synced?: boolean
+  items: PurchaseOrderItem[]
+}
+
+export interface GoodsReceiptItem {
+  id: string
+  goods_receipt_id: string
+  purchase_order_item_id?: string // kosong = di luar PO
+  product_id: string
+  unit: string
+  unit_factor: number
+  ordered_qty: number
+  qty: number
+  base_qty: number
+  unit_cost: number
   subtotal: number
+  batch_no?: string
+  expiry_date?: string | null
+}
+
+export interface GoodsReceipt {
+  id: string
+  number: string
+  purchase_order_id?: string
+  supplier_id?: string
+  branch_id: string
+  invoice_no?: string
+  received_at: string
+  received_by?: string
+  note?: string
+  total: number
+  synced?: boolean
+  items: GoodsReceiptItem[]
+}
+
+export interface GoodsReceiptPayload {
+  purchase_order_id?: string
+  supplier_id?: string
+  invoice_no?: string
+  received_at?: string
+  received_by?: string
+  note?: string
+  // dengan PO: purchase_order_item_id; tanpa PO: product_id (+ unit)
+  items: { purchase_order_item_id?: string; product_id?: string; unit?: string; qty: number; unit_cost?: number; batch_no?: string; expiry_date?: string }[]
+}
+
+export type StockTransferStatus = 'requested' | 'dispatched' | 'received' | 'cancelled'
+
+export interface StockTransferItem {
+  id: string
+  stock_transfer_id: string
+  product_id: string
+  unit: string
+  unit_factor: number
+  requested_qty: number
+  dispatched_qty: number
+  received_qty: number
+  discrepancy: number // dikirim - diterima
+  discrepancy_note?: string
+  unit_cost: number
+}
+
+export interface StockTransfer {
+  id: string
+  number: string
+  from_branch_id: string
+  to_branch_id: string
+  status: StockTransferStatus
+  note?: string
+  requested_by?: string
+  requested_at: string
+  dispatched_by?: string
+  dispatched_at?: string | null
+  received_by?: string
+  received_at?: string | null
+  has_discrepancy: boolean
+  synced?: boolean
+  items: StockTransferItem[]
+}
+
+export interface PurchaseOrderPayload {
+  supplier_id: string
+  branch_id?: string
+  expected_at?: string | null
+  note?: string
+  created_by?: string
+  items: { product_id: string; unit?: string; qty: number; unit_cost?: number }[]
+}
+
+export interface SaleItem {
+  id: string
+  sale_id: string
+  product_id: string
+  qty: number // dalam satuan jual (unit)
+  unit?: string // kosong = satuan dasar
+  unit_factor?: number
+  base_qty?: number // qty dalam satuan dasar
+  price: number // original unit price
+  price_overridden?: boolean
+  discount_type?: 'percent' | 'amount' | ''
+  discount_value?: number
+  discount_amount?: number
+  promotion_id?: string
+  subtotal: number // after line discounts
+  unit_cost?: number // HPP per satuan dasar saat terjual
+  tax_code?: string
+  tax_rate?: number
+  tax_inclusive?: boolean
+  tax_base?: number
+  tax_amount?: number
+  created_at: string
+  updated_at: string
+}
+
+export interface SalePayment {
+  id: string
+  sale_id: string
+  method: string // "cash", "transfer", "qris" or "hutang"
+  amount: number
+  tendered: number
+  reference?: string
   created_at: string
   updated_at: string
 }
@@ -38,23 +368,201 @@ export interface Sale {
   id: string
   branch_id: string
   branch_name: string
+  customer_id?: string
+  customer_name?: string
+  cash_session_id?: string
   receipt_no: string
-  payment_method: string // "cash" or "hutang"
+  payment_method: string // tender method, or "split" for multi-tender sales
+  price_tier?: string
   notes: string
+  subtotal?: number
+  discount_amount?: number
+  promotion_id?: string
+  tax_amount?: number
   total: number
+  change_due?: number
+  print_count?: number
   synced: boolean
   created_at: string
   updated_at: string
   items: SaleItem[]
+  payments?: SalePayment[]
+  tax_summary?: TaxSummary[]
+}
+
+export interface CashMovement {
+  id: string
+  cash_session_id: string
+  type: 'pay_in' | 'pay_out'
+  amount: number
+  reason: string
+  created_at: string
+}
+
+export interface CashSession {
+  id: string
+  branch_id: string
+  till_id: string
+  cashier: string
+  status: 'open' | 'closed'
+  opening_float: number
+  opened_at: string
+  closed_at: string | null
+  expected_cash: number
+  counted_cash: number
+  difference: number // counted - expected
+  close_note: string
+  movements?: CashMovement[]
+}
+
+export interface CashSessionReport {
+  kind: 'X' | 'Z'
+  generated_at: string
+  session: CashSession
+  sales: number
+  subtotal: number
+  discount: number
+  tax: number
+  total: number
+  by_method: { method: string; count: number; amount: number }[]
+  cash_sales: number
+  change_given: number
+  pay_ins: number
+  pay_outs: number
+  expected_cash: number
+  counted_cash?: number
+  difference?: number
+}
+
+export interface ReceiptTemplate {
+  id?: string
+  branch_id: string // kosong = default toko
+  store_name: string
+  address: string
+  phone: string
+  header: string
+  footer: string
+  paper_width: 58 | 80
+  show_tax_summary: boolean
+}
+
+export interface Page<T> {
+  items: T[]
+  total: number
+  page: number
+  size: number
+  pages: number
+}
+
+export interface SaleFilters {
+  start?: string // YYYY-MM-DD
+  end?: string
+  branch_id?: string
+  customer_id?: string
+  cash_session_id?: string
+  payment_method?: string
+  receipt_no?: string
+  product_id?: string
+  min_total?: number
+  max_total?: number
+  synced?: boolean
+  sort?: 'created_at' | 'total' | 'receipt_no' | 'branch'
+  order?: 'asc' | 'desc'
+}
+
+export interface TaxSummary {
+  tax_rate_id: string
+  code: string
+  rate: number
+  inclusive: boolean
+  base: number // DPP
+  tax: number
+}
+
+export interface DraftSaleItem {
+  id: string
+  draft_sale_id: string
+  product_id: string
+  qty: number
+  price: number // 0 = tier price when posted
+  discount_type?: 'percent' | 'amount' | ''
+  discount_value?: number
+}
+
+export interface DraftSale {
+  id: string
+  label: string
+  status: 'open' | 'parked' | 'posted'
+  branch_id: string
+  till_id: string
+  customer_id?: string
+  notes: string
+  discount_type?: 'percent' | 'amount' | ''
+  discount_value?: number
+  items: DraftSaleItem[]
+  preview?: Sale // totals the draft would post at now
+  preview_error?: string
+  created_at: string
+  updated_at: string
+}
+
+export type OpnameStatus = 'counting' | 'finalized' | 'cancelled'
+
+export interface StockOpnameItem {
+  id: string
+  stock_opname_id: string
+  product_id: string
+  system_qty: number // snapshot saat sesi dimulai
+  physical_qty: number
+  counted: boolean
+  counted_at?: string | null
+  expected_qty: number // diisi saat finalisasi
+  variance: number
 }
 
 export interface StockOpname {
   id: string
+  number?: string
   branch_id: string
+  status?: OpnameStatus
+  category_id?: string
+  performed_by?: string
   note: string
+  finalized_by?: string
+  finalized_at?: string | null
   synced: boolean
   created_at: string
   updated_at: string
+  items?: StockOpnameItem[]
+}
+
+export interface StockOpnameSession extends StockOpname {
+  total: number
+  counted: number
+  uncounted: number
+}
+
+export interface StockOpnameCount {
+  id: string
+  stock_opname_id: string
+  product_id: string
+  unit: string
+  unit_factor: number
+  qty: number
+  base_qty: number
+  barcode: string
+  counted_by: string
+  recount: boolean
+  superseded: boolean
+  created_at: string
+}
+
+export interface UncountedProduct {
+  product_id: string
+  sku: string
+  name: string
+  unit: string
+  system_qty: number
 }
 
 export interface SalesAnalytics {
@@ -63,7 +571,13 @@ export interface SalesAnalytics {
   totalRevenue: number
   totalOrders: number
   totalItems: number
+  totalCost?: number
+  grossMargin?: number
   perDay: { day: string; orders: number; items: number; revenue: number }[]
+  perCategory?: CategoryTotal[]
+  marginPerProduct?: MarginRow[]
+  marginPerBranch?: MarginRow[]
+  marginPerDay?: MarginRow[]
 }
 
 export interface SyncSummary {
@@ -72,6 +586,138 @@ export interface SyncSummary {
   dbPath: string
   status: string
   lastError?: string
+  lowStock: number // produk di bawah stok minimum cabang ini
+}
+
+export interface StockBatch {
+  id: string
+  product_id: string
+  branch_id: string
+  batch_no: string
+  expiry_date: string | null
+  qty: number // satuan dasar
+  remaining: number
+  unit_cost: number // per satuan dasar
+  source: StockMovementSource
+  doc_id: string
+  doc_no: string
+  created_at: string
+}
+
+export interface ExpiryRow {
+  batch_id: string
+  product_id: string
+  sku: string
+  name: string
+  unit: string
+  batch_no: string
+  expiry_date: string
+  days_left: number // negatif = sudah lewat
+  remaining: number
+  unit_cost: number
+  value: number
+  status: 'expired' | 'near_expiry'
+}
+
+export interface ExpiryReport {
+  days: number
+  expired: ExpiryRow[]
+  expired_value: number
+  near_expiry: ExpiryRow[]
+  near_expiry_value: number
+}
+
+export type AdjustmentReason = 'damaged' | 'expired' | 'theft' | 'sample' | 'internal_use' | 'correction'
+
+export interface StockAdjustmentItem {
+  id: string
+  stock_adjustment_id: string
+  product_id: string
+  unit: string
+  unit_factor: number
+  qty: number // negatif = stok keluar
+  base_qty: number
+  unit_cost: number // perkiraan sampai diposting
+  value: number // negatif = kerugian
+  note: string
+}
+
+export interface StockAdjustment {
+  id: string
+  number: string
+  branch_id: string
+  reason: AdjustmentReason
+  status: 'pending' | 'posted' | 'rejected'
+  note: string
+  created_by: string
+  approved_by: string
+  approved_at: string | null
+  posted_at: string | null
+  loss_value: number
+  created_at: string
+  items: StockAdjustmentItem[]
+}
+
+export interface StockAdjustmentReport {
+  start: string
+  end: string
+  reasons: { reason: AdjustmentReason; adjustments: number; lines: number; loss_value: number }[]
+  lines: {
+    adjustment_id: string
+    number: string
+    branch_id: string
+    posted_at: string
+    reason: AdjustmentReason
+    product_id: string
+    sku: string
+    name: string
+    unit: string
+    qty: number
+    base_qty: number
+    unit_cost: number
+    value: number
+    note: string
+    created_by: string
+    approved_by: string
+  }[]
+  loss_value: number
+}
+
+export interface ReorderLevel {
+  id: string
+  product_id: string
+  branch_id: string
+  min_stock: number // satuan dasar
+  reorder_qty: number
+  supplier_id: string
+  synced?: boolean
+}
+
+export interface LowStockItem {
+  product_id: string
+  sku: string
+  name: string
+  unit: string
+  stock: number
+  min_stock: number
+  reorder_qty: number
+  supplier_id: string
+  shortage: number
+}
+
+export interface ReorderSuggestion {
+  product_id: string
+  sku: string
+  name: string
+  unit: string
+  supplier_id: string
+  stock: number
+  min_stock: number
+  reorder_qty: number
+  on_order: number
+  daily_sales: number
+  qty: number // satuan dasar
+  unit_cost: number
 }
 
 // Detect backend URL based on environment
@@ -108,10 +754,27 @@ console.log('[API] Using backend URL:', API_BASE);
 console.log('[API] User agent:', navigator.userAgent);
 console.log('[API] Protocol:', window.location.protocol);
 
+// toQuery drops empty values so optional filters can be passed straight through
+function toQuery(params: object): string {
+  const q = new URLSearchParams()
+  Object.entries(params).forEach(([k, v]) => {
+    if (v !== undefined && v !== null && v !== '') q.set(k, String(v))
+  })
+  return q.toString()
+}
+
+// posUser is sent as X-POS-User so stock movements record who made them
+let posUser = ''
+
+export function setPosUser(name: string) {
+  posUser = name.trim()
+}
+
 async function request<T>(path: string, options: RequestInit = {}): Promise<T> {
   const res = await fetch(`${API_BASE}${path}`, {
     headers: {
       'Content-Type': 'application/json',
+      ...(posUser ? { 'X-POS-User': posUser } : {}),
       ...(options.headers ?? {}),
     },
     ...options,
@@ -129,11 +792,29 @@ async function request<T>(path: string, options: RequestInit = {}): Promise<T> {
 
 export const api = {
   listProducts: () => request<Product[]>('/products'),
-  createProduct: (payload: Partial<Product>) =>
+  listProductsPage: (params: { page?: number; size?: number; q?: string; category?: string; brand?: string; sort?: 'updated_at' | 'name' | 'stock' | 'price'; order?: 'asc' | 'desc' } = {}) =>
+    request<Page<Product>>(`/products?${toQuery({ page: 1, ...params })}`),
+  lookupProduct: (code: string) => request<Product>(`/products/lookup?${toQuery({ code })}`),
+  createProduct: (payload: ProductPayload) =>
     request<Product>('/products', { method: 'POST', body: JSON.stringify(payload) }),
-  updateProduct: (id: string, payload: Partial<Product>) =>
+  updateProduct: (id: string, payload: ProductPayload) =>
     request<Product>(`/products/${id}`, { method: 'PUT', body: JSON.stringify(payload) }),
   deleteProduct: (id: string) => request<void>(`/products/${id}`, { method: 'DELETE' }),
+  productPrices: (id: string) => request<ProductPrices>(`/products/${id}/prices`),
+  productMovements: (id: string, filters: { branch_id?: string; source?: StockMovementSource; start?: string; end?: string } = {}) =>
+    request<StockMovement[]>(`/products/${id}/movements?${toQuery(filters)}`),
+  productMovementsPage: (id: string, params: { page?: number; size?: number; branch_id?: string; source?: StockMovementSource; start?: string; end?: string } = {}) =>
+    request<Page<StockMovement>>(`/products/${id}/movements?${toQuery({ page: 1, ...params })}`),
+  downloadStockCard: async (id: string, params: { branch_id?: string; start?: string; end?: string } = {}) => {
+    const res = await fetch(`${API_BASE}/products/${id}/stock-card?${toQuery(params)}`);
+    if (!res.ok) throw new Error(await res.text());
+    const blob = await res.blob();
+    return URL.createObjectURL(blob);
+  },
+  scheduleProductPrice: (id: string, payload: { tier: string; price: number; effective_at: string; branch_id?: string; created_by?: string }) =>
+    request<ScheduledPriceChange>(`/products/${id}/prices/schedule`, { method: 'POST', body: JSON.stringify(payload) }),
+  cancelScheduledPrice: (id: string, scheduleId: string) =>
+    request<{ message: string }>(`/products/${id}/prices/schedule/${scheduleId}`, { method: 'DELETE' }),
   async bulkCreateProducts(rows: ProductInput[]): Promise<{ count: number; items: Product[] }> {
     const res = await fetch(`${API_BASE}/products/bulk`, {
       method: 'POST',
@@ -143,6 +824,22 @@ export const api = {
     if (!res.ok) throw new Error(await res.text())
     return res.json()
   },
+  async importProducts(file: File, opts: ProductImportOptions = {}): Promise<ProductImportResult> {
+    const form = new FormData()
+    form.append('file', file)
+    if (opts.mapping) form.append('mapping', JSON.stringify(opts.mapping))
+    if (opts.match) form.append('match', opts.match)
+    if (opts.mode) form.append('mode', opts.mode)
+    if (opts.ignore?.length) form.append('ignore', opts.ignore.join(','))
+    if (opts.dry_run) form.append('dry_run', 'true')
+    if (opts.adjust_stock) form.append('adjust_stock', 'true')
+    if (opts.manager_pin) form.append('manager_pin', opts.manager_pin)
+    const res = await fetch(`${API_BASE}/products/import`, { method: 'POST', body: form })
+    // 422 = all_or_nothing dibatalkan; hasil per baris tetap dikirim
+    if (!res.ok && res.status !== 422) throw new Error(await res.text())
+    return res.json()
+  },
+  importReportUrl: (name: string) => `${API_BASE}/products/import/reports/${encodeURIComponent(name)}`,
 
   listBranches: () => request<Branch[]>('/branches'),
   createBranch: (payload: Partial<Branch>) =>
@@ -151,31 +848,226 @@ export const api = {
     request<Branch>(`/branches/${id}`, { method: 'PUT', body: JSON.stringify(payload) }),
   deleteBranch: (id: string) => request<void>(`/branches/${id}`, { method: 'DELETE' }),
 
+  listCategories: () => request<Category[]>('/categories'),
+  createCategory: (payload: { name: string; parent_id?: string }) =>
+    request<Category>('/categories', { method: 'POST', body: JSON.stringify(payload) }),
+  updateCategory: (id: string, payload: { name: string; parent_id?: string }) =>
+    request<Category>(`/categories/${id}`, { method: 'PUT', body: JSON.stringify(payload) }),
+  deleteCategory: (id: string) => request<void>(`/categories/${id}`, { method: 'DELETE' }),
+
+  listBrands: () => request<Brand[]>('/brands'),
+  createBrand: (name: string) => request<Brand>('/brands', { method: 'POST', body: JSON.stringify({ name }) }),
+  updateBrand: (id: string, name: string) =>
+    request<Brand>(`/brands/${id}`, { method: 'PUT', body: JSON.stringify({ name }) }),
+  deleteBrand: (id: string) => request<void>(`/brands/${id}`, { method: 'DELETE' }),
+
+  listCustomers: (q = '') => request<Customer[]>(`/customers${q ? `?q=${encodeURIComponent(q)}` : ''}`),
+  getCustomer: (id: string) => request<Customer>(`/customers/${id}`),
+  createCustomer: (payload: Partial<Customer>) =>
+    request<Customer>('/customers', { method: 'POST', body: JSON.stringify(payload) }),
+  updateCustomer: (id: string, payload: Partial<Customer>) =>
+    request<Customer>(`/customers/${id}`, { method: 'PUT', body: JSON.stringify(payload) }),
+  deleteCustomer: (id: string) => request<void>(`/customers/${id}`, { method: 'DELETE' }),
+  customerSales: (id: string) =>
+    request<{ customer: Customer; orders: number; total_spent: number; hutang: number; outstanding: number; last_purchase: string | null; sales: Sale[] }>(`/customers/${id}/sales`),
+
+  listSuppliers: (q = '') => request<Supplier[]>(`/suppliers${q ? `?q=${encodeURIComponent(q)}` : ''}`),
+  getSupplier: (id: string) => request<Supplier>(`/suppliers/${id}`),
+  createSupplier: (payload: Partial<Supplier>) =>
+    request<Supplier>('/suppliers', { method: 'POST', body: JSON.stringify(payload) }),
+  updateSupplier: (id: string, payload: Partial<Supplier>) =>
+    request<Supplier>(`/suppliers/${id}`, { method: 'PUT', body: JSON.stringify(payload) }),
+  deleteSupplier: (id: string) => request<void>(`/suppliers/${id}`, { method: 'DELETE' }),
+
+  listPurchaseOrders: (params: { status?: PurchaseOrderStatus; supplier_id?: string; branch_id?: string; start?: string; end?: string } = {}) => {
+    const qs = new URLSearchParams(Object.entries(params).filter(([, v]) => v) as [string, string][]).toString()
+    return request<PurchaseOrder[]>(`/purchase-orders${qs ? `?${qs}` : ''}`)
+  },
+  getPurchaseOrder: (id: string) => request<PurchaseOrder>(`/purchase-orders/${id}`),
+  createPurchaseOrder: (payload: PurchaseOrderPayload) =>
+    request<PurchaseOrder>('/purchase-orders', { method: 'POST', body: JSON.stringify(payload) }),
+  updatePurchaseOrder: (id: string, payload: PurchaseOrderPayload) =>
+    request<PurchaseOrder>(`/purchase-orders/${id}`, { method: 'PUT', body: JSON.stringify(payload) }),
+  deletePurchaseOrder: (id: string) => request<void>(`/purchase-orders/${id}`, { method: 'DELETE' }),
+  sendPurchaseOrder: (id: string) => request<PurchaseOrder>(`/purchase-orders/${id}/send`, { method: 'POST' }),
+  closePurchaseOrder: (id: string) => request<PurchaseOrder>(`/purchase-orders/${id}/close`, { method: 'POST' }),
+  listGoodsReceipts: (params: { purchase_order_id?: string; supplier_id?: string; product_id?: string; start?: string; end?: string } = {}) => {
+    const qs = new URLSearchParams(Object.entries(params).filter(([, v]) => v) as [string, string][]).toString()
+    return request<GoodsReceipt[]>(`/goods-receipts${qs ? `?${qs}` : ''}`)
+  },
+  getGoodsReceipt: (id: string) => request<GoodsReceipt>(`/goods-receipts/${id}`),
+  createGoodsReceipt: (payload: GoodsReceiptPayload) =>
+    request<GoodsReceipt>('/goods-receipts', { method: 'POST', body: JSON.stringify(payload) }),
+  listTransfers: (params: { status?: StockTransferStatus; direction?: 'incoming' | 'outgoing' } = {}) => {
+    const qs = new URLSearchParams(Object.entries(params).filter(([, v]) => v) as [string, string][]).toString()
+    return request<StockTransfer[]>(`/transfers${qs ? `?${qs}` : ''}`)
+  },
+  getTransfer: (id: string) => request<StockTransfer>(`/transfers/${id}`),
+  createTransfer: (payload: { from_branch_id: string; to_branch_id?: string; requested_by?: string; note?: string; items: { product_id: string; unit?: string; qty: number }[] }) =>
+    request<StockTransfer>('/transfers', { method: 'POST', body: JSON.stringify(payload) }),
+  // items yang tidak disebut memakai qty yang diminta / dikirim
+  dispatchTransfer: (id: string, payload: { dispatched_by?: string; items?: { id: string; qty: number }[] } = {}) =>
+    request<StockTransfer>(`/transfers/${id}/dispatch`, { method: 'POST', body: JSON.stringify(payload) }),
+  receiveTransfer: (id: string, payload: { received_by?: string; items?: { id: string; qty: number; note?: string }[] } = {}) =>
+    request<StockTransfer>(`/transfers/${id}/receive`, { method: 'POST', body: JSON.stringify(payload) }),
+  cancelTransfer: (id: string) => request<StockTransfer>(`/transfers/${id}/cancel`, { method: 'POST' }),
+  listReorderLevels: (filters: { branch_id?: string; product_id?: string } = {}) =>
+    request<ReorderLevel[]>(`/inventory/reorder-levels?${toQuery(filters)}`),
+  setReorderLevel: (productId: string, payload: { branch_id?: string; min_stock: number; reorder_qty: number; supplier_id?: string }) =>
+    request<ReorderLevel>(`/products/${productId}/reorder-level`, { method: 'PUT', body: JSON.stringify(payload) }),
+  deleteReorderLevel: (productId: string, branchId?: string) =>
+    request<void>(`/products/${productId}/reorder-level?${toQuery({ branch_id: branchId })}`, { method: 'DELETE' }),
+  lowStock: () => request<LowStockItem[]>('/inventory/low-stock'),
+  listStockAdjustments: (filters: { status?: string; reason?: AdjustmentReason; branch_id?: string; start?: string; end?: string } = {}) =>
+    request<StockAdjustment[]>(`/stock-adjustments?${toQuery(filters)}`),
+  getStockAdjustment: (id: string) => request<StockAdjustment>(`/stock-adjustments/${id}`),
+  // status 'pending' berarti menunggu persetujuan manajer
+  createStockAdjustment: (payload: { reason: AdjustmentReason; note?: string; created_by?: string; manager_pin?: string; items: { product_id: string; unit?: string; qty: number; note?: string }[] }) =>
+    request<StockAdjustment>('/stock-adjustments', { method: 'POST', body: JSON.stringify(payload) }),
+  approveStockAdjustment: (id: string, payload: { manager_pin: string; approved_by?: string }) =>
+    request<StockAdjustment>(`/stock-adjustments/${id}/approve`, { method: 'POST', body: JSON.stringify(payload) }),
+  rejectStockAdjustment: (id: string, payload: { manager_pin: string; approved_by?: string }) =>
+    request<StockAdjustment>(`/stock-adjustments/${id}/reject`, { method: 'POST', body: JSON.stringify(payload) }),
+  stockAdjustmentReport: (params: { start?: string; end?: string; branch_id?: string } = {}) =>
+    request<StockAdjustmentReport>(`/reports/stock-adjustments?${toQuery(params)}`),
+  downloadStockAdjustmentReport: async (params: { start?: string; end?: string; branch_id?: string } = {}) => {
+    const res = await fetch(`${API_BASE}/reports/stock-adjustments?${toQuery({ ...params, format: 'xlsx' })}`);
+    if (!res.ok) throw new Error(await res.text());
+    const blob = await res.blob();
+    return URL.createObjectURL(blob);
+  },
+  listBatches: (filters: { branch_id?: string; product_id?: string; all?: boolean } = {}) =>
+    request<StockBatch[]>(`/inventory/batches?${toQuery(filters)}`),
+  expiryReport: (days?: number) => request<ExpiryReport>(`/inventory/expiry?${toQuery({ days })}`),
+  downloadExpiryReport: async (days?: number) => {
+    const res = await fetch(`${API_BASE}/inventory/expiry?${toQuery({ days, format: 'xlsx' })}`);
+    if (!res.ok) throw new Error(await res.text());
+    const blob = await res.blob();
+    return URL.createObjectURL(blob);
+  },
+  writeOffBatch: (id: string, payload: { qty?: number; note?: string; performed_by?: string } = {}) =>
+    request<StockBatch>(`/inventory/batches/${id}/write-off`, { method: 'POST', body: JSON.stringify(payload) }),
+  listStockOpnames: (filters: { status?: OpnameStatus; branch_id?: string } = {}) =>
+    request<StockOpname[]>(`/stock-opname?${toQuery(filters)}`),
+  startStockOpname: (payload: { category_id?: string; performed_by?: string; note?: string } = {}) =>
+    request<StockOpnameSession>('/stock-opname/sessions', { method: 'POST', body: JSON.stringify(payload) }),
+  getStockOpname: (id: string) => request<StockOpnameSession>(`/stock-opname/${id}`),
+  uncountedOpnameProducts: (id: string) => request<UncountedProduct[]>(`/stock-opname/${id}/uncounted`),
+  listOpnameCounts: (id: string, filters: { product_id?: string; all?: boolean } = {}) =>
+    request<StockOpnameCount[]>(`/stock-opname/${id}/counts?${toQuery(filters)}`),
+  // code = hasil scan (qty bawaan 1); recount menggantikan hitungan sebelumnya
+  addOpnameCount: (id: string, payload: { product_id?: string; code?: string; unit?: string; qty?: number; recount?: boolean; counted_by?: string }) =>
+    request<{ count: StockOpnameCount; item: StockOpnameItem }>(`/stock-opname/${id}/counts`, { method: 'POST', body: JSON.stringify(payload) }),
+  deleteOpnameCount: (id: string, countId: string) =>
+    request<StockOpnameItem>(`/stock-opname/${id}/counts/${countId}`, { method: 'DELETE' }),
+  finalizeStockOpname: (id: string, payload: { uncounted?: 'keep' | 'zero'; performed_by?: string } = {}) =>
+    request<StockOpnameSession>(`/stock-opname/${id}/finalize`, { method: 'POST', body: JSON.stringify(payload) }),
+  cancelStockOpname: (id: string) =>
+    request<StockOpnameSession>(`/stock-opname/${id}/cancel`, { method: 'POST' }),
+  writeOffExpired: (payload: { note?: string; performed_by?: string } = {}) =>
+    request<{ count: number; value: number; batches: StockBatch[] }>('/inventory/batches/write-off-expired', { method: 'POST', body: JSON.stringify(payload) }),
+  reorderSuggestions: (params: { days?: number; cover_days?: number; supplier_id?: string } = {}) =>
+    request<ReorderSuggestion[]>(`/inventory/reorder-suggestions?${toQuery(params)}`),
+  createSuggestedPurchaseOrder: (payload: { supplier_id: string; product_ids?: string[]; days?: number; cover_days?: number; expected_at?: string; note?: string; created_by?: string }) =>
+    request<PurchaseOrder>('/inventory/reorder-suggestions/purchase-order', { method: 'POST', body: JSON.stringify(payload) }),
+  downloadPurchaseOrder: async (id: string) => {
+    const res = await fetch(`${API_BASE}/purchase-orders/${id}/document`);
+    if (!res.ok) throw new Error(await res.text());
+    const blob = await res.blob();
+    return URL.createObjectURL(blob);
+  },
+
   createSale: (payload: { 
     branch_id: string
+    customer_id?: string
+    // links the sale to the till's open cash session
+    till_id?: string
     receipt_no: string
     payment_method: string
     notes: string
     // optional created_at ISO date/time (e.g. 2025-12-17 or 2025-12-17T14:00:00Z)
     created_at?: string
-    items: { product_id: string; qty: number; price: number }[] 
+    // required when an item price differs from the branch's tier price
+    manager_pin?: string
+    items: { product_id: string; qty: number; unit?: string; price: number }[] 
   }) =>
     request<Sale>('/sales', { method: 'POST', body: JSON.stringify(payload) }),
 
-  listSales: () => request<Sale[]>('/sales'),
+  // Server-rendered receipt; every non-preview fetch counts as a print
+  fetchReceipt: async (saleId: string, format: 'text' | 'escpos' | 'pdf', opts: { width?: 58 | 80; preview?: boolean } = {}) => {
+    const params = new URLSearchParams({ format })
+    if (opts.width) params.set('width', String(opts.width))
+    if (opts.preview) params.set('preview', '1')
+    const res = await fetch(`${API_BASE}/sales/${saleId}/receipt?${params.toString()}`)
+    if (!res.ok) throw new Error(await res.text())
+    return res.blob()
+  },
+  getReceiptTemplate: (branchId = '') =>
+    request<ReceiptTemplate>(`/receipt-template?branch_id=${encodeURIComponent(branchId)}`),
+  saveReceiptTemplate: (payload: ReceiptTemplate) =>
+    request<ReceiptTemplate>('/receipt-template', { method: 'PUT', body: JSON.stringify(payload) }),
+
+  listSales: (filters: SaleFilters = {}) => request<Sale[]>(`/sales?${toQuery(filters)}`),
+  listSalesPage: (filters: SaleFilters & { page?: number; size?: number } = {}) =>
+    request<Page<Sale>>(`/sales?${toQuery({ page: 1, ...filters })}`),
   getSale: (id: string) => request<Sale>(`/sales/${id}`),
-  updateSale: (id: string, payload: { created_at?: string; branch_id?: string; payment_method?: string; notes?: string }) =>
+  updateSale: (id: string, payload: { created_at?: string; branch_id?: string; customer_id?: string; payment_method?: string; notes?: string }) =>
     request<Sale>(`/sales/${id}`, { method: 'PUT', body: JSON.stringify(payload) }),
   deleteSale: (id: string) => request<void>(`/sales/${id}`, { method: 'DELETE' }),
   
   // Sale Items CRUD
-  updateSaleItem: (saleId: string, itemId: string, payload: { qty: number; price: number }) =>
+  updateSaleItem: (saleId: string, itemId: string, payload: { qty: number; unit?: string; price: number; manager_pin?: string }) =>
     request<SaleItem>(`/sales/${saleId}/items/${itemId}`, { method: 'PUT', body: JSON.stringify(payload) }),
-  addSaleItem: (saleId: string, payload: { product_id: string; qty: number; price?: number }) =>
+  addSaleItem: (saleId: string, payload: { product_id: string; qty: number; unit?: string; price?: number; manager_pin?: string }) =>
     request<SaleItem>(`/sales/${saleId}/items`, { method: 'POST', body: JSON.stringify(payload) }),
   deleteSaleItem: (saleId: string, itemId: string) => 
     request<void>(`/sales/${saleId}/items/${itemId}`, { method: 'DELETE' }),
+
+  // Draft / parked carts (never touch stock until posted)
+  listDrafts: (params: { status?: 'open' | 'parked'; branch_id?: string; till_id?: string } = {}) =>
+    request<DraftSale[]>(`/drafts?${new URLSearchParams(params as Record<string, string>).toString()}`),
+  getDraft: (id: string) => request<DraftSale>(`/drafts/${id}`),
+  createDraft: (payload: Partial<Pick<DraftSale, 'label' | 'branch_id' | 'till_id' | 'notes'>> & { items?: { product_id: string; qty: number; unit?: string; price?: number }[] }) =>
+    request<DraftSale>('/drafts', { method: 'POST', body: JSON.stringify(payload) }),
+  addDraftItem: (id: string, payload: { product_id: string; qty: number; unit?: string; price?: number }) =>
+    request<DraftSale>(`/drafts/${id}/items`, { method: 'POST', body: JSON.stringify(payload) }),
+  updateDraftItem: (id: string, itemId: string, payload: { qty: number; unit?: string; price?: number }) =>
+    request<DraftSale>(`/drafts/${id}/items/${itemId}`, { method: 'PUT', body: JSON.stringify(payload) }),
+  deleteDraftItem: (id: string, itemId: string) =>
+    request<DraftSale>(`/drafts/${id}/items/${itemId}`, { method: 'DELETE' }),
+  parkDraft: (id: string, label: string) =>
+    request<DraftSale>(`/drafts/${id}/park`, { method: 'POST', body: JSON.stringify({ label }) }),
+  resumeDraft: (id: string, till_id: string) =>
+    request<DraftSale>(`/drafts/${id}/resume`, { method: 'POST', body: JSON.stringify({ till_id }) }),
+  postDraft: (id: string, payload: { payment_method?: string; payments?: { method: string; amount: number; reference?: string }[]; receipt_no?: string; manager_pin?: string }) =>
+    request<Sale>(`/drafts/${id}/post`, { method: 'POST', body: JSON.stringify(payload) }),
+  deleteDraft: (id: string) => request<void>(`/drafts/${id}`, { method: 'DELETE' }),
+
+  // Cash drawer sessions (shift kasir)
+  listCashSessions: (params: { status?: 'open' | 'closed'; branch_id?: string; till_id?: string } = {}) =>
+    request<CashSession[]>(`/cash-sessions?${new URLSearchParams(params as Record<string, string>).toString()}`),
+  currentCashSession: (tillId: string) => request<CashSession>(`/cash-sessions/current?till_id=${encodeURIComponent(tillId)}`),
+  openCashSession: (payload: { branch_id?: string; till_id: string; cashier: string; opening_float: number }) =>
+    request<CashSession>('/cash-sessions', { method: 'POST', body: JSON.stringify(payload) }),
+  addCashMovement: (id: string, payload: { type: 'pay_in' | 'pay_out'; amount: number; reason?: string }) =>
+    request<CashMovement>(`/cash-sessions/${id}/movements`, { method: 'POST', body: JSON.stringify(payload) }),
+  closeCashSession: (id: string, payload: { counted_cash: number; note?: string }) =>
+    request<CashSessionReport>(`/cash-sessions/${id}/close`, { method: 'POST', body: JSON.stringify(payload) }),
+  cashSessionReport: (id: string) => request<CashSessionReport>(`/cash-sessions/${id}/report`),
+  downloadCashSessionReport: async (id: string) => {
+    const res = await fetch(`${API_BASE}/cash-sessions/${id}/report?format=xlsx`);
+    if (!res.ok) throw new Error(await res.text());
+    const blob = await res.blob();
+    return URL.createObjectURL(blob);
+  },
   
+  exportProducts: async (format: 'xlsx' | 'csv' = 'xlsx') => {
+    const res = await fetch(`${API_BASE}/products/export?format=${format}`);
+    if (!res.ok) throw new Error(await res.text());
+    const blob = await res.blob();
+    return URL.createObjectURL(blob);
+  },
+
   exportSales: async () => {
     const res = await fetch(`${API_BASE}/sales/export`);
     if (!res.ok) throw new Error(await res.text());
@@ -184,8 +1076,9 @@ export const api = {
     return url;
   },
 
+  /** @deprecated one-shot opname; use startStockOpname and the session endpoints */
   createStockOpname: (payload: { branch_id: string; note: string; items: { product_id: string; qty_system: number; qty_physical: number }[] }) =>
-    request('/stock-opname', { method: 'POST', body: JSON.stringify(payload) }),
+    request<StockOpnameSession>('/stock-opname', { method: 'POST', body: JSON.stringify(payload) }),
 
   downloadSalesReport: async (start: string, end: string) => {
     const res = await fetch(`${API_BASE}/reports/sales?start=${encodeURIComponent(start)}&end=${encodeURIComponent(end)}`);
@@ -227,6 +1120,13 @@ export const api = {
     return request<SalesAnalytics>(`/analytics/sales${suffix}`);
   },
 
+  marginReport: (params: { by?: 'product' | 'category' | 'branch' | 'day'; start?: string; end?: string; branch_id?: string } = {}) => {
+    const qs = new URLSearchParams();
+    Object.entries(params).forEach(([k, v]) => { if (v) qs.append(k, v) });
+    const suffix = qs.toString() ? `?${qs.toString()}` : '';
+    return request<MarginRow[]>(`/reports/margins${suffix}`);
+  },
+
   syncSummary: () => request<SyncSummary>('/sync/summary'),
   syncRun: () => request<{ status: string }>('/sync/run', { method: 'POST' }),
 };